@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/packages"
+)
+
+// coreDefinitionPrefix starts the URLs of the StructureDefinitions of the
+// specification, as opposed to those of profiles and logical models
+const coreDefinitionPrefix = "http://hl7.org/fhir/StructureDefinition/"
+
+// restfulInteractionSystem is the code system of the RESTful interactions
+const restfulInteractionSystem = "http://hl7.org/fhir/restful-interaction"
+
+// catalogDefinition holds the members of a StructureDefinition that decide
+// whether it defines a concrete resource type or datatype
+type catalogDefinition struct {
+	URL        string `json:"url"`
+	Type       string `json:"type"`
+	Kind       string `json:"kind"`
+	Abstract   bool   `json:"abstract"`
+	Derivation string `json:"derivation"`
+}
+
+// catalogSearchParameter holds the members of a SearchParameter that the
+// catalog lists
+type catalogSearchParameter struct {
+	Code string   `json:"code"`
+	Base []string `json:"base"`
+	Type string   `json:"type"`
+}
+
+// catalogCodeSystem holds the concepts of a CodeSystem
+type catalogCodeSystem struct {
+	URL     string           `json:"url"`
+	Concept []catalogConcept `json:"concept"`
+}
+
+type catalogConcept struct {
+	Code    string           `json:"code"`
+	Concept []catalogConcept `json:"concept"`
+}
+
+// catalog is the content of a generated catalog
+type catalog struct {
+	resourceTypes    []string
+	dataTypes        []string
+	searchParameters map[string][]catalogSearchParameter
+	interactions     []string
+}
+
+// writeCatalog generates the catalog of the FHIR version of a core package
+// into catalog_<name>.go in outputDir, and returns the file's path
+func writeCatalog(name, cacheDir, pkg, outputDir string) (string, error) {
+	loader := packages.NewLoader()
+	loader.CacheDir = cacheDir
+	p, err := loadPackage(loader, pkg)
+	if err != nil {
+		return "", err
+	}
+	fhirVersion := p.Version
+	if len(p.FHIRVersions) > 0 {
+		fhirVersion = p.FHIRVersions[0]
+	}
+	src, err := generateCatalog(name, fhirVersion, p.Resources)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", p.ID(), err)
+	}
+	path := filepath.Join(outputDir, "catalog_"+name+".go")
+	if err := os.WriteFile(path, src, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// generateCatalog generates the catalog of a FHIR version, the variable
+// <name>Catalog in package version, from the resources of its core package
+func generateCatalog(name, fhirVersion string, resources []*packages.Resource) ([]byte, error) {
+	c := &catalog{searchParameters: make(map[string][]catalogSearchParameter)}
+	for _, r := range resources {
+		if err := c.add(r.ResourceType, r.URL, r.Data); err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Filename, err)
+		}
+	}
+	if len(c.resourceTypes) == 0 {
+		return nil, fmt.Errorf("no resource types defined; is this a core package?")
+	}
+	sort.Strings(c.resourceTypes)
+	sort.Strings(c.dataTypes)
+	for base, params := range c.searchParameters {
+		sort.SliceStable(params, func(i, j int) bool { return params[i].Code < params[j].Code })
+		c.searchParameters[base] = params
+	}
+	return c.emit(name, fhirVersion)
+}
+
+// catalogBundle holds the resources of a Bundle, such as the
+// search-parameters.json of core packages
+type catalogBundle struct {
+	Entry []struct {
+		Resource json.RawMessage `json:"resource"`
+	} `json:"entry"`
+}
+
+// add adds what a resource of the package defines to the catalog. The
+// resources of a Bundle are added in turn.
+func (c *catalog) add(resourceType, url string, data json.RawMessage) error {
+	switch resourceType {
+	case "StructureDefinition":
+		var sd catalogDefinition
+		if err := json.Unmarshal(data, &sd); err != nil {
+			return err
+		}
+		if !strings.HasPrefix(sd.URL, coreDefinitionPrefix) || sd.Abstract || sd.Derivation != "specialization" {
+			return nil
+		}
+		switch sd.Kind {
+		case "resource":
+			c.resourceTypes = appendNew(c.resourceTypes, sd.Type)
+		case "primitive-type", "complex-type":
+			c.dataTypes = appendNew(c.dataTypes, sd.Type)
+		}
+	case "SearchParameter":
+		var sp catalogSearchParameter
+		if err := json.Unmarshal(data, &sp); err != nil {
+			return err
+		}
+		for _, base := range sp.Base {
+			c.searchParameters[base] = append(c.searchParameters[base], sp)
+		}
+	case "CodeSystem":
+		if url != restfulInteractionSystem {
+			return nil
+		}
+		var cs catalogCodeSystem
+		if err := json.Unmarshal(data, &cs); err != nil {
+			return err
+		}
+		c.interactions = flattenConcepts(cs.Concept)
+	case "Bundle":
+		var bundle catalogBundle
+		if err := json.Unmarshal(data, &bundle); err != nil {
+			return err
+		}
+		for i, entry := range bundle.Entry {
+			var head struct {
+				ResourceType string `json:"resourceType"`
+				URL          string `json:"url"`
+			}
+			if err := json.Unmarshal(entry.Resource, &head); err != nil || head.ResourceType == "" {
+				continue
+			}
+			if err := c.add(head.ResourceType, head.URL, entry.Resource); err != nil {
+				return fmt.Errorf("entry %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// appendNew appends s to list unless list holds it already, as when a
+// definition is both in its own file and in a Bundle
+func appendNew(list []string, s string) []string {
+	for _, item := range list {
+		if item == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// flattenConcepts returns the codes of concepts and their children
+func flattenConcepts(concepts []catalogConcept) []string {
+	var codes []string
+	for _, concept := range concepts {
+		codes = append(codes, concept.Code)
+		codes = append(codes, flattenConcepts(concept.Concept)...)
+	}
+	return codes
+}
+
+func (c *catalog) emit(name, fhirVersion string) ([]byte, error) {
+	f := &file{header: "// Code generated by cmd/generator. DO NOT EDIT.", pkg: "version", imports: make(map[string]bool)}
+	f.printf("// %sCatalog lists what FHIR %s defines\n", name, fhirVersion)
+	f.printf("var %sCatalog = &Catalog{\n", name)
+	f.printf("FHIRVersion: %q,\n", fhirVersion)
+	f.printf("ResourceTypes: []string{\n%s},\n", quoteList(c.resourceTypes))
+	f.printf("DataTypes: []string{\n%s},\n", quoteList(c.dataTypes))
+
+	bases := make([]string, 0, len(c.searchParameters))
+	for base := range c.searchParameters {
+		bases = append(bases, base)
+	}
+	sort.Strings(bases)
+	f.printf("SearchParameters: map[string][]SearchParameter{\n")
+	for _, base := range bases {
+		f.printf("%q: {\n", base)
+		var last string
+		for _, sp := range c.searchParameters[base] {
+			if sp.Code == last {
+				continue
+			}
+			last = sp.Code
+			f.printf("{Code: %q, Type: %q},\n", sp.Code, sp.Type)
+		}
+		f.printf("},\n")
+	}
+	f.printf("},\n")
+	f.printf("Interactions: []string{\n%s},\n", quoteList(c.interactions))
+	f.printf("}\n")
+	return f.source()
+}
+
+// quoteList returns strings as the lines of a Go list literal, several to a
+// line
+func quoteList(items []string) string {
+	var b strings.Builder
+	line := 0
+	for _, item := range items {
+		quoted := fmt.Sprintf("%q,", item)
+		if line > 0 && line+len(quoted) > 90 {
+			b.WriteString("\n")
+			line = 0
+		}
+		if line > 0 {
+			b.WriteString(" ")
+			line++
+		}
+		b.WriteString(quoted)
+		line += len(quoted)
+	}
+	if line > 0 {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
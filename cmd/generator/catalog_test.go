@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/packages"
+)
+
+// catalogResources returns the resources of a small core package
+func catalogResources(t *testing.T) []*packages.Resource {
+	var resources []*packages.Resource
+	for _, data := range []string{
+		`{"resourceType": "StructureDefinition", "url": "http://hl7.org/fhir/StructureDefinition/Patient", "type": "Patient", "kind": "resource", "derivation": "specialization"}`,
+		`{"resourceType": "StructureDefinition", "url": "http://hl7.org/fhir/StructureDefinition/Account", "type": "Account", "kind": "resource", "derivation": "specialization"}`,
+		`{"resourceType": "StructureDefinition", "url": "http://hl7.org/fhir/StructureDefinition/DomainResource", "type": "DomainResource", "kind": "resource", "abstract": true, "derivation": "specialization"}`,
+		`{"resourceType": "StructureDefinition", "url": "http://hl7.org/fhir/StructureDefinition/vitalsigns", "type": "Observation", "kind": "resource", "derivation": "constraint"}`,
+		`{"resourceType": "StructureDefinition", "url": "http://hl7.org/fhir/StructureDefinition/HumanName", "type": "HumanName", "kind": "complex-type", "derivation": "specialization"}`,
+		`{"resourceType": "StructureDefinition", "url": "http://hl7.org/fhir/StructureDefinition/boolean", "type": "boolean", "kind": "primitive-type", "derivation": "specialization"}`,
+		`{"resourceType": "StructureDefinition", "url": "http://hl7.org/fhir/StructureDefinition/Definition", "type": "Definition", "kind": "logical", "derivation": "specialization"}`,
+		`{"resourceType": "SearchParameter", "url": "http://hl7.org/fhir/SearchParameter/Patient-name", "code": "name", "base": ["Patient"], "type": "string"}`,
+		`{"resourceType": "SearchParameter", "url": "http://hl7.org/fhir/SearchParameter/Patient-birthdate", "code": "birthdate", "base": ["Patient"], "type": "date"}`,
+		`{"resourceType": "SearchParameter", "url": "http://hl7.org/fhir/SearchParameter/Resource-id", "code": "_id", "base": ["Resource"], "type": "token"}`,
+		`{"resourceType": "SearchParameter", "url": "http://hl7.org/fhir/SearchParameter/individual-phone", "code": "phone", "base": ["Patient", "Person"], "type": "token"}`,
+		`{"resourceType": "CodeSystem", "url": "http://hl7.org/fhir/restful-interaction", "concept": [
+			{"code": "read"},
+			{"code": "search", "concept": [{"code": "search-type"}, {"code": "search-system"}]}
+		]}`,
+		`{"resourceType": "CodeSystem", "url": "http://hl7.org/fhir/administrative-gender", "concept": [{"code": "male"}]}`,
+		`{"resourceType": "Bundle", "id": "searchParams", "type": "collection", "entry": [
+			{"fullUrl": "http://hl7.org/fhir/SearchParameter/individual-gender", "resource":
+				{"resourceType": "SearchParameter", "url": "http://hl7.org/fhir/SearchParameter/individual-gender", "code": "gender", "base": ["Patient", "Person"], "type": "token"}},
+			{"fullUrl": "http://hl7.org/fhir/SearchParameter/Patient-birthdate", "resource":
+				{"resourceType": "SearchParameter", "url": "http://hl7.org/fhir/SearchParameter/Patient-birthdate", "code": "birthdate", "base": ["Patient"], "type": "date"}}
+		]}`,
+	} {
+		var head struct {
+			ResourceType string `json:"resourceType"`
+			URL          string `json:"url"`
+		}
+		if err := json.Unmarshal([]byte(data), &head); err != nil {
+			t.Fatalf("bad test resource: %v", err)
+		}
+		resources = append(resources, &packages.Resource{ResourceType: head.ResourceType, URL: head.URL, Data: json.RawMessage(data)})
+	}
+	return resources
+}
+
+func TestGenerateCatalog(t *testing.T) {
+	src, err := generateCatalog("r4", "4.0.1", catalogResources(t))
+	if err != nil {
+		t.Fatalf("generateCatalog failed: %v", err)
+	}
+	for _, want := range []string{
+		"// Code generated by cmd/generator. DO NOT EDIT.",
+		"// r4Catalog lists what FHIR 4.0.1 defines\nvar r4Catalog = &Catalog{",
+		"ResourceTypes: []string{\n\t\t\"Account\", \"Patient\",\n\t},",
+		"DataTypes: []string{\n\t\t\"HumanName\", \"boolean\",\n\t},",
+		"\"Patient\": {\n\t\t\t{Code: \"birthdate\", Type: \"date\"},\n\t\t\t{Code: \"gender\", Type: \"token\"},\n\t\t\t{Code: \"name\", Type: \"string\"},\n\t\t\t{Code: \"phone\", Type: \"token\"},\n\t\t},",
+		"\"Person\": {\n\t\t\t{Code: \"gender\", Type: \"token\"},\n\t\t\t{Code: \"phone\", Type: \"token\"},",
+		"\"Resource\": {\n\t\t\t{Code: \"_id\", Type: \"token\"},",
+		"Interactions: []string{\n\t\t\"read\", \"search\", \"search-type\", \"search-system\",\n\t},",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("expected %q in\n%s", want, src)
+		}
+	}
+	for _, unwanted := range []string{"DomainResource", "Observation", "Definition", "male"} {
+		if strings.Contains(string(src), unwanted) {
+			t.Errorf("unexpected %s in\n%s", unwanted, src)
+		}
+	}
+
+	if _, err := generateCatalog("r4", "4.0.1", catalogResources(t)[7:]); err == nil {
+		t.Error("expected an error for a package without resource types")
+	}
+}
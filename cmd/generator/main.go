@@ -1,42 +1,63 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-)
-
-type StructureDefinition struct {
-	ResourceType string `json:"resourceType"`
-	Type         string `json:"type"`
-	Name         string `json:"name"`
-	Snapshot     struct {
-		Element []Element `json:"element"`
-	} `json:"snapshot"`
-}
+	"path/filepath"
+	"strings"
 
-type Element struct {
-	Path       string `json:"path"`
-	Min        int    `json:"min"`
-	Max        string `json:"max"`
-	Type       []Type `json:"type"`
-	Definition string `json:"definition"`
-}
-
-type Type struct {
-	Code string `json:"code"`
-}
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/packages"
+)
 
 func main() {
 	var (
-		inputDir  = flag.String("input", "", "Directory containing FHIR StructureDefinitions")
-		outputDir = flag.String("output", "pkg/models", "Output directory for generated Go files")
+		inputDir    = flag.String("input", "", "Directory containing FHIR StructureDefinitions")
+		pkgs        = flag.String("package", "", "Comma-separated FHIR packages to read StructureDefinitions from, as name#version")
+		cacheDir    = flag.String("cache", packages.DefaultCacheDir(), "FHIR package cache directory")
+		outputDir   = flag.String("output", "pkg/models", "Output directory for generated Go files")
+		profilesPkg = flag.String("profiles", "", "Generate wrapper types for the profiles in the input into this Go package, e.g. uscore")
+		catalogName = flag.String("catalog", "", "Generate the catalog of the FHIR version of a core package into package version, e.g. r4")
 	)
 	flag.Parse()
 
-	if *inputDir == "" {
-		log.Fatal("Input directory is required")
+	if *catalogName != "" {
+		if *pkgs == "" {
+			log.Fatal("The core package is required")
+		}
+		path, err := writeCatalog(*catalogName, *cacheDir, *pkgs, *outputDir)
+		if err != nil {
+			log.Fatalf("Failed to generate catalog: %v", err)
+		}
+		fmt.Printf("Generated %s\n", path)
+		return
+	}
+
+	if *inputDir == "" && *pkgs == "" {
+		log.Fatal("Input directory or package is required")
+	}
+
+	// inputs are the definitions to generate code for; all also holds the
+	// definitions they depend on, such as those of extensions
+	var inputs, all []*models.StructureDefinition
+	if *inputDir != "" {
+		defs, err := readDir(*inputDir)
+		if err != nil {
+			log.Fatalf("Failed to read StructureDefinitions: %v", err)
+		}
+		inputs = append(inputs, defs...)
+		all = append(all, defs...)
+	}
+	if *pkgs != "" {
+		defs, deps, err := readPackages(*cacheDir, strings.Split(*pkgs, ","))
+		if err != nil {
+			log.Fatalf("Failed to load packages: %v", err)
+		}
+		inputs = append(inputs, defs...)
+		all = append(all, deps...)
 	}
 
 	// Create output directory if it doesn't exist
@@ -44,12 +65,102 @@ func main() {
 		log.Fatalf("Failed to create output directory: %v", err)
 	}
 
+	if *profilesPkg != "" {
+		g := newProfileGenerator(*profilesPkg, all)
+		files, err := g.generate(inputs)
+		if err != nil {
+			log.Fatalf("Failed to generate profiles: %v", err)
+		}
+		for _, warning := range g.warnings {
+			log.Printf("warning: %s", warning)
+		}
+		for name, src := range files {
+			if err := os.WriteFile(filepath.Join(*outputDir, name), src, 0644); err != nil {
+				log.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+		fmt.Printf("Generated %d files in %s\n", len(files), *outputDir)
+		return
+	}
+
 	// TODO: Implement the actual generation logic
 	fmt.Println("FHIR model generator initialized")
 	fmt.Printf("Input directory: %s\n", *inputDir)
+	fmt.Printf("Packages: %s\n", *pkgs)
+	fmt.Printf("StructureDefinitions: %d\n", len(inputs))
 	fmt.Printf("Output directory: %s\n", *outputDir)
 }
 
+// readDir reads the StructureDefinitions in the .json files in dir
+func readDir(dir string) ([]*models.StructureDefinition, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var definitions []*models.StructureDefinition
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var typeHolder struct {
+			ResourceType models.ResourceType `json:"resourceType"`
+		}
+		if err := json.Unmarshal(data, &typeHolder); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if typeHolder.ResourceType != models.ResourceTypeStructureDefinition {
+			continue
+		}
+		sd := models.NewStructureDefinition()
+		if err := json.Unmarshal(data, sd); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		definitions = append(definitions, sd)
+	}
+	return definitions, nil
+}
+
+// readPackages loads packages given as name#version, or paths to .tgz
+// files, with their dependencies. It returns the StructureDefinitions of
+// the packages named, and those of every package loaded.
+func readPackages(cacheDir string, names []string) (inputs, all []*models.StructureDefinition, err error) {
+	loader := packages.NewLoader()
+	loader.CacheDir = cacheDir
+
+	for _, name := range names {
+		p, err := loadPackage(loader, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, r := range p.Resources {
+			if r.ResourceType != string(models.ResourceTypeStructureDefinition) {
+				continue
+			}
+			sd := models.NewStructureDefinition()
+			if err := r.Decode(sd); err != nil {
+				return nil, nil, err
+			}
+			inputs = append(inputs, sd)
+		}
+	}
+	all, err = loader.StructureDefinitions()
+	if err != nil {
+		return nil, nil, err
+	}
+	return inputs, all, nil
+}
+
+// loadPackage loads a package given as name#version, or the path to a .tgz
+// file, with its dependencies
+func loadPackage(loader *packages.Loader, name string) (*packages.Package, error) {
+	if strings.HasSuffix(name, ".tgz") {
+		return loader.LoadFile(name)
+	}
+	id, version, _ := strings.Cut(strings.TrimSpace(name), "#")
+	return loader.Load(id, version)
+}
+
 // Helper function to convert FHIR types to Go types
 func fhirTypeToGoType(fhirType string) string {
 	switch fhirType {
@@ -58,11 +169,17 @@ func fhirTypeToGoType(fhirType string) string {
 	case "integer":
 		return "int"
 	case "decimal":
-		return "float64"
+		return "fhir.Decimal"
 	case "string", "code", "id", "uri", "url", "canonical", "markdown":
 		return "string"
-	case "date", "dateTime", "instant", "time":
-		return "time.Time"
+	case "date":
+		return "fhir.Date"
+	case "dateTime":
+		return "fhir.DateTime"
+	case "instant":
+		return "fhir.Instant"
+	case "time":
+		return "fhir.Time"
 	default:
 		return "interface{}"
 	}
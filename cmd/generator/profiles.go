@@ -0,0 +1,818 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+// profileModels are the resource models that profile wrapper types can be
+// generated over, keyed by resource type
+var profileModels = map[string]reflect.Type{
+	"Patient":             reflect.TypeOf(models.Patient{}),
+	"OperationOutcome":    reflect.TypeOf(models.OperationOutcome{}),
+	"Parameters":          reflect.TypeOf(models.Parameters{}),
+	"CodeSystem":          reflect.TypeOf(models.CodeSystem{}),
+	"ValueSet":            reflect.TypeOf(models.ValueSet{}),
+	"StructureDefinition": reflect.TypeOf(models.StructureDefinition{}),
+}
+
+// profileGenerator generates wrapper types for profiles: a type embedding
+// the model of the profiled resource, with accessors for the extensions and
+// slices the profile names
+type profileGenerator struct {
+	pkg      string
+	byURL    map[string]*models.StructureDefinition
+	warnings []string
+
+	// extensions holds the extension types used by the profiles, by URL
+	extensions map[string]*extensionType
+	names      map[string]string
+}
+
+// profileType is a profile wrapper type
+type profileType struct {
+	sd          *models.StructureDefinition
+	name        string
+	model       reflect.Type
+	extensions  []extensionAccessor
+	slices      []sliceAccessor
+	mustSupport []string
+}
+
+// extensionType is an extension a profile names. Simple extensions have a
+// value; complex extensions have parts, and a struct type is generated for them.
+type extensionType struct {
+	name  string
+	url   string
+	title string
+	value *valueType
+	parts []extensionPart
+}
+
+// extensionPart is a nested extension of a complex extension
+type extensionPart struct {
+	name     string
+	url      string
+	multiple bool
+	value    valueType
+}
+
+// valueType is the type of an extension's value: the models.Extension
+// field holding it and the Go type it points to
+type valueType struct {
+	field  string
+	goType string
+}
+
+// pointer reports whether single values are held by pointer. Strings are
+// held by value, with "" meaning absent.
+func (v valueType) pointer() bool {
+	return v.goType != "string"
+}
+
+// extensionAccessor is an extension slice of a profile
+type extensionAccessor struct {
+	name      string
+	sliceName string
+	ext       *extensionType
+	multiple  bool
+}
+
+// sliceAccessor is a slice of a repeating element of a profile
+type sliceAccessor struct {
+	name      string
+	sliceName string
+	element   string
+	field     string
+	itemType  string
+	pattern   string
+	multiple  bool
+}
+
+func newProfileGenerator(pkg string, definitions []*models.StructureDefinition) *profileGenerator {
+	g := &profileGenerator{
+		pkg:        pkg,
+		byURL:      make(map[string]*models.StructureDefinition),
+		extensions: make(map[string]*extensionType),
+		names:      make(map[string]string),
+	}
+	for _, sd := range definitions {
+		g.byURL[sd.URL] = sd
+	}
+	return g
+}
+
+func (g *profileGenerator) warnf(format string, args ...interface{}) {
+	g.warnings = append(g.warnings, fmt.Sprintf(format, args...))
+}
+
+// generate returns the source files for the resource profiles among
+// definitions, keyed by file name
+func (g *profileGenerator) generate(definitions []*models.StructureDefinition) (map[string][]byte, error) {
+	var selected []*models.StructureDefinition
+	perType := make(map[string]int)
+	for _, sd := range definitions {
+		if sd.Kind != "resource" || sd.Derivation != "constraint" || sd.Snapshot == nil {
+			continue
+		}
+		if _, ok := profileModels[sd.Type]; !ok {
+			g.warnf("%s: no model for %s", sd.URL, sd.Type)
+			continue
+		}
+		selected = append(selected, sd)
+		perType[sd.Type]++
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].URL < selected[j].URL })
+
+	var profiles []*profileType
+	for _, sd := range selected {
+		// A profile is named after its type unless several profile the same type
+		name := sd.Type
+		if perType[sd.Type] > 1 {
+			name = goName(strings.TrimSuffix(sd.Name, "Profile"))
+		}
+		if other, ok := g.names[name]; ok {
+			return nil, fmt.Errorf("%s and %s would both generate %s", other, sd.URL, name)
+		}
+		g.names[name] = sd.URL
+		profiles = append(profiles, g.profile(sd, name))
+	}
+
+	files := make(map[string][]byte)
+	for _, p := range profiles {
+		src, err := g.emitProfile(p)
+		if err != nil {
+			return nil, err
+		}
+		files[strings.ToLower(p.name)+".go"] = src
+	}
+	if len(g.extensions) > 0 {
+		src, err := g.emitExtensions()
+		if err != nil {
+			return nil, err
+		}
+		files["extensions.go"] = src
+	}
+	return files, nil
+}
+
+// profile analyses a profile's snapshot
+func (g *profileGenerator) profile(sd *models.StructureDefinition, name string) *profileType {
+	p := &profileType{sd: sd, name: name, model: profileModels[sd.Type]}
+	byID := make(map[string]*models.ElementDefinition)
+	for i := range sd.Snapshot.Element {
+		e := &sd.Snapshot.Element[i]
+		byID[elementID(e)] = e
+	}
+	taken := make(map[string]bool)
+
+	for i := range sd.Snapshot.Element {
+		e := &sd.Snapshot.Element[i]
+		id := elementID(e)
+
+		if e.MustSupport != nil && *e.MustSupport && strings.Count(e.Path, ".") > 0 {
+			switch {
+			case !strings.Contains(id, ":"):
+				p.mustSupport = append(p.mustSupport, strings.ReplaceAll(e.Path, "[x]", ""))
+			case e.Path == sd.Type+".extension" && id == e.Path+":"+e.SliceName && extensionProfile(e) != "":
+				p.mustSupport = append(p.mustSupport, fmt.Sprintf("%s.extension('%s')", sd.Type, extensionProfile(e)))
+			}
+		}
+
+		// Only slices of the resource's own elements get accessors
+		if e.SliceName == "" || id != e.Path+":"+e.SliceName || strings.Count(e.Path, ".") != 1 {
+			continue
+		}
+		accessor := goName(e.SliceName)
+		if conflict := g.conflicts(p, accessor, taken); conflict != "" {
+			g.warnf("%s: slice %s: %s", sd.URL, id, conflict)
+			continue
+		}
+
+		if e.Path == sd.Type+".extension" {
+			url := extensionProfile(e)
+			ext, err := g.extension(url, accessor)
+			if err != nil {
+				g.warnf("%s: slice %s: %v", sd.URL, id, err)
+				continue
+			}
+			p.extensions = append(p.extensions, extensionAccessor{
+				name: accessor, sliceName: e.SliceName, ext: ext, multiple: e.Max != "1",
+			})
+			taken[accessor] = true
+			continue
+		}
+
+		s, err := g.slice(p, byID, e, accessor)
+		if err != nil {
+			g.warnf("%s: slice %s: %v", sd.URL, id, err)
+			continue
+		}
+		p.slices = append(p.slices, *s)
+		taken[accessor] = true
+	}
+	return p
+}
+
+// conflicts reports why accessor methods named name (and Set or Add name)
+// cannot be added to a wrapper type, or "" if they can
+func (g *profileGenerator) conflicts(p *profileType, name string, taken map[string]bool) string {
+	switch {
+	case name == "":
+		return "no usable name"
+	case taken[name]:
+		return fmt.Sprintf("accessor %s is already defined", name)
+	}
+	for _, method := range []string{name, "Set" + name, "Add" + name} {
+		switch method {
+		case "Validate", "MissingMustSupport", "MarshalJSON", "UnmarshalJSON", p.model.Name():
+			return fmt.Sprintf("%s would hide a method of the wrapper", method)
+		}
+		if _, ok := p.model.FieldByName(method); ok {
+			return fmt.Sprintf("%s would hide a field of models.%s", method, p.model.Name())
+		}
+		if _, ok := reflect.PointerTo(p.model).MethodByName(method); ok {
+			return fmt.Sprintf("%s would hide a method of models.%s", method, p.model.Name())
+		}
+	}
+	return ""
+}
+
+// extension returns the extension type for an extension definition
+func (g *profileGenerator) extension(url, name string) (*extensionType, error) {
+	if ext, ok := g.extensions[url]; ok {
+		return ext, nil
+	}
+	sd, ok := g.byURL[url]
+	if !ok || sd.Snapshot == nil {
+		return nil, fmt.Errorf("no definition with a snapshot for extension %s", url)
+	}
+	if other, ok := g.names[name]; ok {
+		return nil, fmt.Errorf("extension type %s is already generated for %s", name, other)
+	}
+
+	byID := make(map[string]*models.ElementDefinition)
+	for i := range sd.Snapshot.Element {
+		e := &sd.Snapshot.Element[i]
+		byID[elementID(e)] = e
+	}
+	ext := &extensionType{name: name, url: url, title: sd.Title}
+	if ext.title == "" {
+		ext.title = sd.Name
+	}
+
+	if value := byID["Extension.value[x]"]; value != nil && value.Max != "0" {
+		v, err := extensionValue(value)
+		if err != nil {
+			return nil, err
+		}
+		ext.value = &v
+	} else {
+		for _, e := range sd.Snapshot.Element {
+			id := elementID(&e)
+			partName, ok := strings.CutPrefix(id, "Extension.extension:")
+			if !ok || strings.Contains(partName, ".") {
+				continue
+			}
+			part := extensionPart{name: goName(partName), url: partName, multiple: e.Max != "1"}
+			if raw, _ := byID[id+".url"].Fixed(); raw != nil {
+				json.Unmarshal(raw, &part.url)
+			}
+			value := byID[id+".value[x]"]
+			if value == nil {
+				return nil, fmt.Errorf("extension %s part %s has no value", url, partName)
+			}
+			v, err := extensionValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("extension %s part %s: %w", url, partName, err)
+			}
+			part.value = v
+			ext.parts = append(ext.parts, part)
+		}
+		if len(ext.parts) == 0 {
+			return nil, fmt.Errorf("extension %s has neither a value nor nested extensions", url)
+		}
+	}
+	g.extensions[url] = ext
+	g.names[name] = url
+	return ext, nil
+}
+
+// extensionValue returns the type of an extension's value[x] element
+func extensionValue(e *models.ElementDefinition) (valueType, error) {
+	if len(e.Type) != 1 {
+		return valueType{}, fmt.Errorf("%s allows %d types; only single types are supported", elementID(e), len(e.Type))
+	}
+	field := "Value" + upperFirst(e.Type[0].Code)
+	f, ok := reflect.TypeOf(models.Extension{}).FieldByName(field)
+	if !ok {
+		return valueType{}, fmt.Errorf("models.Extension has no %s field", field)
+	}
+	return valueType{field: field, goType: goTypeName(f.Type.Elem())}, nil
+}
+
+// slice returns the accessor for a slice of a repeating element, which
+// must have value or pattern discriminators that the slice fixes
+func (g *profileGenerator) slice(p *profileType, byID map[string]*models.ElementDefinition, e *models.ElementDefinition, name string) (*sliceAccessor, error) {
+	base := byID[e.Path]
+	if base == nil || base.Slicing == nil || len(base.Slicing.Discriminator) == 0 {
+		return nil, fmt.Errorf("%s is not sliced by discriminators", e.Path)
+	}
+	field, ok := findField(p.model, e.Name())
+	if !ok || field.Type.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("models.%s has no repeating field for %s", p.model.Name(), e.Name())
+	}
+
+	pattern := make(map[string]interface{})
+	sliceID := elementID(e)
+	for _, d := range base.Slicing.Discriminator {
+		if d.Type != "value" && d.Type != "pattern" {
+			return nil, fmt.Errorf("%s discriminators are not supported", d.Type)
+		}
+		if d.Path == "$this" {
+			raw := fixedOrPattern(e)
+			var value map[string]interface{}
+			if raw == nil || json.Unmarshal(raw, &value) != nil {
+				return nil, fmt.Errorf("slice fixes no value for $this")
+			}
+			for k, v := range value {
+				pattern[k] = v
+			}
+			continue
+		}
+		target := byID[sliceID+"."+d.Path]
+		if target == nil || fixedOrPattern(target) == nil {
+			return nil, fmt.Errorf("slice fixes no value for %s", d.Path)
+		}
+		var value interface{}
+		if err := json.Unmarshal(fixedOrPattern(target), &value); err != nil {
+			return nil, err
+		}
+		setPattern(pattern, byID, sliceID, strings.Split(d.Path, "."), value)
+	}
+	data, err := json.Marshal(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &sliceAccessor{
+		name:      name,
+		sliceName: e.SliceName,
+		element:   e.Name(),
+		field:     field.Name,
+		itemType:  goTypeName(field.Type.Elem()),
+		pattern:   string(data),
+		multiple:  e.Max != "1",
+	}, nil
+}
+
+// setPattern sets value at path below the slice element, making the
+// members of repeating elements arrays
+func setPattern(pattern map[string]interface{}, byID map[string]*models.ElementDefinition, id string, path []string, value interface{}) {
+	id += "." + path[0]
+	repeats := byID[id] != nil && byID[id].Max != "1"
+	if len(path) > 1 {
+		var child map[string]interface{}
+		switch existing := pattern[path[0]].(type) {
+		case map[string]interface{}:
+			child = existing
+		case []interface{}:
+			child = existing[0].(map[string]interface{})
+		default:
+			child = make(map[string]interface{})
+			if repeats {
+				pattern[path[0]] = []interface{}{child}
+			} else {
+				pattern[path[0]] = child
+			}
+		}
+		setPattern(child, byID, id, path[1:], value)
+		return
+	}
+	if repeats {
+		if _, ok := value.([]interface{}); !ok {
+			value = []interface{}{value}
+		}
+	}
+	pattern[path[0]] = value
+}
+
+func fixedOrPattern(e *models.ElementDefinition) json.RawMessage {
+	if raw, _ := e.Fixed(); raw != nil {
+		return raw
+	}
+	raw, _ := e.Pattern()
+	return raw
+}
+
+// extensionProfile returns the extension definition an extension slice uses
+func extensionProfile(e *models.ElementDefinition) string {
+	if len(e.Type) == 1 && len(e.Type[0].Profile) == 1 {
+		return e.Type[0].Profile[0]
+	}
+	return ""
+}
+
+func elementID(e *models.ElementDefinition) string {
+	if e.ID != "" {
+		return e.ID
+	}
+	if e.SliceName != "" {
+		return e.Path + ":" + e.SliceName
+	}
+	return e.Path
+}
+
+// findField returns the field of a model, including those of embedded
+// structs, that holds the JSON member name
+func findField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if found, ok := findField(f.Type, name); ok {
+				return found, true
+			}
+			continue
+		}
+		if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// goTypeName returns the name of a type as written in generated code
+func goTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + goTypeName(t.Elem())
+	case reflect.Slice:
+		return "[]" + goTypeName(t.Elem())
+	}
+	if path := t.PkgPath(); path != "" {
+		return path[strings.LastIndex(path, "/")+1:] + "." + t.Name()
+	}
+	return t.Name()
+}
+
+// goName turns a slice or element name into an exported Go identifier
+func goName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s != "" && unicode.IsDigit(rune(s[0])) {
+		s = "X" + s
+	}
+	return s
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// file accumulates the source of a generated file
+type file struct {
+	header  string
+	pkg     string
+	imports map[string]bool
+	body    bytes.Buffer
+}
+
+func (g *profileGenerator) newFile(header string) *file {
+	return &file{header: header, pkg: g.pkg, imports: make(map[string]bool)}
+}
+
+func (f *file) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&f.body, format, args...)
+}
+
+func (f *file) use(path string) {
+	f.imports[path] = true
+}
+
+// useType imports the package of a type name such as fhir.Date
+func (f *file) useType(goType string) {
+	switch {
+	case strings.Contains(goType, "models."):
+		f.use("github.com/eugeneosullivan/golang-fhir-client/pkg/models")
+	case strings.Contains(goType, "fhir."):
+		f.use("github.com/eugeneosullivan/golang-fhir-client/pkg/fhir")
+	}
+}
+
+func (f *file) source() ([]byte, error) {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s\n\npackage %s\n\n", f.header, f.pkg)
+	if len(f.imports) > 0 {
+		paths := make([]string, 0, len(f.imports))
+		for path := range f.imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		out.WriteString("import (\n")
+		for _, path := range paths {
+			if !strings.Contains(path, ".") {
+				fmt.Fprintf(&out, "%q\n", path)
+			}
+		}
+		out.WriteString("\n")
+		for _, path := range paths {
+			if strings.Contains(path, ".") {
+				fmt.Fprintf(&out, "%q\n", path)
+			}
+		}
+		out.WriteString(")\n\n")
+	}
+	out.Write(f.body.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+// quoteJSON returns a JSON pattern as a Go string literal
+func quoteJSON(s string) string {
+	if strings.Contains(s, "`") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+func (g *profileGenerator) emitExtensions() ([]byte, error) {
+	f := g.newFile("// Code generated by cmd/generator. DO NOT EDIT.")
+	urls := make([]string, 0, len(g.extensions))
+	for url := range g.extensions {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	for _, url := range urls {
+		ext := g.extensions[url]
+		f.printf("// %sURL is the canonical URL of the %s\n", ext.name, ext.title)
+		f.printf("const %sURL = %q\n\n", ext.name, ext.url)
+		if ext.value != nil {
+			continue
+		}
+
+		f.use("github.com/eugeneosullivan/golang-fhir-client/pkg/models")
+		f.printf("// %s is the value of the %s\n", ext.name, ext.title)
+		f.printf("type %s struct {\n", ext.name)
+		for _, part := range ext.parts {
+			f.useType(part.value.goType)
+			switch {
+			case part.multiple:
+				f.printf("%s []%s\n", part.name, part.value.goType)
+			case part.value.pointer():
+				f.printf("%s *%s\n", part.name, part.value.goType)
+			default:
+				f.printf("%s %s\n", part.name, part.value.goType)
+			}
+		}
+		f.printf("}\n\n")
+
+		f.printf("// %sFromExtension reads the value of a %s\n", lowerFirst(ext.name), ext.title)
+		f.printf("func %sFromExtension(ext *models.Extension) *%s {\n", lowerFirst(ext.name), ext.name)
+		f.printf("v := &%s{}\n", ext.name)
+		f.printf("for _, part := range ext.Extension {\n")
+		f.printf("switch part.URL {\n")
+		for _, part := range ext.parts {
+			f.printf("case %q:\n", part.url)
+			f.printf("if part.%s != nil {\n", part.value.field)
+			switch {
+			case part.multiple:
+				f.printf("v.%s = append(v.%s, *part.%s)\n", part.name, part.name, part.value.field)
+			case part.value.pointer():
+				f.printf("v.%s = part.%s\n", part.name, part.value.field)
+			default:
+				f.printf("v.%s = *part.%s\n", part.name, part.value.field)
+			}
+			f.printf("}\n")
+		}
+		f.printf("}\n}\nreturn v\n}\n\n")
+
+		f.printf("// Extension returns the value as a %s\n", ext.title)
+		f.printf("func (v *%s) Extension() models.Extension {\n", ext.name)
+		f.printf("ext := models.Extension{URL: %sURL}\n", ext.name)
+		for _, part := range ext.parts {
+			switch {
+			case part.multiple:
+				f.printf("for i := range v.%s {\n", part.name)
+				f.printf("value := v.%s[i]\n", part.name)
+				f.printf("ext.Extension = append(ext.Extension, models.Extension{URL: %q, %s: &value})\n", part.url, part.value.field)
+				f.printf("}\n")
+			case part.value.pointer():
+				f.printf("if v.%s != nil {\n", part.name)
+				f.printf("ext.Extension = append(ext.Extension, models.Extension{URL: %q, %s: v.%s})\n", part.url, part.value.field, part.name)
+				f.printf("}\n")
+			default:
+				f.printf("if v.%s != \"\" {\n", part.name)
+				f.printf("value := v.%s\n", part.name)
+				f.printf("ext.Extension = append(ext.Extension, models.Extension{URL: %q, %s: &value})\n", part.url, part.value.field)
+				f.printf("}\n")
+			}
+		}
+		f.printf("return ext\n}\n\n")
+	}
+	return f.source()
+}
+
+func (g *profileGenerator) emitProfile(p *profileType) ([]byte, error) {
+	sd := p.sd
+	canonical := sd.URL
+	if sd.Version != "" {
+		canonical += "|" + sd.Version
+	}
+	f := g.newFile(fmt.Sprintf("// Code generated by cmd/generator from %s. DO NOT EDIT.", canonical))
+	f.use("context")
+	f.use("encoding/json")
+	f.use("github.com/eugeneosullivan/golang-fhir-client/pkg/models")
+	f.use("github.com/eugeneosullivan/golang-fhir-client/pkg/profiles")
+	f.use("github.com/eugeneosullivan/golang-fhir-client/pkg/validation")
+
+	title := sd.Title
+	if title == "" {
+		title = sd.Name
+	}
+	model := p.model.Name()
+	recv := strings.ToLower(p.name[:1])
+	profileConst := p.name + "Profile"
+
+	f.printf("// %s is the canonical URL of the %s\n", profileConst, title)
+	f.printf("const %s = %q\n\n", profileConst, sd.URL)
+
+	f.printf("// %s is a %s conforming to the %s. It claims the\n", p.name, "models."+model, title)
+	f.printf("// profile in meta.profile when marshaled.\n")
+	f.printf("type %s struct {\n models.%s\n}\n\n", p.name, model)
+
+	f.printf("// New%s creates a %s that claims the profile\n", p.name, p.name)
+	f.printf("func New%s() *%s {\n", p.name, p.name)
+	f.printf("%s := &%s{%s: *models.New%s()}\n", recv, p.name, model, model)
+	f.printf("%s.Meta = profiles.WithProfile(%s.Meta, %s)\n", recv, recv, profileConst)
+	f.printf("return %s\n}\n\n", recv)
+
+	f.printf("// %sMustSupport lists the must-support elements of the profile as FHIRPath\n", p.name)
+	f.printf("var %sMustSupport = []string{\n", p.name)
+	for _, path := range p.mustSupport {
+		f.printf("%q,\n", path)
+	}
+	f.printf("}\n\n")
+
+	f.printf("// MarshalJSON implements custom JSON marshaling, adding the profile to meta.profile\n")
+	f.printf("func (%s %s) MarshalJSON() ([]byte, error) {\n", recv, p.name)
+	f.printf("%s.Meta = profiles.WithProfile(%s.Meta, %s)\n", recv, recv, profileConst)
+	f.printf("return json.Marshal(%s.%s)\n}\n\n", recv, model)
+
+	f.printf("// UnmarshalJSON implements custom JSON unmarshaling for %s\n", p.name)
+	f.printf("func (%s *%s) UnmarshalJSON(data []byte) error {\n", recv, p.name)
+	f.printf("return json.Unmarshal(data, &%s.%s)\n}\n\n", recv, model)
+
+	f.printf("// Validate checks the %s against the profile with v, which must be loaded\n", strings.ToLower(model))
+	f.printf("// with the core StructureDefinitions and the profiles and extensions of\n")
+	f.printf("// this package, returning a *validation.Error listing the errors found\n")
+	f.printf("func (%s *%s) Validate(ctx context.Context, v *validation.Validator) error {\n", recv, p.name)
+	f.printf("return validation.Check(ctx, v, %s)\n}\n\n", recv)
+
+	f.printf("// MissingMustSupport returns the must-support elements the %s has no value for\n", strings.ToLower(model))
+	f.printf("func (%s *%s) MissingMustSupport() []string {\n", recv, p.name)
+	f.printf("return profiles.MissingMustSupport(%s, %sMustSupport)\n}\n", recv, p.name)
+
+	for _, a := range p.extensions {
+		f.printf("\n")
+		g.emitExtensionAccessor(f, p, recv, a)
+	}
+	for _, s := range p.slices {
+		f.printf("\n")
+		g.emitSliceAccessor(f, p, recv, s)
+	}
+	return f.source()
+}
+
+func (g *profileGenerator) emitExtensionAccessor(f *file, p *profileType, recv string, a extensionAccessor) {
+	ext := a.ext
+	url := ext.name + "URL"
+	exts := recv + ".Extension"
+
+	if ext.value == nil {
+		from := lowerFirst(ext.name) + "FromExtension"
+		if a.multiple {
+			f.printf("// %s returns the values of the %s extensions\n", a.name, a.sliceName)
+			f.printf("func (%s *%s) %s() []%s {\n", recv, p.name, a.name, ext.name)
+			f.printf("var values []%s\n", ext.name)
+			f.printf("for _, ext := range profiles.FindExtensions(%s, %s) {\n", exts, url)
+			f.printf("values = append(values, *%s(ext))\n}\n", from)
+			f.printf("return values\n}\n\n")
+
+			f.printf("// Set%s replaces the %s extensions with one for each value\n", a.name, a.sliceName)
+			f.printf("func (%s *%s) Set%s(values ...%s) {\n", recv, p.name, a.name, ext.name)
+			f.printf("exts := make([]models.Extension, len(values))\n")
+			f.printf("for i := range values {\nexts[i] = values[i].Extension()\n}\n")
+			f.printf("%s = profiles.SetExtensions(%s, %s, exts...)\n}\n", exts, exts, url)
+			return
+		}
+		f.printf("// %s returns the value of the %s extension, or nil if it is absent\n", a.name, a.sliceName)
+		f.printf("func (%s *%s) %s() *%s {\n", recv, p.name, a.name, ext.name)
+		f.printf("if ext := profiles.FindExtension(%s, %s); ext != nil {\n", exts, url)
+		f.printf("return %s(ext)\n}\nreturn nil\n}\n\n", from)
+
+		f.printf("// Set%s sets the %s extension, removing it if value is nil\n", a.name, a.sliceName)
+		f.printf("func (%s *%s) Set%s(value *%s) {\n", recv, p.name, a.name, ext.name)
+		f.printf("if value == nil {\n%s = profiles.SetExtensions(%s, %s)\nreturn\n}\n", exts, exts, url)
+		f.printf("%s = profiles.SetExtensions(%s, %s, value.Extension())\n}\n", exts, exts, url)
+		return
+	}
+
+	v := ext.value
+	f.useType(v.goType)
+	switch {
+	case a.multiple:
+		f.printf("// %s returns the values of the %s extensions\n", a.name, a.sliceName)
+		f.printf("func (%s *%s) %s() []%s {\n", recv, p.name, a.name, v.goType)
+		f.printf("var values []%s\n", v.goType)
+		f.printf("for _, ext := range profiles.FindExtensions(%s, %s) {\n", exts, url)
+		f.printf("if ext.%s != nil {\nvalues = append(values, *ext.%s)\n}\n}\n", v.field, v.field)
+		f.printf("return values\n}\n\n")
+
+		f.printf("// Set%s replaces the %s extensions with one for each value\n", a.name, a.sliceName)
+		f.printf("func (%s *%s) Set%s(values ...%s) {\n", recv, p.name, a.name, v.goType)
+		f.printf("exts := make([]models.Extension, len(values))\n")
+		f.printf("for i := range values {\nvalue := values[i]\n")
+		f.printf("exts[i] = models.Extension{URL: %s, %s: &value}\n}\n", url, v.field)
+		f.printf("%s = profiles.SetExtensions(%s, %s, exts...)\n}\n", exts, exts, url)
+	case v.pointer():
+		f.printf("// %s returns the value of the %s extension, or nil if it is absent\n", a.name, a.sliceName)
+		f.printf("func (%s *%s) %s() *%s {\n", recv, p.name, a.name, v.goType)
+		f.printf("if ext := profiles.FindExtension(%s, %s); ext != nil {\n", exts, url)
+		f.printf("return ext.%s\n}\nreturn nil\n}\n\n", v.field)
+
+		f.printf("// Set%s sets the %s extension, removing it if value is nil\n", a.name, a.sliceName)
+		f.printf("func (%s *%s) Set%s(value *%s) {\n", recv, p.name, a.name, v.goType)
+		f.printf("if value == nil {\n%s = profiles.SetExtensions(%s, %s)\nreturn\n}\n", exts, exts, url)
+		f.printf("%s = profiles.SetExtensions(%s, %s, models.Extension{URL: %s, %s: value})\n}\n", exts, exts, url, url, v.field)
+	default:
+		f.printf("// %s returns the value of the %s extension, or \"\" if it is absent\n", a.name, a.sliceName)
+		f.printf("func (%s *%s) %s() string {\n", recv, p.name, a.name)
+		f.printf("if ext := profiles.FindExtension(%s, %s); ext != nil && ext.%s != nil {\n", exts, url, v.field)
+		f.printf("return *ext.%s\n}\nreturn \"\"\n}\n\n", v.field)
+
+		f.printf("// Set%s sets the %s extension, removing it if value is \"\"\n", a.name, a.sliceName)
+		f.printf("func (%s *%s) Set%s(value string) {\n", recv, p.name, a.name)
+		f.printf("if value == \"\" {\n%s = profiles.SetExtensions(%s, %s)\nreturn\n}\n", exts, exts, url)
+		f.printf("%s = profiles.SetExtensions(%s, %s, models.Extension{URL: %s, %s: &value})\n}\n", exts, exts, url, url, v.field)
+	}
+}
+
+func (g *profileGenerator) emitSliceAccessor(f *file, p *profileType, recv string, s sliceAccessor) {
+	f.useType(s.itemType)
+	pattern := lowerFirst(p.name) + s.name + "Pattern"
+	field := recv + "." + s.field
+
+	f.printf("// %s holds the values that identify the %s slice %s\n", pattern, s.element, s.sliceName)
+	f.printf("const %s = %s\n\n", pattern, quoteJSON(s.pattern))
+
+	if s.multiple {
+		f.printf("// %s returns the %s in the %s slice\n", s.name, s.element, s.sliceName)
+		f.printf("func (%s *%s) %s() []*%s {\n", recv, p.name, s.name, s.itemType)
+		f.printf("return profiles.Slice(%s, %s)\n}\n\n", field, pattern)
+	} else {
+		f.printf("// %s returns the %s in the %s slice, or nil\n", s.name, s.element, s.sliceName)
+		f.printf("func (%s *%s) %s() *%s {\n", recv, p.name, s.name, s.itemType)
+		f.printf("if items := profiles.Slice(%s, %s); len(items) > 0 {\nreturn items[0]\n}\n", field, pattern)
+		f.printf("return nil\n}\n\n")
+	}
+
+	f.printf("// Add%s adds item to %s as a member of the %s slice, setting the\n", s.name, s.element, s.sliceName)
+	f.printf("// values the slice requires\n")
+	f.printf("func (%s *%s) Add%s(item %s) error {\n", recv, p.name, s.name, s.itemType)
+	f.printf("if err := profiles.Apply(&item, %s); err != nil {\nreturn err\n}\n", pattern)
+	f.printf("%s = append(%s, item)\nreturn nil\n}\n", field, field)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+// TestGenerateUSCore checks that the committed pkg/profiles/uscore is up to date
+func TestGenerateUSCore(t *testing.T) {
+	const dir = "../../pkg/profiles/uscore"
+	definitions, err := readDir(filepath.Join(dir, "testdata"))
+	if err != nil {
+		t.Fatalf("readDir failed: %v", err)
+	}
+	g := newProfileGenerator("uscore", definitions)
+	files, err := g.generate(definitions)
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if len(g.warnings) > 0 {
+		t.Errorf("unexpected warnings: %v", g.warnings)
+	}
+	for _, name := range []string{"patient.go", "extensions.go"} {
+		if files[name] == nil {
+			t.Errorf("%s was not generated", name)
+		}
+	}
+	for name, src := range files {
+		committed, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("failed to read %s: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(src, committed) {
+			t.Errorf("%s is out of date; run go generate in %s", name, dir)
+		}
+	}
+}
+
+const slicedPatient = `{
+	"resourceType": "StructureDefinition",
+	"url": "http://example.org/fhir/StructureDefinition/contact-patient",
+	"version": "1.0.0",
+	"name": "ContactPatient",
+	"title": "Contact Patient",
+	"status": "active",
+	"kind": "resource",
+	"type": "Patient",
+	"derivation": "constraint",
+	"snapshot": {"element": [
+		{"id": "Patient", "path": "Patient", "min": 0, "max": "*"},
+		{"id": "Patient.telecom", "path": "Patient.telecom", "min": 0, "max": "*", "type": [{"code": "ContactPoint"}],
+			"slicing": {"discriminator": [{"type": "value", "path": "system"}], "rules": "open"}},
+		{"id": "Patient.telecom:phone", "path": "Patient.telecom", "sliceName": "phone", "min": 0, "max": "*", "type": [{"code": "ContactPoint"}]},
+		{"id": "Patient.telecom:phone.system", "path": "Patient.telecom.system", "min": 1, "max": "1", "type": [{"code": "code"}], "fixedCode": "phone"},
+		{"id": "Patient.telecom:email", "path": "Patient.telecom", "sliceName": "email", "min": 0, "max": "1", "type": [{"code": "ContactPoint"}]},
+		{"id": "Patient.telecom:email.system", "path": "Patient.telecom.system", "min": 1, "max": "1", "type": [{"code": "code"}], "fixedCode": "email"},
+		{"id": "Patient.name", "path": "Patient.name", "min": 0, "max": "*", "type": [{"code": "HumanName"}],
+			"slicing": {"discriminator": [{"type": "pattern", "path": "use"}], "rules": "open"}},
+		{"id": "Patient.name:name", "path": "Patient.name", "sliceName": "name", "min": 0, "max": "1", "type": [{"code": "HumanName"}]},
+		{"id": "Patient.name:name.use", "path": "Patient.name.use", "min": 1, "max": "1", "type": [{"code": "code"}], "patternCode": "official"},
+		{"id": "Patient.identifier", "path": "Patient.identifier", "min": 0, "max": "*", "type": [{"code": "Identifier"}],
+			"slicing": {"discriminator": [{"type": "value", "path": "system"}], "rules": "open"}},
+		{"id": "Patient.identifier:mrn", "path": "Patient.identifier", "sliceName": "mrn", "min": 0, "max": "1", "type": [{"code": "Identifier"}]},
+		{"id": "Patient.identifier:mrn.system", "path": "Patient.identifier.system", "min": 1, "max": "1", "type": [{"code": "uri"}], "fixedUri": "http://example.org/mrn"}
+	]}
+}`
+
+func TestGenerateSlices(t *testing.T) {
+	sd := models.NewStructureDefinition()
+	if err := json.Unmarshal([]byte(slicedPatient), sd); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	definitions := []*models.StructureDefinition{sd}
+	g := newProfileGenerator("example", definitions)
+	files, err := g.generate(definitions)
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	src := string(files["patient.go"])
+
+	for _, want := range []string{
+		"// Code generated by cmd/generator from http://example.org/fhir/StructureDefinition/contact-patient|1.0.0. DO NOT EDIT.",
+		"const patientPhonePattern = `{\"system\":\"phone\"}`",
+		"func (p *Patient) Phone() []*models.ContactPoint {",
+		"func (p *Patient) Email() *models.ContactPoint {",
+		"func (p *Patient) AddEmail(item models.ContactPoint) error {",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("expected %q in\n%s", want, src)
+		}
+	}
+	if files["extensions.go"] != nil {
+		t.Error("expected no extensions.go for a profile without extensions")
+	}
+
+	// The name slice would hide the Name field, and the model has no
+	// identifier field
+	if len(g.warnings) != 2 || !strings.Contains(g.warnings[0], "Patient.name:name") || !strings.Contains(g.warnings[1], "Patient.identifier:mrn") {
+		t.Errorf("warnings = %v", g.warnings)
+	}
+}
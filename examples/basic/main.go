@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/operations"
 )
@@ -31,7 +32,7 @@ func main() {
 		},
 	}
 	newPatient.Gender = "male"
-	birthDate := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	birthDate := fhir.NewDate(2000, time.January, 1)
 	newPatient.BirthDate = &birthDate
 
 	// Create the patient
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/operations"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/search"
@@ -40,7 +41,7 @@ func TestBasicCRUD(t *testing.T) {
 		},
 	}
 	newPatient.Gender = "male"
-	birthDate := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	birthDate := fhir.NewDate(2000, time.January, 1)
 	newPatient.BirthDate = &birthDate
 
 	// Create
@@ -125,7 +126,7 @@ func TestSearch(t *testing.T) {
 
 	// Process results
 	for _, entry := range bundle.Entry {
-		resource, err := bundle.GetTypedResource(entry.Resource)
+		resource, err := entry.GetResource()
 		if err != nil {
 			t.Errorf("Failed to convert resource: %v", err)
 			continue
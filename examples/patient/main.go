@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/operations"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/search"
@@ -41,8 +42,8 @@ func main() {
 
 	// Process each entry in the bundle
 	for i, entry := range bundle.Entry {
-		// Convert the raw resource to a Patient
-		resource, err := bundle.GetTypedResource(entry.Resource)
+		// Get the typed resource decoded with the bundle
+		resource, err := entry.GetResource()
 		if err != nil {
 			log.Printf("Failed to convert resource %d: %v", i, err)
 			continue
@@ -72,7 +73,7 @@ func main() {
 
 		// Print birth date
 		if patient.BirthDate != nil {
-			fmt.Printf("Birth Date: %s\n", patient.BirthDate.Format("2006-01-02"))
+			fmt.Printf("Birth Date: %s\n", patient.BirthDate)
 		}
 
 		// Print gender
@@ -119,7 +120,7 @@ func main() {
 		},
 	}
 	newPatient.Gender = "male"
-	birthDate := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	birthDate := fhir.NewDate(2000, time.January, 1)
 	newPatient.BirthDate = &birthDate
 
 	// Create the patient on the server
@@ -41,8 +41,8 @@ func main() {
 
 	// Process each entry in the bundle
 	for i, entry := range bundle.Entry {
-		// Convert the raw resource to a Patient
-		resource, err := bundle.GetTypedResource(entry.Resource)
+		// Get the typed resource decoded with the bundle
+		resource, err := entry.GetResource()
 		if err != nil {
 			log.Printf("Failed to convert resource %d: %v", i, err)
 			continue
@@ -72,7 +72,7 @@ func main() {
 
 		// Print birth date
 		if patient.BirthDate != nil {
-			fmt.Printf("Birth Date: %s\n", patient.BirthDate.Format("2006-01-02"))
+			fmt.Printf("Birth Date: %s\n", patient.BirthDate)
 		}
 
 		// Print gender
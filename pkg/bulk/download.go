@@ -0,0 +1,137 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// partSuffix marks a file that is still being downloaded
+const partSuffix = ".part"
+
+// DownloadedFile is an export file saved to disk
+type DownloadedFile struct {
+	ExportFile
+
+	// Path is where the file was saved
+	Path string
+
+	// IsError is true for files from the manifest's error list
+	IsError bool
+}
+
+// Download saves the manifest's output and error files into dir, several at
+// a time. Files are named after their type and position in the manifest, so
+// running Download again with the same manifest skips files that are
+// complete and resumes partial downloads where the server supports ranges.
+func (c *Client) Download(ctx context.Context, manifest *ExportManifest, dir string) ([]DownloadedFile, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	files := make([]DownloadedFile, 0, len(manifest.Output)+len(manifest.Error))
+	counts := make(map[string]int)
+	name := func(prefix string, f ExportFile) string {
+		counts[prefix+f.Type]++
+		return filepath.Join(dir, prefix+f.Type+"."+strconv.Itoa(counts[prefix+f.Type])+".ndjson")
+	}
+	for _, f := range manifest.Output {
+		files = append(files, DownloadedFile{ExportFile: f, Path: name("", f)})
+	}
+	for _, f := range manifest.Error {
+		files = append(files, DownloadedFile{ExportFile: f, Path: name("error.", f), IsError: true})
+	}
+
+	concurrency := c.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	errs := make([]error, len(files))
+	var wg sync.WaitGroup
+	for i := range files {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if err := c.DownloadFile(ctx, files[i].ExportFile, files[i].Path, manifest.RequiresAccessToken); err != nil {
+				errs[i] = fmt.Errorf("failed to download %s: %w", files[i].URL, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return files, errors.Join(errs...)
+}
+
+// DownloadFile saves a single export file to path. A complete file at path
+// is left as it is; a partial download is resumed. withToken sends the
+// operation's headers, such as Authorization, with the request.
+func (c *Client) DownloadFile(ctx context.Context, file ExportFile, path string, withToken bool) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	part := path + partSuffix
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, file.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/fhir+ndjson")
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	resp, err := c.getFile(req, withToken)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file already holds the whole body
+		return os.Rename(part, path)
+	case resp.StatusCode == http.StatusOK:
+		flags |= os.O_TRUNC
+	default:
+		return statusError(resp)
+	}
+
+	out, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(part, path)
+}
+
+// getFile sends a request for a bulk data file. withToken sends the
+// operation's headers, such as Authorization, with the request.
+func (c *Client) getFile(req *http.Request, withToken bool) (*http.Response, error) {
+	if withToken {
+		return c.op.Do(req)
+	}
+	return c.op.HTTPClient().Do(req)
+}
@@ -0,0 +1,139 @@
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/operations"
+)
+
+// Client drives FHIR Bulk Data Access interactions against a server
+type Client struct {
+	op *operations.HTTPOperation
+
+	// PollInterval is the first wait between status requests when the server
+	// sends no Retry-After. It doubles on each poll up to MaxPollInterval.
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+
+	// Concurrency is the number of files downloaded at once
+	Concurrency int
+
+	// OnProgress is called with the X-Progress header of each in-progress status response
+	OnProgress func(progress string)
+}
+
+// NewClient creates a bulk data client sending requests through op
+func NewClient(op *operations.HTTPOperation) *Client {
+	return &Client{
+		op:              op,
+		PollInterval:    time.Second,
+		MaxPollInterval: time.Minute,
+		Concurrency:     4,
+	}
+}
+
+// ExportRequest holds the parameters of an $export kick-off request
+type ExportRequest struct {
+	// Types limits the export to these resource types (_type)
+	Types []string
+
+	// Since limits the export to resources changed after this time (_since)
+	Since *fhir.Instant
+
+	// TypeFilters are FHIR search queries such as "Observation?category=vital-signs" (_typeFilter)
+	TypeFilters []string
+
+	// OutputFormat is the format of the output files (_outputFormat).
+	// Servers default to application/fhir+ndjson.
+	OutputFormat string
+}
+
+// query encodes the request as kick-off query parameters
+func (r *ExportRequest) query() url.Values {
+	q := url.Values{}
+	if r == nil {
+		return q
+	}
+	if len(r.Types) > 0 {
+		q.Set("_type", strings.Join(r.Types, ","))
+	}
+	if r.Since != nil {
+		q.Set("_since", r.Since.String())
+	}
+	for _, filter := range r.TypeFilters {
+		q.Add("_typeFilter", filter)
+	}
+	if r.OutputFormat != "" {
+		q.Set("_outputFormat", r.OutputFormat)
+	}
+	return q
+}
+
+// ExportFile is an output or error file listed in an export manifest
+type ExportFile struct {
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Count *int   `json:"count,omitempty"`
+}
+
+// ExportManifest is the completion response of an export job
+type ExportManifest struct {
+	TransactionTime     *fhir.Instant `json:"transactionTime"`
+	Request             string        `json:"request"`
+	RequiresAccessToken bool          `json:"requiresAccessToken"`
+	Output              []ExportFile  `json:"output"`
+	Error               []ExportFile  `json:"error,omitempty"`
+}
+
+// ExportJob is an export that has been kicked off
+type ExportJob struct {
+	Job
+}
+
+// SystemExport kicks off an export of all data on the server
+func (c *Client) SystemExport(ctx context.Context, req *ExportRequest) (*ExportJob, error) {
+	return c.export(ctx, "$export", req)
+}
+
+// PatientExport kicks off an export of all data about patients
+func (c *Client) PatientExport(ctx context.Context, req *ExportRequest) (*ExportJob, error) {
+	return c.export(ctx, "Patient/$export", req)
+}
+
+// GroupExport kicks off an export of all data about the patients in a group
+func (c *Client) GroupExport(ctx context.Context, groupID string, req *ExportRequest) (*ExportJob, error) {
+	return c.export(ctx, "Group/"+url.PathEscape(groupID)+"/$export", req)
+}
+
+// export sends an export kick-off request and returns the job it starts
+func (c *Client) export(ctx context.Context, path string, req *ExportRequest) (*ExportJob, error) {
+	if q := req.query(); len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	job, err := c.kickOff(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ExportJob{Job: *job}, nil
+}
+
+// Wait polls the job's status until it completes and returns its manifest
+func (j *ExportJob) Wait(ctx context.Context) (*ExportManifest, error) {
+	body, err := j.Poll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var manifest ExportManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode export manifest: %w", err)
+	}
+	return &manifest, nil
+}
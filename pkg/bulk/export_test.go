@@ -0,0 +1,221 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/operations"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/version"
+)
+
+var exportFiles = map[string]string{
+	"Patient.ndjson":     `{"resourceType":"Patient","id":"1"}` + "\n" + `{"resourceType":"Patient","id":"2"}` + "\n",
+	"Observation.ndjson": `{"resourceType":"Observation","id":"o1"}` + "\n",
+	"errors.ndjson":      `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"processing"}]}` + "\n",
+}
+
+// fakeExportServer implements the bulk export flow, completing on the second status poll
+type fakeExportServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	kickOff  *http.Request
+	polls    int
+	deleted  bool
+	ranges   []string
+	progress []string
+}
+
+func newFakeExportServer(t *testing.T) *fakeExportServer {
+	f := &fakeExportServer{}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeExportServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/$export"):
+		if r.Header.Get("Prefer") != "respond-async" {
+			http.Error(w, "respond-async required", http.StatusBadRequest)
+			return
+		}
+		f.kickOff = r
+		w.Header().Set("Content-Location", f.URL+"/status/1")
+		w.WriteHeader(http.StatusAccepted)
+
+	case r.URL.Path == "/status/1" && r.Method == http.MethodDelete:
+		f.deleted = true
+		w.WriteHeader(http.StatusAccepted)
+
+	case r.URL.Path == "/status/1":
+		f.polls++
+		if f.polls == 1 {
+			w.Header().Set("X-Progress", "50% complete")
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		json.NewEncoder(w).Encode(ExportManifest{
+			Request:             f.URL + "/$export",
+			RequiresAccessToken: true,
+			Output: []ExportFile{
+				{Type: "Patient", URL: f.URL + "/files/Patient.ndjson"},
+				{Type: "Observation", URL: f.URL + "/files/Observation.ndjson"},
+			},
+			Error: []ExportFile{{Type: "OperationOutcome", URL: f.URL + "/files/errors.ndjson"}},
+		})
+
+	case strings.HasPrefix(r.URL.Path, "/files/"):
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		content, ok := exportFiles[strings.TrimPrefix(r.URL.Path, "/files/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if rng := r.Header.Get("Range"); rng != "" {
+			f.ranges = append(f.ranges, rng)
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestClient(server *fakeExportServer) *Client {
+	op := operations.NewHTTPOperation(server.Client(), server.URL)
+	op.SetHeader("Authorization", "Bearer token")
+	c := NewClient(op)
+	c.PollInterval = time.Millisecond
+	return c
+}
+
+func TestExportFlow(t *testing.T) {
+	server := newFakeExportServer(t)
+	c := newTestClient(server)
+	c.OnProgress = func(progress string) { server.progress = append(server.progress, progress) }
+	ctx := context.Background()
+
+	since, _ := fhir.ParseInstant("2024-01-01T00:00:00Z")
+	job, err := c.GroupExport(ctx, "g1", &ExportRequest{
+		Types:        []string{"Patient", "Observation"},
+		Since:        &since,
+		TypeFilters:  []string{"Observation?category=vital-signs"},
+		OutputFormat: "application/fhir+ndjson",
+	})
+	if err != nil {
+		t.Fatalf("GroupExport failed: %v", err)
+	}
+
+	q := server.kickOff.URL.Query()
+	if server.kickOff.URL.Path != "/Group/g1/$export" || q.Get("_type") != "Patient,Observation" ||
+		q.Get("_since") != "2024-01-01T00:00:00Z" || q.Get("_typeFilter") != "Observation?category=vital-signs" ||
+		q.Get("_outputFormat") != "application/fhir+ndjson" {
+		t.Errorf("Unexpected kick-off request %s", server.kickOff.URL)
+	}
+
+	manifest, err := job.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if server.polls != 2 || len(server.progress) != 1 || server.progress[0] != "50% complete" {
+		t.Errorf("Expected two polls reporting progress once, got %d polls and %v", server.polls, server.progress)
+	}
+
+	dir := t.TempDir()
+	files, err := c.Download(ctx, manifest, dir)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if len(files) != 3 || !files[2].IsError {
+		t.Fatalf("Unexpected files %+v", files)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "Patient.1.ndjson"))
+	if err != nil || string(data) != exportFiles["Patient.ndjson"] {
+		t.Errorf("Unexpected Patient file %q: %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "error.OperationOutcome.1.ndjson")); err != nil {
+		t.Errorf("Expected the error file to be saved: %v", err)
+	}
+
+	if err := job.Delete(ctx); err != nil || !server.deleted {
+		t.Errorf("Delete failed: %v", err)
+	}
+}
+
+func TestDownloadResumes(t *testing.T) {
+	server := newFakeExportServer(t)
+	c := newTestClient(server)
+
+	content := exportFiles["Patient.ndjson"]
+	path := filepath.Join(t.TempDir(), "Patient.1.ndjson")
+	if err := os.WriteFile(path+partSuffix, []byte(content[:10]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file := ExportFile{Type: "Patient", URL: server.URL + "/files/Patient.ndjson"}
+	if err := c.DownloadFile(context.Background(), file, path, true); err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	if len(server.ranges) != 1 || server.ranges[0] != "bytes=10-" {
+		t.Errorf("Expected a ranged request, got %v", server.ranges)
+	}
+	data, _ := os.ReadFile(path)
+	if !bytes.Equal(data, []byte(content)) {
+		t.Errorf("Expected %q, got %q", content, data)
+	}
+
+	// A complete file is not downloaded again
+	if err := c.DownloadFile(context.Background(), ExportFile{URL: server.URL + "/missing"}, path, true); err != nil {
+		t.Errorf("Expected the complete file to be skipped: %v", err)
+	}
+}
+
+func TestDownloadWithoutToken(t *testing.T) {
+	server := newFakeExportServer(t)
+	c := newTestClient(server)
+
+	file := ExportFile{Type: "Patient", URL: server.URL + "/files/Patient.ndjson"}
+	err := c.DownloadFile(context.Background(), file, filepath.Join(t.TempDir(), "p.ndjson"), false)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected the request to be sent without the Authorization header, got %v", err)
+	}
+}
+
+func TestExportUnsupportedType(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	op := operations.NewHTTPOperation(server.Client(), server.URL)
+	r4, _ := version.NewVersionManager(version.R4)
+	op.SetVersion(r4)
+
+	_, err := NewClient(op).SystemExport(context.Background(), &ExportRequest{Types: []string{"Patient", "SubscriptionTopic"}})
+	if !errors.Is(err, operations.ErrUnsupportedResourceType) {
+		t.Errorf("expected ErrUnsupportedResourceType, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("%d requests were sent for an unsupported type", requests)
+	}
+}
@@ -0,0 +1,268 @@
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/ndjson"
+)
+
+// ImportInput is an NDJSON file of resources of one type to load
+type ImportInput struct {
+	Type string
+	URL  string
+}
+
+// ImportRequest describes an $import kick-off request
+type ImportRequest struct {
+	// InputFormat is the format of the input files, application/fhir+ndjson if empty
+	InputFormat string
+
+	// InputSource identifies the system the data came from
+	InputSource string
+
+	// StorageType is how the server fetches the inputs, such as "https" or
+	// "aws-s3". It is omitted when empty.
+	StorageType string
+
+	Inputs []ImportInput
+}
+
+// Manifest builds the Parameters resource describing the import
+func (r *ImportRequest) Manifest() *models.Parameters {
+	format := r.InputFormat
+	if format == "" {
+		format = "application/fhir+ndjson"
+	}
+
+	params := models.NewParameters().AddCode("inputFormat", format)
+	if r.InputSource != "" {
+		params.AddURI("inputSource", r.InputSource)
+	}
+	if r.StorageType != "" {
+		params.AddPart("storageDetail", models.NewParameters().AddCode("type", r.StorageType))
+	}
+	for _, input := range r.Inputs {
+		params.AddPart("input", models.NewParameters().AddCode("type", input.Type).AddURI("url", input.URL))
+	}
+	return params
+}
+
+// ImportFile is an entry in an import job's completion manifest. Output
+// entries give the number of resources loaded from an input; error entries
+// point at NDJSON OperationOutcomes describing what could not be loaded.
+type ImportFile struct {
+	Type     string `json:"type"`
+	InputURL string `json:"inputUrl,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Count    *int   `json:"count,omitempty"`
+}
+
+// ImportManifest is the completion response of an import or bulk submit job
+type ImportManifest struct {
+	TransactionTime     string       `json:"transactionTime,omitempty"`
+	Request             string       `json:"request,omitempty"`
+	RequiresAccessToken bool         `json:"requiresAccessToken"`
+	Output              []ImportFile `json:"output"`
+	Error               []ImportFile `json:"error,omitempty"`
+}
+
+// ImportJob is an import that has been kicked off
+type ImportJob struct {
+	Job
+}
+
+// Import kicks off an $import of the request's inputs
+func (c *Client) Import(ctx context.Context, req *ImportRequest) (*ImportJob, error) {
+	job, err := c.kickOff(ctx, http.MethodPost, "$import", req.Manifest())
+	if err != nil {
+		return nil, err
+	}
+	return &ImportJob{Job: *job}, nil
+}
+
+// Wait polls the job's status until it completes and returns its manifest
+func (j *ImportJob) Wait(ctx context.Context) (*ImportManifest, error) {
+	body, err := j.Poll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var manifest ImportManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode import manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// SubmitRequest notifies a server of data available for it to load, using
+// the bulk submit pattern
+type SubmitRequest struct {
+	// Submitter and SubmissionID identify the submission across requests
+	Submitter    models.Identifier
+	SubmissionID string
+
+	// ManifestURL is the location of an export manifest listing the files to load
+	ManifestURL string
+
+	// FHIRBaseURL is the base URL of the server the files were exported from
+	FHIRBaseURL string
+
+	// Status is "in-progress", "complete" or "aborted". It is omitted when empty.
+	Status string
+}
+
+// parameters builds the Parameters resource for a $bulk-submit request
+func (r *SubmitRequest) parameters() *models.Parameters {
+	params := submissionParameters(r.Submitter, r.SubmissionID)
+	if r.ManifestURL != "" {
+		params.AddString("manifestUrl", r.ManifestURL)
+	}
+	if r.FHIRBaseURL != "" {
+		params.AddString("fhirBaseUrl", r.FHIRBaseURL)
+	}
+	if r.Status != "" {
+		params.AddCoding("submissionStatus", models.Coding{Code: r.Status})
+	}
+	return params
+}
+
+// submissionParameters builds the parameters that identify a submission
+func submissionParameters(submitter models.Identifier, submissionID string) *models.Parameters {
+	return models.NewParameters().
+		AddIdentifier("submitter", submitter).
+		AddString("submissionId", submissionID)
+}
+
+// Submit sends a $bulk-submit request
+func (c *Client) Submit(ctx context.Context, req *SubmitRequest) error {
+	httpReq, err := c.newRequest(ctx, http.MethodPost, "$bulk-submit", req.parameters())
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.op.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return statusError(resp)
+	}
+	return nil
+}
+
+// SubmitStatus kicks off a $bulk-submit-status request for a submission.
+// The job completes with a manifest of the results once the server has
+// processed the submission.
+func (c *Client) SubmitStatus(ctx context.Context, submitter models.Identifier, submissionID string) (*ImportJob, error) {
+	job, err := c.kickOff(ctx, http.MethodPost, "$bulk-submit-status", submissionParameters(submitter, submissionID))
+	if err != nil {
+		return nil, err
+	}
+	return &ImportJob{Job: *job}, nil
+}
+
+// ImportError is an issue reported for an input while loading it
+type ImportError struct {
+	// InputURL is the input file the issue relates to, if the server said
+	InputURL string
+
+	// Line is the line of the input file the issue relates to, or zero if not known
+	Line int
+
+	Issue models.OperationOutcomeIssue
+}
+
+func (e *ImportError) Error() string {
+	msg := e.Issue.Message()
+	if e.Line > 0 {
+		msg = "line " + strconv.Itoa(e.Line) + ": " + msg
+	}
+	if e.InputURL != "" {
+		msg = e.InputURL + ": " + msg
+	}
+	return msg
+}
+
+// issueLine finds a line number in an issue's expression, location or diagnostics
+var issueLine = regexp.MustCompile(`(?i)\bline\s*\[?\s*(\d+)`)
+
+// ImportErrors downloads the manifest's error files and returns the issues
+// they report, attributed to input files and lines where the server gives them
+func (c *Client) ImportErrors(ctx context.Context, manifest *ImportManifest) ([]ImportError, error) {
+	var result []ImportError
+	var errs []error
+	for _, file := range manifest.Error {
+		issues, err := c.readErrorFile(ctx, file, manifest.RequiresAccessToken)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read %s: %w", file.URL, err))
+			continue
+		}
+		result = append(result, issues...)
+	}
+	return result, errors.Join(errs...)
+}
+
+// readErrorFile reads the OperationOutcomes in a single error file
+func (c *Client) readErrorFile(ctx context.Context, file ImportFile, withToken bool) ([]ImportError, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, file.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/fhir+ndjson")
+
+	resp, err := c.getFile(req, withToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+
+	reader := ndjson.NewReader(resp.Body, nil)
+	defer reader.Close()
+
+	var result []ImportError
+	for {
+		resource, err := reader.Read()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		outcome, ok := resource.(*models.OperationOutcome)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected OperationOutcome, got %s", reader.Line(), resource.GetResourceType())
+		}
+		for _, issue := range outcome.Issue {
+			result = append(result, ImportError{
+				InputURL: file.InputURL,
+				Line:     lineOf(issue),
+				Issue:    issue,
+			})
+		}
+	}
+}
+
+// lineOf returns the input line an issue refers to, or zero
+func lineOf(issue models.OperationOutcomeIssue) int {
+	candidates := append(append([]string{}, issue.Expression...), issue.Location...)
+	candidates = append(candidates, issue.Diagnostics)
+	for _, s := range candidates {
+		if m := issueLine.FindStringSubmatch(s); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return line
+		}
+	}
+	return 0
+}
@@ -0,0 +1,128 @@
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/operations"
+)
+
+const importErrors = `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"invalid","diagnostics":"Patient.birthDate is invalid","location":["Line[3]"]}]}
+{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"processing","diagnostics":"failed to load line 7: duplicate id"}]}
+`
+
+func TestImportFlow(t *testing.T) {
+	received := make(map[string]*models.Parameters)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/$import", "/$bulk-submit", "/$bulk-submit-status":
+			if r.Header.Get("Content-Type") != "application/fhir+json" {
+				http.Error(w, "expected FHIR JSON", http.StatusUnsupportedMediaType)
+				return
+			}
+			var params models.Parameters
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			received[r.URL.Path] = &params
+			if r.URL.Path == "/$bulk-submit" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			if r.Header.Get("Prefer") != "respond-async" {
+				http.Error(w, "respond-async required", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Location", server.URL+"/status/import")
+			w.WriteHeader(http.StatusAccepted)
+		case "/status/import":
+			json.NewEncoder(w).Encode(ImportManifest{
+				Output: []ImportFile{{Type: "Patient", InputURL: "https://example.org/Patient.ndjson"}},
+				Error: []ImportFile{{
+					Type:     "OperationOutcome",
+					InputURL: "https://example.org/Patient.ndjson",
+					URL:      server.URL + "/errors.ndjson",
+				}},
+			})
+		case "/errors.ndjson":
+			io.WriteString(w, importErrors)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(operations.NewHTTPOperation(server.Client(), server.URL))
+	c.PollInterval = time.Millisecond
+	ctx := context.Background()
+
+	job, err := c.Import(ctx, &ImportRequest{
+		InputSource: "https://example.org/source",
+		StorageType: "https",
+		Inputs:      []ImportInput{{Type: "Patient", URL: "https://example.org/Patient.ndjson"}},
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	params := received["/$import"]
+	if params == nil || len(params.Parameter) != 4 {
+		t.Fatalf("Unexpected import manifest %+v", params)
+	}
+	if p := params.Parameter[0]; p.Name != "inputFormat" || *p.ValueCode != "application/fhir+ndjson" {
+		t.Errorf("Unexpected inputFormat %+v", p)
+	}
+	if p := params.Parameter[3]; p.Name != "input" || len(p.Part) != 2 || *p.Part[1].ValueURI != "https://example.org/Patient.ndjson" {
+		t.Errorf("Unexpected input %+v", p)
+	}
+
+	manifest, err := job.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	importErrs, err := c.ImportErrors(ctx, manifest)
+	if err != nil {
+		t.Fatalf("ImportErrors failed: %v", err)
+	}
+	if len(importErrs) != 2 || importErrs[0].Line != 3 || importErrs[1].Line != 7 {
+		t.Fatalf("Unexpected errors %+v", importErrs)
+	}
+	want := "https://example.org/Patient.ndjson: line 3: error invalid: Patient.birthDate is invalid (at Line[3])"
+	if got := importErrs[0].Error(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	submitter := models.Identifier{System: "https://example.org/submitters", Value: "s1"}
+	err = c.Submit(ctx, &SubmitRequest{
+		Submitter:    submitter,
+		SubmissionID: "batch-1",
+		ManifestURL:  "https://example.org/manifest.json",
+		Status:       "complete",
+	})
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	submit := received["/$bulk-submit"]
+	if submit == nil || len(submit.Parameter) != 4 || submit.Parameter[0].ValueIdentifier.Value != "s1" ||
+		submit.Parameter[3].ValueCoding.Code != "complete" {
+		t.Errorf("Unexpected bulk submit parameters %+v", submit)
+	}
+
+	statusJob, err := c.SubmitStatus(ctx, submitter, "batch-1")
+	if err != nil {
+		t.Fatalf("SubmitStatus failed: %v", err)
+	}
+	if !strings.HasSuffix(statusJob.StatusURL, "/status/import") {
+		t.Errorf("Unexpected status URL %s", statusJob.StatusURL)
+	}
+}
@@ -0,0 +1,73 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/operations"
+)
+
+// Job is an asynchronous bulk request that has been kicked off
+type Job struct {
+	*operations.AsyncJob
+}
+
+// newRequest creates a request to path relative to the base URL, with body
+// encoded as FHIR JSON if it is not nil
+func (c *Client) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	u := strings.TrimSuffix(c.op.BaseURL(), "/") + "/" + path
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/fhir+json")
+	}
+	return req, nil
+}
+
+// kickOff sends an asynchronous kick-off request and returns the job it starts
+func (c *Client) kickOff(ctx context.Context, method, path string, body interface{}) (*Job, error) {
+	job, err := c.op.SendAsync(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	if job.StatusURL == "" {
+		return nil, fmt.Errorf("kick-off response has no Content-Location")
+	}
+	if c.PollInterval > 0 {
+		job.PollInterval = c.PollInterval
+	}
+	if c.MaxPollInterval > 0 {
+		job.MaxPollInterval = c.MaxPollInterval
+	}
+	job.OnProgress = c.OnProgress
+	return &Job{AsyncJob: job}, nil
+}
+
+// Delete cancels the job if it is still running, or tells the server its
+// files can be removed once they have been downloaded
+func (j *Job) Delete(ctx context.Context) error {
+	return j.Cancel(ctx)
+}
+
+// statusError describes an unexpected response, including its body, which
+// is typically an OperationOutcome
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return fmt.Errorf("server returned error status %d: %s", resp.StatusCode, string(body))
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/mapper"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/operations"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/version"
 )
 
@@ -64,6 +65,15 @@ func (c *Client) Version() version.VersionManager {
 	return c.versionManager
 }
 
+// NewHTTPOperation creates an HTTP operation handler for the configured
+// server. Requests for resource types the client's FHIR version does not
+// define fail with operations.ErrUnsupportedResourceType without being sent.
+func (c *Client) NewHTTPOperation() *operations.HTTPOperation {
+	op := operations.NewHTTPOperation(c.httpClient, c.config.BaseURL)
+	op.SetVersion(c.versionManager)
+	return op
+}
+
 // MapResource converts a resource from the client's version to the target version
 func (c *Client) MapResource(resource interface{}, targetVersion version.Version) (interface{}, error) {
 	return c.mapper.MapResource(resource, c.config.FHIRVersion, targetVersion)
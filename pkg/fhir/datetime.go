@@ -0,0 +1,484 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Precision represents the granularity to which a date or time value is known
+type Precision int
+
+const (
+	PrecisionYear Precision = iota + 1
+	PrecisionMonth
+	PrecisionDay
+	PrecisionSecond
+)
+
+// String returns the name of the precision
+func (p Precision) String() string {
+	switch p {
+	case PrecisionYear:
+		return "year"
+	case PrecisionMonth:
+		return "month"
+	case PrecisionDay:
+		return "day"
+	case PrecisionSecond:
+		return "second"
+	default:
+		return "unknown"
+	}
+}
+
+// zoneForm records how the time zone of a value was written
+type zoneForm int
+
+const (
+	zoneNone zoneForm = iota
+	zoneUTC
+	zoneOffset
+)
+
+// dateTimePattern matches the lexical forms shared by date, dateTime and instant
+var dateTimePattern = regexp.MustCompile(
+	`^(\d{4})(?:-(\d{2})(?:-(\d{2})(?:T(\d{2}):(\d{2}):(\d{2})(?:\.(\d{1,9}))?(Z|[+-]\d{2}:\d{2})?)?)?)?$`)
+
+// dateTimeValue is the representation shared by Date, DateTime and Instant.
+// The time is held in the offset it was written with, or UTC when no time
+// zone was given.
+type dateTimeValue struct {
+	t         time.Time
+	precision Precision
+	digits    int
+	zone      zoneForm
+}
+
+// parseDateTimeValue parses any of the date, dateTime and instant forms
+func parseDateTimeValue(s string) (dateTimeValue, error) {
+	m := dateTimePattern.FindStringSubmatch(s)
+	if m == nil {
+		return dateTimeValue{}, fmt.Errorf("invalid date/time %q", s)
+	}
+
+	v := dateTimeValue{precision: PrecisionYear}
+	year, _ := strconv.Atoi(m[1])
+	month, day := 1, 1
+	var hour, minute, second, nanos int
+	loc := time.UTC
+
+	if m[2] != "" {
+		v.precision = PrecisionMonth
+		month, _ = strconv.Atoi(m[2])
+	}
+	if m[3] != "" {
+		v.precision = PrecisionDay
+		day, _ = strconv.Atoi(m[3])
+	}
+	if m[4] != "" {
+		v.precision = PrecisionSecond
+		hour, _ = strconv.Atoi(m[4])
+		minute, _ = strconv.Atoi(m[5])
+		second, _ = strconv.Atoi(m[6])
+		if m[7] != "" {
+			v.digits = len(m[7])
+			nanos, _ = strconv.Atoi(m[7] + strings.Repeat("0", 9-len(m[7])))
+		}
+		switch {
+		case m[8] == "Z":
+			v.zone = zoneUTC
+		case m[8] != "":
+			v.zone = zoneOffset
+			offset, err := parseOffset(m[8])
+			if err != nil {
+				return dateTimeValue{}, fmt.Errorf("invalid date/time %q: %w", s, err)
+			}
+			loc = time.FixedZone("", offset)
+		}
+	}
+
+	if month < 1 || month > 12 || hour > 23 || minute > 59 || second > 59 {
+		return dateTimeValue{}, fmt.Errorf("invalid date/time %q: field out of range", s)
+	}
+	v.t = time.Date(year, time.Month(month), day, hour, minute, second, nanos, loc)
+	if v.t.Day() != day {
+		return dateTimeValue{}, fmt.Errorf("invalid date/time %q: day out of range", s)
+	}
+	return v, nil
+}
+
+// parseOffset parses a "+hh:mm" or "-hh:mm" offset into seconds east of UTC
+func parseOffset(s string) (int, error) {
+	hours, _ := strconv.Atoi(s[1:3])
+	minutes, _ := strconv.Atoi(s[4:6])
+	if hours > 14 || minutes > 59 || (hours == 14 && minutes != 0) {
+		return 0, fmt.Errorf("time zone offset %s out of range", s)
+	}
+	offset := hours*3600 + minutes*60
+	if s[0] == '-' {
+		offset = -offset
+	}
+	return offset, nil
+}
+
+// dateTimeValueOf builds a second-precision value from t, keeping as many
+// fractional digits as are needed to represent it exactly
+func dateTimeValueOf(t time.Time) dateTimeValue {
+	v := dateTimeValue{t: t, precision: PrecisionSecond, zone: zoneOffset}
+	if t.Location() == time.UTC {
+		v.zone = zoneUTC
+	}
+	if nanos := t.Nanosecond(); nanos != 0 {
+		v.digits = 9
+		for nanos%10 == 0 {
+			nanos /= 10
+			v.digits--
+		}
+	}
+	return v
+}
+
+// String formats the value at its own precision
+func (v dateTimeValue) String() string {
+	if v.precision == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(v.t.Format("2006"))
+	if v.precision >= PrecisionMonth {
+		b.WriteString(v.t.Format("-01"))
+	}
+	if v.precision >= PrecisionDay {
+		b.WriteString(v.t.Format("-02"))
+	}
+	if v.precision >= PrecisionSecond {
+		b.WriteString(v.t.Format("T15:04:05"))
+		if v.digits > 0 {
+			b.WriteByte('.')
+			b.WriteString(fmt.Sprintf("%09d", v.t.Nanosecond())[:v.digits])
+		}
+		switch v.zone {
+		case zoneUTC:
+			b.WriteByte('Z')
+		case zoneOffset:
+			b.WriteString(v.t.Format("-07:00"))
+		}
+	}
+	return b.String()
+}
+
+// bounds returns the half-open interval [start, end) covered by the value
+func (v dateTimeValue) bounds() (time.Time, time.Time) {
+	start := v.t
+	switch v.precision {
+	case PrecisionYear:
+		return start, start.AddDate(1, 0, 0)
+	case PrecisionMonth:
+		return start, start.AddDate(0, 1, 0)
+	case PrecisionDay:
+		return start, start.AddDate(0, 0, 1)
+	default:
+		step := time.Second
+		for i := 0; i < v.digits; i++ {
+			step /= 10
+		}
+		return start, start.Add(step)
+	}
+}
+
+// compare orders two values. When the values have different precisions and
+// their intervals overlap the order is indeterminate and ok is false.
+func (v dateTimeValue) compare(other dateTimeValue) (result int, ok bool) {
+	start, end := v.bounds()
+	otherStart, otherEnd := other.bounds()
+
+	switch {
+	case !end.After(otherStart):
+		return -1, true
+	case !otherEnd.After(start):
+		return 1, true
+	case start.Equal(otherStart) && end.Equal(otherEnd):
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// equal reports whether two values denote the same interval at the same precision
+func (v dateTimeValue) equal(other dateTimeValue) bool {
+	return v.precision == other.precision && v.digits == other.digits && v.t.Equal(other.t)
+}
+
+// marshal returns the JSON string form of the value
+func (v dateTimeValue) marshal(typeName string) ([]byte, error) {
+	if v.precision == 0 {
+		return nil, fmt.Errorf("fhir: cannot marshal zero %s", typeName)
+	}
+	return []byte(strconv.Quote(v.String())), nil
+}
+
+// unquote returns the contents of a JSON string, reporting false for null
+func unquote(data []byte, typeName string) (string, bool, error) {
+	if string(data) == "null" {
+		return "", false, nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return "", false, fmt.Errorf("fhir: %s must be a JSON string, got %s", typeName, data)
+	}
+	return s, true, nil
+}
+
+// Date represents a FHIR date: a year, year-month or full date with no time
+// of day or time zone
+type Date struct {
+	v dateTimeValue
+}
+
+// NewDate returns a day-precision Date
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{v: dateTimeValue{
+		t:         time.Date(year, month, day, 0, 0, 0, 0, time.UTC),
+		precision: PrecisionDay,
+	}}
+}
+
+// ParseDate parses a date in YYYY, YYYY-MM or YYYY-MM-DD form
+func ParseDate(s string) (Date, error) {
+	v, err := parseDateTimeValue(s)
+	if err != nil {
+		return Date{}, err
+	}
+	if v.precision > PrecisionDay {
+		return Date{}, fmt.Errorf("invalid date %q: time of day not allowed", s)
+	}
+	return Date{v: v}, nil
+}
+
+// Time returns the start of the date in UTC
+func (d Date) Time() time.Time {
+	return d.v.t
+}
+
+// Precision returns the precision the date is known to
+func (d Date) Precision() Precision {
+	return d.v.precision
+}
+
+// IsZero reports whether d is the zero Date
+func (d Date) IsZero() bool {
+	return d.v.precision == 0
+}
+
+// Range returns the half-open interval [start, end) covered by the date,
+// e.g. the whole of 1980 for "1980"
+func (d Date) Range() (time.Time, time.Time) {
+	return d.v.bounds()
+}
+
+// Equal reports whether d and other are the same date at the same precision
+func (d Date) Equal(other Date) bool {
+	return d.v.equal(other.v)
+}
+
+// Compare returns -1, 0 or +1 as d is before, the same as, or after other.
+// ok is false when the order cannot be determined because the dates have
+// different precisions and overlap, e.g. "1980" and "1980-07".
+func (d Date) Compare(other Date) (result int, ok bool) {
+	return d.v.compare(other.v)
+}
+
+// String returns the date in its original precision
+func (d Date) String() string {
+	return d.v.String()
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Date) MarshalJSON() ([]byte, error) {
+	return d.v.marshal("Date")
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Date) UnmarshalJSON(data []byte) error {
+	s, ok, err := unquote(data, "date")
+	if !ok || err != nil {
+		return err
+	}
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// DateTime represents a FHIR dateTime: a partial or full date, optionally
+// with a time of day and time zone
+type DateTime struct {
+	v dateTimeValue
+}
+
+// NewDateTime returns a second-precision DateTime for t, keeping its offset
+// and as many fractional second digits as t needs
+func NewDateTime(t time.Time) DateTime {
+	return DateTime{v: dateTimeValueOf(t)}
+}
+
+// ParseDateTime parses a dateTime in any of the forms FHIR permits
+func ParseDateTime(s string) (DateTime, error) {
+	v, err := parseDateTimeValue(s)
+	if err != nil {
+		return DateTime{}, err
+	}
+	return DateTime{v: v}, nil
+}
+
+// Time returns the start of the value. Values without a time zone are
+// interpreted as UTC.
+func (d DateTime) Time() time.Time {
+	return d.v.t
+}
+
+// Precision returns the precision the value is known to
+func (d DateTime) Precision() Precision {
+	return d.v.precision
+}
+
+// FractionDigits returns the number of fractional second digits written
+func (d DateTime) FractionDigits() int {
+	return d.v.digits
+}
+
+// HasTimeZone reports whether the value was given with a time zone
+func (d DateTime) HasTimeZone() bool {
+	return d.v.zone != zoneNone
+}
+
+// IsZero reports whether d is the zero DateTime
+func (d DateTime) IsZero() bool {
+	return d.v.precision == 0
+}
+
+// Range returns the half-open interval [start, end) covered by the value
+func (d DateTime) Range() (time.Time, time.Time) {
+	return d.v.bounds()
+}
+
+// Equal reports whether d and other denote the same time at the same precision
+func (d DateTime) Equal(other DateTime) bool {
+	return d.v.equal(other.v)
+}
+
+// Compare returns -1, 0 or +1 as d is before, the same as, or after other.
+// ok is false when the values have different precisions and overlap.
+func (d DateTime) Compare(other DateTime) (result int, ok bool) {
+	return d.v.compare(other.v)
+}
+
+// String returns the value in its original precision and time zone form
+func (d DateTime) String() string {
+	return d.v.String()
+}
+
+// MarshalJSON implements json.Marshaler
+func (d DateTime) MarshalJSON() ([]byte, error) {
+	return d.v.marshal("DateTime")
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *DateTime) UnmarshalJSON(data []byte) error {
+	s, ok, err := unquote(data, "dateTime")
+	if !ok || err != nil {
+		return err
+	}
+	parsed, err := ParseDateTime(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Instant represents a FHIR instant: a full date and time to at least the
+// second, with a time zone
+type Instant struct {
+	v dateTimeValue
+}
+
+// NewInstant returns an Instant for t
+func NewInstant(t time.Time) Instant {
+	return Instant{v: dateTimeValueOf(t)}
+}
+
+// ParseInstant parses an instant such as 2015-02-07T13:28:17.239+02:00
+func ParseInstant(s string) (Instant, error) {
+	v, err := parseDateTimeValue(s)
+	if err != nil {
+		return Instant{}, err
+	}
+	if v.precision != PrecisionSecond || v.zone == zoneNone {
+		return Instant{}, fmt.Errorf("invalid instant %q: time and time zone required", s)
+	}
+	return Instant{v: v}, nil
+}
+
+// Time returns the instant as a time.Time in the offset it was written with
+func (i Instant) Time() time.Time {
+	return i.v.t
+}
+
+// FractionDigits returns the number of fractional second digits written
+func (i Instant) FractionDigits() int {
+	return i.v.digits
+}
+
+// IsZero reports whether i is the zero Instant
+func (i Instant) IsZero() bool {
+	return i.v.precision == 0
+}
+
+// Range returns the half-open interval [start, end) covered by the instant
+// at its written precision
+func (i Instant) Range() (time.Time, time.Time) {
+	return i.v.bounds()
+}
+
+// Equal reports whether i and other denote the same instant at the same precision
+func (i Instant) Equal(other Instant) bool {
+	return i.v.equal(other.v)
+}
+
+// Compare returns -1, 0 or +1 as i is before, the same as, or after other.
+// ok is false when the instants have different fractional precisions and overlap.
+func (i Instant) Compare(other Instant) (result int, ok bool) {
+	return i.v.compare(other.v)
+}
+
+// String returns the instant in its original form
+func (i Instant) String() string {
+	return i.v.String()
+}
+
+// MarshalJSON implements json.Marshaler
+func (i Instant) MarshalJSON() ([]byte, error) {
+	return i.v.marshal("Instant")
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (i *Instant) UnmarshalJSON(data []byte) error {
+	s, ok, err := unquote(data, "instant")
+	if !ok || err != nil {
+		return err
+	}
+	parsed, err := ParseInstant(s)
+	if err != nil {
+		return err
+	}
+	*i = parsed
+	return nil
+}
@@ -0,0 +1,189 @@
+package fhir
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDateTimeRoundTrip(t *testing.T) {
+	inputs := []string{
+		"1980",
+		"1980-07",
+		"1980-07-15",
+		"2015-02-07T13:28:17Z",
+		"2015-02-07T13:28:17.2390Z",
+		"2015-02-07T13:28:17-05:00",
+		"2015-02-07T13:28:17.239+02:00",
+		"2015-02-07T13:28:17",
+	}
+
+	for _, input := range inputs {
+		var d DateTime
+		if err := json.Unmarshal([]byte(`"`+input+`"`), &d); err != nil {
+			t.Errorf("Failed to unmarshal %s: %v", input, err)
+			continue
+		}
+		data, err := json.Marshal(d)
+		if err != nil {
+			t.Errorf("Failed to marshal %s: %v", input, err)
+			continue
+		}
+		if string(data) != `"`+input+`"` {
+			t.Errorf("Round trip mismatch: got %s, want %q", data, input)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		input     string
+		precision Precision
+		wantErr   bool
+	}{
+		{"1980", PrecisionYear, false},
+		{"1980-07", PrecisionMonth, false},
+		{"1980-07-15", PrecisionDay, false},
+		{"1980-02-30", 0, true},
+		{"1980-13", 0, true},
+		{"1980-07-15T10:00:00Z", 0, true},
+		{"not-a-date", 0, true},
+	}
+
+	for _, tt := range tests {
+		d, err := ParseDate(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Expected error parsing %q", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", tt.input, err)
+			continue
+		}
+		if d.Precision() != tt.precision {
+			t.Errorf("Expected precision %s for %q, got %s", tt.precision, tt.input, d.Precision())
+		}
+		if d.String() != tt.input {
+			t.Errorf("Expected %q, got %q", tt.input, d.String())
+		}
+	}
+}
+
+func TestParseInstantRequiresTimeZone(t *testing.T) {
+	if _, err := ParseInstant("2015-02-07T13:28:17"); err == nil {
+		t.Error("Expected error for instant without time zone")
+	}
+	if _, err := ParseInstant("2015-02-07"); err == nil {
+		t.Error("Expected error for instant without time")
+	}
+	i, err := ParseInstant("2015-02-07T13:28:17.239+02:00")
+	if err != nil {
+		t.Fatalf("Failed to parse instant: %v", err)
+	}
+	want := time.Date(2015, 2, 7, 11, 28, 17, 239000000, time.UTC)
+	if !i.Time().Equal(want) {
+		t.Errorf("Expected %v, got %v", want, i.Time())
+	}
+}
+
+func TestDateRange(t *testing.T) {
+	d, _ := ParseDate("1980-02")
+	start, end := d.Range()
+	if !start.Equal(time.Date(1980, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected range start %v", start)
+	}
+	if !end.Equal(time.Date(1980, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected range end %v", end)
+	}
+
+	dt, _ := ParseDateTime("2015-02-07T13:28:17.23Z")
+	start, end = dt.Range()
+	if end.Sub(start) != 10*time.Millisecond {
+		t.Errorf("Expected a 10ms range, got %v", end.Sub(start))
+	}
+}
+
+func TestDateTimeCompare(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		result int
+		ok     bool
+	}{
+		{"1980", "1981", -1, true},
+		{"1980-07", "1980-06-30", 1, true},
+		{"1980", "1980-07", 0, false},
+		{"1980-07-15", "1980-07-15", 0, true},
+		{"2015-02-07T13:28:17Z", "2015-02-07T15:28:17+02:00", 0, true},
+		{"2015-02-07T13:28:17Z", "2015-02-07T13:28:17.5Z", 0, false},
+		{"2015-02-07T13:28:17Z", "2015-02-07T13:28:18.000Z", -1, true},
+	}
+
+	for _, tt := range tests {
+		a, err := ParseDateTime(tt.a)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", tt.a, err)
+		}
+		b, err := ParseDateTime(tt.b)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", tt.b, err)
+		}
+		result, ok := a.Compare(b)
+		if result != tt.result || ok != tt.ok {
+			t.Errorf("Compare(%s, %s) = %d, %v; want %d, %v", tt.a, tt.b, result, ok, tt.result, tt.ok)
+		}
+	}
+}
+
+func TestNewDateTimeKeepsOffset(t *testing.T) {
+	loc := time.FixedZone("", -5*3600)
+	d := NewDateTime(time.Date(2020, 1, 2, 3, 4, 5, 120000000, loc))
+	if d.String() != "2020-01-02T03:04:05.12-05:00" {
+		t.Errorf("Unexpected format %s", d.String())
+	}
+	if !d.HasTimeZone() {
+		t.Error("Expected time zone to be present")
+	}
+}
+
+func TestTimeRoundTrip(t *testing.T) {
+	for _, input := range []string{"08:30:00", "23:59:59.5", "00:00:00.000"} {
+		var tm Time
+		if err := json.Unmarshal([]byte(`"`+input+`"`), &tm); err != nil {
+			t.Errorf("Failed to unmarshal %s: %v", input, err)
+			continue
+		}
+		data, err := json.Marshal(tm)
+		if err != nil {
+			t.Errorf("Failed to marshal %s: %v", input, err)
+			continue
+		}
+		if string(data) != `"`+input+`"` {
+			t.Errorf("Round trip mismatch: got %s, want %q", data, input)
+		}
+	}
+
+	if _, err := ParseTime("24:00:00"); err == nil {
+		t.Error("Expected error for hour 24")
+	}
+
+	a, _ := ParseTime("08:30:00")
+	b, _ := ParseTime("08:30:00.5")
+	if _, ok := a.Compare(b); ok {
+		t.Error("Expected comparison of overlapping precisions to be indeterminate")
+	}
+	c := NewTime(9, 0, 0, 0)
+	if result, ok := a.Compare(c); !ok || result != -1 {
+		t.Errorf("Expected 08:30:00 before 09:00:00, got %d, %v", result, ok)
+	}
+}
+
+func TestZeroValuesDoNotMarshal(t *testing.T) {
+	if _, err := json.Marshal(Date{}); err == nil {
+		t.Error("Expected error marshaling zero Date")
+	}
+	if _, err := json.Marshal(Time{}); err == nil {
+		t.Error("Expected error marshaling zero Time")
+	}
+}
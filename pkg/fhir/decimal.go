@@ -0,0 +1,198 @@
+package fhir
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// decimalPattern matches the lexical form of a FHIR decimal
+var decimalPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// maxDecimalExponent bounds the exponent of a decimal, so that a value such
+// as 1e999999999 is refused rather than expanded to a billion digits by Rat
+// and Range
+const maxDecimalExponent = 1000
+
+// Decimal represents a FHIR decimal. It keeps the lexical form it was read
+// with, so "0.10" is written back as "0.10", and does exact arithmetic.
+type Decimal struct {
+	s string
+}
+
+// ParseDecimal parses a decimal in FHIR's lexical form, e.g. "0.10" or "1.5e3"
+func ParseDecimal(s string) (Decimal, error) {
+	if !decimalPattern.MatchString(s) {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		exponent, err := strconv.Atoi(s[i+1:])
+		if err != nil || exponent > maxDecimalExponent || exponent < -maxDecimalExponent {
+			return Decimal{}, fmt.Errorf("decimal %q is out of range", s)
+		}
+	}
+	return Decimal{s: s}, nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics on error
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// NewDecimalFromFloat returns the shortest Decimal that round-trips to f. It
+// returns an error for NaN and infinities, which FHIR decimals cannot hold.
+func NewDecimalFromFloat(f float64) (Decimal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}, fmt.Errorf("invalid decimal %v", f)
+	}
+	return Decimal{s: strconv.FormatFloat(f, 'f', -1, 64)}, nil
+}
+
+// NewDecimalFromInt returns a Decimal for the integer i
+func NewDecimalFromInt(i int64) Decimal {
+	return Decimal{s: strconv.FormatInt(i, 10)}
+}
+
+// IsZero reports whether d is the zero Decimal, as opposed to the value 0
+func (d Decimal) IsZero() bool {
+	return d.s == ""
+}
+
+// String returns the decimal in its original lexical form
+func (d Decimal) String() string {
+	return d.s
+}
+
+// Rat returns the exact value of the decimal
+func (d Decimal) Rat() *big.Rat {
+	r, ok := new(big.Rat).SetString(d.s)
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+// Float64 returns the nearest float64 to the decimal
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.s, 64)
+	return f
+}
+
+// parts splits the decimal into its digits before and after the point and its exponent
+func (d Decimal) parts() (integer, fraction string, exponent int) {
+	mantissa := strings.TrimPrefix(d.s, "-")
+	if i := strings.IndexAny(mantissa, "eE"); i >= 0 {
+		exponent, _ = strconv.Atoi(mantissa[i+1:])
+		mantissa = mantissa[:i]
+	}
+	integer, fraction, _ = strings.Cut(mantissa, ".")
+	return integer, fraction, exponent
+}
+
+// Scale returns the number of decimal places the value is given to. It is
+// negative for values such as "1.5e3" that are known only to the hundreds.
+func (d Decimal) Scale() int {
+	_, fraction, exponent := d.parts()
+	return len(fraction) - exponent
+}
+
+// SignificantFigures returns the number of significant digits in the value,
+// counting trailing zeros after the decimal point
+func (d Decimal) SignificantFigures() int {
+	integer, fraction, _ := d.parts()
+	digits := strings.TrimLeft(integer+fraction, "0")
+	if digits == "" {
+		// All zeros: the significant figures are those after the point, if any
+		return max(len(fraction), 1)
+	}
+	return len(digits)
+}
+
+// Cmp compares the values of d and other, returning -1, 0 or +1. Values that
+// differ only in precision, such as "0.1" and "0.10", compare equal.
+func (d Decimal) Cmp(other Decimal) int {
+	return d.Rat().Cmp(other.Rat())
+}
+
+// Equal reports whether d and other have the same value
+func (d Decimal) Equal(other Decimal) bool {
+	return d.Cmp(other) == 0
+}
+
+// Add returns d + other, given to the larger of the two scales
+func (d Decimal) Add(other Decimal) Decimal {
+	return decimalFromRat(new(big.Rat).Add(d.Rat(), other.Rat()), max(d.Scale(), other.Scale()))
+}
+
+// Sub returns d - other, given to the larger of the two scales
+func (d Decimal) Sub(other Decimal) Decimal {
+	return decimalFromRat(new(big.Rat).Sub(d.Rat(), other.Rat()), max(d.Scale(), other.Scale()))
+}
+
+// Mul returns d * other, given to the sum of the two scales
+func (d Decimal) Mul(other Decimal) Decimal {
+	return decimalFromRat(new(big.Rat).Mul(d.Rat(), other.Rat()), d.Scale()+other.Scale())
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	if strings.HasPrefix(d.s, "-") {
+		return Decimal{s: d.s[1:]}
+	}
+	return Decimal{s: "-" + d.s}
+}
+
+// Range returns the interval implied by the precision of the value, as used
+// by FHIR search: 100 covers [99.5, 100.5) and 0.10 covers [0.095, 0.105)
+func (d Decimal) Range() (low, high Decimal) {
+	scale := d.Scale()
+	half := new(big.Rat).SetFrac(big.NewInt(1), big.NewInt(2))
+	if scale >= 0 {
+		half.Quo(half, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	} else {
+		half.Mul(half, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-scale)), nil)))
+	}
+
+	value := d.Rat()
+	return decimalFromRat(new(big.Rat).Sub(value, half), scale+1),
+		decimalFromRat(new(big.Rat).Add(value, half), scale+1)
+}
+
+// decimalFromRat formats r to the given number of decimal places, which must
+// be enough to represent r exactly for the result to be exact
+func decimalFromRat(r *big.Rat, scale int) Decimal {
+	s := r.FloatString(max(scale, 0))
+	if s == "-0" || strings.HasPrefix(s, "-0.") && strings.Trim(s[3:], "0") == "" {
+		s = s[1:]
+	}
+	return Decimal{s: s}
+}
+
+// MarshalJSON implements json.Marshaler, writing the decimal as a JSON number
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if d.s == "" {
+		return nil, fmt.Errorf("fhir: cannot marshal zero Decimal")
+	}
+	return []byte(d.s), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping the number's lexical form
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	parsed, err := ParseDecimal(s)
+	if err != nil {
+		return fmt.Errorf("fhir: decimal must be a JSON number, got %s", data)
+	}
+	*d = parsed
+	return nil
+}
@@ -0,0 +1,133 @@
+package fhir
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDecimalRoundTrip(t *testing.T) {
+	inputs := []string{"0.10", "100", "-3.14159", "12345678901234567890.123456789", "1.50e3", "0"}
+
+	for _, input := range inputs {
+		var d Decimal
+		if err := json.Unmarshal([]byte(input), &d); err != nil {
+			t.Errorf("Failed to unmarshal %s: %v", input, err)
+			continue
+		}
+		data, err := json.Marshal(d)
+		if err != nil {
+			t.Errorf("Failed to marshal %s: %v", input, err)
+			continue
+		}
+		if string(data) != input {
+			t.Errorf("Round trip mismatch: got %s, want %s", data, input)
+		}
+	}
+}
+
+func TestDecimalRoundTripInStruct(t *testing.T) {
+	jsonData := `{"value":0.10,"unit":"mmol/L"}`
+	var q struct {
+		Value *Decimal `json:"value"`
+		Unit  string   `json:"unit"`
+	}
+	if err := json.Unmarshal([]byte(jsonData), &q); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	data, err := json.Marshal(q)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	if string(data) != jsonData {
+		t.Errorf("Expected %s, got %s", jsonData, data)
+	}
+}
+
+func TestDecimalRejectsInvalid(t *testing.T) {
+	for _, input := range []string{`"0.10"`, `01`, `1.`, `.5`, `true`, `1e999999999`, `1e-1001`, `1e99999999999999999999`} {
+		var d Decimal
+		if err := json.Unmarshal([]byte(input), &d); err == nil {
+			t.Errorf("Expected error unmarshaling %s", input)
+		}
+	}
+}
+
+func TestDecimalFromFloat(t *testing.T) {
+	d, err := NewDecimalFromFloat(0.1)
+	if err != nil || d.String() != "0.1" {
+		t.Errorf("Expected 0.1, got %s: %v", d, err)
+	}
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := NewDecimalFromFloat(f); err == nil {
+			t.Errorf("Expected error for %v", f)
+		}
+	}
+}
+
+func TestDecimalPrecision(t *testing.T) {
+	tests := []struct {
+		input       string
+		scale       int
+		significant int
+	}{
+		{"0.10", 2, 2},
+		{"100", 0, 3},
+		{"1.50e3", -1, 3},
+		{"0.0050", 4, 2},
+		{"0.00", 2, 2},
+	}
+
+	for _, tt := range tests {
+		d := MustParseDecimal(tt.input)
+		if d.Scale() != tt.scale {
+			t.Errorf("Scale(%s) = %d, want %d", tt.input, d.Scale(), tt.scale)
+		}
+		if d.SignificantFigures() != tt.significant {
+			t.Errorf("SignificantFigures(%s) = %d, want %d", tt.input, d.SignificantFigures(), tt.significant)
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	a := MustParseDecimal("0.10")
+	b := MustParseDecimal("0.2")
+
+	if got := a.Add(b).String(); got != "0.30" {
+		t.Errorf("Expected 0.10 + 0.2 = 0.30, got %s", got)
+	}
+	if got := a.Sub(b).String(); got != "-0.10" {
+		t.Errorf("Expected 0.10 - 0.2 = -0.10, got %s", got)
+	}
+	if got := a.Mul(b).String(); got != "0.020" {
+		t.Errorf("Expected 0.10 * 0.2 = 0.020, got %s", got)
+	}
+	if !a.Equal(MustParseDecimal("0.1")) {
+		t.Error("Expected 0.10 to equal 0.1")
+	}
+	if a.Cmp(b) != -1 {
+		t.Error("Expected 0.10 < 0.2")
+	}
+	if got := a.Neg().String(); got != "-0.10" {
+		t.Errorf("Expected -0.10, got %s", got)
+	}
+}
+
+func TestDecimalRange(t *testing.T) {
+	tests := []struct {
+		input, low, high string
+	}{
+		{"100", "99.5", "100.5"},
+		{"0.10", "0.095", "0.105"},
+		{"1.5e3", "1450", "1550"},
+		{"1e1000", "5" + strings.Repeat("0", 999), "15" + strings.Repeat("0", 999)},
+	}
+
+	for _, tt := range tests {
+		low, high := MustParseDecimal(tt.input).Range()
+		if low.String() != tt.low || high.String() != tt.high {
+			t.Errorf("Range(%s) = [%s, %s), want [%s, %s)", tt.input, low, high, tt.low, tt.high)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package fhir
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timePattern matches the lexical form of a FHIR time
+var timePattern = regexp.MustCompile(`^(\d{2}):(\d{2}):(\d{2})(?:\.(\d{1,9}))?$`)
+
+// Time represents a FHIR time: a time of day with no date or time zone
+type Time struct {
+	d      time.Duration
+	digits int
+	valid  bool
+}
+
+// NewTime returns a Time for the given time of day. The nanoseconds are kept
+// to as many fractional digits as they need.
+func NewTime(hour, minute, second, nanosecond int) Time {
+	t := Time{
+		d: time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute +
+			time.Duration(second)*time.Second + time.Duration(nanosecond),
+		valid: true,
+	}
+	if nanosecond != 0 {
+		t.digits = 9
+		for nanosecond%10 == 0 {
+			nanosecond /= 10
+			t.digits--
+		}
+	}
+	return t
+}
+
+// ParseTime parses a time in hh:mm:ss or hh:mm:ss.fff form
+func ParseTime(s string) (Time, error) {
+	m := timePattern.FindStringSubmatch(s)
+	if m == nil {
+		return Time{}, fmt.Errorf("invalid time %q", s)
+	}
+
+	hour, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	second, _ := strconv.Atoi(m[3])
+	if hour > 23 || minute > 59 || second > 59 {
+		return Time{}, fmt.Errorf("invalid time %q: field out of range", s)
+	}
+
+	var nanos int
+	if m[4] != "" {
+		nanos, _ = strconv.Atoi(m[4] + strings.Repeat("0", 9-len(m[4])))
+	}
+	t := NewTime(hour, minute, second, nanos)
+	t.digits = len(m[4])
+	return t, nil
+}
+
+// SinceMidnight returns the time of day as a duration since midnight
+func (t Time) SinceMidnight() time.Duration {
+	return t.d
+}
+
+// Hour returns the hour of the day
+func (t Time) Hour() int {
+	return int(t.d / time.Hour)
+}
+
+// Minute returns the minute within the hour
+func (t Time) Minute() int {
+	return int(t.d % time.Hour / time.Minute)
+}
+
+// Second returns the second within the minute
+func (t Time) Second() int {
+	return int(t.d % time.Minute / time.Second)
+}
+
+// Nanosecond returns the nanosecond within the second
+func (t Time) Nanosecond() int {
+	return int(t.d % time.Second)
+}
+
+// FractionDigits returns the number of fractional second digits written
+func (t Time) FractionDigits() int {
+	return t.digits
+}
+
+// IsZero reports whether t is the zero Time, as opposed to midnight
+func (t Time) IsZero() bool {
+	return !t.valid
+}
+
+// Range returns the half-open interval [start, end) covered by the time at
+// its written precision, as durations since midnight
+func (t Time) Range() (time.Duration, time.Duration) {
+	step := time.Second
+	for i := 0; i < t.digits; i++ {
+		step /= 10
+	}
+	return t.d, t.d + step
+}
+
+// Equal reports whether t and other are the same time at the same precision
+func (t Time) Equal(other Time) bool {
+	return t.d == other.d && t.digits == other.digits && t.valid == other.valid
+}
+
+// Compare returns -1, 0 or +1 as t is before, the same as, or after other.
+// ok is false when the times have different precisions and overlap.
+func (t Time) Compare(other Time) (result int, ok bool) {
+	start, end := t.Range()
+	otherStart, otherEnd := other.Range()
+
+	switch {
+	case end <= otherStart:
+		return -1, true
+	case otherEnd <= start:
+		return 1, true
+	case start == otherStart && end == otherEnd:
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// String returns the time in hh:mm:ss form with its original fractional digits
+func (t Time) String() string {
+	if !t.valid {
+		return ""
+	}
+	s := fmt.Sprintf("%02d:%02d:%02d", t.Hour(), t.Minute(), t.Second())
+	if t.digits > 0 {
+		s += "." + fmt.Sprintf("%09d", t.Nanosecond())[:t.digits]
+	}
+	return s
+}
+
+// MarshalJSON implements json.Marshaler
+func (t Time) MarshalJSON() ([]byte, error) {
+	if !t.valid {
+		return nil, fmt.Errorf("fhir: cannot marshal zero Time")
+	}
+	return []byte(strconv.Quote(t.String())), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *Time) UnmarshalJSON(data []byte) error {
+	s, ok, err := unquote(data, "time")
+	if !ok || err != nil {
+		return err
+	}
+	parsed, err := ParseTime(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
@@ -0,0 +1,837 @@
+package fhirpath
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/resolver"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/terminology"
+)
+
+// ReferenceResolver follows references for resolve(). It is satisfied by
+// *resolver.Resolver.
+type ReferenceResolver interface {
+	Resolve(ctx context.Context, ref models.Reference, scope *resolver.Scope) (models.Resource, error)
+}
+
+// Evaluator evaluates FHIRPath expressions, caching their parsed form. The
+// zero value is ready to use.
+type Evaluator struct {
+	// Resolver follows references for resolve() that do not point to a
+	// contained resource or an entry of the Bundle being evaluated. When it
+	// is nil such references resolve to nothing.
+	Resolver ReferenceResolver
+
+	// Terminology checks codes for memberOf(). memberOf() is an error when it is nil.
+	Terminology terminology.Service
+
+	// Variables are made available to expressions as %name. Values may be
+	// anything accepted as input to Evaluate.
+	Variables map[string]interface{}
+
+	// Trace, if set, receives the values passed to trace()
+	Trace func(name string, values Collection)
+
+	mu    sync.Mutex
+	cache map[string]*Expression
+}
+
+// NewEvaluator creates an evaluator with no resolver, terminology service or variables
+func NewEvaluator() *Evaluator {
+	return &Evaluator{cache: make(map[string]*Expression)}
+}
+
+// Evaluate parses and evaluates an expression with the default evaluator.
+// See Evaluator.Evaluate for the inputs accepted.
+func Evaluate(input interface{}, expression string) (Collection, error) {
+	return NewEvaluator().Evaluate(context.Background(), input, expression)
+}
+
+// Evaluate evaluates the expression with the default evaluator
+func (x *Expression) Evaluate(ctx context.Context, input interface{}) (Collection, error) {
+	return NewEvaluator().EvaluateExpression(ctx, input, x)
+}
+
+// compile returns the parsed form of an expression, parsing it on first use
+func (e *Evaluator) compile(expression string) (*Expression, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if x, ok := e.cache[expression]; ok {
+		return x, nil
+	}
+	x, err := Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	if e.cache == nil {
+		e.cache = make(map[string]*Expression)
+	}
+	e.cache[expression] = x
+	return x, nil
+}
+
+// Evaluate evaluates an expression against input, which is available to
+// the expression as %resource and %context. input may be a models.Resource
+// or any other value that marshals to a FHIR JSON object, JSON as []byte or
+// json.RawMessage, a tree decoded from JSON, or a Collection.
+func (e *Evaluator) Evaluate(ctx context.Context, input interface{}, expression string) (Collection, error) {
+	x, err := e.compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return e.EvaluateExpression(ctx, input, x)
+}
+
+// EvaluateExpression evaluates a parsed expression against input, like Evaluate
+func (e *Evaluator) EvaluateExpression(ctx context.Context, input interface{}, x *Expression) (Collection, error) {
+	focus, err := toCollection(input)
+	if err != nil {
+		return nil, err
+	}
+	return e.run(ctx, x, focus, focus)
+}
+
+// EvaluateBoolean evaluates an expression that yields a single boolean. An
+// empty result is false.
+func (e *Evaluator) EvaluateBoolean(ctx context.Context, input interface{}, expression string) (bool, error) {
+	result, err := e.Evaluate(ctx, input, expression)
+	if err != nil {
+		return false, err
+	}
+	value, known, err := booleanOf(result)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", expression, err)
+	}
+	return known && value, nil
+}
+
+// EvaluateInvariant evaluates the expression of a constraint with node as
+// its focus and resource as %resource. The invariant holds unless the
+// expression yields false; an empty result does not violate it. It
+// implements validation.InvariantEvaluator.
+func (e *Evaluator) EvaluateInvariant(expression string, resource, node interface{}) (bool, error) {
+	x, err := e.compile(expression)
+	if err != nil {
+		return false, err
+	}
+	root, err := toCollection(resource)
+	if err != nil {
+		return false, err
+	}
+	focus, err := toCollection(node)
+	if err != nil {
+		return false, err
+	}
+	result, err := e.run(context.Background(), x, root, focus)
+	if err != nil {
+		return false, err
+	}
+	value, known, err := booleanOf(result)
+	if err != nil {
+		return false, err
+	}
+	return !known || value, nil
+}
+
+// run evaluates x with focus as its input, within resource
+func (e *Evaluator) run(ctx context.Context, x *Expression, resource, focus Collection) (Collection, error) {
+	c := &evalContext{
+		ctx:      ctx,
+		e:        e,
+		this:     focus,
+		context:  focus,
+		resource: resource,
+		now:      time.Now(),
+	}
+	result, err := x.root.eval(c, focus)
+	if err != nil {
+		return nil, fmt.Errorf("fhirpath: %s: %w", x.source, err)
+	}
+	return result, nil
+}
+
+// toCollection converts an input to a collection
+func toCollection(input interface{}) (Collection, error) {
+	switch v := input.(type) {
+	case nil:
+		return nil, nil
+	case Collection:
+		return v, nil
+	case *Element:
+		return Collection{v}, nil
+	case map[string]interface{}:
+		return Collection{newElement(v, nil, "", "", "")}, nil
+	case []interface{}:
+		var result Collection
+		for _, item := range v {
+			c, err := toCollection(item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, c...)
+		}
+		return result, nil
+	case json.RawMessage:
+		return decodeCollection(v)
+	case []byte:
+		return decodeCollection(v)
+	case bool, int64, string, fhir.Decimal, Date, DateTime, Time, Quantity:
+		return Collection{v}, nil
+	case int:
+		return Collection{int64(v)}, nil
+	case float64:
+		d, err := fhir.NewDecimalFromFloat(v)
+		return Collection{d}, err
+	case fhir.Date:
+		d, err := ParseDate(v.String())
+		return Collection{d}, err
+	case fhir.DateTime:
+		dt, err := ParseDateTime(v.String())
+		return Collection{dt}, err
+	case fhir.Instant:
+		dt, err := ParseDateTime(v.String())
+		return Collection{dt}, err
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %w", err)
+	}
+	return decodeCollection(data)
+}
+
+// decodeCollection decodes JSON into a collection, keeping numbers exact
+func decodeCollection(data []byte) (Collection, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, fmt.Errorf("failed to decode input: %w", err)
+	}
+	return toCollection(tree)
+}
+
+// evalContext is the state of an evaluation
+type evalContext struct {
+	ctx context.Context
+	e   *Evaluator
+
+	// this is $this: the input of the expression, or the item a function
+	// argument is being evaluated for
+	this  Collection
+	index int
+	total Collection
+
+	context  Collection
+	resource Collection
+	now      time.Time
+}
+
+// with returns a context for evaluating a function argument for one item
+func (c *evalContext) with(item interface{}, index int) *evalContext {
+	child := *c
+	child.this = Collection{item}
+	child.index = index
+	return &child
+}
+
+// arg evaluates a function argument that is not evaluated per item
+func (c *evalContext) arg(a expr) (Collection, error) {
+	return a.eval(c, c.this)
+}
+
+// variable returns the value of an environment variable
+func (c *evalContext) variable(name string) (Collection, error) {
+	switch name {
+	case "resource", "rootResource":
+		return c.resource, nil
+	case "context":
+		return c.context, nil
+	case "ucum":
+		return Collection{ucumSystem}, nil
+	case "sct":
+		return Collection{"http://snomed.info/sct"}, nil
+	case "loinc":
+		return Collection{"http://loinc.org"}, nil
+	}
+	if value, ok := c.e.Variables[name]; ok {
+		return toCollection(value)
+	}
+	if vs, ok := strings.CutPrefix(name, "vs-"); ok {
+		return Collection{"http://hl7.org/fhir/ValueSet/" + vs}, nil
+	}
+	if ext, ok := strings.CutPrefix(name, "ext-"); ok {
+		return Collection{"http://hl7.org/fhir/StructureDefinition/" + ext}, nil
+	}
+	return nil, fmt.Errorf("undefined variable %%%s", name)
+}
+
+func (x *literalExpr) eval(c *evalContext, focus Collection) (Collection, error) {
+	return x.value, nil
+}
+
+func (x *variableExpr) eval(c *evalContext, focus Collection) (Collection, error) {
+	return c.variable(x.name)
+}
+
+func (x *specialExpr) eval(c *evalContext, focus Collection) (Collection, error) {
+	switch x.name {
+	case "$this":
+		return c.this, nil
+	case "$index":
+		return Collection{int64(c.index)}, nil
+	default:
+		return c.total, nil
+	}
+}
+
+func (x *invokeExpr) eval(c *evalContext, focus Collection) (Collection, error) {
+	input := focus
+	if x.target != nil {
+		var err error
+		if input, err = x.target.eval(c, focus); err != nil {
+			return nil, err
+		}
+	}
+	if x.call {
+		return c.call(x.name, input, x.args)
+	}
+
+	// A type name at the start of a path selects the items of that type,
+	// as in Patient.name
+	if x.target == nil && unicode.IsUpper(rune(x.name[0])) {
+		var result Collection
+		for _, item := range input {
+			if isType(item, typeSpecifier{name: x.name}) {
+				result = append(result, item)
+			}
+		}
+		return result, nil
+	}
+
+	var result Collection
+	for _, item := range input {
+		if e, ok := item.(*Element); ok {
+			result = append(result, e.child(x.name)...)
+		}
+	}
+	return result, nil
+}
+
+func (x *indexerExpr) eval(c *evalContext, focus Collection) (Collection, error) {
+	target, err := x.target.eval(c, focus)
+	if err != nil {
+		return nil, err
+	}
+	index, err := x.index.eval(c, c.this)
+	if err != nil {
+		return nil, err
+	}
+	i, ok, err := integerOf(index)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if i < 0 || i >= int64(len(target)) {
+		return nil, nil
+	}
+	return Collection{target[i]}, nil
+}
+
+func (x *unaryExpr) eval(c *evalContext, focus Collection) (Collection, error) {
+	operand, err := x.operand.eval(c, focus)
+	if err != nil {
+		return nil, err
+	}
+	value, err := singleton(operand)
+	if err != nil || value == nil || x.op == "+" {
+		return operand, err
+	}
+	switch v := systemItem(value).(type) {
+	case int64:
+		return integer(new(big.Int).Neg(big.NewInt(v))), nil
+	case fhir.Decimal:
+		return Collection{v.Neg()}, nil
+	case Quantity:
+		return Collection{Quantity{Value: v.Value.Neg(), Unit: v.Unit}}, nil
+	}
+	return nil, fmt.Errorf("cannot negate %s", describeType(value))
+}
+
+func (x *typeExpr) eval(c *evalContext, focus Collection) (Collection, error) {
+	operand, err := x.operand.eval(c, focus)
+	if err != nil {
+		return nil, err
+	}
+	if x.op == "is" {
+		return isOperator(operand, x.typ)
+	}
+	return asOperator(operand, x.typ), nil
+}
+
+// isOperator implements is and is()
+func isOperator(operand Collection, spec typeSpecifier) (Collection, error) {
+	value, err := singleton(operand)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	return Collection{isType(value, spec)}, nil
+}
+
+// asOperator implements as and as()
+func asOperator(operand Collection, spec typeSpecifier) Collection {
+	var result Collection
+	for _, item := range operand {
+		if isType(item, spec) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func (x *binaryExpr) eval(c *evalContext, focus Collection) (Collection, error) {
+	left, err := x.left.eval(c, focus)
+	if err != nil {
+		return nil, err
+	}
+
+	switch x.op {
+	case "and", "or", "xor", "implies":
+		return x.logic(c, focus, left)
+	}
+
+	right, err := x.right.eval(c, focus)
+	if err != nil {
+		return nil, err
+	}
+
+	switch x.op {
+	case "|":
+		return distinct(append(append(Collection{}, left...), right...)), nil
+
+	case "=", "!=":
+		if len(left) == 0 || len(right) == 0 {
+			return nil, nil
+		}
+		result, ok := equalCollections(left, right)
+		if !ok {
+			return nil, nil
+		}
+		return Collection{result == (x.op == "=")}, nil
+
+	case "~", "!~":
+		return Collection{equivalentCollections(left, right) == (x.op == "~")}, nil
+
+	case "<", "<=", ">", ">=":
+		a, err := singleton(left)
+		if err != nil {
+			return nil, err
+		}
+		b, err := singleton(right)
+		if err != nil || a == nil || b == nil {
+			return nil, err
+		}
+		result, ok, err := compare(a, b)
+		if err != nil || !ok {
+			return nil, err
+		}
+		switch x.op {
+		case "<":
+			return Collection{result < 0}, nil
+		case "<=":
+			return Collection{result <= 0}, nil
+		case ">":
+			return Collection{result > 0}, nil
+		default:
+			return Collection{result >= 0}, nil
+		}
+
+	case "in", "contains":
+		item, collection := left, right
+		if x.op == "contains" {
+			item, collection = right, left
+		}
+		value, err := singleton(item)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		return Collection{contains(collection, value)}, nil
+
+	case "&":
+		a, err := stringOf(left)
+		if err != nil {
+			return nil, err
+		}
+		b, err := stringOf(right)
+		if err != nil {
+			return nil, err
+		}
+		return Collection{a + b}, nil
+	}
+
+	a, err := singleton(left)
+	if err != nil {
+		return nil, err
+	}
+	b, err := singleton(right)
+	if err != nil || a == nil || b == nil {
+		return nil, err
+	}
+	return arithmetic(x.op, systemItem(a), systemItem(b))
+}
+
+// logic evaluates the boolean operators with three-valued logic, only
+// evaluating the right operand when it can affect the result
+func (x *binaryExpr) logic(c *evalContext, focus, left Collection) (Collection, error) {
+	a, knownA, err := booleanOf(left)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case x.op == "and" && knownA && !a,
+		x.op == "or" && knownA && a:
+		return Collection{a}, nil
+	case x.op == "implies" && knownA && !a:
+		return Collection{true}, nil
+	}
+
+	right, err := x.right.eval(c, focus)
+	if err != nil {
+		return nil, err
+	}
+	b, knownB, err := booleanOf(right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch x.op {
+	case "and":
+		switch {
+		case knownB && !b:
+			return Collection{false}, nil
+		case knownA && knownB:
+			return Collection{true}, nil
+		}
+	case "or":
+		switch {
+		case knownB && b:
+			return Collection{true}, nil
+		case knownA && knownB:
+			return Collection{false}, nil
+		}
+	case "xor":
+		if knownA && knownB {
+			return Collection{a != b}, nil
+		}
+	case "implies":
+		switch {
+		case knownB && b:
+			return Collection{true}, nil
+		case knownA && knownB:
+			return Collection{false}, nil
+		}
+	}
+	return nil, nil
+}
+
+// integer converts the result of integer arithmetic to an Integer, or empty
+// if it does not fit in 64 bits
+func integer(n *big.Int) Collection {
+	if !n.IsInt64() {
+		return nil
+	}
+	return Collection{n.Int64()}
+}
+
+// arithmetic applies +, -, *, /, div or mod to two system values
+func arithmetic(op string, a, b interface{}) (Collection, error) {
+	a, b = promote(a, b)
+
+	switch x := a.(type) {
+	case int64:
+		y, ok := b.(int64)
+		if !ok {
+			break
+		}
+		if op == "/" {
+			return arithmetic(op, fhir.NewDecimalFromInt(x), fhir.NewDecimalFromInt(y))
+		}
+		if y == 0 && (op == "div" || op == "mod") {
+			return nil, nil
+		}
+		bx, by := big.NewInt(x), big.NewInt(y)
+		switch op {
+		case "+":
+			return integer(bx.Add(bx, by)), nil
+		case "-":
+			return integer(bx.Sub(bx, by)), nil
+		case "*":
+			return integer(bx.Mul(bx, by)), nil
+		case "div":
+			return integer(bx.Quo(bx, by)), nil
+		case "mod":
+			return integer(bx.Rem(bx, by)), nil
+		}
+
+	case fhir.Decimal:
+		y, ok := b.(fhir.Decimal)
+		if !ok {
+			if q, isQuantity := b.(Quantity); isQuantity && op == "*" {
+				return Collection{Quantity{Value: x.Mul(q.Value), Unit: q.Unit}}, nil
+			}
+			break
+		}
+		switch op {
+		case "+":
+			return Collection{x.Add(y)}, nil
+		case "-":
+			return Collection{x.Sub(y)}, nil
+		case "*":
+			return Collection{x.Mul(y)}, nil
+		}
+		if y.Rat().Sign() == 0 {
+			return nil, nil
+		}
+		quotient := new(big.Rat).Quo(x.Rat(), y.Rat())
+		switch op {
+		case "/":
+			return Collection{decimalFromRat(quotient)}, nil
+		case "div":
+			return Collection{fhir.MustParseDecimal(truncate(quotient).String())}, nil
+		case "mod":
+			whole := new(big.Rat).SetInt(truncate(quotient))
+			return Collection{x.Sub(decimalFromRat(whole.Mul(whole, y.Rat())))}, nil
+		}
+
+	case string:
+		if y, ok := b.(string); ok && op == "+" {
+			return Collection{x + y}, nil
+		}
+
+	case Quantity:
+		switch y := b.(type) {
+		case Quantity:
+			switch op {
+			case "+":
+				q, err := addQuantities(x, y, 1)
+				return Collection{q}, err
+			case "-":
+				q, err := addQuantities(x, y, -1)
+				return Collection{q}, err
+			case "*":
+				return Collection{Quantity{Value: x.Value.Mul(y.Value), Unit: combineUnits(x.Unit, y.Unit, ".")}}, nil
+			case "/":
+				if y.Value.Rat().Sign() == 0 {
+					return nil, nil
+				}
+				if converted, ok := convertQuantity(y, x.Unit); ok {
+					return Collection{Quantity{Value: decimalFromRat(new(big.Rat).Quo(x.Value.Rat(), converted.Value.Rat())), Unit: "1"}}, nil
+				}
+				value := decimalFromRat(new(big.Rat).Quo(x.Value.Rat(), y.Value.Rat()))
+				return Collection{Quantity{Value: value, Unit: combineUnits(x.Unit, y.Unit, "/")}}, nil
+			}
+		case fhir.Decimal:
+			switch op {
+			case "*":
+				return Collection{Quantity{Value: x.Value.Mul(y), Unit: x.Unit}}, nil
+			case "/":
+				if y.Rat().Sign() == 0 {
+					return nil, nil
+				}
+				return Collection{Quantity{Value: decimalFromRat(new(big.Rat).Quo(x.Value.Rat(), y.Rat())), Unit: x.Unit}}, nil
+			}
+		}
+
+	case Date, DateTime, Time:
+		q, ok := b.(Quantity)
+		if !ok || op != "+" && op != "-" {
+			break
+		}
+		amount := truncate(q.Value.Rat()).Int64()
+		if op == "-" {
+			amount = -amount
+		}
+		unit := q.Unit
+		if u, isUCUM := ucumDurations[unit]; isUCUM {
+			unit = u
+		}
+		t, _ := temporalValue(x)
+		moved, err := t.add(amount, unit)
+		if err != nil {
+			return nil, err
+		}
+		switch x.(type) {
+		case Date:
+			return Collection{Date{moved}}, nil
+		case DateTime:
+			return Collection{DateTime{moved}}, nil
+		default:
+			return Collection{Time{moved}}, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot apply %s to %s and %s", op, describeType(a), describeType(b))
+}
+
+// ucumDurations maps UCUM time units to the calendar durations used for
+// date arithmetic. 'a' and 'mo' are not included as they are averages.
+var ucumDurations = map[string]string{
+	"wk": "week", "d": "day", "h": "hour", "min": "minute", "s": "second", "ms": "millisecond",
+}
+
+// combineUnits builds the unit of a product or quotient of quantities
+func combineUnits(a, b, op string) string {
+	switch {
+	case b == "1":
+		return a
+	case a == "1" && op == ".":
+		return b
+	}
+	return a + op + b
+}
+
+// truncate returns the integer part of r
+func truncate(r *big.Rat) *big.Int {
+	return new(big.Int).Quo(r.Num(), r.Denom())
+}
+
+// singleton returns the only item of a collection, or nil if it is empty
+func singleton(c Collection) (interface{}, error) {
+	switch len(c) {
+	case 0:
+		return nil, nil
+	case 1:
+		return c[0], nil
+	}
+	return nil, fmt.Errorf("expected a single item but found %d", len(c))
+}
+
+// booleanOf interprets a collection as a boolean: empty is unknown, and a
+// single item that is not a boolean is true
+func booleanOf(c Collection) (value, known bool, err error) {
+	item, err := singleton(c)
+	if err != nil || item == nil {
+		return false, false, err
+	}
+	if b, ok := systemItem(item).(bool); ok {
+		return b, true, nil
+	}
+	return true, true, nil
+}
+
+// integerOf returns the value of a collection holding a single integer
+func integerOf(c Collection) (int64, bool, error) {
+	item, err := singleton(c)
+	if err != nil || item == nil {
+		return 0, false, err
+	}
+	n, ok := systemItem(item).(int64)
+	if !ok {
+		return 0, false, fmt.Errorf("expected an integer but found %s", describeType(item))
+	}
+	return n, true, nil
+}
+
+// stringOf returns the value of a collection holding a single string, or
+// "" if it is empty
+func stringOf(c Collection) (string, error) {
+	item, err := singleton(c)
+	if err != nil || item == nil {
+		return "", err
+	}
+	s, ok := systemItem(item).(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string but found %s", describeType(item))
+	}
+	return s, nil
+}
+
+// resolve follows the references in input: Reference elements and
+// canonical or uri values
+func (c *evalContext) resolve(input Collection) (Collection, error) {
+	var result Collection
+	for _, item := range input {
+		var ref string
+		switch v := systemItem(item).(type) {
+		case string:
+			ref = v
+		case *Element:
+			if obj, ok := v.Value.(map[string]interface{}); ok {
+				ref, _ = obj["reference"].(string)
+			}
+		}
+		if ref == "" {
+			continue
+		}
+
+		if found := c.resolveLocally(ref); found != nil {
+			result = append(result, found)
+			continue
+		}
+		if c.e.Resolver == nil || strings.HasPrefix(ref, "#") {
+			continue
+		}
+		resource, err := c.e.Resolver.Resolve(c.ctx, models.Reference{Reference: ref}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", ref, err)
+		}
+		resolved, err := toCollection(resource)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, resolved...)
+	}
+	return result, nil
+}
+
+// resolveLocally finds the target of a reference among the contained
+// resources of %resource, or the entries of a Bundle being evaluated
+func (c *evalContext) resolveLocally(ref string) *Element {
+	for _, item := range c.resource {
+		root, ok := item.(*Element)
+		if !ok {
+			continue
+		}
+		if id, ok := strings.CutPrefix(ref, "#"); ok {
+			if id == "" {
+				return root
+			}
+			for _, contained := range root.child("contained") {
+				if e := contained.(*Element); resourceID(e) == id {
+					return e
+				}
+			}
+			continue
+		}
+
+		if root.Type != "Bundle" {
+			continue
+		}
+		for _, entry := range root.child("entry") {
+			entry := entry.(*Element)
+			fullURL, _ := entry.Value.(map[string]interface{})["fullUrl"].(string)
+			for _, resource := range entry.child("resource") {
+				e := resource.(*Element)
+				local := e.Type + "/" + resourceID(e)
+				if fullURL == ref || local == ref || strings.HasSuffix(ref, "/"+local) || strings.HasSuffix(fullURL, "/"+ref) {
+					return e
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resourceID returns the id of a resource element
+func resourceID(e *Element) string {
+	obj, _ := e.Value.(map[string]interface{})
+	id, _ := obj["id"].(string)
+	return id
+}
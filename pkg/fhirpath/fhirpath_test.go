@@ -0,0 +1,326 @@
+package fhirpath
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/resolver"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/terminology"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/validation"
+)
+
+var (
+	_ ReferenceResolver             = (*resolver.Resolver)(nil)
+	_ validation.InvariantEvaluator = (*Evaluator)(nil)
+)
+
+// fakeResolver resolves references from a map
+type fakeResolver map[string]models.Resource
+
+func (f fakeResolver) Resolve(ctx context.Context, ref models.Reference, scope *resolver.Scope) (models.Resource, error) {
+	resource, ok := f[ref.Reference]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return resource, nil
+}
+
+func readExample(t *testing.T, name string) []byte {
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return data
+}
+
+func TestEvaluate(t *testing.T) {
+	result, err := Evaluate(readExample(t, "patient-example.json"), "Patient.name.where(use = 'official').given")
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if got := result.Strings(); !reflect.DeepEqual(got, []string{"Peter", "James"}) {
+		t.Errorf("got %v, want [Peter James]", got)
+	}
+}
+
+func TestEvaluateModel(t *testing.T) {
+	active := true
+	patient := models.NewPatient()
+	patient.ID = "p1"
+	patient.Active = &active
+	patient.Name = []models.HumanName{{Family: "Chalmers", Given: []string{"Peter"}}}
+
+	ok, err := NewEvaluator().EvaluateBoolean(context.Background(), patient, "active and name.family = 'Chalmers'")
+	if err != nil {
+		t.Fatalf("EvaluateBoolean failed: %v", err)
+	}
+	if !ok {
+		t.Error("expected the expression to be true")
+	}
+
+	ok, err = NewEvaluator().EvaluateBoolean(context.Background(), patient, "gender = 'male'")
+	if err != nil {
+		t.Fatalf("EvaluateBoolean failed: %v", err)
+	}
+	if ok {
+		t.Error("expected an empty result to be false")
+	}
+}
+
+func TestParse(t *testing.T) {
+	x, err := Parse("name.given.first()")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if x.String() != "name.given.first()" {
+		t.Errorf("String() = %q", x.String())
+	}
+	result, err := x.Evaluate(context.Background(), readExample(t, "patient-example.json"))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if got := result.Strings(); !reflect.DeepEqual(got, []string{"Peter"}) {
+		t.Errorf("got %v, want [Peter]", got)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	_, err := Parse("name.where(given = 'Jim'")
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected a SyntaxError, got %v", err)
+	}
+	if syntaxErr.Position != 24 {
+		t.Errorf("Position = %d, want 24", syntaxErr.Position)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	e := NewEvaluator()
+	e.Variables = map[string]interface{}{
+		"minimum": 3,
+		"names":   []string{"Peter", "Jim"},
+	}
+	ok, err := e.EvaluateBoolean(context.Background(), readExample(t, "patient-example.json"),
+		"name.count() >= %minimum and name.given.distinct().where($this in %names).count() = 2")
+	if err != nil {
+		t.Fatalf("EvaluateBoolean failed: %v", err)
+	}
+	if !ok {
+		t.Error("expected the expression to be true")
+	}
+}
+
+func TestTrace(t *testing.T) {
+	var traced []string
+	e := NewEvaluator()
+	e.Trace = func(name string, values Collection) {
+		traced = append(traced, name+"="+strings.Join(values.Strings(), ","))
+	}
+	if _, err := e.Evaluate(context.Background(), readExample(t, "patient-example.json"), "name[1].given.trace('given')"); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if !reflect.DeepEqual(traced, []string{"given=Jim"}) {
+		t.Errorf("traced %v", traced)
+	}
+}
+
+func TestResolveContained(t *testing.T) {
+	patient := `{
+		"resourceType": "Patient",
+		"id": "p1",
+		"contained": [{"resourceType": "Organization", "id": "org", "name": "Acme"}],
+		"managingOrganization": {"reference": "#org"}
+	}`
+	result, err := Evaluate([]byte(patient), "managingOrganization.resolve().name")
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if got := result.Strings(); !reflect.DeepEqual(got, []string{"Acme"}) {
+		t.Errorf("got %v, want [Acme]", got)
+	}
+}
+
+func TestResolveBundleEntry(t *testing.T) {
+	bundle := `{
+		"resourceType": "Bundle",
+		"type": "collection",
+		"entry": [
+			{"fullUrl": "http://example.org/fhir/Patient/p1", "resource": {
+				"resourceType": "Patient", "id": "p1", "managingOrganization": {"reference": "Organization/org"}
+			}},
+			{"fullUrl": "http://example.org/fhir/Organization/org", "resource": {
+				"resourceType": "Organization", "id": "org", "name": "Acme"
+			}}
+		]
+	}`
+	result, err := Evaluate([]byte(bundle), "Bundle.entry.resource.ofType(Patient).managingOrganization.resolve().name")
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if got := result.Strings(); !reflect.DeepEqual(got, []string{"Acme"}) {
+		t.Errorf("got %v, want [Acme]", got)
+	}
+}
+
+func TestResolveWithResolver(t *testing.T) {
+	org := &models.GenericResource{Base: models.Base{ResourceType: "Organization", ID: "1"}}
+	e := NewEvaluator()
+	e.Resolver = fakeResolver{"Organization/1": org}
+
+	result, err := e.Evaluate(context.Background(), readExample(t, "patient-example.json"),
+		"managingOrganization.resolve().is(Organization)")
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if value, ok := result.Bool(); !ok || !value {
+		t.Errorf("got %s, want [true]", result)
+	}
+
+	e.Resolver = fakeResolver{}
+	if _, err := e.Evaluate(context.Background(), readExample(t, "patient-example.json"), "managingOrganization.resolve()"); err == nil {
+		t.Error("expected an error for an unresolvable reference")
+	}
+
+	// Without a resolver, external references resolve to nothing
+	result, err = Evaluate(readExample(t, "patient-example.json"), "managingOrganization.resolve()")
+	if err != nil || len(result) != 0 {
+		t.Errorf("got %s, %v; want an empty result", result, err)
+	}
+}
+
+func TestMemberOf(t *testing.T) {
+	local := terminology.NewLocal()
+	if err := local.LoadDir("../terminology/testdata"); err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+	e := NewEvaluator()
+	e.Terminology = local
+
+	condition := `{
+		"resourceType": "Condition",
+		"code": {"coding": [{"system": "http://example.org/fhir/CodeSystem/conditions", "code": "type1"}]}
+	}`
+	tests := map[string]bool{
+		"code.memberOf('http://example.org/fhir/ValueSet/diabetes')":               true,
+		"code.coding.first().memberOf('http://example.org/fhir/ValueSet/chronic')": true,
+		"'asthma'.memberOf('http://example.org/fhir/ValueSet/diabetes')":           false,
+	}
+	for expression, want := range tests {
+		got, err := e.EvaluateBoolean(context.Background(), []byte(condition), expression)
+		if err != nil {
+			t.Errorf("%s: %v", expression, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s = %v, want %v", expression, got, want)
+		}
+	}
+
+	if _, err := Evaluate([]byte(condition), "code.memberOf('http://example.org/fhir/ValueSet/diabetes')"); err == nil {
+		t.Error("expected an error without a terminology service")
+	}
+}
+
+func TestEvaluateInvariant(t *testing.T) {
+	e := NewEvaluator()
+	resource := map[string]interface{}{
+		"resourceType": "Patient",
+		"contact": []interface{}{
+			map[string]interface{}{"telecom": []interface{}{map[string]interface{}{"value": "555"}}}},
+	}
+	contact := resource["contact"].([]interface{})[0]
+
+	tests := []struct {
+		expression string
+		node       interface{}
+		want       bool
+	}{
+		{"name.exists() or telecom.exists()", contact, true},
+		{"name.exists() or organization.exists()", contact, false},
+		{"%resource.contact.exists()", contact, true},
+		{"name.given", contact, true},
+	}
+	for _, tt := range tests {
+		got, err := e.EvaluateInvariant(tt.expression, resource, tt.node)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expression, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expression, got, tt.want)
+		}
+	}
+
+	if _, err := e.EvaluateInvariant("name.", resource, contact); err == nil {
+		t.Error("expected a syntax error")
+	}
+}
+
+func TestDecimalPrecision(t *testing.T) {
+	result, err := Evaluate(nil, "0.1 + 0.2")
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if result.String() != "[0.3]" {
+		t.Errorf("got %s, want [0.3]", result)
+	}
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	for _, expression := range []string{
+		"9223372036854775807 + 1",
+		"-9223372036854775807 - 2",
+		"4611686018427387904 * 2",
+		"-(-9223372036854775807 - 1)",
+		"(-9223372036854775807 - 1) div -1",
+		"(-9223372036854775807 - 1).abs()",
+		"2.power(63)",
+		"10.power(100000000000)",
+		"100000000000000000000.5.floor()",
+	} {
+		result, err := Evaluate(nil, expression)
+		if err != nil {
+			t.Errorf("%s failed: %v", expression, err)
+		} else if len(result) != 0 {
+			t.Errorf("%s = %s, want empty", expression, result)
+		}
+	}
+
+	result, err := Evaluate(nil, "9223372036854775806 + 1")
+	if err != nil || result.String() != "[9223372036854775807]" {
+		t.Errorf("got %s, %v, want [9223372036854775807]", result, err)
+	}
+}
+
+func TestType(t *testing.T) {
+	patient := readExample(t, "patient-example.json")
+	for _, tt := range []struct {
+		expression string
+		want       string
+	}{
+		{"Patient.active.type().namespace", "[FHIR]"},
+		{"Patient.active.type().name", "[boolean]"},
+		{"Patient.active.type().baseType", "[FHIR.Element]"},
+		{"Patient.type().name", "[Patient]"},
+		{"Patient.type().baseType", "[FHIR.DomainResource]"},
+		{"Patient.name.first().type().name", "[HumanName]"},
+		{"true.type().namespace", "[System]"},
+		{"true.type().name", "[Boolean]"},
+		{"1.5.type().name", "[Decimal]"},
+		{"Patient.active.type().name = 'boolean'", "[true]"},
+		{"Patient.active.is(Boolean).not()", "[true]"},
+	} {
+		result, err := Evaluate(patient, tt.expression)
+		if err != nil {
+			t.Errorf("%s failed: %v", tt.expression, err)
+		} else if result.String() != tt.want {
+			t.Errorf("%s = %s, want %s", tt.expression, result, tt.want)
+		}
+	}
+}
@@ -0,0 +1,1365 @@
+package fhirpath
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/terminology"
+)
+
+// function is an entry in the function table. Arguments are passed
+// unevaluated so that functions such as where() can evaluate them per item.
+type function struct {
+	minArgs, maxArgs int
+	fn               func(c *evalContext, input Collection, args []expr) (Collection, error)
+}
+
+// functions is the function table, filled in init to break the
+// initialization cycle through eval
+var functions map[string]function
+
+// call invokes a function on input
+func (c *evalContext) call(name string, input Collection, args []expr) (Collection, error) {
+	f, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s()", name)
+	}
+	if len(args) < f.minArgs || len(args) > f.maxArgs {
+		return nil, fmt.Errorf("%s() takes %s, got %d", name, arity(f), len(args))
+	}
+	result, err := f.fn(c, input, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", name, err)
+	}
+	return result, nil
+}
+
+func arity(f function) string {
+	switch {
+	case f.minArgs == f.maxArgs && f.maxArgs == 1:
+		return "1 argument"
+	case f.minArgs == f.maxArgs:
+		return fmt.Sprintf("%d arguments", f.maxArgs)
+	}
+	return fmt.Sprintf("%d to %d arguments", f.minArgs, f.maxArgs)
+}
+
+func init() {
+	functions = map[string]function{
+		// Existence
+		"empty":      {0, 0, fnEmpty},
+		"exists":     {0, 1, fnExists},
+		"all":        {1, 1, fnAll},
+		"allTrue":    {0, 0, booleans(func(values []bool) bool { return !containsBool(values, false) })},
+		"anyTrue":    {0, 0, booleans(func(values []bool) bool { return containsBool(values, true) })},
+		"allFalse":   {0, 0, booleans(func(values []bool) bool { return !containsBool(values, true) })},
+		"anyFalse":   {0, 0, booleans(func(values []bool) bool { return containsBool(values, false) })},
+		"subsetOf":   {1, 1, fnSubsetOf},
+		"supersetOf": {1, 1, fnSupersetOf},
+		"count":      {0, 0, fnCount},
+		"distinct":   {0, 0, fnDistinct},
+		"isDistinct": {0, 0, fnIsDistinct},
+
+		// Filtering and projection
+		"where":  {1, 1, fnWhere},
+		"select": {1, 1, fnSelect},
+		"repeat": {1, 1, fnRepeat},
+		"ofType": {1, 1, fnOfType},
+
+		// Subsetting
+		"single":    {0, 0, fnSingle},
+		"first":     {0, 0, fnFirst},
+		"last":      {0, 0, fnLast},
+		"tail":      {0, 0, fnTail},
+		"skip":      {1, 1, fnSkip},
+		"take":      {1, 1, fnTake},
+		"intersect": {1, 1, fnIntersect},
+		"exclude":   {1, 1, fnExclude},
+
+		// Combining
+		"union":   {1, 1, fnUnion},
+		"combine": {1, 1, fnCombine},
+
+		// Conversion
+		"iif":                {2, 3, fnIif},
+		"toBoolean":          {0, 0, conversion(toBoolean)},
+		"convertsToBoolean":  {0, 0, convertsTo(toBoolean)},
+		"toInteger":          {0, 0, conversion(toInteger)},
+		"convertsToInteger":  {0, 0, convertsTo(toInteger)},
+		"toDecimal":          {0, 0, conversion(toDecimal)},
+		"convertsToDecimal":  {0, 0, convertsTo(toDecimal)},
+		"toString":           {0, 0, conversion(toStringValue)},
+		"convertsToString":   {0, 0, convertsTo(toStringValue)},
+		"toDate":             {0, 0, conversion(toDate)},
+		"convertsToDate":     {0, 0, convertsTo(toDate)},
+		"toDateTime":         {0, 0, conversion(toDateTime)},
+		"convertsToDateTime": {0, 0, convertsTo(toDateTime)},
+		"toTime":             {0, 0, conversion(toTime)},
+		"convertsToTime":     {0, 0, convertsTo(toTime)},
+		"toQuantity":         {0, 1, fnToQuantity},
+		"convertsToQuantity": {0, 1, fnConvertsToQuantity},
+
+		// String manipulation
+		"indexOf":        {1, 1, fnIndexOf},
+		"substring":      {1, 2, fnSubstring},
+		"startsWith":     {1, 1, stringPredicate(strings.HasPrefix)},
+		"endsWith":       {1, 1, stringPredicate(strings.HasSuffix)},
+		"contains":       {1, 1, stringPredicate(strings.Contains)},
+		"upper":          {0, 0, stringFunction(strings.ToUpper)},
+		"lower":          {0, 0, stringFunction(strings.ToLower)},
+		"trim":           {0, 0, stringFunction(strings.TrimSpace)},
+		"replace":        {2, 2, fnReplace},
+		"matches":        {1, 1, fnMatches},
+		"replaceMatches": {2, 2, fnReplaceMatches},
+		"length":         {0, 0, fnLength},
+		"toChars":        {0, 0, fnToChars},
+		"split":          {1, 1, fnSplit},
+		"join":           {0, 1, fnJoin},
+		"encode":         {1, 1, fnEncode},
+		"decode":         {1, 1, fnDecode},
+		"escape":         {1, 1, fnEscape},
+		"unescape":       {1, 1, fnUnescape},
+
+		// Math
+		"abs":      {0, 0, fnAbs},
+		"ceiling":  {0, 0, rounding(func(r *big.Rat) *big.Int { return ceil(r) })},
+		"floor":    {0, 0, rounding(func(r *big.Rat) *big.Int { return floor(r) })},
+		"truncate": {0, 0, rounding(truncate)},
+		"round":    {0, 1, fnRound},
+		"exp":      {0, 0, float(math.Exp)},
+		"ln":       {0, 0, float(math.Log)},
+		"sqrt":     {0, 0, float(math.Sqrt)},
+		"log":      {1, 1, fnLog},
+		"power":    {1, 1, fnPower},
+
+		// Tree navigation
+		"children":    {0, 0, fnChildren},
+		"descendants": {0, 0, fnDescendants},
+
+		// Utility
+		"trace":     {1, 2, fnTrace},
+		"now":       {0, 0, fnNow},
+		"today":     {0, 0, fnToday},
+		"timeOfDay": {0, 0, fnTimeOfDay},
+		"aggregate": {1, 2, fnAggregate},
+
+		// Types and boolean logic
+		"is":   {1, 1, fnIs},
+		"as":   {1, 1, fnAs},
+		"type": {0, 0, fnType},
+		"not":  {0, 0, fnNot},
+
+		// FHIR additions
+		"extension":  {1, 1, fnExtension},
+		"hasValue":   {0, 0, fnHasValue},
+		"getValue":   {0, 0, fnGetValue},
+		"resolve":    {0, 0, fnResolve},
+		"memberOf":   {1, 1, fnMemberOf},
+		"htmlChecks": {0, 0, func(*evalContext, Collection, []expr) (Collection, error) { return Collection{true}, nil }},
+	}
+}
+
+// each evaluates arg once per item of input with $this and $index set
+func (c *evalContext) each(input Collection, arg expr, fn func(item interface{}, result Collection) error) error {
+	for i, item := range input {
+		child := c.with(item, i)
+		result, err := arg.eval(child, child.this)
+		if err != nil {
+			return err
+		}
+		if err := fn(item, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// typeArgument reads a type specifier passed as a function argument
+func typeArgument(arg expr) (typeSpecifier, error) {
+	inv, ok := arg.(*invokeExpr)
+	if !ok || inv.call {
+		return typeSpecifier{}, fmt.Errorf("expected a type name")
+	}
+	if inv.target == nil {
+		return typeSpecifier{name: inv.name}, nil
+	}
+	if ns, ok := inv.target.(*invokeExpr); ok && !ns.call && ns.target == nil {
+		return typeSpecifier{namespace: ns.name, name: inv.name}, nil
+	}
+	return typeSpecifier{}, fmt.Errorf("expected a type name")
+}
+
+func fnEmpty(c *evalContext, input Collection, args []expr) (Collection, error) {
+	return Collection{len(input) == 0}, nil
+}
+
+func fnExists(c *evalContext, input Collection, args []expr) (Collection, error) {
+	if len(args) == 1 {
+		filtered, err := fnWhere(c, input, args)
+		if err != nil {
+			return nil, err
+		}
+		input = filtered
+	}
+	return Collection{len(input) > 0}, nil
+}
+
+func fnAll(c *evalContext, input Collection, args []expr) (Collection, error) {
+	all := true
+	err := c.each(input, args[0], func(item interface{}, result Collection) error {
+		value, known, err := booleanOf(result)
+		if err == nil && (!known || !value) {
+			all = false
+		}
+		return err
+	})
+	return Collection{all}, err
+}
+
+// booleans builds the allTrue family, which require boolean items
+func booleans(test func(values []bool) bool) func(*evalContext, Collection, []expr) (Collection, error) {
+	return func(c *evalContext, input Collection, args []expr) (Collection, error) {
+		values := make([]bool, len(input))
+		for i, item := range input {
+			b, ok := systemItem(item).(bool)
+			if !ok {
+				return nil, fmt.Errorf("expected booleans but found %s", describeType(item))
+			}
+			values[i] = b
+		}
+		return Collection{test(values)}, nil
+	}
+}
+
+func containsBool(values []bool, b bool) bool {
+	for _, v := range values {
+		if v == b {
+			return true
+		}
+	}
+	return false
+}
+
+func fnSubsetOf(c *evalContext, input Collection, args []expr) (Collection, error) {
+	other, err := c.arg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range input {
+		if !contains(other, item) {
+			return Collection{false}, nil
+		}
+	}
+	return Collection{true}, nil
+}
+
+func fnSupersetOf(c *evalContext, input Collection, args []expr) (Collection, error) {
+	other, err := c.arg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range other {
+		if !contains(input, item) {
+			return Collection{false}, nil
+		}
+	}
+	return Collection{true}, nil
+}
+
+func fnCount(c *evalContext, input Collection, args []expr) (Collection, error) {
+	return Collection{int64(len(input))}, nil
+}
+
+func fnDistinct(c *evalContext, input Collection, args []expr) (Collection, error) {
+	return distinct(input), nil
+}
+
+func fnIsDistinct(c *evalContext, input Collection, args []expr) (Collection, error) {
+	return Collection{len(distinct(input)) == len(input)}, nil
+}
+
+func fnWhere(c *evalContext, input Collection, args []expr) (Collection, error) {
+	var filtered Collection
+	err := c.each(input, args[0], func(item interface{}, result Collection) error {
+		value, known, err := booleanOf(result)
+		if known && value {
+			filtered = append(filtered, item)
+		}
+		return err
+	})
+	return filtered, err
+}
+
+func fnSelect(c *evalContext, input Collection, args []expr) (Collection, error) {
+	var projected Collection
+	err := c.each(input, args[0], func(item interface{}, result Collection) error {
+		projected = append(projected, result...)
+		return nil
+	})
+	return projected, err
+}
+
+func fnRepeat(c *evalContext, input Collection, args []expr) (Collection, error) {
+	var result Collection
+	queue := input
+	for len(queue) > 0 {
+		var next Collection
+		err := c.each(queue, args[0], func(item interface{}, projected Collection) error {
+			for _, p := range projected {
+				if !contains(result, p) {
+					result = append(result, p)
+					next = append(next, p)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		queue = next
+	}
+	return result, nil
+}
+
+func fnOfType(c *evalContext, input Collection, args []expr) (Collection, error) {
+	spec, err := typeArgument(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return asOperator(input, spec), nil
+}
+
+func fnIs(c *evalContext, input Collection, args []expr) (Collection, error) {
+	spec, err := typeArgument(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return isOperator(input, spec)
+}
+
+func fnAs(c *evalContext, input Collection, args []expr) (Collection, error) {
+	return fnOfType(c, input, args)
+}
+
+// fnType returns the type of each item as a SimpleTypeInfo for system
+// values and FHIR primitives, or a ClassInfo for other FHIR types, with the
+// members namespace, name and baseType. Items whose type is not known are
+// left out.
+func fnType(c *evalContext, input Collection, args []expr) (Collection, error) {
+	var result Collection
+	for _, item := range input {
+		namespace, name := typeName(item)
+		if name == "" {
+			continue
+		}
+		info := map[string]interface{}{"namespace": namespace, "name": name}
+		kind := "SimpleTypeInfo"
+		if namespace == "System" {
+			info["baseType"] = "System.Any"
+		} else {
+			if _, primitive := primitiveTypes[name]; !primitive {
+				kind = "ClassInfo"
+			}
+			if base := baseTypes[name]; base != "" {
+				info["baseType"] = "FHIR." + base
+			}
+		}
+		result = append(result, &Element{Value: info, Type: kind})
+	}
+	return result, nil
+}
+
+func fnSingle(c *evalContext, input Collection, args []expr) (Collection, error) {
+	if len(input) > 1 {
+		return nil, fmt.Errorf("expected a single item but found %d", len(input))
+	}
+	return input, nil
+}
+
+func fnFirst(c *evalContext, input Collection, args []expr) (Collection, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+	return input[:1], nil
+}
+
+func fnLast(c *evalContext, input Collection, args []expr) (Collection, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+	return input[len(input)-1:], nil
+}
+
+func fnTail(c *evalContext, input Collection, args []expr) (Collection, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+	return input[1:], nil
+}
+
+// countArgument evaluates the integer argument of skip() and take()
+func (c *evalContext) countArgument(arg expr) (int, error) {
+	value, err := c.arg(arg)
+	if err != nil {
+		return 0, err
+	}
+	n, _, err := integerOf(value)
+	return int(n), err
+}
+
+func fnSkip(c *evalContext, input Collection, args []expr) (Collection, error) {
+	n, err := c.countArgument(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if n >= len(input) {
+		return nil, nil
+	}
+	return input[max(n, 0):], nil
+}
+
+func fnTake(c *evalContext, input Collection, args []expr) (Collection, error) {
+	n, err := c.countArgument(args[0])
+	if err != nil || n <= 0 {
+		return nil, err
+	}
+	return input[:min(n, len(input))], nil
+}
+
+func fnIntersect(c *evalContext, input Collection, args []expr) (Collection, error) {
+	other, err := c.arg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	var result Collection
+	for _, item := range distinct(input) {
+		if contains(other, item) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func fnExclude(c *evalContext, input Collection, args []expr) (Collection, error) {
+	other, err := c.arg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	var result Collection
+	for _, item := range input {
+		if !contains(other, item) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func fnUnion(c *evalContext, input Collection, args []expr) (Collection, error) {
+	other, err := c.arg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return distinct(append(append(Collection{}, input...), other...)), nil
+}
+
+func fnCombine(c *evalContext, input Collection, args []expr) (Collection, error) {
+	other, err := c.arg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return append(append(Collection{}, input...), other...), nil
+}
+
+func fnIif(c *evalContext, input Collection, args []expr) (Collection, error) {
+	criterion, err := c.arg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	value, known, err := booleanOf(criterion)
+	if err != nil {
+		return nil, err
+	}
+	if known && value {
+		return c.arg(args[1])
+	}
+	if len(args) == 3 {
+		return c.arg(args[2])
+	}
+	return nil, nil
+}
+
+func fnNot(c *evalContext, input Collection, args []expr) (Collection, error) {
+	value, known, err := booleanOf(input)
+	if err != nil || !known {
+		return nil, err
+	}
+	return Collection{!value}, nil
+}
+
+// conversion builds a toX() function from a converter, which reports false
+// for values that do not convert
+func conversion(convert func(interface{}) (interface{}, bool)) func(*evalContext, Collection, []expr) (Collection, error) {
+	return func(c *evalContext, input Collection, args []expr) (Collection, error) {
+		item, err := singleton(input)
+		if err != nil || item == nil {
+			return nil, err
+		}
+		if value, ok := convert(systemItem(item)); ok {
+			return Collection{value}, nil
+		}
+		return nil, nil
+	}
+}
+
+// convertsTo builds a convertsToX() function from a converter
+func convertsTo(convert func(interface{}) (interface{}, bool)) func(*evalContext, Collection, []expr) (Collection, error) {
+	return func(c *evalContext, input Collection, args []expr) (Collection, error) {
+		item, err := singleton(input)
+		if err != nil || item == nil {
+			return nil, err
+		}
+		_, ok := convert(systemItem(item))
+		return Collection{ok}, nil
+	}
+}
+
+var (
+	integerString  = regexp.MustCompile(`^[+-]?\d+$`)
+	decimalString  = regexp.MustCompile(`^[+-]?\d+(\.\d+)?$`)
+	quantityString = regexp.MustCompile(`^([+-]?\d+(?:\.\d+)?)\s*(?:'([^']+)'|([a-z]+))?$`)
+)
+
+func toBoolean(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case bool:
+		return v, true
+	case int64:
+		if v == 0 || v == 1 {
+			return v == 1, true
+		}
+	case fhir.Decimal:
+		switch v.Rat().Cmp(big.NewRat(0, 1)) {
+		case 0:
+			return false, true
+		}
+		if v.Rat().Cmp(big.NewRat(1, 1)) == 0 {
+			return true, true
+		}
+	case string:
+		switch strings.ToLower(v) {
+		case "true", "t", "yes", "y", "1", "1.0":
+			return true, true
+		case "false", "f", "no", "n", "0", "0.0":
+			return false, true
+		}
+	}
+	return nil, false
+}
+
+func toInteger(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case bool:
+		if v {
+			return int64(1), true
+		}
+		return int64(0), true
+	case string:
+		if integerString.MatchString(v) {
+			return parseInteger(strings.TrimPrefix(v, "+"))
+		}
+	}
+	return nil, false
+}
+
+func toDecimal(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case fhir.Decimal:
+		return v, true
+	case int64:
+		return fhir.NewDecimalFromInt(v), true
+	case bool:
+		if v {
+			return fhir.MustParseDecimal("1.0"), true
+		}
+		return fhir.MustParseDecimal("0.0"), true
+	case string:
+		if decimalString.MatchString(v) {
+			d, err := fhir.ParseDecimal(strings.TrimPrefix(v, "+"))
+			return d, err == nil
+		}
+	}
+	return nil, false
+}
+
+func toStringValue(v interface{}) (interface{}, bool) {
+	if _, ok := v.(*Element); ok {
+		return nil, false
+	}
+	return convertToString(v)
+}
+
+func toDate(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case Date:
+		return v, true
+	case DateTime:
+		t := v.temporal
+		t.precision = min(t.precision, precisionDay)
+		t.hour, t.minute, t.second, t.millis, t.hasZone, t.offset = 0, 0, 0, 0, false, 0
+		return Date{t}, true
+	case string:
+		if d, err := ParseDate(v); err == nil {
+			return d, true
+		}
+		if dt, err := ParseDateTime(v); err == nil {
+			return toDate(dt)
+		}
+	}
+	return nil, false
+}
+
+func toDateTime(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case DateTime:
+		return v, true
+	case Date:
+		return DateTime{v.temporal}, true
+	case string:
+		if dt, err := ParseDateTime(v); err == nil {
+			return dt, true
+		}
+	}
+	return nil, false
+}
+
+func toTime(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case Time:
+		return v, true
+	case string:
+		if t, err := ParseTime(v); err == nil {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func toQuantity(v interface{}) (Quantity, bool) {
+	switch v := v.(type) {
+	case Quantity:
+		return v, true
+	case int64:
+		return Quantity{Value: fhir.NewDecimalFromInt(v), Unit: "1"}, true
+	case fhir.Decimal:
+		return Quantity{Value: v, Unit: "1"}, true
+	case bool:
+		d, _ := toDecimal(v)
+		return Quantity{Value: d.(fhir.Decimal), Unit: "1"}, true
+	case string:
+		m := quantityString.FindStringSubmatch(v)
+		if m == nil {
+			return Quantity{}, false
+		}
+		value, err := fhir.ParseDecimal(strings.TrimPrefix(m[1], "+"))
+		if err != nil {
+			return Quantity{}, false
+		}
+		switch {
+		case m[2] != "":
+			return Quantity{Value: value, Unit: m[2]}, true
+		case m[3] != "":
+			unit, ok := calendarUnits[m[3]]
+			return Quantity{Value: value, Unit: unit}, ok
+		}
+		return Quantity{Value: value, Unit: "1"}, true
+	}
+	return Quantity{}, false
+}
+
+// quantityArgument converts the input of toQuantity() and convertsToQuantity(),
+// in the unit given as an argument if there is one
+func (c *evalContext) quantityArgument(input Collection, args []expr) (Quantity, bool, error) {
+	item, err := singleton(input)
+	if err != nil || item == nil {
+		return Quantity{}, false, err
+	}
+	q, ok := toQuantity(systemItem(item))
+	if !ok || len(args) == 0 {
+		return q, ok, nil
+	}
+	unit, err := c.arg(args[0])
+	if err != nil {
+		return Quantity{}, false, err
+	}
+	target, err := stringOf(unit)
+	if err != nil {
+		return Quantity{}, false, err
+	}
+	q, ok = convertQuantity(q, target)
+	return q, ok, nil
+}
+
+func fnToQuantity(c *evalContext, input Collection, args []expr) (Collection, error) {
+	q, ok, err := c.quantityArgument(input, args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return Collection{q}, nil
+}
+
+func fnConvertsToQuantity(c *evalContext, input Collection, args []expr) (Collection, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+	_, ok, err := c.quantityArgument(input, args)
+	if err != nil {
+		return nil, err
+	}
+	return Collection{ok}, nil
+}
+
+// inputString returns the string a string function applies to. ok is false
+// if the input is empty.
+func inputString(input Collection) (string, bool, error) {
+	item, err := singleton(input)
+	if err != nil || item == nil {
+		return "", false, err
+	}
+	s, ok := systemItem(item).(string)
+	if !ok {
+		return "", false, fmt.Errorf("expected a string but found %s", describeType(item))
+	}
+	return s, true, nil
+}
+
+// stringArguments evaluates string arguments, reporting false if any is empty
+func (c *evalContext) stringArguments(args []expr) ([]string, bool, error) {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		value, err := c.arg(arg)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(value) == 0 {
+			return nil, false, nil
+		}
+		if values[i], err = stringOf(value); err != nil {
+			return nil, false, err
+		}
+	}
+	return values, true, nil
+}
+
+// stringFunction builds a function that maps its input string to a string
+func stringFunction(fn func(string) string) func(*evalContext, Collection, []expr) (Collection, error) {
+	return func(c *evalContext, input Collection, args []expr) (Collection, error) {
+		s, ok, err := inputString(input)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return Collection{fn(s)}, nil
+	}
+}
+
+// stringPredicate builds a function that tests its input string against an argument
+func stringPredicate(fn func(s, arg string) bool) func(*evalContext, Collection, []expr) (Collection, error) {
+	return func(c *evalContext, input Collection, args []expr) (Collection, error) {
+		s, ok, err := inputString(input)
+		if err != nil || !ok {
+			return nil, err
+		}
+		values, ok, err := c.stringArguments(args)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return Collection{fn(s, values[0])}, nil
+	}
+}
+
+func fnIndexOf(c *evalContext, input Collection, args []expr) (Collection, error) {
+	s, ok, err := inputString(input)
+	if err != nil || !ok {
+		return nil, err
+	}
+	values, ok, err := c.stringArguments(args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	i := strings.Index(s, values[0])
+	if i < 0 {
+		return Collection{int64(-1)}, nil
+	}
+	return Collection{int64(utf8.RuneCountInString(s[:i]))}, nil
+}
+
+func fnSubstring(c *evalContext, input Collection, args []expr) (Collection, error) {
+	s, ok, err := inputString(input)
+	if err != nil || !ok {
+		return nil, err
+	}
+	runes := []rune(s)
+	start, err := c.countArgument(args[0])
+	if err != nil || start < 0 || start >= len(runes) {
+		return nil, err
+	}
+	end := len(runes)
+	if len(args) == 2 {
+		length, err := c.countArgument(args[1])
+		if err != nil {
+			return nil, err
+		}
+		end = min(start+max(length, 0), end)
+	}
+	return Collection{string(runes[start:end])}, nil
+}
+
+func fnReplace(c *evalContext, input Collection, args []expr) (Collection, error) {
+	s, ok, err := inputString(input)
+	if err != nil || !ok {
+		return nil, err
+	}
+	values, ok, err := c.stringArguments(args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return Collection{strings.ReplaceAll(s, values[0], values[1])}, nil
+}
+
+func fnMatches(c *evalContext, input Collection, args []expr) (Collection, error) {
+	s, ok, err := inputString(input)
+	if err != nil || !ok {
+		return nil, err
+	}
+	values, ok, err := c.stringArguments(args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	re, err := regexp.Compile("(?s)" + values[0])
+	if err != nil {
+		return nil, err
+	}
+	return Collection{re.MatchString(s)}, nil
+}
+
+func fnReplaceMatches(c *evalContext, input Collection, args []expr) (Collection, error) {
+	s, ok, err := inputString(input)
+	if err != nil || !ok {
+		return nil, err
+	}
+	values, ok, err := c.stringArguments(args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	re, err := regexp.Compile("(?s)" + values[0])
+	if err != nil {
+		return nil, err
+	}
+	return Collection{re.ReplaceAllString(s, values[1])}, nil
+}
+
+func fnLength(c *evalContext, input Collection, args []expr) (Collection, error) {
+	s, ok, err := inputString(input)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return Collection{int64(utf8.RuneCountInString(s))}, nil
+}
+
+func fnToChars(c *evalContext, input Collection, args []expr) (Collection, error) {
+	s, ok, err := inputString(input)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var chars Collection
+	for _, r := range s {
+		chars = append(chars, string(r))
+	}
+	return chars, nil
+}
+
+func fnSplit(c *evalContext, input Collection, args []expr) (Collection, error) {
+	s, ok, err := inputString(input)
+	if err != nil || !ok {
+		return nil, err
+	}
+	values, ok, err := c.stringArguments(args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var parts Collection
+	for _, part := range strings.Split(s, values[0]) {
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+func fnJoin(c *evalContext, input Collection, args []expr) (Collection, error) {
+	separator := ""
+	if len(args) == 1 {
+		values, ok, err := c.stringArguments(args)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			separator = values[0]
+		}
+	}
+	parts := make([]string, len(input))
+	for i, item := range input {
+		s, ok := systemItem(item).(string)
+		if !ok {
+			return nil, fmt.Errorf("expected strings but found %s", describeType(item))
+		}
+		parts[i] = s
+	}
+	return Collection{strings.Join(parts, separator)}, nil
+}
+
+func fnEncode(c *evalContext, input Collection, args []expr) (Collection, error) {
+	s, ok, err := inputString(input)
+	if err != nil || !ok {
+		return nil, err
+	}
+	values, ok, err := c.stringArguments(args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	switch values[0] {
+	case "base64":
+		return Collection{base64.StdEncoding.EncodeToString([]byte(s))}, nil
+	case "urlbase64":
+		return Collection{base64.URLEncoding.EncodeToString([]byte(s))}, nil
+	case "hex":
+		return Collection{hex.EncodeToString([]byte(s))}, nil
+	}
+	return nil, fmt.Errorf("unknown encoding %q", values[0])
+}
+
+func fnDecode(c *evalContext, input Collection, args []expr) (Collection, error) {
+	s, ok, err := inputString(input)
+	if err != nil || !ok {
+		return nil, err
+	}
+	values, ok, err := c.stringArguments(args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var data []byte
+	switch values[0] {
+	case "base64":
+		data, err = base64.StdEncoding.DecodeString(s)
+	case "urlbase64":
+		data, err = base64.URLEncoding.DecodeString(s)
+	case "hex":
+		data, err = hex.DecodeString(s)
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", values[0])
+	}
+	if err != nil {
+		return nil, nil
+	}
+	return Collection{string(data)}, nil
+}
+
+// htmlEscaper escapes the characters that are special in HTML
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&#39;")
+
+func fnEscape(c *evalContext, input Collection, args []expr) (Collection, error) {
+	s, ok, err := inputString(input)
+	if err != nil || !ok {
+		return nil, err
+	}
+	values, ok, err := c.stringArguments(args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	switch values[0] {
+	case "html":
+		return Collection{htmlEscaper.Replace(s)}, nil
+	case "json":
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(s)
+		data := bytes.TrimSpace(buf.Bytes())
+		return Collection{string(data[1 : len(data)-1])}, nil
+	}
+	return nil, fmt.Errorf("unknown escaping %q", values[0])
+}
+
+func fnUnescape(c *evalContext, input Collection, args []expr) (Collection, error) {
+	s, ok, err := inputString(input)
+	if err != nil || !ok {
+		return nil, err
+	}
+	values, ok, err := c.stringArguments(args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	switch values[0] {
+	case "html":
+		return Collection{html.UnescapeString(s)}, nil
+	case "json":
+		var unescaped string
+		if err := json.Unmarshal([]byte(`"`+s+`"`), &unescaped); err != nil {
+			return nil, nil
+		}
+		return Collection{unescaped}, nil
+	}
+	return nil, fmt.Errorf("unknown escaping %q", values[0])
+}
+
+// inputNumber returns the number a math function applies to
+func inputNumber(input Collection) (interface{}, error) {
+	item, err := singleton(input)
+	if err != nil || item == nil {
+		return nil, err
+	}
+	switch v := systemItem(item).(type) {
+	case int64, fhir.Decimal, Quantity:
+		return v, nil
+	}
+	return nil, fmt.Errorf("expected a number but found %s", describeType(item))
+}
+
+func fnAbs(c *evalContext, input Collection, args []expr) (Collection, error) {
+	n, err := inputNumber(input)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	switch v := n.(type) {
+	case int64:
+		return integer(new(big.Int).Abs(big.NewInt(v))), nil
+	case fhir.Decimal:
+		if v.Rat().Sign() < 0 {
+			v = v.Neg()
+		}
+		return Collection{v}, nil
+	default:
+		q := v.(Quantity)
+		if q.Value.Rat().Sign() < 0 {
+			q.Value = q.Value.Neg()
+		}
+		return Collection{q}, nil
+	}
+}
+
+// rounding builds ceiling(), floor() and truncate(), which return integers
+func rounding(fn func(*big.Rat) *big.Int) func(*evalContext, Collection, []expr) (Collection, error) {
+	return func(c *evalContext, input Collection, args []expr) (Collection, error) {
+		n, err := inputNumber(input)
+		if err != nil || n == nil {
+			return nil, err
+		}
+		switch v := n.(type) {
+		case int64:
+			return Collection{v}, nil
+		case fhir.Decimal:
+			return integer(fn(v.Rat())), nil
+		}
+		return nil, fmt.Errorf("expected a number but found %s", describeType(n))
+	}
+}
+
+func floor(r *big.Rat) *big.Int {
+	t := truncate(r)
+	if r.Sign() < 0 && !r.IsInt() {
+		t.Sub(t, big.NewInt(1))
+	}
+	return t
+}
+
+func ceil(r *big.Rat) *big.Int {
+	t := truncate(r)
+	if r.Sign() > 0 && !r.IsInt() {
+		t.Add(t, big.NewInt(1))
+	}
+	return t
+}
+
+func fnRound(c *evalContext, input Collection, args []expr) (Collection, error) {
+	n, err := inputNumber(input)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	places := 0
+	if len(args) == 1 {
+		if places, err = c.countArgument(args[0]); err != nil {
+			return nil, err
+		}
+		if places < 0 {
+			return nil, fmt.Errorf("precision must not be negative")
+		}
+	}
+	var r *big.Rat
+	switch v := n.(type) {
+	case int64:
+		r = new(big.Rat).SetInt64(v)
+	case fhir.Decimal:
+		r = v.Rat()
+	default:
+		return nil, fmt.Errorf("expected a number but found %s", describeType(n))
+	}
+	return Collection{fhir.MustParseDecimal(roundRat(r, places).FloatString(places))}, nil
+}
+
+// float builds the math functions computed in floating point
+func float(fn func(float64) float64) func(*evalContext, Collection, []expr) (Collection, error) {
+	return func(c *evalContext, input Collection, args []expr) (Collection, error) {
+		n, err := inputNumber(input)
+		if err != nil || n == nil {
+			return nil, err
+		}
+		return floatResult(fn(floatOf(n)))
+	}
+}
+
+func floatOf(n interface{}) float64 {
+	switch v := n.(type) {
+	case int64:
+		return float64(v)
+	case fhir.Decimal:
+		return v.Float64()
+	case Quantity:
+		return v.Value.Float64()
+	}
+	return math.NaN()
+}
+
+// floatResult converts a floating point result to a Decimal, or empty if it is not a number
+func floatResult(f float64) (Collection, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, nil
+	}
+	return Collection{fhir.MustParseDecimal(strconv.FormatFloat(f, 'f', -1, 64))}, nil
+}
+
+func fnLog(c *evalContext, input Collection, args []expr) (Collection, error) {
+	n, err := inputNumber(input)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	base, err := c.arg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	b, err := inputNumber(base)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	return floatResult(math.Log(floatOf(n)) / math.Log(floatOf(b)))
+}
+
+func fnPower(c *evalContext, input Collection, args []expr) (Collection, error) {
+	n, err := inputNumber(input)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	exponent, err := c.arg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	e, err := inputNumber(exponent)
+	if err != nil || e == nil {
+		return nil, err
+	}
+	if x, ok := n.(int64); ok {
+		if y, ok := e.(int64); ok && y >= 0 {
+			// Any base other than -1, 0 and 1 overflows well before an exponent of 64
+			if (x < -1 || x > 1) && y >= 64 {
+				return nil, nil
+			}
+			return integer(new(big.Int).Exp(big.NewInt(x), big.NewInt(y), nil)), nil
+		}
+	}
+	return floatResult(math.Pow(floatOf(n), floatOf(e)))
+}
+
+func fnChildren(c *evalContext, input Collection, args []expr) (Collection, error) {
+	var result Collection
+	for _, item := range input {
+		if e, ok := item.(*Element); ok {
+			result = append(result, e.children()...)
+		}
+	}
+	return result, nil
+}
+
+func fnDescendants(c *evalContext, input Collection, args []expr) (Collection, error) {
+	var result Collection
+	queue := input
+	for len(queue) > 0 {
+		next, _ := fnChildren(c, queue, nil)
+		result = append(result, next...)
+		queue = next
+	}
+	return result, nil
+}
+
+func fnTrace(c *evalContext, input Collection, args []expr) (Collection, error) {
+	values, ok, err := c.stringArguments(args[:1])
+	if err != nil || !ok || c.e.Trace == nil {
+		return input, err
+	}
+	traced := input
+	if len(args) == 2 {
+		traced, err = fnSelect(c, input, args[1:])
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.e.Trace(values[0], traced)
+	return input, nil
+}
+
+func fnNow(c *evalContext, input Collection, args []expr) (Collection, error) {
+	return Collection{DateTime{temporalOf(c.now, precisionYear)}}, nil
+}
+
+func fnToday(c *evalContext, input Collection, args []expr) (Collection, error) {
+	d, _ := toDate(DateTime{temporalOf(c.now, precisionYear)})
+	return Collection{d}, nil
+}
+
+func fnTimeOfDay(c *evalContext, input Collection, args []expr) (Collection, error) {
+	t := temporalOf(c.now, precisionHour)
+	t.year, t.month, t.day = 0, 0, 0
+	return Collection{Time{t}}, nil
+}
+
+func fnAggregate(c *evalContext, input Collection, args []expr) (Collection, error) {
+	var total Collection
+	if len(args) == 2 {
+		var err error
+		if total, err = c.arg(args[1]); err != nil {
+			return nil, err
+		}
+	}
+	for i, item := range input {
+		child := c.with(item, i)
+		child.total = total
+		result, err := args[0].eval(child, child.this)
+		if err != nil {
+			return nil, err
+		}
+		total = result
+	}
+	return total, nil
+}
+
+func fnExtension(c *evalContext, input Collection, args []expr) (Collection, error) {
+	values, ok, err := c.stringArguments(args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var result Collection
+	for _, item := range input {
+		e, ok := item.(*Element)
+		if !ok {
+			continue
+		}
+		for _, ext := range e.child("extension") {
+			obj, _ := ext.(*Element).Value.(map[string]interface{})
+			if obj["url"] == values[0] {
+				result = append(result, ext)
+			}
+		}
+	}
+	return result, nil
+}
+
+func fnHasValue(c *evalContext, input Collection, args []expr) (Collection, error) {
+	if len(input) != 1 {
+		return Collection{false}, nil
+	}
+	e, ok := input[0].(*Element)
+	if !ok {
+		return Collection{false}, nil
+	}
+	_, isObject := e.Value.(map[string]interface{})
+	return Collection{e.Value != nil && !isObject}, nil
+}
+
+func fnGetValue(c *evalContext, input Collection, args []expr) (Collection, error) {
+	if len(input) != 1 {
+		return nil, nil
+	}
+	e, ok := input[0].(*Element)
+	if !ok {
+		return nil, nil
+	}
+	if _, isObject := e.Value.(map[string]interface{}); isObject || e.Value == nil {
+		return nil, nil
+	}
+	value, _ := systemValue(e)
+	return Collection{value}, nil
+}
+
+func fnResolve(c *evalContext, input Collection, args []expr) (Collection, error) {
+	return c.resolve(input)
+}
+
+func fnMemberOf(c *evalContext, input Collection, args []expr) (Collection, error) {
+	item, err := singleton(input)
+	if err != nil || item == nil {
+		return nil, err
+	}
+	values, ok, err := c.stringArguments(args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if c.e.Terminology == nil {
+		return nil, fmt.Errorf("no terminology service is configured")
+	}
+
+	req := &terminology.ValidateCodeRequest{ValueSet: values[0]}
+	switch v := systemItem(item).(type) {
+	case string:
+		req.Code = v
+	case *Element:
+		data, err := json.Marshal(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		if v.Type == "CodeableConcept" || isCodeableConcept(v.Value) {
+			req.CodeableConcept = &models.CodeableConcept{}
+			err = json.Unmarshal(data, req.CodeableConcept)
+		} else {
+			req.Coding = &models.Coding{}
+			err = json.Unmarshal(data, req.Coding)
+		}
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("expected a code, Coding or CodeableConcept but found %s", describeType(item))
+	}
+
+	result, err := c.e.Terminology.ValidateCode(c.ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return Collection{result.Result}, nil
+}
+
+// isCodeableConcept reports whether an untyped element looks like a CodeableConcept
+func isCodeableConcept(value interface{}) bool {
+	obj, _ := value.(map[string]interface{})
+	_, hasCoding := obj["coding"]
+	_, hasCode := obj["code"]
+	return hasCoding || !hasCode && obj["text"] != nil
+}
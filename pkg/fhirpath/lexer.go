@@ -0,0 +1,240 @@
+package fhirpath
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind identifies the lexical class of a token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenString
+	tokenNumber
+	tokenDateTime
+	tokenTime
+	tokenVariable
+	tokenOperator
+)
+
+// token is a lexical token of an expression
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+
+	// delimited is set for identifiers written in backticks, which are never keywords
+	delimited bool
+}
+
+// is reports whether the token is the given operator or undelimited keyword
+func (t token) is(text string) bool {
+	return (t.kind == tokenOperator || t.kind == tokenIdentifier && !t.delimited) && t.text == text
+}
+
+var (
+	dateTimeLiteral = regexp.MustCompile(`^@\d{4}(-\d{2}(-\d{2})?)?(T(\d{2}(:\d{2}(:\d{2}(\.\d+)?)?)?(Z|[+-]\d{2}:\d{2})?)?)?`)
+	timeLiteral     = regexp.MustCompile(`^@T\d{2}(:\d{2}(:\d{2}(\.\d+)?)?)?`)
+)
+
+// lex splits an expression into tokens
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+
+		case strings.HasPrefix(input[i:], "//"):
+			end := strings.IndexByte(input[i:], '\n')
+			if end < 0 {
+				end = len(input) - i
+			}
+			i += end
+
+		case strings.HasPrefix(input[i:], "/*"):
+			end := strings.Index(input[i+2:], "*/")
+			if end < 0 {
+				return nil, syntaxError(i, "unterminated comment")
+			}
+			i += end + 4
+
+		case c == '\'' || c == '`':
+			text, n, err := unescape(input[i:], c)
+			if err != nil {
+				return nil, syntaxError(i, err.Error())
+			}
+			kind := tokenString
+			if c == '`' {
+				kind = tokenIdentifier
+			}
+			tokens = append(tokens, token{kind: kind, text: text, pos: i, delimited: c == '`'})
+			i += n
+
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(input) && isDigit(input[i]) {
+				i++
+			}
+			if i+1 < len(input) && input[i] == '.' && isDigit(input[i+1]) {
+				i++
+				for i < len(input) && isDigit(input[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: input[start:i], pos: start})
+			if i < len(input) && input[i] == 'L' {
+				i++
+			}
+
+		case c == '@':
+			if m := timeLiteral.FindString(input[i:]); m != "" {
+				tokens = append(tokens, token{kind: tokenTime, text: m[2:], pos: i})
+				i += len(m)
+				break
+			}
+			m := dateTimeLiteral.FindString(input[i:])
+			if m == "" {
+				return nil, syntaxError(i, "invalid date/time literal")
+			}
+			tokens = append(tokens, token{kind: tokenDateTime, text: m[1:], pos: i})
+			i += len(m)
+
+		case c == '%':
+			start := i
+			i++
+			if i < len(input) && (input[i] == '`' || input[i] == '\'') {
+				text, n, err := unescape(input[i:], input[i])
+				if err != nil {
+					return nil, syntaxError(i, err.Error())
+				}
+				tokens = append(tokens, token{kind: tokenVariable, text: text, pos: start})
+				i += n
+				break
+			}
+			n := identifierLength(input[i:])
+			if n == 0 {
+				return nil, syntaxError(start, "expected a variable name after %")
+			}
+			tokens = append(tokens, token{kind: tokenVariable, text: input[i : i+n], pos: start})
+			i += n
+
+		case c == '$' || c == '_' || isLetter(c):
+			start := i
+			if c == '$' {
+				i++
+			}
+			n := identifierLength(input[i:])
+			if n == 0 {
+				return nil, syntaxError(start, "expected an identifier after $")
+			}
+			i += n
+			tokens = append(tokens, token{kind: tokenIdentifier, text: input[start:i], pos: start})
+
+		default:
+			op := ""
+			for _, candidate := range []string{"<=", ">=", "!=", "!~"} {
+				if strings.HasPrefix(input[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" && strings.ContainsRune(".,()[]{}+-*/|&=~<>", rune(c)) {
+				op = string(c)
+			}
+			if op == "" {
+				r, _ := utf8.DecodeRuneInString(input[i:])
+				return nil, syntaxError(i, fmt.Sprintf("unexpected character %q", r))
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(input)}), nil
+}
+
+// unescape reads a string or delimited identifier that starts with quote,
+// returning its contents and the number of bytes it occupies
+func unescape(input string, quote byte) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(input); i++ {
+		c := input[i]
+		if c == quote {
+			return b.String(), i + 1, nil
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(input) {
+			break
+		}
+		switch input[i] {
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			if i+4 >= len(input) {
+				return "", 0, fmt.Errorf("invalid unicode escape")
+			}
+			code, err := strconv.ParseUint(input[i+1:i+5], 16, 32)
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid unicode escape")
+			}
+			b.WriteRune(rune(code))
+			i += 4
+		default:
+			b.WriteByte(input[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated %c", quote)
+}
+
+// identifierLength returns the length of the identifier at the start of input
+func identifierLength(input string) int {
+	n := 0
+	for n < len(input) && (input[n] == '_' || isLetter(input[n]) || isDigit(input[n])) {
+		n++
+	}
+	if n > 0 && isDigit(input[0]) {
+		return 0
+	}
+	return n
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c < utf8.RuneSelf && unicode.IsLetter(rune(c))
+}
+
+// SyntaxError is returned for expressions that cannot be parsed
+type SyntaxError struct {
+	// Position is the byte offset in the expression at which the error was found
+	Position int
+	Message  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("fhirpath: syntax error at position %d: %s", e.Position, e.Message)
+}
+
+func syntaxError(pos int, message string) error {
+	return &SyntaxError{Position: pos, Message: message}
+}
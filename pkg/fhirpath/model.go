@@ -0,0 +1,252 @@
+package fhirpath
+
+import (
+	"strings"
+	"unicode"
+)
+
+// elementTypes gives the types of the elements of the common resources and
+// datatypes, keyed by type name or, for backbone elements, by path. A type
+// of "@" marks a backbone element, whose children are listed under its
+// path, and "#path" an element defined by reference to another. Choice
+// elements are listed as name[x] and typed by the suffix of their member.
+// Members of types not listed here are typed from their JSON values.
+var elementTypes = map[string]map[string]string{
+	"Resource": {
+		"id": "id", "meta": "Meta", "implicitRules": "uri", "language": "code",
+	},
+	"DomainResource": {
+		"text": "Narrative", "contained": "Resource", "extension": "Extension", "modifierExtension": "Extension",
+	},
+	"Element": {
+		"id": "string", "extension": "Extension",
+	},
+	"BackboneElement": {
+		"modifierExtension": "Extension",
+	},
+	"Extension": {
+		"url": "uri", "value[x]": "",
+	},
+	"Meta": {
+		"versionId": "id", "lastUpdated": "instant", "source": "uri", "profile": "canonical",
+		"security": "Coding", "tag": "Coding",
+	},
+	"Narrative": {
+		"status": "code", "div": "xhtml",
+	},
+	"Coding": {
+		"system": "uri", "version": "string", "code": "code", "display": "string", "userSelected": "boolean",
+	},
+	"CodeableConcept": {
+		"coding": "Coding", "text": "string",
+	},
+	"Identifier": {
+		"use": "code", "type": "CodeableConcept", "system": "uri", "value": "string",
+		"period": "Period", "assigner": "Reference",
+	},
+	"HumanName": {
+		"use": "code", "text": "string", "family": "string", "given": "string",
+		"prefix": "string", "suffix": "string", "period": "Period",
+	},
+	"ContactPoint": {
+		"system": "code", "value": "string", "use": "code", "rank": "positiveInt", "period": "Period",
+	},
+	"Address": {
+		"use": "code", "type": "code", "text": "string", "line": "string", "city": "string",
+		"district": "string", "state": "string", "postalCode": "string", "country": "string", "period": "Period",
+	},
+	"Period": {
+		"start": "dateTime", "end": "dateTime",
+	},
+	"Quantity": {
+		"value": "decimal", "comparator": "code", "unit": "string", "system": "uri", "code": "code",
+	},
+	"Range": {
+		"low": "Quantity", "high": "Quantity",
+	},
+	"Ratio": {
+		"numerator": "Quantity", "denominator": "Quantity",
+	},
+	"Reference": {
+		"reference": "string", "type": "uri", "identifier": "Identifier", "display": "string",
+	},
+	"Attachment": {
+		"contentType": "code", "language": "code", "data": "base64Binary", "url": "url",
+		"size": "unsignedInt", "hash": "base64Binary", "title": "string", "creation": "dateTime",
+	},
+	"Annotation": {
+		"author[x]": "", "time": "dateTime", "text": "markdown",
+	},
+	"Patient": {
+		"identifier": "Identifier", "active": "boolean", "name": "HumanName", "telecom": "ContactPoint",
+		"gender": "code", "birthDate": "date", "deceased[x]": "", "address": "Address",
+		"maritalStatus": "CodeableConcept", "multipleBirth[x]": "", "photo": "Attachment",
+		"contact": "@", "communication": "@", "generalPractitioner": "Reference",
+		"managingOrganization": "Reference", "link": "@",
+	},
+	"Patient.contact": {
+		"relationship": "CodeableConcept", "name": "HumanName", "telecom": "ContactPoint",
+		"address": "Address", "gender": "code", "organization": "Reference", "period": "Period",
+	},
+	"Patient.communication": {
+		"language": "CodeableConcept", "preferred": "boolean",
+	},
+	"Patient.link": {
+		"other": "Reference", "type": "code",
+	},
+	"Observation": {
+		"identifier": "Identifier", "basedOn": "Reference", "partOf": "Reference", "status": "code",
+		"category": "CodeableConcept", "code": "CodeableConcept", "subject": "Reference", "focus": "Reference",
+		"encounter": "Reference", "effective[x]": "", "issued": "instant", "performer": "Reference",
+		"value[x]": "", "dataAbsentReason": "CodeableConcept", "interpretation": "CodeableConcept",
+		"note": "Annotation", "bodySite": "CodeableConcept", "method": "CodeableConcept",
+		"specimen": "Reference", "device": "Reference", "referenceRange": "@", "hasMember": "Reference",
+		"derivedFrom": "Reference", "component": "@",
+	},
+	"Observation.referenceRange": {
+		"low": "Quantity", "high": "Quantity", "type": "CodeableConcept", "appliesTo": "CodeableConcept",
+		"age": "Range", "text": "string",
+	},
+	"Observation.component": {
+		"code": "CodeableConcept", "value[x]": "", "dataAbsentReason": "CodeableConcept",
+		"interpretation": "CodeableConcept", "referenceRange": "#Observation.referenceRange",
+	},
+	"Bundle": {
+		"identifier": "Identifier", "type": "code", "timestamp": "instant", "total": "unsignedInt",
+		"link": "@", "entry": "@", "signature": "Signature",
+	},
+	"Bundle.link": {
+		"relation": "string", "url": "uri",
+	},
+	"Bundle.entry": {
+		"link": "#Bundle.link", "fullUrl": "uri", "resource": "Resource", "search": "@", "request": "@", "response": "@",
+	},
+	"Bundle.entry.search": {
+		"mode": "code", "score": "decimal",
+	},
+	"Bundle.entry.request": {
+		"method": "code", "url": "uri", "ifNoneMatch": "string", "ifModifiedSince": "instant",
+		"ifMatch": "string", "ifNoneExist": "string",
+	},
+	"Bundle.entry.response": {
+		"status": "string", "location": "uri", "etag": "string", "lastModified": "instant", "outcome": "Resource",
+	},
+	"OperationOutcome": {
+		"issue": "@",
+	},
+	"OperationOutcome.issue": {
+		"severity": "code", "code": "code", "details": "CodeableConcept", "diagnostics": "string",
+		"location": "string", "expression": "string",
+	},
+	"Parameters": {
+		"parameter": "@",
+	},
+	"Parameters.parameter": {
+		"name": "string", "value[x]": "", "resource": "Resource", "part": "#Parameters.parameter",
+	},
+}
+
+// baseTypes maps each type to the type it is derived from
+var baseTypes = map[string]string{
+	"DomainResource":   "Resource",
+	"Bundle":           "Resource",
+	"Binary":           "Resource",
+	"Parameters":       "Resource",
+	"Patient":          "DomainResource",
+	"Observation":      "DomainResource",
+	"OperationOutcome": "DomainResource",
+
+	"BackboneElement": "Element",
+	"code":            "string",
+	"id":              "string",
+	"markdown":        "string",
+	"url":             "uri",
+	"canonical":       "uri",
+	"oid":             "uri",
+	"uuid":            "uri",
+	"unsignedInt":     "integer",
+	"positiveInt":     "integer",
+	"Age":             "Quantity",
+	"Count":           "Quantity",
+	"Distance":        "Quantity",
+	"Duration":        "Quantity",
+	"SimpleQuantity":  "Quantity",
+	"MoneyQuantity":   "Quantity",
+}
+
+func init() {
+	for name := range elementTypes {
+		if _, ok := baseTypes[name]; ok || name == "Resource" || name == "Element" || strings.Contains(name, ".") {
+			continue
+		}
+		baseTypes[name] = "Element"
+	}
+	for name := range primitiveTypes {
+		if _, ok := baseTypes[name]; !ok {
+			baseTypes[name] = "Element"
+		}
+	}
+}
+
+// childType returns the type of member key of an element typed by model,
+// and the model of the child's own children
+func childType(model, key string) (typeName, childModel string) {
+	for m := model; m != ""; m = parentModel(m) {
+		fields := elementTypes[m]
+		if t, ok := fields[key]; ok {
+			switch {
+			case t == "@":
+				return "BackboneElement", m + "." + key
+			case strings.HasPrefix(t, "#"):
+				return "BackboneElement", t[1:]
+			}
+			return t, t
+		}
+		for name := range fields {
+			prefix, isChoice := strings.CutSuffix(name, "[x]")
+			if isChoice && strings.HasPrefix(key, prefix) {
+				if t := suffixType(key[len(prefix):]); t != "" {
+					return t, t
+				}
+			}
+		}
+	}
+
+	// Unknown elements: type choice members from their suffix, such as valueQuantity
+	for i := 1; i < len(key); i++ {
+		if unicode.IsUpper(rune(key[i])) {
+			if t := suffixType(key[i:]); t != "" {
+				return t, t
+			}
+		}
+	}
+	return "", ""
+}
+
+// parentModel returns the model that model inherits elements from
+func parentModel(model string) string {
+	if strings.Contains(model, ".") {
+		return "BackboneElement"
+	}
+	return baseTypes[model]
+}
+
+// suffixType returns the type named by a choice member's suffix, e.g.
+// "dateTime" for "DateTime" and "Quantity" for "Quantity"
+func suffixType(suffix string) string {
+	if suffix == "" || !unicode.IsUpper(rune(suffix[0])) {
+		return ""
+	}
+	lower := strings.ToLower(suffix[:1]) + suffix[1:]
+	if _, ok := primitiveTypes[lower]; ok {
+		return lower
+	}
+	if _, ok := elementTypes[suffix]; ok || quantityTypes[suffix] {
+		return suffix
+	}
+	switch suffix {
+	case "Timing", "Signature", "SampledData", "Money", "ContactDetail", "Dosage", "Expression", "UsageContext", "RelatedArtifact":
+		return suffix
+	}
+	return ""
+}
@@ -0,0 +1,404 @@
+package fhirpath
+
+import (
+	"strings"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+)
+
+// expr is a node of a parsed expression
+type expr interface {
+	eval(c *evalContext, focus Collection) (Collection, error)
+}
+
+// literalExpr is a constant, including the empty collection {}
+type literalExpr struct {
+	value Collection
+}
+
+// invokeExpr is a member access or function call on target, or on the
+// focus when target is nil
+type invokeExpr struct {
+	target expr
+	name   string
+	call   bool
+	args   []expr
+}
+
+// variableExpr is an environment variable such as %resource
+type variableExpr struct {
+	name string
+}
+
+// specialExpr is one of $this, $index and $total
+type specialExpr struct {
+	name string
+}
+
+// indexerExpr is target[index]
+type indexerExpr struct {
+	target, index expr
+}
+
+// unaryExpr is a polarity operator
+type unaryExpr struct {
+	op      string
+	operand expr
+}
+
+// binaryExpr is an infix operator other than is and as
+type binaryExpr struct {
+	op          string
+	left, right expr
+}
+
+// typeExpr is operand is Type or operand as Type
+type typeExpr struct {
+	op      string
+	operand expr
+	typ     typeSpecifier
+}
+
+// typeSpecifier is a possibly qualified type name, e.g. FHIR.Patient
+type typeSpecifier struct {
+	namespace, name string
+}
+
+// calendarUnits maps the calendar duration keywords to their singular form
+var calendarUnits = map[string]string{
+	"year": "year", "years": "year",
+	"month": "month", "months": "month",
+	"week": "week", "weeks": "week",
+	"day": "day", "days": "day",
+	"hour": "hour", "hours": "hour",
+	"minute": "minute", "minutes": "minute",
+	"second": "second", "seconds": "second",
+	"millisecond": "millisecond", "milliseconds": "millisecond",
+}
+
+// Expression is a parsed FHIRPath expression
+type Expression struct {
+	source string
+	root   expr
+}
+
+// Parse parses a FHIRPath expression
+func Parse(expression string) (*Expression, error) {
+	tokens, err := lex(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, syntaxError(t.pos, "unexpected "+describe(t))
+	}
+	return &Expression{source: expression, root: root}, nil
+}
+
+// MustParse is like Parse but panics on error
+func MustParse(expression string) *Expression {
+	e, err := Parse(expression)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// parser is a recursive descent parser over the tokens of an expression
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the given operators or keywords
+func (p *parser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	for _, op := range ops {
+		if t.is(op) {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		t := p.peek()
+		return syntaxError(t.pos, "expected "+op+" but found "+describe(t))
+	}
+	return nil
+}
+
+func describe(t token) string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return "'" + t.text + "'"
+}
+
+// binaryLevels lists the infix operators from lowest to highest precedence.
+// is and as sit between union and additive and are handled by parseType.
+var binaryLevels = [][]string{
+	{"implies"},
+	{"or", "xor"},
+	{"and"},
+	{"in", "contains"},
+	{"=", "~", "!=", "!~"},
+	{"<=", "<", ">=", ">"},
+	{"|"},
+	nil,
+	{"+", "-", "&"},
+	{"*", "/", "div", "mod"},
+}
+
+func (p *parser) parseExpression() (expr, error) {
+	return p.parseLevel(0)
+}
+
+func (p *parser) parseLevel(level int) (expr, error) {
+	if level == len(binaryLevels) {
+		return p.parseUnary()
+	}
+	if binaryLevels[level] == nil {
+		return p.parseType(level)
+	}
+
+	left, err := p.parseLevel(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(binaryLevels[level]...)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseLevel(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseType(level int) (expr, error) {
+	operand, err := p.parseLevel(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("is", "as")
+		if !ok {
+			return operand, nil
+		}
+		spec, err := p.parseTypeSpecifier()
+		if err != nil {
+			return nil, err
+		}
+		operand = &typeExpr{op: op, operand: operand, typ: spec}
+	}
+}
+
+func (p *parser) parseTypeSpecifier() (typeSpecifier, error) {
+	t := p.next()
+	if t.kind != tokenIdentifier {
+		return typeSpecifier{}, syntaxError(t.pos, "expected a type name but found "+describe(t))
+	}
+	spec := typeSpecifier{name: t.text}
+	if p.peek().is(".") && p.tokens[p.pos+1].kind == tokenIdentifier {
+		p.pos++
+		spec = typeSpecifier{namespace: t.text, name: p.next().text}
+	}
+	return spec, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if op, ok := p.accept("+", "-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: op, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (expr, error) {
+	e, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.peek().is("."):
+			p.pos++
+			e, err = p.parseInvocation(e)
+			if err != nil {
+				return nil, err
+			}
+		case p.peek().is("["):
+			p.pos++
+			index, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			e = &indexerExpr{target: e, index: index}
+		default:
+			return e, nil
+		}
+	}
+}
+
+// parseInvocation parses a member name or function call applied to target
+func (p *parser) parseInvocation(target expr) (expr, error) {
+	t := p.next()
+	if t.kind != tokenIdentifier || strings.HasPrefix(t.text, "$") {
+		return nil, syntaxError(t.pos, "expected a name but found "+describe(t))
+	}
+	inv := &invokeExpr{target: target, name: t.text}
+	if !p.peek().is("(") {
+		return inv, nil
+	}
+	p.pos++
+	inv.call = true
+	if _, ok := p.accept(")"); ok {
+		return inv, nil
+	}
+	for {
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		inv.args = append(inv.args, arg)
+		if _, ok := p.accept(","); !ok {
+			break
+		}
+	}
+	return inv, p.expect(")")
+}
+
+func (p *parser) parseTerm() (expr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenNumber:
+		p.pos++
+		return p.parseNumber(t)
+
+	case tokenString:
+		p.pos++
+		return &literalExpr{value: Collection{t.text}}, nil
+
+	case tokenDateTime:
+		p.pos++
+		text := strings.TrimSuffix(t.text, "T")
+		if !strings.Contains(t.text, "T") {
+			d, err := ParseDate(text)
+			if err != nil {
+				return nil, syntaxError(t.pos, err.Error())
+			}
+			return &literalExpr{value: Collection{d}}, nil
+		}
+		dt, err := ParseDateTime(text)
+		if err != nil {
+			return nil, syntaxError(t.pos, err.Error())
+		}
+		return &literalExpr{value: Collection{dt}}, nil
+
+	case tokenTime:
+		p.pos++
+		tm, err := ParseTime(t.text)
+		if err != nil {
+			return nil, syntaxError(t.pos, err.Error())
+		}
+		return &literalExpr{value: Collection{tm}}, nil
+
+	case tokenVariable:
+		p.pos++
+		return &variableExpr{name: t.text}, nil
+
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			p.pos++
+			e, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			return e, p.expect(")")
+		case "{":
+			p.pos++
+			return &literalExpr{}, p.expect("}")
+		}
+
+	case tokenIdentifier:
+		if !t.delimited {
+			switch t.text {
+			case "true", "false":
+				p.pos++
+				return &literalExpr{value: Collection{t.text == "true"}}, nil
+			case "$this", "$index", "$total":
+				p.pos++
+				return &specialExpr{name: t.text}, nil
+			}
+		}
+		return p.parseInvocation(nil)
+	}
+	return nil, syntaxError(t.pos, "unexpected "+describe(t))
+}
+
+// parseNumber parses an integer or decimal literal, which becomes a
+// quantity when followed by a unit string or calendar duration keyword
+func (p *parser) parseNumber(t token) (expr, error) {
+	unit, isQuantity := "", false
+	switch next := p.peek(); {
+	case next.kind == tokenString:
+		unit, isQuantity = next.text, true
+	case next.kind == tokenIdentifier && !next.delimited && calendarUnits[next.text] != "":
+		unit, isQuantity = calendarUnits[next.text], true
+	}
+
+	if isQuantity {
+		p.pos++
+		value, err := fhir.ParseDecimal(t.text)
+		if err != nil {
+			return nil, syntaxError(t.pos, err.Error())
+		}
+		return &literalExpr{value: Collection{Quantity{Value: value, Unit: unit}}}, nil
+	}
+	if !strings.Contains(t.text, ".") {
+		n, ok := parseInteger(t.text)
+		if !ok {
+			return nil, syntaxError(t.pos, "integer out of range")
+		}
+		return &literalExpr{value: Collection{n}}, nil
+	}
+	value, err := fhir.ParseDecimal(t.text)
+	if err != nil {
+		return nil, syntaxError(t.pos, err.Error())
+	}
+	return &literalExpr{value: Collection{value}}, nil
+}
@@ -0,0 +1,127 @@
+package fhirpath
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+)
+
+// unit is a UCUM unit expressed as a multiple of its dimension's base unit
+type unit struct {
+	dimension string
+	factor    *big.Rat
+}
+
+// units lists the UCUM units that quantities can be converted between.
+// Calendar durations of a week or less equal their UCUM counterparts;
+// years and months do not, as UCUM's 'a' and 'mo' are averages.
+var units = map[string]unit{}
+
+func init() {
+	add := func(dimension, factor string, codes ...string) {
+		f, _ := new(big.Rat).SetString(factor)
+		for _, code := range codes {
+			units[code] = unit{dimension: dimension, factor: f}
+		}
+	}
+	add("g", "0.000000001", "ng")
+	add("g", "0.000001", "ug")
+	add("g", "0.001", "mg")
+	add("g", "1", "g")
+	add("g", "1000", "kg")
+	add("g", "453.59237", "[lb_av]")
+	add("g", "28.349523125", "[oz_av]")
+	add("m", "0.001", "mm")
+	add("m", "0.01", "cm")
+	add("m", "1", "m")
+	add("m", "1000", "km")
+	add("m", "0.0254", "[in_i]")
+	add("m", "0.3048", "[ft_i]")
+	add("L", "0.001", "mL")
+	add("L", "0.1", "dL")
+	add("L", "1", "L", "l")
+	add("s", "0.001", "ms", "millisecond")
+	add("s", "1", "s", "second")
+	add("s", "60", "min", "minute")
+	add("s", "3600", "h", "hour")
+	add("s", "86400", "d", "day")
+	add("s", "604800", "wk", "week")
+	add("mo", "1", "mo")
+	add("a", "1", "a")
+	add("month", "1", "month")
+	add("year", "1", "year")
+	add("1", "1", "1")
+}
+
+// convertQuantity expresses q in the given unit, if they measure the same dimension
+func convertQuantity(q Quantity, to string) (Quantity, bool) {
+	if q.Unit == to {
+		return q, true
+	}
+	from, okFrom := units[q.Unit]
+	target, okTo := units[to]
+	if !okFrom || !okTo || from.dimension != target.dimension {
+		return Quantity{}, false
+	}
+	value := new(big.Rat).Mul(q.Value.Rat(), from.factor)
+	value.Quo(value, target.factor)
+	return Quantity{Value: decimalFromRat(value), Unit: to}, true
+}
+
+// compareQuantities orders two quantities, converting units where possible
+func compareQuantities(a, b Quantity) (int, bool) {
+	converted, ok := convertQuantity(b, a.Unit)
+	if !ok {
+		return 0, false
+	}
+	return a.Value.Cmp(converted.Value), true
+}
+
+// decimalFromRat formats r exactly when it has a short decimal expansion,
+// and to eight decimal places otherwise
+func decimalFromRat(r *big.Rat) fhir.Decimal {
+	s := r.FloatString(8)
+	if exact, ok := decimalPlaces(r); ok {
+		s = r.FloatString(exact)
+	} else {
+		s = strings.TrimRight(s, "0")
+	}
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" {
+		s = "0"
+	}
+	return fhir.MustParseDecimal(s)
+}
+
+// decimalPlaces returns the number of decimal places needed to write r
+// exactly, reporting false if its decimal expansion does not terminate
+func decimalPlaces(r *big.Rat) (int, bool) {
+	d := new(big.Int).Set(r.Denom())
+	places := 0
+	for _, p := range []int64{2, 5} {
+		n, prime, m := 0, big.NewInt(p), new(big.Int)
+		for {
+			q, rem := new(big.Int).QuoRem(d, prime, m)
+			if rem.Sign() != 0 {
+				break
+			}
+			d, n = q, n+1
+		}
+		places = max(places, n)
+	}
+	return places, d.Cmp(big.NewInt(1)) == 0
+}
+
+// addQuantities implements + and - for two quantities
+func addQuantities(a, b Quantity, sign int) (Quantity, error) {
+	converted, ok := convertQuantity(b, a.Unit)
+	if !ok {
+		return Quantity{}, fmt.Errorf("cannot add %s and %s", a, b)
+	}
+	if sign < 0 {
+		return Quantity{Value: a.Value.Sub(converted.Value), Unit: a.Unit}, nil
+	}
+	return Quantity{Value: a.Value.Add(converted.Value), Unit: a.Unit}, nil
+}
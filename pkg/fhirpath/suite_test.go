@@ -0,0 +1,134 @@
+package fhirpath
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The suite tests read files in the format of the official FHIRPath test
+// suite (tests-fhir-r4.xml) from testdata. The official suite names XML
+// input files; they are read from the JSON file of the same name in
+// testdata, and a missing input fails the tests that use it.
+
+// knownFailures lists the suite tests that are expected to fail, by name,
+// with the reason. A listed test that passes fails the suite so that the
+// list stays accurate.
+var knownFailures = map[string]string{}
+
+type testSuite struct {
+	Groups []testGroup `xml:"group"`
+}
+
+type testGroup struct {
+	Name  string      `xml:"name,attr"`
+	Tests []suiteCase `xml:"test"`
+}
+
+type suiteCase struct {
+	Name       string `xml:"name,attr"`
+	InputFile  string `xml:"inputfile,attr"`
+	Predicate  string `xml:"predicate,attr"`
+	Expression struct {
+		Text    string `xml:",chardata"`
+		Invalid string `xml:"invalid,attr"`
+	} `xml:"expression"`
+	Outputs []struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"output"`
+}
+
+func TestSuite(t *testing.T) {
+	files, err := filepath.Glob("testdata/tests-fhir-r4*.xml")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no suite files found: %v", err)
+	}
+	inputs := map[string]Collection{}
+	for _, file := range files {
+		suite, err := readSuite(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, group := range suite.Groups {
+			for _, tc := range group.Tests {
+				tc := tc
+				t.Run(group.Name+"/"+tc.Name, func(t *testing.T) {
+					err := runSuiteCase(tc, inputs)
+					reason, known := knownFailures[tc.Name]
+					switch {
+					case err != nil && known:
+						t.Logf("known failure (%s): %v", reason, err)
+					case err != nil:
+						t.Error(err)
+					case known:
+						t.Errorf("passes but is listed as a known failure (%s)", reason)
+					}
+				})
+			}
+		}
+	}
+}
+
+func readSuite(file string) (*testSuite, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	var suite testSuite
+	if err := xml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	return &suite, nil
+}
+
+func runSuiteCase(tc suiteCase, inputs map[string]Collection) error {
+	var input Collection
+	if tc.InputFile != "" {
+		name := inputName(tc.InputFile)
+		var ok bool
+		if input, ok = inputs[name]; !ok {
+			data, err := os.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				return fmt.Errorf("input %s is missing: %w", name, err)
+			}
+			if input, err = toCollection(data); err != nil {
+				return fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			inputs[name] = input
+		}
+	}
+
+	source := strings.TrimSpace(tc.Expression.Text)
+	result, err := NewEvaluator().Evaluate(context.Background(), input, source)
+	if tc.Expression.Invalid != "" {
+		if err == nil {
+			return fmt.Errorf("%s: expected an error, got %s", source, result)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	if tc.Predicate == "true" {
+		result = Collection{len(result) > 0}
+	}
+	if len(result) != len(tc.Outputs) {
+		return fmt.Errorf("%s: got %s, want %d values", source, result, len(tc.Outputs))
+	}
+	for i, want := range tc.Outputs {
+		if got := formatItem(result[i]); got != want.Value {
+			return fmt.Errorf("%s: value %d is %s, want %s", source, i, got, want.Value)
+		}
+	}
+	return nil
+}
+
+// inputName returns the testdata file read for an input named by the suite
+func inputName(inputFile string) string {
+	return strings.TrimSuffix(inputFile, ".xml") + ".json"
+}
@@ -0,0 +1,387 @@
+package fhirpath
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// precision is the granularity of a date or time value. Seconds and
+// milliseconds are compared as a single precision.
+type precision int
+
+const (
+	precisionYear precision = iota + 1
+	precisionMonth
+	precisionDay
+	precisionHour
+	precisionMinute
+	precisionSecond
+	precisionMillisecond
+)
+
+// temporal is the representation shared by Date, DateTime and Time. Fields
+// beyond the precision are zero.
+type temporal struct {
+	year, month, day             int
+	hour, minute, second, millis int
+
+	precision precision
+
+	// first is precisionYear for dates and date-times and precisionHour for times
+	first precision
+
+	hasZone bool
+	offset  int // seconds east of UTC
+}
+
+// Date is a FHIRPath Date, known to the year, month or day
+type Date struct {
+	temporal
+}
+
+// DateTime is a FHIRPath DateTime, known to any precision from the year to
+// the millisecond, with an optional time zone
+type DateTime struct {
+	temporal
+}
+
+// Time is a FHIRPath Time, a time of day known to the hour, minute, second
+// or millisecond
+type Time struct {
+	temporal
+}
+
+var (
+	datePattern     = regexp.MustCompile(`^(\d{4})(?:-(\d{2})(?:-(\d{2}))?)?$`)
+	dateTimePattern = regexp.MustCompile(`^(\d{4})(?:-(\d{2})(?:-(\d{2})(?:T(\d{2})(?::(\d{2})(?::(\d{2})(?:\.(\d+))?)?)?)?)?)?(Z|[+-]\d{2}:\d{2})?$`)
+	timePattern     = regexp.MustCompile(`^T?(\d{2})(?::(\d{2})(?::(\d{2})(?:\.(\d+))?)?)?$`)
+)
+
+// ParseDate parses a date in YYYY, YYYY-MM or YYYY-MM-DD form
+func ParseDate(s string) (Date, error) {
+	m := datePattern.FindStringSubmatch(s)
+	if m == nil {
+		return Date{}, fmt.Errorf("invalid date %q", s)
+	}
+	t, err := buildTemporal(s, precisionYear, m[1:4], nil, "")
+	return Date{t}, err
+}
+
+// ParseDateTime parses a date-time from a year to a millisecond, with an
+// optional time zone, e.g. "2015-02-04T14:34" or "2015-02-04T14:34:28.123Z"
+func ParseDateTime(s string) (DateTime, error) {
+	m := dateTimePattern.FindStringSubmatch(s)
+	if m == nil {
+		return DateTime{}, fmt.Errorf("invalid date/time %q", s)
+	}
+	t, err := buildTemporal(s, precisionYear, m[1:4], m[4:8], m[8])
+	return DateTime{t}, err
+}
+
+// ParseTime parses a time of day in hh, hh:mm, hh:mm:ss or hh:mm:ss.fff form
+func ParseTime(s string) (Time, error) {
+	m := timePattern.FindStringSubmatch(s)
+	if m == nil {
+		return Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	t, err := buildTemporal(s, precisionHour, nil, m[1:5], "")
+	return Time{t}, err
+}
+
+// buildTemporal assembles a value from the matched date fields (year, month,
+// day), time fields (hour, minute, second, fraction) and zone
+func buildTemporal(s string, first precision, date, clock []string, zone string) (temporal, error) {
+	t := temporal{first: first}
+	fields := []*int{&t.year, &t.month, &t.day}
+	for i, f := range date {
+		if f == "" {
+			break
+		}
+		*fields[i], _ = strconv.Atoi(f)
+		t.precision = precisionYear + precision(i)
+	}
+	if first == precisionHour || t.precision == precisionDay {
+		fields = []*int{&t.hour, &t.minute, &t.second}
+		for i, f := range clock {
+			if f == "" {
+				break
+			}
+			if i == 3 {
+				t.millis, _ = strconv.Atoi((f + "00")[:3])
+				t.precision = precisionMillisecond
+				break
+			}
+			*fields[i], _ = strconv.Atoi(f)
+			t.precision = precisionHour + precision(i)
+		}
+	}
+
+	if zone != "" && t.precision >= precisionHour {
+		t.hasZone = true
+		if zone != "Z" {
+			hours, _ := strconv.Atoi(zone[1:3])
+			minutes, _ := strconv.Atoi(zone[4:6])
+			t.offset = hours*3600 + minutes*60
+			if zone[0] == '-' {
+				t.offset = -t.offset
+			}
+		}
+	}
+
+	if first == precisionYear && t.precision >= precisionMonth && (t.month < 1 || t.month > 12) ||
+		first == precisionYear && t.precision >= precisionDay && (t.day < 1 || t.day > daysIn(t.year, t.month)) ||
+		t.hour > 23 || t.minute > 59 || t.second > 59 {
+		return temporal{}, fmt.Errorf("invalid date/time %q: field out of range", s)
+	}
+	return t, nil
+}
+
+// daysIn returns the number of days in a month
+func daysIn(year, month int) int {
+	return time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// temporalOf builds a millisecond-precision value from a time
+func temporalOf(t time.Time, first precision) temporal {
+	_, offset := t.Zone()
+	return temporal{
+		year: t.Year(), month: int(t.Month()), day: t.Day(),
+		hour: t.Hour(), minute: t.Minute(), second: t.Second(), millis: t.Nanosecond() / 1e6,
+		precision: precisionMillisecond, first: first,
+		hasZone: first == precisionYear, offset: offset,
+	}
+}
+
+// time returns the value as a time, filling missing fields with their minimum
+func (t temporal) time() time.Time {
+	month, day := max(t.month, 1), max(t.day, 1)
+	return time.Date(t.year, time.Month(month), day, t.hour, t.minute, t.second, t.millis*1e6, time.FixedZone("", t.offset))
+}
+
+// normalized returns the value shifted to UTC, if it has a time zone
+func (t temporal) normalized() temporal {
+	if !t.hasZone || t.offset == 0 || t.first == precisionHour {
+		return t
+	}
+	u := t.time().UTC()
+	n := t
+	n.year, n.month, n.day = u.Year(), int(u.Month()), u.Day()
+	n.hour, n.minute = u.Hour(), u.Minute()
+	n.offset = 0
+	return n
+}
+
+// field returns the value of the field at precision p, with seconds and
+// milliseconds combined
+func (t temporal) field(p precision) int {
+	switch p {
+	case precisionYear:
+		return t.year
+	case precisionMonth:
+		return t.month
+	case precisionDay:
+		return t.day
+	case precisionHour:
+		return t.hour
+	case precisionMinute:
+		return t.minute
+	default:
+		return t.second*1000 + t.millis
+	}
+}
+
+// comparablePrecision treats milliseconds as seconds
+func (t temporal) comparablePrecision() precision {
+	return min(t.precision, precisionSecond)
+}
+
+// compare orders two values. ok is false if they are equal up to the
+// coarser precision of the two but have different precisions.
+func (t temporal) compare(other temporal) (result int, ok bool) {
+	a, b := t.normalized(), other.normalized()
+	last := min(a.comparablePrecision(), b.comparablePrecision())
+	for p := a.first; p <= last; p++ {
+		if x, y := a.field(p), b.field(p); x != y {
+			if x < y {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	if a.comparablePrecision() != b.comparablePrecision() {
+		return 0, false
+	}
+	return 0, true
+}
+
+// add returns the value moved by a calendar duration. Units finer than the
+// value's precision are converted to its precision and truncated.
+func (t temporal) add(amount int64, unit string) (temporal, error) {
+	p, ok := unitPrecisions[unit]
+	if !ok {
+		return temporal{}, fmt.Errorf("cannot add %s to a date or time", unit)
+	}
+	if unit == "week" {
+		amount *= 7
+	}
+	for p > t.precision && p > precisionDay {
+		amount /= precisionFactors[p]
+		p--
+	}
+	if p > t.precision {
+		// days to months or years are not a fixed ratio; approximate as the spec allows
+		switch {
+		case t.precision == precisionMonth:
+			amount /= 30
+		default:
+			amount /= 365
+		}
+		p = t.precision
+	}
+
+	r := t
+	switch p {
+	case precisionYear:
+		r.year += int(amount)
+		r.clampDay()
+	case precisionMonth:
+		months := r.year*12 + r.month - 1 + int(amount)
+		r.year, r.month = floorDiv(months, 12), months-floorDiv(months, 12)*12+1
+		r.clampDay()
+	default:
+		var d time.Duration
+		switch p {
+		case precisionDay:
+			moved := t.time().AddDate(0, 0, int(amount))
+			r.year, r.month, r.day = moved.Year(), int(moved.Month()), moved.Day()
+			return r, nil
+		case precisionHour:
+			d = time.Duration(amount) * time.Hour
+		case precisionMinute:
+			d = time.Duration(amount) * time.Minute
+		case precisionSecond:
+			d = time.Duration(amount) * time.Second
+		case precisionMillisecond:
+			d = time.Duration(amount) * time.Millisecond
+		}
+		moved := t.time().Add(d)
+		if t.first == precisionHour {
+			moved = time.Date(0, 1, 1, t.hour, t.minute, t.second, t.millis*1e6, time.UTC).Add(d)
+		} else {
+			r.year, r.month, r.day = moved.Year(), int(moved.Month()), moved.Day()
+		}
+		r.hour, r.minute, r.second, r.millis = moved.Hour(), moved.Minute(), moved.Second(), moved.Nanosecond()/1e6
+		if r.precision == precisionSecond && r.millis != 0 {
+			r.precision = precisionMillisecond
+		}
+	}
+	return r, nil
+}
+
+// clampDay moves a day past the end of the month back to its last day
+func (t *temporal) clampDay() {
+	if t.precision >= precisionDay {
+		t.day = min(t.day, daysIn(t.year, t.month))
+	}
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// unitPrecisions maps calendar duration units to the precision they change
+var unitPrecisions = map[string]precision{
+	"year": precisionYear, "month": precisionMonth, "week": precisionDay, "day": precisionDay,
+	"hour": precisionHour, "minute": precisionMinute, "second": precisionSecond, "millisecond": precisionMillisecond,
+}
+
+// precisionFactors gives the number of units of each precision in the next coarser one
+var precisionFactors = map[precision]int64{
+	precisionHour: 24, precisionMinute: 60, precisionSecond: 60, precisionMillisecond: 1000,
+}
+
+// dateString formats the date part
+func (t temporal) dateString() string {
+	s := fmt.Sprintf("%04d", t.year)
+	if t.precision >= precisionMonth {
+		s += fmt.Sprintf("-%02d", t.month)
+	}
+	if t.precision >= precisionDay {
+		s += fmt.Sprintf("-%02d", t.day)
+	}
+	return s
+}
+
+// timeString formats the time part
+func (t temporal) timeString() string {
+	s := fmt.Sprintf("%02d", t.hour)
+	if t.precision >= precisionMinute {
+		s += fmt.Sprintf(":%02d", t.minute)
+	}
+	if t.precision >= precisionSecond {
+		s += fmt.Sprintf(":%02d", t.second)
+	}
+	if t.precision >= precisionMillisecond {
+		s += fmt.Sprintf(".%03d", t.millis)
+	}
+	return s
+}
+
+// zoneString formats the time zone
+func (t temporal) zoneString() string {
+	if !t.hasZone {
+		return ""
+	}
+	if t.offset == 0 {
+		return "Z"
+	}
+	sign, offset := '+', t.offset
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("%c%02d:%02d", sign, offset/3600, offset%3600/60)
+}
+
+// String returns the date in YYYY, YYYY-MM or YYYY-MM-DD form
+func (d Date) String() string {
+	return d.dateString()
+}
+
+// String returns the date-time in its original precision
+func (d DateTime) String() string {
+	s := d.dateString()
+	if d.precision >= precisionHour {
+		s += "T" + d.timeString() + d.zoneString()
+	}
+	return s
+}
+
+// String returns the time in its original precision, without a leading T
+func (t Time) String() string {
+	return t.timeString()
+}
+
+// literal returns the value as a FHIRPath literal
+func literal(v interface{}) string {
+	switch v := v.(type) {
+	case Date:
+		return "@" + v.String()
+	case DateTime:
+		if v.precision < precisionHour {
+			return "@" + v.String() + "T"
+		}
+		return "@" + v.String()
+	case Time:
+		return "@T" + v.String()
+	case string:
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+	}
+	return fmt.Sprint(v)
+}
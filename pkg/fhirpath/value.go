@@ -0,0 +1,647 @@
+package fhirpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+)
+
+// Collection is the result of evaluating an expression. Its items are
+// *Element for nodes of the input, or the system types bool, int64,
+// fhir.Decimal, string, Date, DateTime, Time and Quantity.
+type Collection []interface{}
+
+// Element is a node of the tree an expression navigates: a resource, a
+// complex element, or a FHIR primitive with its id and extensions
+type Element struct {
+	// Value is the decoded JSON: a map[string]interface{} for resources and
+	// complex elements, and a string, bool or json.Number for primitives. It
+	// is nil for primitives that have only an id or extensions.
+	Value interface{}
+
+	// Type is the FHIR type of the element, e.g. "HumanName" or "date", or
+	// "" if it is not known
+	Type string
+
+	// Name is the name of the member the element was read from
+	Name string
+
+	// extra is the "_name" member holding a primitive's id and extensions
+	extra map[string]interface{}
+
+	// model is the path used to look up the types of the element's children,
+	// e.g. "Patient.contact" for backbone elements
+	model string
+}
+
+// Quantity is a FHIRPath Quantity. Unit is a UCUM code, or a calendar
+// duration keyword such as "year" in its singular form.
+type Quantity struct {
+	Value fhir.Decimal
+	Unit  string
+}
+
+// String returns the quantity as a FHIRPath literal
+func (q Quantity) String() string {
+	if _, ok := unitPrecisions[q.Unit]; ok {
+		return q.Value.String() + " " + q.Unit
+	}
+	return q.Value.String() + " '" + q.Unit + "'"
+}
+
+// String formats the collection's values, for diagnostics
+func (c Collection) String() string {
+	parts := make([]string, len(c))
+	for i, item := range c {
+		parts[i] = formatItem(item)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// formatItem formats a single item, for diagnostics and the test suite
+func formatItem(item interface{}) string {
+	if e, ok := item.(*Element); ok {
+		if v, ok := systemValue(e); ok {
+			return formatItem(v)
+		}
+		data, _ := json.Marshal(e.Value)
+		return string(data)
+	}
+	if s, ok := item.(string); ok {
+		return s
+	}
+	return literal(item)
+}
+
+// Bool returns the value of a collection holding a single boolean. ok is
+// false if the collection is empty or holds anything else.
+func (c Collection) Bool() (value, ok bool) {
+	if len(c) != 1 {
+		return false, false
+	}
+	value, ok = systemItem(c[0]).(bool)
+	return value, ok
+}
+
+// Strings returns the string form of each item that is a primitive value
+func (c Collection) Strings() []string {
+	var result []string
+	for _, item := range c {
+		if s, ok := convertToString(item); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// newElement wraps a decoded JSON value read from member name of a parent
+// whose children are typed by model
+func newElement(value interface{}, extra map[string]interface{}, name, typeName, model string) *Element {
+	e := &Element{Value: value, Name: name, Type: typeName, model: model, extra: extra}
+	if obj, ok := value.(map[string]interface{}); ok {
+		if rt, ok := obj["resourceType"].(string); ok {
+			e.Type, e.model = rt, rt
+			return e
+		}
+	}
+	if e.Type == "" {
+		switch v := value.(type) {
+		case bool:
+			e.Type = "boolean"
+		case json.Number:
+			e.Type = "decimal"
+			if isIntegerText(string(v)) {
+				e.Type = "integer"
+			}
+		case float64:
+			e.Type = "decimal"
+			if v == float64(int64(v)) {
+				e.Type = "integer"
+			}
+		case string:
+			e.Type = "string"
+		}
+	}
+	return e
+}
+
+func isIntegerText(s string) bool {
+	return !strings.ContainsAny(s, ".eE")
+}
+
+// primitiveTypes lists the FHIR primitive types and the system types they map to
+var primitiveTypes = map[string]string{
+	"boolean": "Boolean", "integer": "Integer", "unsignedInt": "Integer", "positiveInt": "Integer", "integer64": "Integer",
+	"decimal": "Decimal", "string": "String", "code": "String", "id": "String", "markdown": "String",
+	"uri": "String", "url": "String", "canonical": "String", "oid": "String", "uuid": "String", "base64Binary": "String",
+	"xhtml": "String", "date": "Date", "dateTime": "DateTime", "instant": "DateTime", "time": "Time",
+}
+
+// quantityTypes lists the FHIR types that convert to a system Quantity
+var quantityTypes = map[string]bool{
+	"Quantity": true, "Age": true, "Count": true, "Distance": true, "Duration": true,
+	"SimpleQuantity": true, "MoneyQuantity": true,
+}
+
+// systemItem converts an element to its system value where it has one and
+// returns other items unchanged
+func systemItem(item interface{}) interface{} {
+	if e, ok := item.(*Element); ok {
+		if v, ok := systemValue(e); ok {
+			return v
+		}
+	}
+	return item
+}
+
+// systemValue returns the system value of a primitive or Quantity element
+func systemValue(e *Element) (interface{}, bool) {
+	switch v := e.Value.(type) {
+	case bool:
+		return v, true
+	case json.Number:
+		return numberValue(string(v), e.Type)
+	case float64:
+		return numberValue(strconv.FormatFloat(v, 'f', -1, 64), e.Type)
+	case string:
+		switch e.Type {
+		case "date":
+			if d, err := ParseDate(v); err == nil {
+				return d, true
+			}
+		case "dateTime", "instant":
+			if dt, err := ParseDateTime(v); err == nil {
+				return dt, true
+			}
+		case "time":
+			if t, err := ParseTime(v); err == nil {
+				return t, true
+			}
+		}
+		return v, true
+	case map[string]interface{}:
+		if quantityTypes[e.Type] {
+			return quantityOf(v)
+		}
+	}
+	return nil, false
+}
+
+// numberValue converts a JSON number to an Integer or Decimal
+func numberValue(s, typeName string) (interface{}, bool) {
+	if primitiveTypes[typeName] == "Integer" || typeName != "decimal" && isIntegerText(s) {
+		if n, ok := parseInteger(s); ok {
+			return n, true
+		}
+	}
+	d, err := fhir.ParseDecimal(s)
+	return d, err == nil
+}
+
+func parseInteger(s string) (int64, bool) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
+
+// quantityOf converts a FHIR Quantity to a system Quantity, using the UCUM
+// code when there is one and the human-readable unit otherwise
+func quantityOf(obj map[string]interface{}) (interface{}, bool) {
+	var value fhir.Decimal
+	switch v := obj["value"].(type) {
+	case json.Number:
+		value, _ = fhir.ParseDecimal(string(v))
+	case float64:
+		var err error
+		if value, err = fhir.NewDecimalFromFloat(v); err != nil {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	unit, _ := obj["unit"].(string)
+	if code, ok := obj["code"].(string); ok && (obj["system"] == nil || obj["system"] == ucumSystem) {
+		unit = code
+	}
+	return Quantity{Value: value, Unit: unit}, true
+}
+
+const ucumSystem = "http://unitsofmeasure.org"
+
+// children returns the child elements of an element, in member order
+func (e *Element) children() Collection {
+	obj, ok := e.Value.(map[string]interface{})
+	if !ok {
+		if e.extra == nil {
+			return nil
+		}
+		obj = e.extra
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		if key != "resourceType" {
+			keys = append(keys, strings.TrimPrefix(key, "_"))
+		}
+	}
+	sort.Strings(keys)
+	var result Collection
+	for i, key := range keys {
+		if i > 0 && keys[i-1] == key {
+			continue
+		}
+		result = append(result, e.member(key, obj)...)
+	}
+	return result
+}
+
+// child returns the elements of member name, or of the choice element
+// name[x] whichever type is present
+func (e *Element) child(name string) Collection {
+	obj, ok := e.Value.(map[string]interface{})
+	if !ok {
+		// Primitives have only id and extension, held in the "_name" member
+		if e.extra == nil || (name != "id" && name != "extension") {
+			return nil
+		}
+		return (&Element{Value: e.extra, model: "Element"}).child(name)
+	}
+	if _, ok := obj[name]; ok {
+		return e.member(name, obj)
+	}
+	if _, ok := obj["_"+name]; ok {
+		return e.member(name, obj)
+	}
+	for key := range obj {
+		key = strings.TrimPrefix(key, "_")
+		if suffix := strings.TrimPrefix(key, name); suffix != key && suffix != "" && unicode.IsUpper(rune(suffix[0])) {
+			return e.member(key, obj)
+		}
+	}
+	return nil
+}
+
+// member returns the elements of a member of obj, pairing primitive values
+// with their "_key" extensions
+func (e *Element) member(key string, obj map[string]interface{}) Collection {
+	typeName, model := childType(e.model, key)
+	value, extra := obj[key], obj["_"+key]
+
+	values, isList := value.([]interface{})
+	extras, _ := extra.([]interface{})
+	if !isList {
+		if value == nil && extra == nil {
+			return nil
+		}
+		values, extras = []interface{}{value}, []interface{}{extra}
+	}
+	var result Collection
+	for i := 0; i < max(len(values), len(extras)); i++ {
+		var v interface{}
+		var x map[string]interface{}
+		if i < len(values) {
+			v = values[i]
+		}
+		if i < len(extras) {
+			x, _ = extras[i].(map[string]interface{})
+		}
+		if v == nil && x == nil {
+			continue
+		}
+		result = append(result, newElement(v, x, key, typeName, model))
+	}
+	return result
+}
+
+// typeName returns the namespace and name of an item's type
+func typeName(item interface{}) (namespace, name string) {
+	switch v := item.(type) {
+	case *Element:
+		return "FHIR", v.Type
+	case bool:
+		return "System", "Boolean"
+	case int64:
+		return "System", "Integer"
+	case fhir.Decimal:
+		return "System", "Decimal"
+	case string:
+		return "System", "String"
+	case Date:
+		return "System", "Date"
+	case DateTime:
+		return "System", "DateTime"
+	case Time:
+		return "System", "Time"
+	case Quantity:
+		return "System", "Quantity"
+	}
+	return "System", "Any"
+}
+
+// isType reports whether an item is of the given type or one derived from it
+func isType(item interface{}, spec typeSpecifier) bool {
+	namespace, name := typeName(item)
+	if spec.namespace != "" && spec.namespace != namespace {
+		return false
+	}
+	if namespace == "System" {
+		return name == spec.name
+	}
+	for t := name; t != ""; {
+		if t == spec.name {
+			return true
+		}
+		base, ok := baseTypes[t]
+		if e := item.(*Element); !ok && t == e.model {
+			// Resources outside the built-in model are still domain resources
+			if obj, isObject := e.Value.(map[string]interface{}); isObject && obj["resourceType"] == t {
+				base = "DomainResource"
+			}
+		}
+		t = base
+	}
+	return false
+}
+
+// convertToString returns the string form of a primitive value
+func convertToString(item interface{}) (string, bool) {
+	switch v := systemItem(item).(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case fhir.Decimal:
+		return v.String(), true
+	case Date, DateTime, Time:
+		return fmt.Sprint(v), true
+	case Quantity:
+		return v.String(), true
+	}
+	return "", false
+}
+
+// equal implements the = operator for two items. ok is false when the
+// result is empty, as for dates of different precisions.
+func equal(a, b interface{}) (result, ok bool) {
+	a, b = systemItem(a), systemItem(b)
+	a, b = promote(a, b)
+
+	switch x := a.(type) {
+	case fhir.Decimal:
+		y, isDecimal := b.(fhir.Decimal)
+		return isDecimal && x.Cmp(y) == 0, true
+	case Quantity:
+		y, isQuantity := b.(Quantity)
+		if !isQuantity {
+			return false, true
+		}
+		c, comparable := compareQuantities(x, y)
+		if !comparable {
+			return false, false
+		}
+		return c == 0, true
+	case Date, DateTime, Time:
+		tx, ty, comparable := temporals(x, b)
+		if !comparable {
+			return false, true
+		}
+		c, ok := tx.compare(ty)
+		return c == 0, ok
+	case *Element:
+		y, isElement := b.(*Element)
+		return isElement && reflect.DeepEqual(x.Value, y.Value), true
+	}
+	return a == b, true
+}
+
+// equivalent implements the ~ operator for two items
+func equivalent(a, b interface{}) bool {
+	a, b = systemItem(a), systemItem(b)
+	a, b = promote(a, b)
+
+	switch x := a.(type) {
+	case string:
+		y, isString := b.(string)
+		return isString && normalizeSpace(x) == normalizeSpace(y)
+	case fhir.Decimal:
+		y, isDecimal := b.(fhir.Decimal)
+		if !isDecimal {
+			return false
+		}
+		scale := max(min(x.Scale(), y.Scale()), 0)
+		return new(big.Rat).Sub(roundRat(x.Rat(), scale), roundRat(y.Rat(), scale)).Sign() == 0
+	case Date, DateTime, Time:
+		tx, ty, comparable := temporals(x, b)
+		if !comparable {
+			return false
+		}
+		c, ok := tx.compare(ty)
+		return ok && c == 0
+	case *Element:
+		y, isElement := b.(*Element)
+		if !isElement {
+			return false
+		}
+		return equivalentCollections(x.children(), y.children())
+	}
+	result, ok := equal(a, b)
+	return ok && result
+}
+
+// normalizeSpace lower-cases a string and collapses its whitespace
+func normalizeSpace(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// roundRat rounds r half away from zero to scale decimal places
+func roundRat(r *big.Rat, scale int) *big.Rat {
+	d, _ := fhir.ParseDecimal(r.FloatString(scale))
+	return d.Rat()
+}
+
+// promote converts an Integer to a Decimal when compared with a Decimal, a
+// Date to a DateTime when compared with a DateTime, and a string to a date
+// or time when compared with one, as JSON trees carry no type for strings
+func promote(a, b interface{}) (interface{}, interface{}) {
+	switch x := a.(type) {
+	case int64:
+		switch b.(type) {
+		case fhir.Decimal:
+			return fhir.NewDecimalFromInt(x), b
+		case Quantity:
+			return Quantity{Value: fhir.NewDecimalFromInt(x), Unit: "1"}, b
+		}
+	case fhir.Decimal:
+		if y, ok := b.(int64); ok {
+			return a, fhir.NewDecimalFromInt(y)
+		}
+		if _, ok := b.(Quantity); ok {
+			return Quantity{Value: x, Unit: "1"}, b
+		}
+	case Quantity:
+		if _, ok := b.(Quantity); !ok {
+			b2, a2 := promote(b, a)
+			return a2, b2
+		}
+	case string:
+		if converted, ok := parseLike(x, b); ok {
+			return converted, b
+		}
+	case Date, DateTime, Time:
+		if y, ok := b.(string); ok {
+			if converted, ok := parseLike(y, a); ok {
+				return a, converted
+			}
+		}
+	}
+	return a, b
+}
+
+// parseLike parses s as the same kind of date or time as like
+func parseLike(s string, like interface{}) (interface{}, bool) {
+	switch like.(type) {
+	case Date, DateTime:
+		if d, err := ParseDate(s); err == nil {
+			return d, true
+		}
+		if dt, err := ParseDateTime(s); err == nil {
+			return dt, true
+		}
+	case Time:
+		if t, err := ParseTime(s); err == nil {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// temporals returns the representations of two dates or times, reporting
+// false if they are of incompatible kinds
+func temporals(a, b interface{}) (temporal, temporal, bool) {
+	ta, okA := temporalValue(a)
+	tb, okB := temporalValue(b)
+	if !okA || !okB || ta.first != tb.first {
+		return temporal{}, temporal{}, false
+	}
+	return ta, tb, true
+}
+
+func temporalValue(v interface{}) (temporal, bool) {
+	switch v := v.(type) {
+	case Date:
+		return v.temporal, true
+	case DateTime:
+		return v.temporal, true
+	case Time:
+		return v.temporal, true
+	}
+	return temporal{}, false
+}
+
+// compare orders two items for the comparison operators. ok is false when
+// the order is not known; an error is returned for incomparable types.
+func compare(a, b interface{}) (result int, ok bool, err error) {
+	a, b = systemItem(a), systemItem(b)
+	a, b = promote(a, b)
+
+	switch x := a.(type) {
+	case int64:
+		if y, isInt := b.(int64); isInt {
+			switch {
+			case x < y:
+				return -1, true, nil
+			case x > y:
+				return 1, true, nil
+			}
+			return 0, true, nil
+		}
+	case fhir.Decimal:
+		if y, isDecimal := b.(fhir.Decimal); isDecimal {
+			return x.Cmp(y), true, nil
+		}
+	case string:
+		if y, isString := b.(string); isString {
+			return strings.Compare(x, y), true, nil
+		}
+	case Quantity:
+		if y, isQuantity := b.(Quantity); isQuantity {
+			c, comparable := compareQuantities(x, y)
+			return c, comparable, nil
+		}
+	case Date, DateTime, Time:
+		if tx, ty, comparable := temporals(x, b); comparable {
+			c, ok := tx.compare(ty)
+			return c, ok, nil
+		}
+	}
+	return 0, false, fmt.Errorf("cannot compare %s with %s", describeType(a), describeType(b))
+}
+
+// describeType names an item's type for error messages
+func describeType(item interface{}) string {
+	namespace, name := typeName(item)
+	return namespace + "." + name
+}
+
+// equalCollections implements = for collections: the same items in the same order
+func equalCollections(a, b Collection) (result, ok bool) {
+	if len(a) != len(b) {
+		return false, true
+	}
+	for i := range a {
+		r, ok := equal(a[i], b[i])
+		if !ok || !r {
+			return r, ok
+		}
+	}
+	return true, true
+}
+
+// equivalentCollections implements ~ for collections: the same items in any order
+func equivalentCollections(a, b Collection) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	used := make([]bool, len(b))
+	for _, x := range a {
+		found := false
+		for j, y := range b {
+			if !used[j] && equivalent(x, y) {
+				used[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// contains reports whether an item equal to v is in c
+func contains(c Collection, v interface{}) bool {
+	for _, item := range c {
+		if r, ok := equal(item, v); ok && r {
+			return true
+		}
+	}
+	return false
+}
+
+// distinct returns c without repeated items
+func distinct(c Collection) Collection {
+	var result Collection
+	for _, item := range c {
+		if !contains(result, item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
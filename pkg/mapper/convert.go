@@ -0,0 +1,548 @@
+package mapper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/version"
+)
+
+// Loss is data that a conversion could not carry into the target version,
+// or could only carry approximately
+type Loss struct {
+	// Path locates the element in the resource converted, e.g.
+	// Encounter.statusHistory or Bundle.entry[0].resource.statusHistory
+	Path string
+
+	// Reason says what was lost
+	Reason string
+}
+
+// Report lists the data lost converting a resource between versions
+type Report struct {
+	From version.Version
+	To   version.Version
+	Lost []Loss
+}
+
+// Lossless reports whether the conversion kept all the data
+func (r *Report) Lossless() bool {
+	return len(r.Lost) == 0
+}
+
+// crossVersionIDs are the version segments of cross-version extension URLs
+var crossVersionIDs = map[version.Version]string{
+	version.STU3: "3.0",
+	version.R4:   "4.0",
+	version.R4B:  "4.3",
+	version.R5:   "5.0",
+}
+
+// crossVersionURL returns the URL of the extension that carries an element
+// of one version in another, e.g.
+// http://hl7.org/fhir/5.0/StructureDefinition/extension-Encounter.plannedStartDate
+func crossVersionURL(v version.Version, path string) string {
+	return fmt.Sprintf("http://hl7.org/fhir/%s/StructureDefinition/extension-%s", crossVersionIDs[v], strings.TrimSuffix(path, "[x]"))
+}
+
+// versionMap holds the conversions between two adjacent versions
+type versionMap struct {
+	older, newer version.Version
+
+	// resources holds the conversions of each resource type. Types without
+	// one are copied unchanged.
+	resources map[string]*resourceMap
+}
+
+// resourceMap describes how a resource type changed between two versions
+type resourceMap struct {
+	// renamed lists elements that were renamed within their parent
+	renamed []rename
+
+	// olderOnly and newerOnly list elements that exist in one version
+	// only. The other version carries them in cross-version extensions.
+	olderOnly, newerOnly []crossVersion
+
+	// toNewer and toOlder make the remaining changes. They run before the
+	// renames, so they see the element names of the version converted from.
+	toNewer, toOlder func(c *conversion, resource map[string]interface{})
+}
+
+// rename is an element renamed within its parent
+type rename struct {
+	older string // the path in the older version, e.g. Encounter.period
+	newer string // the name in the newer version, e.g. actualPeriod
+}
+
+// crossVersion is an element that exists in one version only
+type crossVersion struct {
+	// path is the element's path, ending in [x] for choice elements
+	path string
+
+	// typ is the element's datatype. It is empty for backbone elements and
+	// types that cannot be extension values in the other version, which
+	// are lost.
+	typ string
+
+	// repeats reports whether the element repeats
+	repeats bool
+}
+
+// conversion converts one resource between adjacent versions
+type conversion struct {
+	from, to version.Version
+	maps     *versionMap
+	report   *Report
+
+	// resourceType is the type of the resource converted, and base what
+	// replaces it at the start of the paths reported, for resources
+	// nested in bundles and contained resources
+	resourceType string
+	base         string
+
+	// target lists the resource types of the version converted to, so that
+	// nested resources of types it lacks are dropped
+	target *version.Catalog
+}
+
+// versionMaps holds the conversions between adjacent versions. Versions
+// further apart are converted through the versions between them.
+var versionMaps = []*versionMap{stu3r4, r4r4b, r4r5, r4br5}
+
+// r4r4b holds the conversions between R4 and R4B. The resources with
+// conversion maps did not change between the two, so resources are copied.
+var r4r4b = &versionMap{older: version.R4, newer: version.R4B}
+
+// r4br5 holds the conversions between R4B and R5, which are those between
+// R4 and R5
+var r4br5 = &versionMap{older: version.R4B, newer: version.R5, resources: r4r5.resources}
+
+// findVersionMap returns the conversions between two adjacent versions
+func findVersionMap(from, to version.Version) *versionMap {
+	for _, m := range versionMaps {
+		if (m.older == from && m.newer == to) || (m.older == to && m.newer == from) {
+			return m
+		}
+	}
+	return nil
+}
+
+// convertResource converts a resource, given as a JSON tree, in place
+// between the versions of maps
+func convertResource(resource map[string]interface{}, maps *versionMap, from, to version.Version, report *Report, base string) error {
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" {
+		return fmt.Errorf("resource has no resourceType")
+	}
+	if base == "" {
+		base = resourceType
+	}
+	c := &conversion{from: from, to: to, maps: maps, report: report, resourceType: resourceType, base: base}
+	if manager, err := version.NewVersionManager(to); err == nil {
+		c.target = manager.Catalog()
+	}
+
+	if items := list(resource["contained"]); items != nil {
+		kept := make([]interface{}, 0, len(items))
+		for i, item := range items {
+			if contained, ok := item.(map[string]interface{}); ok {
+				keep, err := c.nested(contained, fmt.Sprintf("%s.contained[%d]", base, i))
+				if err != nil {
+					return err
+				}
+				if !keep {
+					continue
+				}
+			}
+			kept = append(kept, item)
+		}
+		setList(resource, "contained", kept)
+	}
+	if resourceType == "Bundle" {
+		c.entries(resource)
+	}
+
+	rm := maps.resources[resourceType]
+	if rm == nil {
+		return nil
+	}
+	if to == maps.newer {
+		c.toExtensions(resource, rm.olderOnly)
+		if rm.toNewer != nil {
+			rm.toNewer(c, resource)
+		}
+		for _, r := range rm.renamed {
+			parent, name := splitPath(r.older)
+			each(resource, parent, func(obj map[string]interface{}) { renameKey(obj, name, r.newer) })
+		}
+		c.fromExtensions(resource, rm.newerOnly)
+	} else {
+		c.toExtensions(resource, rm.newerOnly)
+		if rm.toOlder != nil {
+			rm.toOlder(c, resource)
+		}
+		for _, r := range rm.renamed {
+			parent, name := splitPath(r.older)
+			each(resource, parent, func(obj map[string]interface{}) { renameKey(obj, r.newer, name) })
+		}
+		c.fromExtensions(resource, rm.olderOnly)
+	}
+	return nil
+}
+
+// nested converts a resource held within the one being converted. It
+// returns false, recording the loss, if the target version does not define
+// the resource's type, in which case the resource must be dropped.
+func (c *conversion) nested(resource map[string]interface{}, base string) (bool, error) {
+	if resourceType, _ := resource["resourceType"].(string); resourceType != "" && c.target != nil && !c.target.HasResourceType(resourceType) {
+		c.report.Lost = append(c.report.Lost, Loss{Path: base, Reason: fmt.Sprintf("%s is not defined in %s", resourceType, c.to)})
+		return false, nil
+	}
+	return true, convertResource(resource, c.maps, c.from, c.to, c.report, base)
+}
+
+// entries converts the resources in a Bundle's entries, dropping the
+// entries whose resources the target version has no type for
+func (c *conversion) entries(resource map[string]interface{}) {
+	items := list(resource["entry"])
+	if items == nil {
+		return
+	}
+	kept := make([]interface{}, 0, len(items))
+	for i, item := range items {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			kept = append(kept, item)
+			continue
+		}
+		if r, ok := entry["resource"].(map[string]interface{}); ok {
+			keep, err := c.nested(r, fmt.Sprintf("%s.entry[%d].resource", c.base, i))
+			if err != nil {
+				c.lose("Bundle.entry.resource", "%v", err)
+			}
+			if !keep {
+				continue
+			}
+		}
+		if response, ok := entry["response"].(map[string]interface{}); ok {
+			if r, ok := response["outcome"].(map[string]interface{}); ok {
+				keep, err := c.nested(r, fmt.Sprintf("%s.entry[%d].response.outcome", c.base, i))
+				if err != nil {
+					c.lose("Bundle.entry.response.outcome", "%v", err)
+				}
+				if !keep {
+					delete(response, "outcome")
+				}
+			}
+		}
+		kept = append(kept, entry)
+	}
+	setList(resource, "entry", kept)
+}
+
+// lose records data lost at path, a path starting with the resource type
+func (c *conversion) lose(path, format string, args ...interface{}) {
+	path = c.base + strings.TrimPrefix(path, c.resourceType)
+	c.report.Lost = append(c.report.Lost, Loss{Path: path, Reason: fmt.Sprintf(format, args...)})
+}
+
+// toExtensions moves elements that the target version lacks into
+// cross-version extensions on their parents
+func (c *conversion) toExtensions(resource map[string]interface{}, elements []crossVersion) {
+	for _, e := range elements {
+		parent, name := splitPath(e.path)
+		each(resource, parent, func(obj map[string]interface{}) {
+			for _, key := range elementKeys(obj, name) {
+				value := obj[key]
+				primitive := obj["_"+key]
+				delete(obj, key)
+				delete(obj, "_"+key)
+				if e.typ == "" && !strings.HasSuffix(name, "[x]") {
+					c.lose(e.path, "%s has no equivalent in %s", name, c.to)
+					continue
+				}
+				valueKey := "value" + strings.TrimPrefix(key, strings.TrimSuffix(name, "[x]"))
+				if e.typ != "" {
+					valueKey = "value" + upperFirst(e.typ)
+				}
+				primitives := list(primitive)
+				for i, item := range list(value) {
+					ext := map[string]interface{}{"url": crossVersionURL(c.from, e.path), valueKey: item}
+					if i < len(primitives) && primitives[i] != nil {
+						ext["_"+valueKey] = primitives[i]
+					}
+					obj["extension"] = append(list(obj["extension"]), ext)
+				}
+			}
+		})
+	}
+}
+
+// fromExtensions restores elements of the target version from the
+// cross-version extensions that carried them
+func (c *conversion) fromExtensions(resource map[string]interface{}, elements []crossVersion) {
+	for _, e := range elements {
+		parent, name := splitPath(e.path)
+		url := crossVersionURL(c.to, e.path)
+		each(resource, parent, func(obj map[string]interface{}) {
+			var kept []interface{}
+			for _, item := range list(obj["extension"]) {
+				ext, ok := item.(map[string]interface{})
+				if !ok || ext["url"] != url {
+					kept = append(kept, item)
+					continue
+				}
+				valueKey := extensionValueKey(ext)
+				if valueKey == "" {
+					continue
+				}
+				key := name
+				if strings.HasSuffix(name, "[x]") {
+					key = strings.TrimSuffix(name, "[x]") + strings.TrimPrefix(valueKey, "value")
+				}
+				if !e.repeats {
+					obj[key] = ext[valueKey]
+					if primitive, ok := ext["_"+valueKey]; ok {
+						obj["_"+key] = primitive
+					}
+					continue
+				}
+				obj[key] = append(list(obj[key]), ext[valueKey])
+				if primitive, ok := ext["_"+valueKey]; ok {
+					primitives := list(obj["_"+key])
+					for len(primitives) < len(list(obj[key]))-1 {
+						primitives = append(primitives, nil)
+					}
+					obj["_"+key] = append(primitives, primitive)
+				}
+			}
+			setList(obj, "extension", kept)
+		})
+	}
+}
+
+// choicesToExtensions moves types of a choice element that the target
+// version lacks, e.g. valueAttachment, into cross-version extensions of
+// version v. path is the element's path without [x], e.g.
+// Observation.value.
+func choicesToExtensions(obj map[string]interface{}, v version.Version, path string, keys ...string) {
+	_, name := splitPath(path)
+	for _, key := range keys {
+		value, ok := obj[key]
+		if !ok {
+			continue
+		}
+		valueKey := "value" + strings.TrimPrefix(key, name)
+		ext := map[string]interface{}{"url": crossVersionURL(v, path), valueKey: value}
+		if primitive, ok := obj["_"+key]; ok {
+			ext["_"+valueKey] = primitive
+		}
+		obj["extension"] = append(list(obj["extension"]), ext)
+		delete(obj, key)
+		delete(obj, "_"+key)
+	}
+}
+
+// restoreChoices restores the types of a choice element carried in
+// cross-version extensions of version v
+func restoreChoices(obj map[string]interface{}, v version.Version, path string) {
+	_, name := splitPath(path)
+	url := crossVersionURL(v, path)
+	var kept []interface{}
+	for _, item := range list(obj["extension"]) {
+		ext, ok := item.(map[string]interface{})
+		if !ok || ext["url"] != url {
+			kept = append(kept, item)
+			continue
+		}
+		if valueKey := extensionValueKey(ext); valueKey != "" {
+			key := name + strings.TrimPrefix(valueKey, "value")
+			obj[key] = ext[valueKey]
+			if primitive, ok := ext["_"+valueKey]; ok {
+				obj["_"+key] = primitive
+			}
+		}
+	}
+	setList(obj, "extension", kept)
+}
+
+// elementKeys returns the members of obj holding an element, which for a
+// choice element such as value[x] are those like valueQuantity
+func elementKeys(obj map[string]interface{}, name string) []string {
+	prefix, choice := strings.CutSuffix(name, "[x]")
+	if !choice {
+		if _, ok := obj[name]; ok {
+			return []string{name}
+		}
+		return nil
+	}
+	var keys []string
+	for key := range obj {
+		if rest, ok := strings.CutPrefix(key, prefix); ok && rest != "" && rest[0] >= 'A' && rest[0] <= 'Z' {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// extensionValueKey returns the member holding an extension's value
+func extensionValueKey(ext map[string]interface{}) string {
+	for key := range ext {
+		if strings.HasPrefix(key, "value") {
+			return key
+		}
+	}
+	return ""
+}
+
+// each calls fn for every object at path, a dotted path starting with the
+// resource type, descending into arrays
+func each(resource map[string]interface{}, path string, fn func(obj map[string]interface{})) {
+	var walk func(v interface{}, segments []string)
+	walk = func(v interface{}, segments []string) {
+		switch v := v.(type) {
+		case []interface{}:
+			for _, item := range v {
+				walk(item, segments)
+			}
+		case map[string]interface{}:
+			if len(segments) == 0 {
+				fn(v)
+				return
+			}
+			if next, ok := v[segments[0]]; ok {
+				walk(next, segments[1:])
+			}
+		}
+	}
+	walk(resource, strings.Split(path, ".")[1:])
+}
+
+// splitPath splits an element path into its parent's path and its name
+func splitPath(path string) (string, string) {
+	i := strings.LastIndex(path, ".")
+	return path[:i], path[i+1:]
+}
+
+// renameKey renames a member of obj, and the member holding the
+// extensions of its primitive values
+func renameKey(obj map[string]interface{}, old, new string) {
+	for _, prefix := range []string{"", "_"} {
+		if v, ok := obj[prefix+old]; ok {
+			obj[prefix+new] = v
+			delete(obj, prefix+old)
+		}
+	}
+}
+
+// list returns a JSON value as an array: arrays as they are, nil as an
+// empty array and anything else as an array of one
+func list(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
+
+// setList sets a repeating member of obj, deleting it if items is empty
+func setList(obj map[string]interface{}, key string, items []interface{}) {
+	if len(items) == 0 {
+		delete(obj, key)
+		return
+	}
+	obj[key] = items
+}
+
+// first returns the first item of a possibly repeating member of obj, and
+// reports the rest lost
+func (c *conversion) first(obj map[string]interface{}, key, path string) interface{} {
+	items := list(obj[key])
+	if len(items) == 0 {
+		return nil
+	}
+	if len(items) > 1 {
+		c.lose(path, "%s allows a single %s; %d more were dropped", c.to, key, len(items)-1)
+	}
+	return items[0]
+}
+
+// codeableConcept wraps a Coding in a CodeableConcept
+func codeableConcept(coding interface{}) map[string]interface{} {
+	return map[string]interface{}{"coding": []interface{}{coding}}
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// decodeTree returns a copy of a resource as a JSON tree. The resource may
+// be a model, a JSON tree or JSON bytes.
+func decodeTree(resource interface{}) (map[string]interface{}, error) {
+	var data []byte
+	switch r := resource.(type) {
+	case nil:
+		return nil, fmt.Errorf("resource is nil")
+	case []byte:
+		data = r
+	case json.RawMessage:
+		data = r
+	default:
+		var err error
+		data, err = json.Marshal(resource)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal resource: %w", err)
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, fmt.Errorf("failed to decode resource: %w", err)
+	}
+	obj, ok := tree.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("resource is not a JSON object")
+	}
+	return obj, nil
+}
+
+// encodeTree returns a JSON tree in the form of the original resource
+func encodeTree(tree map[string]interface{}, original interface{}) (interface{}, error) {
+	if _, ok := original.(map[string]interface{}); ok {
+		return tree, nil
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal converted resource: %w", err)
+	}
+	switch original.(type) {
+	case []byte:
+		return data, nil
+	case json.RawMessage:
+		return json.RawMessage(data), nil
+	}
+
+	t := reflect.TypeOf(original)
+	if t.Kind() == reflect.Ptr {
+		v := reflect.New(t.Elem())
+		if err := json.Unmarshal(data, v.Interface()); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal converted resource: %w", err)
+		}
+		return v.Interface(), nil
+	}
+	v := reflect.New(t)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal converted resource: %w", err)
+	}
+	return v.Elem().Interface(), nil
+}
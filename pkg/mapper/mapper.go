@@ -30,39 +30,62 @@ func NewMapper() *DefaultMapper {
 	}
 
 	// Initialize version managers for supported versions
-	r4Manager, _ := version.NewVersionManager(version.R4)
-	r5Manager, _ := version.NewVersionManager(version.R5)
-
-	mapper.versionManagers[version.R4] = r4Manager
-	mapper.versionManagers[version.R5] = r5Manager
+	for _, v := range version.Versions {
+		manager, _ := version.NewVersionManager(v)
+		mapper.versionManagers[v] = manager
+	}
 
 	return mapper
 }
 
-// MapResource converts a resource from one version to another
+// MapResource converts a resource from one version to another. The
+// resource may be a model such as *models.Patient, a JSON tree or JSON
+// bytes, and the result has the same form. Data the target version cannot
+// hold is dropped; use Convert to find out what.
 func (m *DefaultMapper) MapResource(resource interface{}, fromVersion, toVersion version.Version) (interface{}, error) {
+	result, _, err := m.Convert(resource, fromVersion, toVersion)
+	return result, err
+}
+
+// Convert converts a resource like MapResource, and reports the data lost
+// in the conversion. Elements that exist in only one of the versions are
+// carried in cross-version extensions where their type allows, and
+// restored from them when converting back.
+func (m *DefaultMapper) Convert(resource interface{}, fromVersion, toVersion version.Version) (interface{}, *Report, error) {
+	report := &Report{From: fromVersion, To: toVersion}
 	if fromVersion == toVersion {
-		return resource, nil
+		return resource, report, nil
 	}
 
 	path, err := m.GetMappingPath(fromVersion, toVersion)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	result := resource
+	tree, err := decodeTree(resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resourceType, _ := tree["resourceType"].(string); resourceType != "" && !m.CanMap(resourceType, fromVersion, toVersion) {
+		if equivalent := m.versionManagers[toVersion].GetBaseResource(resourceType); equivalent != resourceType {
+			return nil, nil, fmt.Errorf("cannot map %s from %s to %s: %s has %s instead", resourceType, fromVersion, toVersion, toVersion, equivalent)
+		}
+		return nil, nil, fmt.Errorf("cannot map %s from %s to %s: the resource type is not defined in both", resourceType, fromVersion, toVersion)
+	}
 	for i := 0; i < len(path)-1; i++ {
 		currentVersion := path[i]
 		nextVersion := path[i+1]
 
-		// TODO: Implement actual mapping logic between versions
-		result, err = m.mapBetweenVersions(result, currentVersion, nextVersion)
-		if err != nil {
-			return nil, err
+		if err := m.mapBetweenVersions(tree, currentVersion, nextVersion, report); err != nil {
+			return nil, nil, err
 		}
 	}
 
-	return result, nil
+	result, err := encodeTree(tree, resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, report, nil
 }
 
 // CanMap checks if mapping is possible between two versions for a given resource type
@@ -83,25 +106,38 @@ func (m *DefaultMapper) GetMappingPath(fromVersion, toVersion version.Version) (
 		return []version.Version{fromVersion}, nil
 	}
 
-	// For now, we only support direct mapping between adjacent versions
-	// This can be extended to support more complex mapping paths
-	if fromVersion == version.R4 && toVersion == version.R5 {
-		return []version.Version{version.R4, version.R5}, nil
-	}
-	if fromVersion == version.R5 && toVersion == version.R4 {
-		return []version.Version{version.R5, version.R4}, nil
+	// Search the versions with conversions between them breadth first, so
+	// that the path takes the fewest steps, e.g. STU3 to R5 through R4
+	previous := map[version.Version]version.Version{fromVersion: fromVersion}
+	queue := []version.Version{fromVersion}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == toVersion {
+			path := []version.Version{current}
+			for current != fromVersion {
+				current = previous[current]
+				path = append([]version.Version{current}, path...)
+			}
+			return path, nil
+		}
+		for _, next := range version.Versions {
+			if _, seen := previous[next]; !seen && findVersionMap(current, next) != nil {
+				previous[next] = current
+				queue = append(queue, next)
+			}
+		}
 	}
 
 	return nil, fmt.Errorf("no mapping path available from %s to %s", fromVersion, toVersion)
 }
 
-// mapBetweenVersions performs the actual mapping between two adjacent versions
-func (m *DefaultMapper) mapBetweenVersions(resource interface{}, fromVersion, toVersion version.Version) (interface{}, error) {
-	// TODO: Implement version-specific mapping logic
-	// This would include:
-	// 1. Type conversions
-	// 2. Field mappings
-	// 3. Handling deprecated fields
-	// 4. Adding new required fields
-	return resource, nil
+// mapBetweenVersions converts a resource, given as a JSON tree, in place
+// between two adjacent versions, adding the data lost to report
+func (m *DefaultMapper) mapBetweenVersions(resource map[string]interface{}, fromVersion, toVersion version.Version, report *Report) error {
+	maps := findVersionMap(fromVersion, toVersion)
+	if maps == nil {
+		return fmt.Errorf("no conversion from %s to %s", fromVersion, toVersion)
+	}
+	return convertResource(resource, maps, fromVersion, toVersion, report, "")
 }
@@ -0,0 +1,130 @@
+package mapper
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/version"
+)
+
+const r4Condition = `{"resourceType": "Condition", "id": "c1", "recorder": {"reference": "Practitioner/dr1"}}`
+
+func TestMapResourceForms(t *testing.T) {
+	m := NewMapper()
+
+	generic := &models.GenericResource{}
+	if err := json.Unmarshal([]byte(r4Condition), generic); err != nil {
+		t.Fatal(err)
+	}
+	var tree map[string]interface{}
+	json.Unmarshal([]byte(r4Condition), &tree)
+
+	for name, resource := range map[string]interface{}{
+		"model": generic,
+		"tree":  tree,
+		"bytes": []byte(r4Condition),
+		"raw":   json.RawMessage(r4Condition),
+	} {
+		result, err := m.MapResource(resource, version.R4, version.R5)
+		if err != nil {
+			t.Fatalf("%s: MapResource failed: %v", name, err)
+		}
+		data, ok := result.([]byte)
+		if !ok {
+			if data, err = json.Marshal(result); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+		var got map[string]interface{}
+		json.Unmarshal(data, &got)
+		if got["participant"] == nil || got["recorder"] != nil || got["id"] != "c1" {
+			t.Errorf("%s: unexpected result %s", name, data)
+		}
+	}
+
+	if _, ok := tree["participant"]; ok {
+		t.Error("MapResource changed the tree passed in")
+	}
+	if _, ok := generic.Unknown["participant"]; ok {
+		t.Error("MapResource changed the model passed in")
+	}
+
+	result, err := m.MapResource(generic, version.R4, version.R5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result.(*models.GenericResource); !ok {
+		t.Errorf("expected a *models.GenericResource, got %T", result)
+	}
+}
+
+func TestMapResourceUnchanged(t *testing.T) {
+	m := NewMapper()
+	patient := models.NewPatient()
+	if result, err := m.MapResource(patient, version.R4, version.R4); err != nil || result != patient {
+		t.Errorf("expected the same version to return the resource, got %v, %v", result, err)
+	}
+
+	// Types without a conversion map are copied
+	result, report, err := m.Convert([]byte(`{"resourceType": "Organization", "name": "Acme"}`), version.R5, version.R4)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if string(result.([]byte)) != `{"name":"Acme","resourceType":"Organization"}` || !report.Lossless() {
+		t.Errorf("unexpected result %s, %+v", result, report)
+	}
+
+	if _, err := m.MapResource(`"Patient"`, version.R4, version.R5); err == nil {
+		t.Error("expected an error for a resource that is not a JSON object")
+	}
+	if _, err := m.MapResource([]byte(`{"id": "x"}`), version.R4, version.R5); err == nil {
+		t.Error("expected an error for a resource without a resourceType")
+	}
+	_, err = m.MapResource([]byte(`{"resourceType": "DeviceUsage", "status": "active"}`), version.R5, version.R4)
+	if err == nil || !strings.Contains(err.Error(), "R4 has DeviceUseStatement instead") {
+		t.Errorf("expected an error for a type R4 lacks, got %v", err)
+	}
+	if _, err := m.MapResource([]byte(`{"resourceType": "SubscriptionTopic"}`), version.R5, version.STU3); err == nil {
+		t.Error("expected an error for a type STU3 lacks")
+	}
+	if _, err := m.MapResource(patient, version.R4, version.Version("DSTU2")); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+}
+
+func TestGetMappingPath(t *testing.T) {
+	m := NewMapper()
+	path, err := m.GetMappingPath(version.R5, version.R4)
+	if err != nil || len(path) != 2 || path[0] != version.R5 || path[1] != version.R4 {
+		t.Errorf("GetMappingPath = %v, %v", path, err)
+	}
+	for _, tc := range []struct {
+		from, to version.Version
+		want     []version.Version
+	}{
+		{version.STU3, version.R5, []version.Version{version.STU3, version.R4, version.R5}},
+		{version.R5, version.STU3, []version.Version{version.R5, version.R4, version.STU3}},
+		{version.STU3, version.R4B, []version.Version{version.STU3, version.R4, version.R4B}},
+		{version.R4B, version.R5, []version.Version{version.R4B, version.R5}},
+	} {
+		path, err := m.GetMappingPath(tc.from, tc.to)
+		if err != nil || !reflect.DeepEqual(path, tc.want) {
+			t.Errorf("GetMappingPath(%s, %s) = %v, %v, want %v", tc.from, tc.to, path, err, tc.want)
+		}
+	}
+	if _, err := m.GetMappingPath(version.R4, version.Version("DSTU2")); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+	if !m.CanMap("Patient", version.R4, version.R5) {
+		t.Error("expected Patient to be mappable")
+	}
+	if m.CanMap("SubscriptionTopic", version.R5, version.R4) || !m.CanMap("SubscriptionTopic", version.R4B, version.R5) {
+		t.Error("expected SubscriptionTopic to be mappable between R4B and R5 only")
+	}
+	if m.CanMap("Patient", version.R4, version.Version("DSTU2")) {
+		t.Error("expected no mapping to an unknown version")
+	}
+}
@@ -0,0 +1,482 @@
+package mapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/version"
+)
+
+// participantTypeSystem is the code system of Condition.participant.function
+const participantTypeSystem = "http://terminology.hl7.org/CodeSystem/provenance-participant-type"
+
+// r4r5 holds the conversions between R4 and R5
+var r4r5 = &versionMap{
+	older: version.R4,
+	newer: version.R5,
+	resources: map[string]*resourceMap{
+		// Patient is unchanged between R4 and R5; it is listed so that
+		// contained resources and extensions are converted
+		"Patient": {},
+
+		"Observation": {
+			newerOnly: []crossVersion{
+				{path: "Observation.instantiates[x]"},
+				{path: "Observation.triggeredBy", repeats: true},
+				{path: "Observation.bodyStructure", typ: "Reference"},
+				{path: "Observation.referenceRange.normalValue", typ: "CodeableConcept"},
+				{path: "Observation.component.referenceRange.normalValue", typ: "CodeableConcept"},
+			},
+			toNewer: observationToR5,
+			toOlder: observationToR4,
+		},
+
+		"Encounter": {
+			renamed: []rename{
+				{older: "Encounter.period", newer: "actualPeriod"},
+				{older: "Encounter.participant.individual", newer: "actor"},
+				{older: "Encounter.location.physicalType", newer: "form"},
+				{older: "Encounter.hospitalization", newer: "admission"},
+			},
+			olderOnly: []crossVersion{
+				{path: "Encounter.statusHistory", repeats: true},
+				{path: "Encounter.classHistory", repeats: true},
+				{path: "Encounter.diagnosis.rank", typ: "positiveInt"},
+			},
+			newerOnly: []crossVersion{
+				{path: "Encounter.plannedStartDate", typ: "dateTime"},
+				{path: "Encounter.plannedEndDate", typ: "dateTime"},
+				{path: "Encounter.subjectStatus", typ: "CodeableConcept"},
+				{path: "Encounter.careTeam", typ: "Reference", repeats: true},
+				{path: "Encounter.virtualService", repeats: true},
+			},
+			toNewer: encounterToR5,
+			toOlder: encounterToR4,
+		},
+
+		"Condition": {
+			toNewer: conditionToR5,
+			toOlder: conditionToR4,
+		},
+
+		"MedicationRequest": {
+			renamed: []rename{
+				{older: "MedicationRequest.dispenseRequest.performer", newer: "dispenser"},
+			},
+			olderOnly: []crossVersion{
+				{path: "MedicationRequest.detectedIssue", typ: "Reference", repeats: true},
+			},
+			newerOnly: []crossVersion{
+				{path: "MedicationRequest.statusChanged", typ: "dateTime"},
+				{path: "MedicationRequest.renderedDosageInstruction", typ: "markdown"},
+				{path: "MedicationRequest.effectiveDosePeriod", typ: "Period"},
+				{path: "MedicationRequest.device", repeats: true},
+				{path: "MedicationRequest.dispenseRequest.dispenserInstruction", typ: "Annotation", repeats: true},
+				{path: "MedicationRequest.dispenseRequest.doseAdministrationAid", typ: "CodeableConcept"},
+			},
+			toNewer: medicationRequestToR5,
+			toOlder: medicationRequestToR4,
+		},
+
+		"Bundle": {
+			newerOnly: []crossVersion{
+				{path: "Bundle.issues"},
+			},
+		},
+	},
+}
+
+// sampledDataUnits are the R5 SampledData.intervalUnit codes that R4's
+// SampledData.period, in milliseconds, can express, with their size in ms
+var sampledDataUnits = map[string]float64{
+	"us":  0.001,
+	"ms":  1,
+	"s":   1000,
+	"min": 60000,
+	"h":   3600000,
+}
+
+// observationToR5 converts R4 SampledData values, whose period became an
+// interval with a unit, and restores R5 value types carried in extensions
+func observationToR5(c *conversion, resource map[string]interface{}) {
+	for _, path := range []string{"Observation", "Observation.component"} {
+		each(resource, path, func(obj map[string]interface{}) {
+			restoreChoices(obj, version.R5, path+".value")
+			sampled, ok := obj["valueSampledData"].(map[string]interface{})
+			if !ok {
+				return
+			}
+			if period, ok := sampled["period"]; ok {
+				sampled["interval"] = period
+				sampled["intervalUnit"] = "ms"
+				delete(sampled, "period")
+			}
+		})
+	}
+}
+
+// observationToR4 converts R5 SampledData values and moves the value types
+// R4 lacks into extensions
+func observationToR4(c *conversion, resource map[string]interface{}) {
+	for _, path := range []string{"Observation", "Observation.component"} {
+		each(resource, path, func(obj map[string]interface{}) {
+			choicesToExtensions(obj, version.R5, path+".value", "valueAttachment", "valueReference")
+			sampled, ok := obj["valueSampledData"].(map[string]interface{})
+			if !ok {
+				return
+			}
+			for _, key := range []string{"codeMap", "offsets"} {
+				if _, ok := sampled[key]; ok {
+					c.lose(path+".valueSampledData."+key, "SampledData.%s has no equivalent in R4", key)
+					delete(sampled, key)
+				}
+			}
+			interval, hasInterval := sampled["interval"]
+			unit, _ := sampled["intervalUnit"].(string)
+			delete(sampled, "interval")
+			delete(sampled, "intervalUnit")
+			if !hasInterval {
+				return
+			}
+			size, known := sampledDataUnits[unit]
+			value, err := strconv.ParseFloat(fmt.Sprint(interval), 64)
+			switch {
+			case unit == "ms":
+				sampled["period"] = interval
+			case known && err == nil:
+				sampled["period"] = json.Number(strconv.FormatFloat(value*size, 'f', -1, 64))
+			default:
+				c.lose(path+".valueSampledData.interval", "interval unit %q cannot be expressed in milliseconds", unit)
+			}
+		})
+	}
+}
+
+// codeMapping is the code a code that the other version lacks becomes
+type codeMapping struct {
+	code string
+
+	// approximate reports whether the codes differ in meaning, which is
+	// reported as a loss
+	approximate bool
+}
+
+// encounterStatusToR5 maps R4 Encounter.status codes that R5 dropped
+var encounterStatusToR5 = map[string]codeMapping{
+	"arrived":  {"in-progress", true},
+	"triaged":  {"in-progress", true},
+	"onleave":  {"on-hold", false},
+	"finished": {"completed", false},
+}
+
+// encounterStatusToR4 maps R5 Encounter.status codes that R4 lacks
+var encounterStatusToR4 = map[string]codeMapping{
+	"on-hold":      {"onleave", false},
+	"completed":    {"finished", false},
+	"discharged":   {"finished", true},
+	"discontinued": {"cancelled", true},
+}
+
+// encounterSpecialArrangements moved from Encounter.hospitalization to
+// Encounter in R5
+var encounterSpecialArrangements = []string{"dietPreference", "specialCourtesy", "specialArrangement"}
+
+func encounterToR5(c *conversion, resource map[string]interface{}) {
+	c.mapCode(resource, "status", "Encounter.status", encounterStatusToR5)
+	if class, ok := resource["class"]; ok {
+		resource["class"] = []interface{}{codeableConcept(class)}
+	}
+	if serviceType, ok := resource["serviceType"]; ok {
+		resource["serviceType"] = []interface{}{map[string]interface{}{"concept": serviceType}}
+	}
+	reasons := codeableReferences(resource, "reasonCode", "reasonReference")
+	for i, reason := range reasons {
+		reasons[i] = map[string]interface{}{"value": []interface{}{reason}}
+	}
+	setList(resource, "reason", reasons)
+
+	each(resource, "Encounter.diagnosis", func(diagnosis map[string]interface{}) {
+		if condition, ok := diagnosis["condition"]; ok {
+			diagnosis["condition"] = []interface{}{map[string]interface{}{"reference": condition}}
+		}
+		if use, ok := diagnosis["use"]; ok {
+			diagnosis["use"] = []interface{}{use}
+		}
+	})
+	if hospitalization, ok := resource["hospitalization"].(map[string]interface{}); ok {
+		for _, key := range encounterSpecialArrangements {
+			if value, ok := hospitalization[key]; ok {
+				resource[key] = value
+				delete(hospitalization, key)
+			}
+		}
+		if len(hospitalization) == 0 {
+			delete(resource, "hospitalization")
+		}
+	}
+}
+
+func encounterToR4(c *conversion, resource map[string]interface{}) {
+	c.mapCode(resource, "status", "Encounter.status", encounterStatusToR4)
+	if class := c.first(resource, "class", "Encounter.class"); class != nil {
+		delete(resource, "class")
+		if concept, ok := class.(map[string]interface{}); ok {
+			if coding := list(concept["coding"]); len(coding) > 0 {
+				resource["class"] = coding[0]
+				if len(coding) > 1 || concept["text"] != nil {
+					c.lose("Encounter.class", "R4 class is a single Coding; the other codings and text were dropped")
+				}
+			} else {
+				c.lose("Encounter.class", "class has no coding for R4")
+			}
+		}
+	}
+	if serviceType := c.first(resource, "serviceType", "Encounter.serviceType"); serviceType != nil {
+		delete(resource, "serviceType")
+		if concept := conceptOf(c, serviceType, "Encounter.serviceType"); concept != nil {
+			resource["serviceType"] = concept
+		}
+	}
+
+	var reasons []interface{}
+	for _, item := range list(resource["reason"]) {
+		reason, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, ok := reason["use"]; ok {
+			c.lose("Encounter.reason.use", "reason use has no equivalent in R4")
+		}
+		reasons = append(reasons, list(reason["value"])...)
+	}
+	delete(resource, "reason")
+	splitCodeableReferences(resource, reasons, "reasonCode", "reasonReference")
+
+	each(resource, "Encounter.diagnosis", func(diagnosis map[string]interface{}) {
+		if condition := c.first(diagnosis, "condition", "Encounter.diagnosis.condition"); condition != nil {
+			delete(diagnosis, "condition")
+			if ref, ok := condition.(map[string]interface{})["reference"]; ok {
+				diagnosis["condition"] = ref
+			} else {
+				c.lose("Encounter.diagnosis.condition", "R4 diagnosis needs a reference to a Condition; the code was dropped")
+			}
+		}
+		if use := c.first(diagnosis, "use", "Encounter.diagnosis.use"); use != nil {
+			diagnosis["use"] = use
+		}
+	})
+
+	admission, _ := resource["admission"].(map[string]interface{})
+	for _, key := range encounterSpecialArrangements {
+		if value, ok := resource[key]; ok {
+			if admission == nil {
+				admission = make(map[string]interface{})
+				resource["admission"] = admission
+			}
+			admission[key] = value
+			delete(resource, key)
+		}
+	}
+}
+
+// conditionParticipants are the R4 Condition elements that became
+// participants in R5, with their participant function
+var conditionParticipants = []struct{ element, function string }{
+	{"recorder", "author"},
+	{"asserter", "informant"},
+}
+
+func conditionToR5(c *conversion, resource map[string]interface{}) {
+	var participants []interface{}
+	for _, p := range conditionParticipants {
+		if actor, ok := resource[p.element]; ok {
+			participants = append(participants, map[string]interface{}{
+				"function": codeableConcept(map[string]interface{}{"system": participantTypeSystem, "code": p.function}),
+				"actor":    actor,
+			})
+			delete(resource, p.element)
+		}
+	}
+	setList(resource, "participant", append(list(resource["participant"]), participants...))
+
+	var evidence []interface{}
+	for _, item := range list(resource["evidence"]) {
+		e, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		evidence = append(evidence, codeableReferences(e, "code", "detail")...)
+	}
+	setList(resource, "evidence", evidence)
+}
+
+func conditionToR4(c *conversion, resource map[string]interface{}) {
+	for _, item := range list(resource["participant"]) {
+		participant, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		placed := false
+		for _, p := range conditionParticipants {
+			if hasCode(participant["function"], participantTypeSystem, p.function) {
+				if _, taken := resource[p.element]; !taken {
+					resource[p.element] = participant["actor"]
+					placed = true
+				}
+				break
+			}
+		}
+		if !placed {
+			c.lose("Condition.participant", "R4 has only a single recorder and asserter; another participant was dropped")
+		}
+	}
+	delete(resource, "participant")
+
+	var evidence []interface{}
+	for _, item := range list(resource["evidence"]) {
+		cr, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		e := make(map[string]interface{})
+		if concept, ok := cr["concept"]; ok {
+			e["code"] = []interface{}{concept}
+		}
+		if ref, ok := cr["reference"]; ok {
+			e["detail"] = []interface{}{ref}
+		}
+		evidence = append(evidence, e)
+	}
+	setList(resource, "evidence", evidence)
+}
+
+// medicationRequestStatusToR4 maps R5 MedicationRequest.status codes that R4 lacks
+var medicationRequestStatusToR4 = map[string]codeMapping{
+	"ended": {"stopped", true},
+}
+
+func medicationRequestToR5(c *conversion, resource map[string]interface{}) {
+	medication := codeableReferences(resource, "medicationCodeableConcept", "medicationReference")
+	if len(medication) > 0 {
+		resource["medication"] = medication[0]
+	}
+
+	if reported, ok := resource["reportedBoolean"]; ok {
+		resource["reported"] = reported
+		delete(resource, "reportedBoolean")
+	}
+	if source, ok := resource["reportedReference"]; ok {
+		resource["reported"] = true
+		resource["informationSource"] = []interface{}{source}
+		delete(resource, "reportedReference")
+	}
+	if performer, ok := resource["performer"]; ok {
+		resource["performer"] = []interface{}{performer}
+	}
+	setList(resource, "reason", codeableReferences(resource, "reasonCode", "reasonReference"))
+}
+
+func medicationRequestToR4(c *conversion, resource map[string]interface{}) {
+	c.mapCode(resource, "status", "MedicationRequest.status", medicationRequestStatusToR4)
+	if medication, ok := resource["medication"].(map[string]interface{}); ok {
+		delete(resource, "medication")
+		if concept, ok := medication["concept"]; ok {
+			resource["medicationCodeableConcept"] = concept
+			if _, ok := medication["reference"]; ok {
+				c.lose("MedicationRequest.medication", "R4 medication[x] is a code or a reference; the reference was dropped")
+			}
+		} else if ref, ok := medication["reference"]; ok {
+			resource["medicationReference"] = ref
+		}
+	}
+
+	// R4 has either a reported flag or the reference that reported it
+	if source := c.first(resource, "informationSource", "MedicationRequest.informationSource"); source != nil {
+		resource["reportedReference"] = source
+	} else if reported, ok := resource["reported"]; ok {
+		resource["reportedBoolean"] = reported
+	}
+	delete(resource, "informationSource")
+	delete(resource, "reported")
+
+	if performer := c.first(resource, "performer", "MedicationRequest.performer"); performer != nil {
+		resource["performer"] = performer
+	}
+	reasons := list(resource["reason"])
+	delete(resource, "reason")
+	splitCodeableReferences(resource, reasons, "reasonCode", "reasonReference")
+}
+
+// codeableReferences removes a pair of R4 elements holding concepts and
+// references from obj and returns them as R5 CodeableReferences
+func codeableReferences(obj map[string]interface{}, conceptKey, referenceKey string) []interface{} {
+	var result []interface{}
+	for _, concept := range list(obj[conceptKey]) {
+		result = append(result, map[string]interface{}{"concept": concept})
+	}
+	for _, ref := range list(obj[referenceKey]) {
+		result = append(result, map[string]interface{}{"reference": ref})
+	}
+	delete(obj, conceptKey)
+	delete(obj, referenceKey)
+	return result
+}
+
+// splitCodeableReferences adds R5 CodeableReferences to obj as a pair of
+// repeating R4 elements holding concepts and references
+func splitCodeableReferences(obj map[string]interface{}, items []interface{}, conceptKey, referenceKey string) {
+	for _, item := range items {
+		cr, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if concept, ok := cr["concept"]; ok {
+			obj[conceptKey] = append(list(obj[conceptKey]), concept)
+		}
+		if ref, ok := cr["reference"]; ok {
+			obj[referenceKey] = append(list(obj[referenceKey]), ref)
+		}
+	}
+}
+
+// mapCode replaces a code that the target version lacks
+func (c *conversion) mapCode(obj map[string]interface{}, key, path string, mappings map[string]codeMapping) {
+	code, ok := obj[key].(string)
+	if !ok {
+		return
+	}
+	if mapped, ok := mappings[code]; ok {
+		obj[key] = mapped.code
+		if mapped.approximate {
+			c.lose(path, "%s %s has no %s equivalent and became %s", key, code, c.to, mapped.code)
+		}
+	}
+}
+
+// conceptOf returns the concept of a CodeableReference, reporting a
+// reference lost
+func conceptOf(c *conversion, item interface{}, path string) interface{} {
+	cr, ok := item.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if _, ok := cr["reference"]; ok {
+		c.lose(path, "R4 %s is a CodeableConcept; the reference was dropped", path)
+	}
+	return cr["concept"]
+}
+
+// hasCode reports whether a CodeableConcept has a coding with system and code
+func hasCode(concept interface{}, system, code string) bool {
+	cc, ok := concept.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for _, item := range list(cc["coding"]) {
+		if coding, ok := item.(map[string]interface{}); ok && coding["system"] == system && coding["code"] == code {
+			return true
+		}
+	}
+	return false
+}
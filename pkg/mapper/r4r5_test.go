@@ -0,0 +1,366 @@
+package mapper
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/version"
+)
+
+// convertJSON converts a resource given as JSON and returns it decoded
+func convertJSON(t *testing.T, data string, from, to version.Version) (map[string]interface{}, *Report) {
+	t.Helper()
+	result, report, err := NewMapper().Convert([]byte(data), from, to)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal(result.([]byte), &tree); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	return tree, report
+}
+
+// assertJSON checks that a tree equals the expected JSON
+func assertJSON(t *testing.T, got map[string]interface{}, want string) {
+	t.Helper()
+	var expected map[string]interface{}
+	if err := json.Unmarshal([]byte(want), &expected); err != nil {
+		t.Fatalf("bad expected JSON: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		data, _ := json.MarshalIndent(got, "", "  ")
+		t.Errorf("got\n%s\nwant\n%s", data, want)
+	}
+}
+
+// assertLost checks that a report lists exactly the paths given
+func assertLost(t *testing.T, report *Report, paths ...string) {
+	t.Helper()
+	var got []string
+	for _, loss := range report.Lost {
+		got = append(got, loss.Path)
+	}
+	if !reflect.DeepEqual(got, paths) {
+		t.Errorf("lost %v (%+v), want %v", got, report.Lost, paths)
+	}
+}
+
+const r4Encounter = `{
+	"resourceType": "Encounter",
+	"id": "e1",
+	"status": "finished",
+	"statusHistory": [{"status": "arrived", "period": {"start": "2024-01-01T09:00:00Z"}}],
+	"class": {"system": "http://terminology.hl7.org/CodeSystem/v3-ActCode", "code": "IMP"},
+	"serviceType": {"text": "Cardiology"},
+	"subject": {"reference": "Patient/p1"},
+	"participant": [{"individual": {"reference": "Practitioner/dr1"}}],
+	"period": {"start": "2024-01-01T09:00:00Z", "end": "2024-01-03T12:00:00Z"},
+	"reasonCode": [{"text": "Chest pain"}],
+	"reasonReference": [{"reference": "Condition/c1"}],
+	"diagnosis": [{"condition": {"reference": "Condition/c2"}, "use": {"text": "Admission"}, "rank": 1}],
+	"hospitalization": {
+		"admitSource": {"text": "Emergency"},
+		"dietPreference": [{"text": "Vegetarian"}]
+	},
+	"location": [{"location": {"reference": "Location/l1"}, "physicalType": {"text": "Ward"}}]
+}`
+
+func TestEncounterR4ToR5(t *testing.T) {
+	tree, report := convertJSON(t, r4Encounter, version.R4, version.R5)
+	assertJSON(t, tree, `{
+		"resourceType": "Encounter",
+		"id": "e1",
+		"status": "completed",
+		"class": [{"coding": [{"system": "http://terminology.hl7.org/CodeSystem/v3-ActCode", "code": "IMP"}]}],
+		"serviceType": [{"concept": {"text": "Cardiology"}}],
+		"subject": {"reference": "Patient/p1"},
+		"participant": [{"actor": {"reference": "Practitioner/dr1"}}],
+		"actualPeriod": {"start": "2024-01-01T09:00:00Z", "end": "2024-01-03T12:00:00Z"},
+		"reason": [
+			{"value": [{"concept": {"text": "Chest pain"}}]},
+			{"value": [{"reference": {"reference": "Condition/c1"}}]}
+		],
+		"diagnosis": [{
+			"extension": [{"url": "http://hl7.org/fhir/4.0/StructureDefinition/extension-Encounter.diagnosis.rank", "valuePositiveInt": 1}],
+			"condition": [{"reference": {"reference": "Condition/c2"}}],
+			"use": [{"text": "Admission"}]
+		}],
+		"admission": {"admitSource": {"text": "Emergency"}},
+		"dietPreference": [{"text": "Vegetarian"}],
+		"location": [{"location": {"reference": "Location/l1"}, "form": {"text": "Ward"}}]
+	}`)
+	assertLost(t, report, "Encounter.statusHistory")
+
+	// Converting back restores everything but the status history
+	data, _ := json.Marshal(tree)
+	back, report := convertJSON(t, string(data), version.R5, version.R4)
+	var original map[string]interface{}
+	json.Unmarshal([]byte(r4Encounter), &original)
+	delete(original, "statusHistory")
+	expected, _ := json.Marshal(original)
+	assertJSON(t, back, string(expected))
+	assertLost(t, report)
+}
+
+func TestEncounterR5ToR4(t *testing.T) {
+	tree, report := convertJSON(t, `{
+		"resourceType": "Encounter",
+		"status": "discharged",
+		"class": [
+			{"coding": [{"system": "http://terminology.hl7.org/CodeSystem/v3-ActCode", "code": "AMB"}]},
+			{"coding": [{"system": "http://terminology.hl7.org/CodeSystem/v3-ActCode", "code": "VR"}]}
+		],
+		"plannedStartDate": "2024-02-01T10:00:00Z",
+		"careTeam": [{"reference": "CareTeam/t1"}, {"reference": "CareTeam/t2"}],
+		"virtualService": [{"addressUrl": "https://meet.example.org/abc"}],
+		"reason": [{"use": [{"text": "Chief complaint"}], "value": [{"concept": {"text": "Headache"}}]}],
+		"diagnosis": [{"condition": [{"concept": {"text": "Migraine"}}]}]
+	}`, version.R5, version.R4)
+
+	assertJSON(t, tree, `{
+		"resourceType": "Encounter",
+		"status": "finished",
+		"class": {"system": "http://terminology.hl7.org/CodeSystem/v3-ActCode", "code": "AMB"},
+		"extension": [
+			{"url": "http://hl7.org/fhir/5.0/StructureDefinition/extension-Encounter.plannedStartDate", "valueDateTime": "2024-02-01T10:00:00Z"},
+			{"url": "http://hl7.org/fhir/5.0/StructureDefinition/extension-Encounter.careTeam", "valueReference": {"reference": "CareTeam/t1"}},
+			{"url": "http://hl7.org/fhir/5.0/StructureDefinition/extension-Encounter.careTeam", "valueReference": {"reference": "CareTeam/t2"}}
+		],
+		"reasonCode": [{"text": "Headache"}],
+		"diagnosis": [{}]
+	}`)
+	assertLost(t, report,
+		"Encounter.virtualService",
+		"Encounter.status",
+		"Encounter.class",
+		"Encounter.reason.use",
+		"Encounter.diagnosis.condition",
+	)
+
+	// The extensions are restored in R5
+	data, _ := json.Marshal(tree)
+	back, _ := convertJSON(t, string(data), version.R4, version.R5)
+	if back["plannedStartDate"] != "2024-02-01T10:00:00Z" || len(back["careTeam"].([]interface{})) != 2 {
+		t.Errorf("expected the R5 elements to be restored, got %v", back)
+	}
+	if _, ok := back["extension"]; ok {
+		t.Errorf("expected the cross-version extensions to be removed, got %v", back["extension"])
+	}
+}
+
+func TestObservationConversion(t *testing.T) {
+	r5 := `{
+		"resourceType": "Observation",
+		"status": "final",
+		"code": {"text": "ECG"},
+		"bodyStructure": {"reference": "BodyStructure/b1"},
+		"valueAttachment": {"contentType": "application/pdf", "url": "Binary/ecg"},
+		"component": [{
+			"code": {"text": "Lead I"},
+			"valueSampledData": {"origin": {"value": 0}, "interval": 0.5, "intervalUnit": "s", "dimensions": 1, "offsets": "0 1"}
+		}],
+		"referenceRange": [{"normalValue": {"text": "Normal"}}]
+	}`
+	tree, report := convertJSON(t, r5, version.R5, version.R4)
+	assertJSON(t, tree, `{
+		"resourceType": "Observation",
+		"status": "final",
+		"code": {"text": "ECG"},
+		"extension": [
+			{"url": "http://hl7.org/fhir/5.0/StructureDefinition/extension-Observation.bodyStructure", "valueReference": {"reference": "BodyStructure/b1"}},
+			{"url": "http://hl7.org/fhir/5.0/StructureDefinition/extension-Observation.value", "valueAttachment": {"contentType": "application/pdf", "url": "Binary/ecg"}}
+		],
+		"component": [{
+			"code": {"text": "Lead I"},
+			"valueSampledData": {"origin": {"value": 0}, "period": 500, "dimensions": 1}
+		}],
+		"referenceRange": [{
+			"extension": [{"url": "http://hl7.org/fhir/5.0/StructureDefinition/extension-Observation.referenceRange.normalValue", "valueCodeableConcept": {"text": "Normal"}}]
+		}]
+	}`)
+	assertLost(t, report, "Observation.component.valueSampledData.offsets")
+
+	data, _ := json.Marshal(tree)
+	back, _ := convertJSON(t, string(data), version.R4, version.R5)
+	var expected map[string]interface{}
+	json.Unmarshal([]byte(r5), &expected)
+	component := expected["component"].([]interface{})[0].(map[string]interface{})
+	component["valueSampledData"] = map[string]interface{}{"origin": map[string]interface{}{"value": 0}, "interval": 500, "intervalUnit": "ms", "dimensions": 1}
+	want, _ := json.Marshal(expected)
+	assertJSON(t, back, string(want))
+}
+
+func TestConditionConversion(t *testing.T) {
+	r4 := `{
+		"resourceType": "Condition",
+		"clinicalStatus": {"coding": [{"system": "http://terminology.hl7.org/CodeSystem/condition-clinical", "code": "active"}]},
+		"code": {"text": "Asthma"},
+		"subject": {"reference": "Patient/p1"},
+		"recorder": {"reference": "Practitioner/dr1"},
+		"asserter": {"reference": "Patient/p1"},
+		"evidence": [{"code": [{"text": "Wheezing"}], "detail": [{"reference": "Observation/o1"}]}]
+	}`
+	tree, report := convertJSON(t, r4, version.R4, version.R5)
+	assertJSON(t, tree, `{
+		"resourceType": "Condition",
+		"clinicalStatus": {"coding": [{"system": "http://terminology.hl7.org/CodeSystem/condition-clinical", "code": "active"}]},
+		"code": {"text": "Asthma"},
+		"subject": {"reference": "Patient/p1"},
+		"participant": [
+			{"function": {"coding": [{"system": "http://terminology.hl7.org/CodeSystem/provenance-participant-type", "code": "author"}]}, "actor": {"reference": "Practitioner/dr1"}},
+			{"function": {"coding": [{"system": "http://terminology.hl7.org/CodeSystem/provenance-participant-type", "code": "informant"}]}, "actor": {"reference": "Patient/p1"}}
+		],
+		"evidence": [{"concept": {"text": "Wheezing"}}, {"reference": {"reference": "Observation/o1"}}]
+	}`)
+	assertLost(t, report)
+
+	tree["participant"] = append(tree["participant"].([]interface{}), map[string]interface{}{
+		"function": map[string]interface{}{"text": "Verifier"},
+		"actor":    map[string]interface{}{"reference": "Practitioner/dr2"},
+	})
+	data, _ := json.Marshal(tree)
+	back, report := convertJSON(t, string(data), version.R5, version.R4)
+	if back["recorder"] == nil || back["asserter"] == nil || back["participant"] != nil {
+		t.Errorf("expected the recorder and asserter to be restored, got %v", back)
+	}
+	if evidence := back["evidence"].([]interface{}); len(evidence) != 2 {
+		t.Errorf("evidence = %v", evidence)
+	}
+	assertLost(t, report, "Condition.participant")
+}
+
+func TestMedicationRequestConversion(t *testing.T) {
+	tree, report := convertJSON(t, `{
+		"resourceType": "MedicationRequest",
+		"status": "active",
+		"intent": "order",
+		"medicationCodeableConcept": {"text": "Amoxicillin 500mg"},
+		"reportedReference": {"reference": "Patient/p1"},
+		"subject": {"reference": "Patient/p1"},
+		"performer": {"reference": "Practitioner/dr1"},
+		"reasonCode": [{"text": "Otitis media"}],
+		"detectedIssue": [{"reference": "DetectedIssue/d1"}],
+		"dispenseRequest": {"performer": {"reference": "Organization/pharmacy"}}
+	}`, version.R4, version.R5)
+	assertJSON(t, tree, `{
+		"resourceType": "MedicationRequest",
+		"status": "active",
+		"intent": "order",
+		"medication": {"concept": {"text": "Amoxicillin 500mg"}},
+		"reported": true,
+		"informationSource": [{"reference": "Patient/p1"}],
+		"subject": {"reference": "Patient/p1"},
+		"performer": [{"reference": "Practitioner/dr1"}],
+		"reason": [{"concept": {"text": "Otitis media"}}],
+		"extension": [{"url": "http://hl7.org/fhir/4.0/StructureDefinition/extension-MedicationRequest.detectedIssue", "valueReference": {"reference": "DetectedIssue/d1"}}],
+		"dispenseRequest": {"dispenser": {"reference": "Organization/pharmacy"}}
+	}`)
+	assertLost(t, report)
+
+	tree, report = convertJSON(t, `{
+		"resourceType": "MedicationRequest",
+		"status": "ended",
+		"intent": "order",
+		"medication": {"reference": {"reference": "Medication/m1"}},
+		"reported": false,
+		"subject": {"reference": "Patient/p1"},
+		"performer": [{"reference": "Practitioner/dr1"}, {"reference": "Practitioner/dr2"}],
+		"reason": [{"reference": {"reference": "Condition/c1"}}],
+		"device": [{"concept": {"text": "Inhaler spacer"}}],
+		"renderedDosageInstruction": "Two puffs daily"
+	}`, version.R5, version.R4)
+	assertJSON(t, tree, `{
+		"resourceType": "MedicationRequest",
+		"status": "stopped",
+		"intent": "order",
+		"medicationReference": {"reference": "Medication/m1"},
+		"reportedBoolean": false,
+		"subject": {"reference": "Patient/p1"},
+		"performer": {"reference": "Practitioner/dr1"},
+		"reasonReference": [{"reference": "Condition/c1"}],
+		"extension": [{"url": "http://hl7.org/fhir/5.0/StructureDefinition/extension-MedicationRequest.renderedDosageInstruction", "valueMarkdown": "Two puffs daily"}]
+	}`)
+	assertLost(t, report, "MedicationRequest.device", "MedicationRequest.status", "MedicationRequest.performer")
+}
+
+func TestBundleConversion(t *testing.T) {
+	tree, report := convertJSON(t, `{
+		"resourceType": "Bundle",
+		"type": "collection",
+		"entry": [
+			{"fullUrl": "urn:uuid:1", "resource": {"resourceType": "Patient", "id": "p1"}},
+			{"fullUrl": "urn:uuid:2", "resource": {
+				"resourceType": "Encounter",
+				"status": "arrived",
+				"statusHistory": [{"status": "planned", "period": {}}],
+				"contained": [{"resourceType": "Condition", "id": "c", "asserter": {"reference": "Patient/p1"}}]
+			}}
+		]
+	}`, version.R4, version.R5)
+
+	encounter := tree["entry"].([]interface{})[1].(map[string]interface{})["resource"].(map[string]interface{})
+	if encounter["status"] != "in-progress" {
+		t.Errorf("expected the entry to be converted, got %v", encounter)
+	}
+	condition := encounter["contained"].([]interface{})[0].(map[string]interface{})
+	if condition["participant"] == nil {
+		t.Errorf("expected the contained resource to be converted, got %v", condition)
+	}
+	assertLost(t, report, "Bundle.entry[1].resource.statusHistory", "Bundle.entry[1].resource.status")
+	if !strings.Contains(report.Lost[1].Reason, "arrived") {
+		t.Errorf("expected the reason to name the status, got %q", report.Lost[1].Reason)
+	}
+
+	_, report = convertJSON(t, `{
+		"resourceType": "Bundle",
+		"type": "searchset",
+		"issues": {"resourceType": "OperationOutcome", "issue": []}
+	}`, version.R5, version.R4)
+	assertLost(t, report, "Bundle.issues")
+}
+
+func TestNestedTypesMissingFromTarget(t *testing.T) {
+	tree, report := convertJSON(t, `{
+		"resourceType": "Bundle",
+		"type": "collection",
+		"entry": [
+			{"fullUrl": "urn:uuid:1", "resource": {"resourceType": "SubscriptionTopic", "url": "http://example.org/topic", "status": "active"}},
+			{"fullUrl": "urn:uuid:2", "resource": {
+				"resourceType": "Patient",
+				"id": "p1",
+				"contained": [
+					{"resourceType": "SubscriptionTopic", "id": "t", "url": "http://example.org/topic", "status": "active"},
+					{"resourceType": "Organization", "id": "o"}
+				]
+			}}
+		]
+	}`, version.R5, version.R4)
+	assertJSON(t, tree, `{
+		"resourceType": "Bundle",
+		"type": "collection",
+		"entry": [
+			{"fullUrl": "urn:uuid:2", "resource": {
+				"resourceType": "Patient",
+				"id": "p1",
+				"contained": [{"resourceType": "Organization", "id": "o"}]
+			}}
+		]
+	}`)
+	assertLost(t, report, "Bundle.entry[0].resource", "Bundle.entry[1].resource.contained[0]")
+	if !strings.Contains(report.Lost[0].Reason, "SubscriptionTopic") {
+		t.Errorf("expected the reason to name the type, got %q", report.Lost[0].Reason)
+	}
+
+	// R4B defines SubscriptionTopic, so it is kept
+	tree, report = convertJSON(t, `{
+		"resourceType": "Patient",
+		"contained": [{"resourceType": "SubscriptionTopic", "id": "t", "status": "active"}]
+	}`, version.R5, version.R4B)
+	if len(list(tree["contained"])) != 1 {
+		t.Errorf("expected the contained topic to be kept, got %v", tree)
+	}
+	assertLost(t, report)
+}
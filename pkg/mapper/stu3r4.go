@@ -0,0 +1,377 @@
+package mapper
+
+import (
+	"strings"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/version"
+)
+
+// Code systems of the R4 Condition status CodeableConcepts
+const (
+	conditionClinicalSystem     = "http://terminology.hl7.org/CodeSystem/condition-clinical"
+	conditionVerificationSystem = "http://terminology.hl7.org/CodeSystem/condition-ver-status"
+)
+
+// stu3r4 holds the conversions between STU3 and R4
+var stu3r4 = &versionMap{
+	older: version.STU3,
+	newer: version.R4,
+	resources: map[string]*resourceMap{
+		"Patient": {
+			olderOnly: []crossVersion{
+				{path: "Patient.animal"},
+			},
+		},
+
+		"Observation": {
+			renamed: []rename{
+				{older: "Observation.context", newer: "encounter"},
+			},
+			newerOnly: []crossVersion{
+				{path: "Observation.partOf", typ: "Reference", repeats: true},
+				{path: "Observation.focus", typ: "Reference", repeats: true},
+			},
+			toNewer: observationToR4FromSTU3,
+			toOlder: observationToSTU3,
+		},
+
+		"Encounter": {
+			renamed: []rename{
+				{older: "Encounter.incomingReferral", newer: "basedOn"},
+				{older: "Encounter.reason", newer: "reasonCode"},
+				{older: "Encounter.diagnosis.role", newer: "use"},
+			},
+			newerOnly: []crossVersion{
+				{path: "Encounter.serviceType", typ: "CodeableConcept"},
+				{path: "Encounter.reasonReference", typ: "Reference", repeats: true},
+				{path: "Encounter.location.physicalType", typ: "CodeableConcept"},
+			},
+			toNewer: encounterToR4FromSTU3,
+			toOlder: encounterToSTU3,
+		},
+
+		"Condition": {
+			renamed: []rename{
+				{older: "Condition.context", newer: "encounter"},
+			},
+			olderOnly: []crossVersion{
+				{path: "Condition.assertedDate", typ: "dateTime"},
+			},
+			newerOnly: []crossVersion{
+				{path: "Condition.recordedDate", typ: "dateTime"},
+				{path: "Condition.recorder", typ: "Reference"},
+				{path: "Condition.stage.type", typ: "CodeableConcept"},
+			},
+			toNewer: conditionToR4FromSTU3,
+			toOlder: conditionToSTU3,
+		},
+
+		"MedicationRequest": {
+			renamed: []rename{
+				{older: "MedicationRequest.context", newer: "encounter"},
+				{older: "MedicationRequest.substitution.allowed", newer: "allowedBoolean"},
+			},
+			olderOnly: []crossVersion{
+				{path: "MedicationRequest.definition", typ: "Reference", repeats: true},
+			},
+			newerOnly: []crossVersion{
+				{path: "MedicationRequest.statusReason", typ: "CodeableConcept"},
+				{path: "MedicationRequest.doNotPerform", typ: "boolean"},
+				{path: "MedicationRequest.reported[x]"},
+				{path: "MedicationRequest.performer", typ: "Reference"},
+				{path: "MedicationRequest.performerType", typ: "CodeableConcept"},
+				{path: "MedicationRequest.instantiatesCanonical", typ: "canonical", repeats: true},
+				{path: "MedicationRequest.instantiatesUri", typ: "uri", repeats: true},
+				{path: "MedicationRequest.courseOfTherapyType", typ: "CodeableConcept"},
+				{path: "MedicationRequest.insurance", typ: "Reference", repeats: true},
+				{path: "MedicationRequest.dispenseRequest.initialFill"},
+				{path: "MedicationRequest.dispenseRequest.dispenseInterval", typ: "Duration"},
+			},
+			toNewer: medicationRequestToR4FromSTU3,
+			toOlder: medicationRequestToSTU3,
+		},
+
+		"Bundle": {
+			newerOnly: []crossVersion{
+				{path: "Bundle.timestamp", typ: "instant"},
+			},
+		},
+	},
+}
+
+// observationRelated maps STU3 Observation.related types to the R4
+// elements that replaced them
+var observationRelated = map[string]string{
+	"has-member":   "hasMember",
+	"derived-from": "derivedFrom",
+}
+
+func observationToR4FromSTU3(c *conversion, resource map[string]interface{}) {
+	c.episodeOfCare(resource, "context", "Observation.context")
+
+	for _, item := range list(resource["related"]) {
+		related, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		relation, _ := related["type"].(string)
+		key, ok := observationRelated[relation]
+		if !ok {
+			c.lose("Observation.related", "related type %q has no equivalent in R4", relation)
+			continue
+		}
+		resource[key] = append(list(resource[key]), related["target"])
+	}
+	delete(resource, "related")
+
+	if _, ok := resource["comment"]; ok {
+		note := map[string]interface{}{"text": resource["comment"]}
+		if primitive, ok := resource["_comment"]; ok {
+			note["_text"] = primitive
+		}
+		resource["note"] = []interface{}{note}
+		delete(resource, "comment")
+		delete(resource, "_comment")
+	}
+
+	for _, path := range []string{"Observation", "Observation.component"} {
+		each(resource, path, func(obj map[string]interface{}) {
+			if interpretation, ok := obj["interpretation"]; ok {
+				obj["interpretation"] = []interface{}{interpretation}
+			}
+			choicesToExtensions(obj, version.STU3, path+".value", "valueAttachment")
+			restoreChoices(obj, version.R4, path+".value")
+		})
+	}
+	restoreChoices(resource, version.R4, "Observation.effective")
+}
+
+func observationToSTU3(c *conversion, resource map[string]interface{}) {
+	var related []interface{}
+	for _, relation := range []string{"has-member", "derived-from"} {
+		key := observationRelated[relation]
+		for _, target := range list(resource[key]) {
+			related = append(related, map[string]interface{}{"type": relation, "target": target})
+		}
+		delete(resource, key)
+	}
+	setList(resource, "related", related)
+
+	if note := c.first(resource, "note", "Observation.note"); note != nil {
+		delete(resource, "note")
+		if n, ok := note.(map[string]interface{}); ok {
+			renameKey(n, "text", "comment")
+			for _, key := range []string{"comment", "_comment"} {
+				if value, ok := n[key]; ok {
+					resource[key] = value
+					delete(n, key)
+				}
+			}
+			if len(n) > 0 {
+				c.lose("Observation.note", "STU3 comment is a string; the note's author and time were dropped")
+			}
+		}
+	}
+
+	// An instant is also a valid dateTime
+	renameKey(resource, "effectiveInstant", "effectiveDateTime")
+	choicesToExtensions(resource, version.R4, "Observation.effective", "effectiveTiming")
+
+	for _, path := range []string{"Observation", "Observation.component"} {
+		each(resource, path, func(obj map[string]interface{}) {
+			if interpretation := c.first(obj, "interpretation", path+".interpretation"); interpretation != nil {
+				obj["interpretation"] = interpretation
+			}
+			choicesToExtensions(obj, version.R4, path+".value", "valueInteger")
+			restoreChoices(obj, version.STU3, path+".value")
+		})
+	}
+}
+
+func encounterToR4FromSTU3(c *conversion, resource map[string]interface{}) {
+	if appointment, ok := resource["appointment"]; ok {
+		resource["appointment"] = []interface{}{appointment}
+	}
+	retarget(resource["incomingReferral"], "ReferralRequest", "ServiceRequest")
+}
+
+func encounterToSTU3(c *conversion, resource map[string]interface{}) {
+	if appointment := c.first(resource, "appointment", "Encounter.appointment"); appointment != nil {
+		resource["appointment"] = appointment
+	}
+	retarget(resource["basedOn"], "ServiceRequest", "ReferralRequest")
+}
+
+// conditionVerificationToR4 maps STU3 Condition.verificationStatus codes
+// that R4 lacks
+var conditionVerificationToR4 = map[string]codeMapping{
+	"unknown": {"unconfirmed", true},
+}
+
+// conditionVerificationToSTU3 maps R4 Condition.verificationStatus codes
+// that STU3 lacks
+var conditionVerificationToSTU3 = map[string]codeMapping{
+	"unconfirmed": {"provisional", true},
+}
+
+// conditionClinicalToSTU3 maps R4 Condition.clinicalStatus codes that STU3
+// lacks
+var conditionClinicalToSTU3 = map[string]codeMapping{
+	"relapse": {"recurrence", false},
+}
+
+// conditionStatuses are the Condition elements that became CodeableConcepts
+// in R4, with their code systems
+var conditionStatuses = []struct{ element, system string }{
+	{"clinicalStatus", conditionClinicalSystem},
+	{"verificationStatus", conditionVerificationSystem},
+}
+
+func conditionToR4FromSTU3(c *conversion, resource map[string]interface{}) {
+	c.episodeOfCare(resource, "context", "Condition.context")
+	c.mapCode(resource, "verificationStatus", "Condition.verificationStatus", conditionVerificationToR4)
+	for _, s := range conditionStatuses {
+		if code, ok := resource[s.element]; ok {
+			resource[s.element] = codeableConcept(map[string]interface{}{"system": s.system, "code": code})
+		}
+	}
+	if stage, ok := resource["stage"]; ok {
+		resource["stage"] = []interface{}{stage}
+	}
+	choicesToExtensions(resource, version.STU3, "Condition.abatement", "abatementBoolean")
+}
+
+func conditionToSTU3(c *conversion, resource map[string]interface{}) {
+	for _, s := range conditionStatuses {
+		concept, ok := resource[s.element]
+		if !ok {
+			continue
+		}
+		delete(resource, s.element)
+		if code := codeIn(concept, s.system); code != "" {
+			resource[s.element] = code
+		} else {
+			c.lose("Condition."+s.element, "STU3 %s is a code; the concept has no %s coding", s.element, s.system)
+		}
+	}
+	c.mapCode(resource, "clinicalStatus", "Condition.clinicalStatus", conditionClinicalToSTU3)
+	c.mapCode(resource, "verificationStatus", "Condition.verificationStatus", conditionVerificationToSTU3)
+	if stage := c.first(resource, "stage", "Condition.stage"); stage != nil {
+		resource["stage"] = stage
+	}
+	restoreChoices(resource, version.STU3, "Condition.abatement")
+}
+
+// medicationRequestIntentToSTU3 maps R4 MedicationRequest.intent codes that
+// STU3 lacks
+var medicationRequestIntentToSTU3 = map[string]codeMapping{
+	"original-order": {"order", true},
+	"reflex-order":   {"order", true},
+	"filler-order":   {"order", true},
+	"option":         {"proposal", true},
+}
+
+// dosageDoses are the Dosage dose[x] and rate[x] members that R4 moved
+// into Dosage.doseAndRate
+var dosageDoses = []string{"doseRange", "doseQuantity", "rateRatio", "rateRange", "rateQuantity"}
+
+func medicationRequestToR4FromSTU3(c *conversion, resource map[string]interface{}) {
+	c.episodeOfCare(resource, "context", "MedicationRequest.context")
+	if requester, ok := resource["requester"].(map[string]interface{}); ok {
+		delete(resource, "requester")
+		if agent, ok := requester["agent"]; ok {
+			resource["requester"] = agent
+		}
+		if _, ok := requester["onBehalfOf"]; ok {
+			c.lose("MedicationRequest.requester.onBehalfOf", "onBehalfOf has no equivalent in R4")
+		}
+	}
+	if category, ok := resource["category"]; ok {
+		resource["category"] = []interface{}{category}
+	}
+	each(resource, "MedicationRequest.dosageInstruction", func(dosage map[string]interface{}) {
+		doseAndRate := make(map[string]interface{})
+		for _, key := range dosageDoses {
+			if value, ok := dosage[key]; ok {
+				doseAndRate[key] = value
+				delete(dosage, key)
+			}
+		}
+		if len(doseAndRate) > 0 {
+			dosage["doseAndRate"] = []interface{}{doseAndRate}
+		}
+	})
+}
+
+func medicationRequestToSTU3(c *conversion, resource map[string]interface{}) {
+	c.mapCode(resource, "intent", "MedicationRequest.intent", medicationRequestIntentToSTU3)
+	if requester, ok := resource["requester"]; ok {
+		resource["requester"] = map[string]interface{}{"agent": requester}
+	}
+	if category := c.first(resource, "category", "MedicationRequest.category"); category != nil {
+		resource["category"] = category
+	}
+	each(resource, "MedicationRequest.substitution", func(substitution map[string]interface{}) {
+		if _, ok := substitution["allowedCodeableConcept"]; ok {
+			c.lose("MedicationRequest.substitution.allowedCodeableConcept", "STU3 substitution.allowed is a boolean; the concept was dropped")
+			delete(substitution, "allowedCodeableConcept")
+		}
+	})
+	each(resource, "MedicationRequest.dosageInstruction", func(dosage map[string]interface{}) {
+		doseAndRate := c.first(dosage, "doseAndRate", "MedicationRequest.dosageInstruction.doseAndRate")
+		delete(dosage, "doseAndRate")
+		dr, ok := doseAndRate.(map[string]interface{})
+		if !ok {
+			return
+		}
+		if _, ok := dr["type"]; ok {
+			c.lose("MedicationRequest.dosageInstruction.doseAndRate.type", "doseAndRate.type has no equivalent in STU3")
+		}
+		for _, key := range dosageDoses {
+			if value, ok := dr[key]; ok {
+				dosage[key] = value
+			}
+		}
+	})
+}
+
+// episodeOfCare reports a STU3 context that references an EpisodeOfCare,
+// which the R4 encounter that replaced it cannot
+func (c *conversion) episodeOfCare(resource map[string]interface{}, key, path string) {
+	ref, _ := resource[key].(map[string]interface{})
+	if reference, _ := ref["reference"].(string); strings.HasPrefix(reference, "EpisodeOfCare/") {
+		c.lose(path, "R4 encounter cannot reference an EpisodeOfCare")
+		delete(resource, key)
+	}
+}
+
+// retarget changes the type of relative references to a resource type that
+// was renamed
+func retarget(refs interface{}, from, to string) {
+	for _, item := range list(refs) {
+		ref, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if reference, ok := ref["reference"].(string); ok {
+			if id, ok := strings.CutPrefix(reference, from+"/"); ok {
+				ref["reference"] = to + "/" + id
+			}
+		}
+	}
+}
+
+// codeIn returns the code of a CodeableConcept's coding with system
+func codeIn(concept interface{}, system string) string {
+	cc, ok := concept.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	for _, item := range list(cc["coding"]) {
+		if coding, ok := item.(map[string]interface{}); ok && coding["system"] == system {
+			code, _ := coding["code"].(string)
+			return code
+		}
+	}
+	return ""
+}
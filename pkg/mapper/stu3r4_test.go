@@ -0,0 +1,226 @@
+package mapper
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/version"
+)
+
+// mustJSON encodes a converted tree to convert it again
+func mustJSON(t *testing.T, tree map[string]interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+const stu3Observation = `{
+	"resourceType": "Observation",
+	"id": "o1",
+	"status": "final",
+	"code": {"text": "Heart rate"},
+	"context": {"reference": "Encounter/e1"},
+	"effectiveDateTime": "2024-01-01T09:00:00Z",
+	"valueAttachment": {"url": "http://example.org/ecg.pdf"},
+	"interpretation": {"text": "Normal"},
+	"comment": "Resting",
+	"related": [
+		{"type": "has-member", "target": {"reference": "Observation/o2"}},
+		{"type": "replaces", "target": {"reference": "Observation/o0"}}
+	],
+	"component": [{"code": {"text": "Rhythm"}, "valueString": "sinus", "interpretation": {"text": "Normal"}}]
+}`
+
+func TestObservationSTU3ToR4(t *testing.T) {
+	tree, report := convertJSON(t, stu3Observation, version.STU3, version.R4)
+	assertJSON(t, tree, `{
+		"resourceType": "Observation",
+		"id": "o1",
+		"status": "final",
+		"code": {"text": "Heart rate"},
+		"encounter": {"reference": "Encounter/e1"},
+		"effectiveDateTime": "2024-01-01T09:00:00Z",
+		"extension": [{"url": "http://hl7.org/fhir/3.0/StructureDefinition/extension-Observation.value", "valueAttachment": {"url": "http://example.org/ecg.pdf"}}],
+		"interpretation": [{"text": "Normal"}],
+		"note": [{"text": "Resting"}],
+		"hasMember": [{"reference": "Observation/o2"}],
+		"component": [{"code": {"text": "Rhythm"}, "valueString": "sinus", "interpretation": [{"text": "Normal"}]}]
+	}`)
+	assertLost(t, report, "Observation.related")
+
+	// The attachment comes back from its extension
+	back, report := convertJSON(t, mustJSON(t, tree), version.R4, version.STU3)
+	if back["valueAttachment"] == nil || back["extension"] != nil || back["comment"] != "Resting" || back["context"] == nil {
+		t.Errorf("unexpected round trip %v", back)
+	}
+	assertLost(t, report)
+}
+
+func TestObservationR4ToSTU3(t *testing.T) {
+	tree, report := convertJSON(t, `{
+		"resourceType": "Observation",
+		"status": "final",
+		"code": {"text": "Steps"},
+		"partOf": [{"reference": "Procedure/p1"}],
+		"effectiveTiming": {"repeat": {"frequency": 1}},
+		"valueInteger": 8000,
+		"note": [{"text": "Walk", "authorString": "Pat"}],
+		"derivedFrom": [{"reference": "Observation/o3"}]
+	}`, version.R4, version.STU3)
+	assertJSON(t, tree, `{
+		"resourceType": "Observation",
+		"status": "final",
+		"code": {"text": "Steps"},
+		"extension": [
+			{"url": "http://hl7.org/fhir/4.0/StructureDefinition/extension-Observation.partOf", "valueReference": {"reference": "Procedure/p1"}},
+			{"url": "http://hl7.org/fhir/4.0/StructureDefinition/extension-Observation.effective", "valueTiming": {"repeat": {"frequency": 1}}},
+			{"url": "http://hl7.org/fhir/4.0/StructureDefinition/extension-Observation.value", "valueInteger": 8000}
+		],
+		"comment": "Walk",
+		"related": [{"type": "derived-from", "target": {"reference": "Observation/o3"}}]
+	}`)
+	assertLost(t, report, "Observation.note")
+
+	back, _ := convertJSON(t, mustJSON(t, tree), version.STU3, version.R4)
+	if back["valueInteger"] == nil || back["effectiveTiming"] == nil || back["partOf"] == nil || back["extension"] != nil {
+		t.Errorf("unexpected round trip %v", back)
+	}
+}
+
+func TestEncounterSTU3Conversion(t *testing.T) {
+	const stu3 = `{
+		"resourceType": "Encounter",
+		"status": "finished",
+		"incomingReferral": [{"reference": "ReferralRequest/r1"}],
+		"reason": [{"text": "Chest pain"}],
+		"appointment": {"reference": "Appointment/a1"},
+		"diagnosis": [{"condition": {"reference": "Condition/c1"}, "role": {"text": "Admission"}}]
+	}`
+	tree, report := convertJSON(t, stu3, version.STU3, version.R4)
+	assertJSON(t, tree, `{
+		"resourceType": "Encounter",
+		"status": "finished",
+		"basedOn": [{"reference": "ServiceRequest/r1"}],
+		"reasonCode": [{"text": "Chest pain"}],
+		"appointment": [{"reference": "Appointment/a1"}],
+		"diagnosis": [{"condition": {"reference": "Condition/c1"}, "use": {"text": "Admission"}}]
+	}`)
+	assertLost(t, report)
+
+	back, report := convertJSON(t, mustJSON(t, tree), version.R4, version.STU3)
+	assertJSON(t, back, stu3)
+	assertLost(t, report)
+}
+
+func TestConditionSTU3Conversion(t *testing.T) {
+	tree, report := convertJSON(t, `{
+		"resourceType": "Condition",
+		"clinicalStatus": "active",
+		"verificationStatus": "unknown",
+		"context": {"reference": "EpisodeOfCare/eoc1"},
+		"assertedDate": "2024-01-01",
+		"abatementBoolean": false,
+		"stage": {"summary": {"text": "Stage 2"}}
+	}`, version.STU3, version.R4)
+	assertJSON(t, tree, `{
+		"resourceType": "Condition",
+		"clinicalStatus": {"coding": [{"system": "http://terminology.hl7.org/CodeSystem/condition-clinical", "code": "active"}]},
+		"verificationStatus": {"coding": [{"system": "http://terminology.hl7.org/CodeSystem/condition-ver-status", "code": "unconfirmed"}]},
+		"extension": [
+			{"url": "http://hl7.org/fhir/3.0/StructureDefinition/extension-Condition.assertedDate", "valueDateTime": "2024-01-01"},
+			{"url": "http://hl7.org/fhir/3.0/StructureDefinition/extension-Condition.abatement", "valueBoolean": false}
+		],
+		"stage": [{"summary": {"text": "Stage 2"}}]
+	}`)
+	assertLost(t, report, "Condition.context", "Condition.verificationStatus")
+
+	back, report := convertJSON(t, `{
+		"resourceType": "Condition",
+		"clinicalStatus": {"coding": [{"system": "http://terminology.hl7.org/CodeSystem/condition-clinical", "code": "relapse"}]},
+		"verificationStatus": {"text": "Unsure"},
+		"recorder": {"reference": "Practitioner/dr1"}
+	}`, version.R4, version.STU3)
+	assertJSON(t, back, `{
+		"resourceType": "Condition",
+		"clinicalStatus": "recurrence",
+		"extension": [{"url": "http://hl7.org/fhir/4.0/StructureDefinition/extension-Condition.recorder", "valueReference": {"reference": "Practitioner/dr1"}}]
+	}`)
+	assertLost(t, report, "Condition.verificationStatus")
+}
+
+func TestMedicationRequestSTU3Conversion(t *testing.T) {
+	tree, report := convertJSON(t, `{
+		"resourceType": "MedicationRequest",
+		"status": "active",
+		"intent": "order",
+		"medicationCodeableConcept": {"text": "Amoxicillin"},
+		"requester": {"agent": {"reference": "Practitioner/dr1"}, "onBehalfOf": {"reference": "Organization/o1"}},
+		"category": {"text": "Outpatient"},
+		"dosageInstruction": [{"text": "500mg", "doseQuantity": {"value": 500, "unit": "mg"}}],
+		"substitution": {"allowed": true}
+	}`, version.STU3, version.R4)
+	assertJSON(t, tree, `{
+		"resourceType": "MedicationRequest",
+		"status": "active",
+		"intent": "order",
+		"medicationCodeableConcept": {"text": "Amoxicillin"},
+		"requester": {"reference": "Practitioner/dr1"},
+		"category": [{"text": "Outpatient"}],
+		"dosageInstruction": [{"text": "500mg", "doseAndRate": [{"doseQuantity": {"value": 500, "unit": "mg"}}]}],
+		"substitution": {"allowedBoolean": true}
+	}`)
+	assertLost(t, report, "MedicationRequest.requester.onBehalfOf")
+
+	back, report := convertJSON(t, `{
+		"resourceType": "MedicationRequest",
+		"status": "active",
+		"intent": "filler-order",
+		"reportedBoolean": true,
+		"dosageInstruction": [{"doseAndRate": [{"type": {"text": "calculated"}, "doseQuantity": {"value": 1}}]}],
+		"dispenseRequest": {"initialFill": {"quantity": {"value": 10}}}
+	}`, version.R4, version.STU3)
+	assertJSON(t, back, `{
+		"resourceType": "MedicationRequest",
+		"status": "active",
+		"intent": "order",
+		"extension": [{"url": "http://hl7.org/fhir/4.0/StructureDefinition/extension-MedicationRequest.reported", "valueBoolean": true}],
+		"dosageInstruction": [{"doseQuantity": {"value": 1}}],
+		"dispenseRequest": {}
+	}`)
+	assertLost(t, report,
+		"MedicationRequest.dispenseRequest.initialFill",
+		"MedicationRequest.intent",
+		"MedicationRequest.dosageInstruction.doseAndRate.type")
+}
+
+func TestConvertThroughR4(t *testing.T) {
+	tree, report := convertJSON(t, `{
+		"resourceType": "Encounter",
+		"status": "finished",
+		"period": {"start": "2024-01-01"},
+		"reason": [{"text": "Chest pain"}],
+		"appointment": {"reference": "Appointment/a1"}
+	}`, version.STU3, version.R5)
+	assertJSON(t, tree, `{
+		"resourceType": "Encounter",
+		"status": "completed",
+		"actualPeriod": {"start": "2024-01-01"},
+		"reason": [{"value": [{"concept": {"text": "Chest pain"}}]}],
+		"appointment": [{"reference": "Appointment/a1"}]
+	}`)
+	if report.From != version.STU3 || report.To != version.R5 {
+		t.Errorf("report is from %s to %s", report.From, report.To)
+	}
+	assertLost(t, report)
+
+	// Elements of the oldest version travel through the versions between
+	tree, _ = convertJSON(t, `{"resourceType": "Patient", "animal": {"species": {"text": "Dog"}}}`, version.STU3, version.R4B)
+	assertJSON(t, tree, `{"resourceType": "Patient"}`)
+	tree, report = convertJSON(t, `{"resourceType": "Condition", "assertedDate": "2024-01-01"}`, version.STU3, version.R5)
+	back, _ := convertJSON(t, mustJSON(t, tree), version.R5, version.STU3)
+	assertJSON(t, back, `{"resourceType": "Condition", "assertedDate": "2024-01-01"}`)
+	assertLost(t, report)
+}
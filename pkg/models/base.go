@@ -2,7 +2,8 @@ package models
 
 import (
 	"encoding/json"
-	"time"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
 )
 
 // Resource represents the base interface that all FHIR resources must implement
@@ -15,22 +16,37 @@ type ResourceType string
 
 // Base FHIR resource types
 const (
-	ResourceTypePatient           ResourceType = "Patient"
-	ResourceTypeObservation       ResourceType = "Observation"
-	ResourceTypeCondition         ResourceType = "Condition"
-	ResourceTypeMedicationRequest ResourceType = "MedicationRequest"
+	ResourceTypePatient             ResourceType = "Patient"
+	ResourceTypeObservation         ResourceType = "Observation"
+	ResourceTypeCondition           ResourceType = "Condition"
+	ResourceTypeMedicationRequest   ResourceType = "MedicationRequest"
+	ResourceTypeOperationOutcome    ResourceType = "OperationOutcome"
+	ResourceTypeParameters          ResourceType = "Parameters"
+	ResourceTypeBundle              ResourceType = "Bundle"
+	ResourceTypeBinary              ResourceType = "Binary"
+	ResourceTypeValueSet            ResourceType = "ValueSet"
+	ResourceTypeCodeSystem          ResourceType = "CodeSystem"
+	ResourceTypeStructureDefinition ResourceType = "StructureDefinition"
 	// Add more resource types as needed
 )
 
 // Base represents common fields present in all FHIR resources
 type Base struct {
-	ResourceType ResourceType      `json:"resourceType"`
-	ID           string            `json:"id,omitempty"`
-	Meta         *Meta             `json:"meta,omitempty"`
-	Language     string            `json:"language,omitempty"`
-	Text         *Narrative        `json:"text,omitempty"`
-	Extension    []Extension       `json:"extension,omitempty"`
-	Contained    []json.RawMessage `json:"contained,omitempty"`
+	ResourceType ResourceType       `json:"resourceType"`
+	ID           string             `json:"id,omitempty"`
+	Meta         *Meta              `json:"meta,omitempty"`
+	Language     string             `json:"language,omitempty"`
+	Text         *Narrative         `json:"text,omitempty"`
+	Extension    []Extension        `json:"extension,omitempty"`
+	Contained    ContainedResources `json:"contained,omitempty"`
+
+	// Unknown holds JSON members that have no corresponding field, keyed by
+	// member name, so that they are written back out when the resource is marshaled
+	Unknown map[string]json.RawMessage `json:"-"`
+
+	// Primitives holds the ids and extensions of primitive elements, which
+	// FHIR JSON carries in "_name" siblings of the value
+	Primitives PrimitiveElements `json:"-"`
 }
 
 // GetResourceType implements the Resource interface
@@ -38,20 +54,40 @@ func (b Base) GetResourceType() string {
 	return string(b.ResourceType)
 }
 
+// GetID returns the logical id of the resource
+func (b Base) GetID() string {
+	return b.ID
+}
+
+// resourceBase gives access to the Base of any resource that embeds it
+func (b *Base) resourceBase() *Base {
+	return b
+}
+
+// baseResource is implemented by resources that embed Base
+type baseResource interface {
+	Resource
+	resourceBase() *Base
+}
+
 // Meta represents FHIR resource metadata
 type Meta struct {
-	VersionID   string     `json:"versionId,omitempty"`
-	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
-	Source      string     `json:"source,omitempty"`
-	Profile     []string   `json:"profile,omitempty"`
-	Security    []Coding   `json:"security,omitempty"`
-	Tag         []Coding   `json:"tag,omitempty"`
+	VersionID   string                     `json:"versionId,omitempty"`
+	LastUpdated *fhir.Instant              `json:"lastUpdated,omitempty"`
+	Source      string                     `json:"source,omitempty"`
+	Profile     []string                   `json:"profile,omitempty"`
+	Security    []Coding                   `json:"security,omitempty"`
+	Tag         []Coding                   `json:"tag,omitempty"`
+	Unknown     map[string]json.RawMessage `json:"-"`
+	Primitives  PrimitiveElements          `json:"-"`
 }
 
 // Narrative represents the human-readable summary of a resource
 type Narrative struct {
-	Status NarrativeStatus `json:"status"`
-	Div    string          `json:"div"`
+	Status     NarrativeStatus            `json:"status"`
+	Div        string                     `json:"div"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
 }
 
 // NarrativeStatus represents the status of a narrative text
@@ -66,72 +102,171 @@ const (
 
 // Extension represents a FHIR extension
 type Extension struct {
-	URL                  string           `json:"url"`
-	ValueString          *string          `json:"valueString,omitempty"`
-	ValueInteger         *int             `json:"valueInteger,omitempty"`
-	ValueBoolean         *bool            `json:"valueBoolean,omitempty"`
-	ValueCode            *string          `json:"valueCode,omitempty"`
-	ValueDateTime        *time.Time       `json:"valueDateTime,omitempty"`
-	ValueQuantity        *Quantity        `json:"valueQuantity,omitempty"`
-	ValueReference       *Reference       `json:"valueReference,omitempty"`
-	ValueCodeableConcept *CodeableConcept `json:"valueCodeableConcept,omitempty"`
+	URL                  string                     `json:"url"`
+	Extension            []Extension                `json:"extension,omitempty"`
+	ValueString          *string                    `json:"valueString,omitempty"`
+	ValueInteger         *int                       `json:"valueInteger,omitempty"`
+	ValueDecimal         *fhir.Decimal              `json:"valueDecimal,omitempty"`
+	ValueBoolean         *bool                      `json:"valueBoolean,omitempty"`
+	ValueCode            *string                    `json:"valueCode,omitempty"`
+	ValueCoding          *Coding                    `json:"valueCoding,omitempty"`
+	ValueDate            *fhir.Date                 `json:"valueDate,omitempty"`
+	ValueDateTime        *fhir.DateTime             `json:"valueDateTime,omitempty"`
+	ValueQuantity        *Quantity                  `json:"valueQuantity,omitempty"`
+	ValueReference       *Reference                 `json:"valueReference,omitempty"`
+	ValueCodeableConcept *CodeableConcept           `json:"valueCodeableConcept,omitempty"`
+	Unknown              map[string]json.RawMessage `json:"-"`
+	Primitives           PrimitiveElements          `json:"-"`
+}
+
+// Element represents the id and extensions that may be attached to a FHIR
+// primitive value. In JSON these appear in a sibling member whose name is the
+// value's name prefixed with an underscore.
+type Element struct {
+	ID        string      `json:"id,omitempty"`
+	Extension []Extension `json:"extension,omitempty"`
+}
+
+// IsEmpty reports whether the element carries neither an id nor extensions
+func (e *Element) IsEmpty() bool {
+	return e == nil || (e.ID == "" && len(e.Extension) == 0)
+}
+
+// GetExtension returns the first extension with the given URL, or nil
+func (e *Element) GetExtension(url string) *Extension {
+	if e == nil {
+		return nil
+	}
+	for i := range e.Extension {
+		if e.Extension[i].URL == url {
+			return &e.Extension[i]
+		}
+	}
+	return nil
+}
+
+// PrimitiveElements maps the JSON name of a primitive value to its element
+// data. Single-valued primitives have one entry; repeating primitives are
+// aligned by index with their values, with nil for values that have no
+// element data.
+type PrimitiveElements map[string][]*Element
+
+// Get returns the element data for a single-valued primitive, or nil
+func (p PrimitiveElements) Get(name string) *Element {
+	return p.GetAt(name, 0)
+}
+
+// GetAt returns the element data for the value at index i of a repeating
+// primitive, or nil
+func (p PrimitiveElements) GetAt(name string, i int) *Element {
+	elements := p[name]
+	if i < 0 || i >= len(elements) {
+		return nil
+	}
+	return elements[i]
+}
+
+// Set sets the element data for a single-valued primitive
+func (p *PrimitiveElements) Set(name string, element *Element) {
+	p.SetAt(name, 0, element)
+}
+
+// SetAt sets the element data for the value at index i of a repeating
+// primitive, padding earlier values with nil as needed
+func (p *PrimitiveElements) SetAt(name string, i int, element *Element) {
+	if *p == nil {
+		*p = make(PrimitiveElements)
+	}
+	elements := (*p)[name]
+	for len(elements) <= i {
+		elements = append(elements, nil)
+	}
+	elements[i] = element
+	(*p)[name] = elements
 }
 
 // Coding represents a code from a code system
 type Coding struct {
-	System       string `json:"system,omitempty"`
-	Version      string `json:"version,omitempty"`
-	Code         string `json:"code,omitempty"`
-	Display      string `json:"display,omitempty"`
-	UserSelected *bool  `json:"userSelected,omitempty"`
+	System       string                     `json:"system,omitempty"`
+	Version      string                     `json:"version,omitempty"`
+	Code         string                     `json:"code,omitempty"`
+	Display      string                     `json:"display,omitempty"`
+	UserSelected *bool                      `json:"userSelected,omitempty"`
+	Unknown      map[string]json.RawMessage `json:"-"`
+	Primitives   PrimitiveElements          `json:"-"`
 }
 
 // CodeableConcept represents a concept that may be defined by one or more code systems
 type CodeableConcept struct {
-	Coding []Coding `json:"coding,omitempty"`
-	Text   string   `json:"text,omitempty"`
+	Coding     []Coding                   `json:"coding,omitempty"`
+	Text       string                     `json:"text,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
 }
 
 // Quantity represents a measured or measurable amount
 type Quantity struct {
-	Value      *float64 `json:"value,omitempty"`
-	Comparator string   `json:"comparator,omitempty"`
-	Unit       string   `json:"unit,omitempty"`
-	System     string   `json:"system,omitempty"`
-	Code       string   `json:"code,omitempty"`
+	Value      *fhir.Decimal              `json:"value,omitempty"`
+	Comparator string                     `json:"comparator,omitempty"`
+	Unit       string                     `json:"unit,omitempty"`
+	System     string                     `json:"system,omitempty"`
+	Code       string                     `json:"code,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
 }
 
 // Reference represents a reference to another resource
 type Reference struct {
-	Reference string `json:"reference,omitempty"`
-	Type      string `json:"type,omitempty"`
-	Display   string `json:"display,omitempty"`
+	Reference  string                     `json:"reference,omitempty"`
+	Type       string                     `json:"type,omitempty"`
+	Identifier *Identifier                `json:"identifier,omitempty"`
+	Display    string                     `json:"display,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
+}
+
+// Identifier represents a business identifier for a resource
+type Identifier struct {
+	Use        string                     `json:"use,omitempty"`
+	Type       *CodeableConcept           `json:"type,omitempty"`
+	System     string                     `json:"system,omitempty"`
+	Value      string                     `json:"value,omitempty"`
+	Period     *Period                    `json:"period,omitempty"`
+	Assigner   *Reference                 `json:"assigner,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
 }
 
 // Period represents a time period
 type Period struct {
-	Start *time.Time `json:"start,omitempty"`
-	End   *time.Time `json:"end,omitempty"`
+	Start      *fhir.DateTime             `json:"start,omitempty"`
+	End        *fhir.DateTime             `json:"end,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
 }
 
 // HumanName represents a human name
 type HumanName struct {
-	Use    string   `json:"use,omitempty"`
-	Text   string   `json:"text,omitempty"`
-	Family string   `json:"family,omitempty"`
-	Given  []string `json:"given,omitempty"`
-	Prefix []string `json:"prefix,omitempty"`
-	Suffix []string `json:"suffix,omitempty"`
-	Period *Period  `json:"period,omitempty"`
+	Use        string                     `json:"use,omitempty"`
+	Text       string                     `json:"text,omitempty"`
+	Family     string                     `json:"family,omitempty"`
+	Given      []string                   `json:"given,omitempty"`
+	Prefix     []string                   `json:"prefix,omitempty"`
+	Suffix     []string                   `json:"suffix,omitempty"`
+	Period     *Period                    `json:"period,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
 }
 
 // ContactPoint represents contact information
 type ContactPoint struct {
-	System ContactPointSystem `json:"system,omitempty"`
-	Value  string             `json:"value,omitempty"`
-	Use    ContactPointUse    `json:"use,omitempty"`
-	Rank   *int               `json:"rank,omitempty"`
-	Period *Period            `json:"period,omitempty"`
+	System     ContactPointSystem         `json:"system,omitempty"`
+	Value      string                     `json:"value,omitempty"`
+	Use        ContactPointUse            `json:"use,omitempty"`
+	Rank       *int                       `json:"rank,omitempty"`
+	Period     *Period                    `json:"period,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
 }
 
 // ContactPointSystem represents the type of contact point
@@ -157,3 +292,135 @@ const (
 	ContactPointUseOld    ContactPointUse = "old"
 	ContactPointUseMobile ContactPointUse = "mobile"
 )
+
+// UnmarshalJSON implements custom JSON unmarshaling for Meta, retaining primitive extensions and unrecognised members
+func (m *Meta) UnmarshalJSON(data []byte) error {
+	type Alias Meta
+	return decodeElement(data, (*Alias)(m), &m.Unknown, &m.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for Meta, re-emitting primitive extensions and unrecognised members
+func (m Meta) MarshalJSON() ([]byte, error) {
+	type Alias Meta
+	return encodeElement(Alias(m), m.Unknown, m.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Narrative, retaining primitive extensions and unrecognised members
+func (n *Narrative) UnmarshalJSON(data []byte) error {
+	type Alias Narrative
+	return decodeElement(data, (*Alias)(n), &n.Unknown, &n.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for Narrative, re-emitting primitive extensions and unrecognised members
+func (n Narrative) MarshalJSON() ([]byte, error) {
+	type Alias Narrative
+	return encodeElement(Alias(n), n.Unknown, n.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Extension, retaining primitive extensions and unrecognised members
+func (e *Extension) UnmarshalJSON(data []byte) error {
+	type Alias Extension
+	return decodeElement(data, (*Alias)(e), &e.Unknown, &e.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for Extension, re-emitting primitive extensions and unrecognised members
+func (e Extension) MarshalJSON() ([]byte, error) {
+	type Alias Extension
+	return encodeElement(Alias(e), e.Unknown, e.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Coding, retaining primitive extensions and unrecognised members
+func (c *Coding) UnmarshalJSON(data []byte) error {
+	type Alias Coding
+	return decodeElement(data, (*Alias)(c), &c.Unknown, &c.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for Coding, re-emitting primitive extensions and unrecognised members
+func (c Coding) MarshalJSON() ([]byte, error) {
+	type Alias Coding
+	return encodeElement(Alias(c), c.Unknown, c.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for CodeableConcept, retaining primitive extensions and unrecognised members
+func (c *CodeableConcept) UnmarshalJSON(data []byte) error {
+	type Alias CodeableConcept
+	return decodeElement(data, (*Alias)(c), &c.Unknown, &c.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for CodeableConcept, re-emitting primitive extensions and unrecognised members
+func (c CodeableConcept) MarshalJSON() ([]byte, error) {
+	type Alias CodeableConcept
+	return encodeElement(Alias(c), c.Unknown, c.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Quantity, retaining primitive extensions and unrecognised members
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	type Alias Quantity
+	return decodeElement(data, (*Alias)(q), &q.Unknown, &q.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for Quantity, re-emitting primitive extensions and unrecognised members
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	type Alias Quantity
+	return encodeElement(Alias(q), q.Unknown, q.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Reference, retaining primitive extensions and unrecognised members
+func (r *Reference) UnmarshalJSON(data []byte) error {
+	type Alias Reference
+	return decodeElement(data, (*Alias)(r), &r.Unknown, &r.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for Reference, re-emitting primitive extensions and unrecognised members
+func (r Reference) MarshalJSON() ([]byte, error) {
+	type Alias Reference
+	return encodeElement(Alias(r), r.Unknown, r.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Identifier, retaining primitive extensions and unrecognised members
+func (i *Identifier) UnmarshalJSON(data []byte) error {
+	type Alias Identifier
+	return decodeElement(data, (*Alias)(i), &i.Unknown, &i.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for Identifier, re-emitting primitive extensions and unrecognised members
+func (i Identifier) MarshalJSON() ([]byte, error) {
+	type Alias Identifier
+	return encodeElement(Alias(i), i.Unknown, i.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Period, retaining primitive extensions and unrecognised members
+func (p *Period) UnmarshalJSON(data []byte) error {
+	type Alias Period
+	return decodeElement(data, (*Alias)(p), &p.Unknown, &p.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for Period, re-emitting primitive extensions and unrecognised members
+func (p Period) MarshalJSON() ([]byte, error) {
+	type Alias Period
+	return encodeElement(Alias(p), p.Unknown, p.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for HumanName, retaining primitive extensions and unrecognised members
+func (h *HumanName) UnmarshalJSON(data []byte) error {
+	type Alias HumanName
+	return decodeElement(data, (*Alias)(h), &h.Unknown, &h.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for HumanName, re-emitting primitive extensions and unrecognised members
+func (h HumanName) MarshalJSON() ([]byte, error) {
+	type Alias HumanName
+	return encodeElement(Alias(h), h.Unknown, h.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ContactPoint, retaining primitive extensions and unrecognised members
+func (c *ContactPoint) UnmarshalJSON(data []byte) error {
+	type Alias ContactPoint
+	return decodeElement(data, (*Alias)(c), &c.Unknown, &c.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ContactPoint, re-emitting primitive extensions and unrecognised members
+func (c ContactPoint) MarshalJSON() ([]byte, error) {
+	type Alias ContactPoint
+	return encodeElement(Alias(c), c.Unknown, c.Primitives)
+}
@@ -0,0 +1,31 @@
+package models
+
+// Binary represents a FHIR Binary resource, raw content such as a document
+// or image along with its media type
+type Binary struct {
+	Base
+	ContentType     string     `json:"contentType"`
+	SecurityContext *Reference `json:"securityContext,omitempty"`
+	Data            []byte     `json:"data,omitempty"`
+}
+
+// NewBinary creates a new Binary with the given content
+func NewBinary(contentType string, data []byte) *Binary {
+	return &Binary{
+		Base:        Base{ResourceType: ResourceTypeBinary},
+		ContentType: contentType,
+		Data:        data,
+	}
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Binary, retaining primitive extensions and unrecognised members
+func (b *Binary) UnmarshalJSON(data []byte) error {
+	type Alias Binary
+	return decodeElement(data, (*Alias)(b), &b.Unknown, &b.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for Binary, re-emitting primitive extensions and unrecognised members
+func (b Binary) MarshalJSON() ([]byte, error) {
+	type Alias Binary
+	return encodeElement(Alias(b), b.Unknown, b.Primitives)
+}
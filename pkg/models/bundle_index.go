@@ -0,0 +1,345 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Bundle search entry modes
+const (
+	SearchModeMatch   = "match"
+	SearchModeInclude = "include"
+	SearchModeOutcome = "outcome"
+)
+
+// ExtensionMatchGrade is the URL of the extension giving a $match candidate's match grade
+const ExtensionMatchGrade = "http://hl7.org/fhir/StructureDefinition/match-grade"
+
+// bundleTarget returns the fullUrl a reference points to within a bundle,
+// or "" when it can only be matched on type and id. Relative references are
+// relative to the server base of the referring entry's fullUrl.
+func bundleTarget(ref *ParsedReference, referrerFullURL string) string {
+	switch ref.Kind {
+	case ReferenceKindURN, ReferenceKindAbsolute:
+		return ref.Raw
+	case ReferenceKindRelative:
+		if referrer, err := ParseReference(referrerFullURL); err == nil && referrer.Kind == ReferenceKindAbsolute {
+			return referrer.BaseURL + "/" + ref.RelativeURL()
+		}
+	}
+	return ""
+}
+
+// entryIdentity returns the type, id and version of a bundle entry's resource
+func entryIdentity(entry *BundleEntry) (resourceType, id, version string) {
+	if r, ok := entry.resource.(baseResource); ok {
+		base := r.resourceBase()
+		if base.Meta != nil {
+			version = base.Meta.VersionID
+		}
+		return string(base.ResourceType), base.ID, version
+	}
+
+	var holder struct {
+		ResourceType string `json:"resourceType"`
+		ID           string `json:"id"`
+		Meta         *struct {
+			VersionID string `json:"versionId"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(entry.Resource, &holder); err != nil {
+		return "", "", ""
+	}
+	if holder.Meta != nil {
+		version = holder.Meta.VersionID
+	}
+	return holder.ResourceType, holder.ID, version
+}
+
+// FindEntry returns the entry a reference points to, following the FHIR
+// rules for resolving references in bundles. referrerFullURL is the fullUrl
+// of the entry the reference was found in. It returns nil when the bundle
+// has no such entry.
+func (b *Bundle) FindEntry(ref *ParsedReference, referrerFullURL string) *BundleEntry {
+	if ref.Kind != ReferenceKindURN && ref.Kind != ReferenceKindAbsolute && ref.Kind != ReferenceKindRelative {
+		return nil
+	}
+
+	target := bundleTarget(ref, referrerFullURL)
+	if target != "" {
+		for i := range b.Entry {
+			entry := &b.Entry[i]
+			if entry.FullURL == target {
+				return entry
+			}
+			if ref.Version != "" {
+				// A versioned reference matches the unversioned fullUrl of that version
+				unversioned := strings.TrimSuffix(target, "/_history/"+ref.Version)
+				if _, _, version := entryIdentity(entry); entry.FullURL == unversioned && version == ref.Version {
+					return entry
+				}
+			}
+		}
+		return nil
+	}
+
+	// Fall back to matching on type and id when the referrer has no RESTful fullUrl
+	if ref.Kind == ReferenceKindRelative {
+		for i := range b.Entry {
+			entry := &b.Entry[i]
+			resourceType, id, version := entryIdentity(entry)
+			if resourceType == ref.ResourceType && id == ref.ID && (ref.Version == "" || version == ref.Version) {
+				return entry
+			}
+		}
+	}
+	return nil
+}
+
+// indexedEntry is a decoded bundle entry and the references it makes
+type indexedEntry struct {
+	entry    *BundleEntry
+	resource Resource
+	version  string
+	refs     []pathReference
+}
+
+// pathReference is a reference found at a dotted element path within a resource
+type pathReference struct {
+	path string
+	ref  *ParsedReference
+}
+
+// BundleIndex links the entries of a bundle so that the resources brought
+// in by _include and _revinclude can be navigated without further requests
+type BundleIndex struct {
+	entries  []indexedEntry
+	byURL    map[string]int
+	byTypeID map[string]int
+	position map[Resource]int
+}
+
+// Index builds an index over the bundle's entries, decoding them as needed
+func (b *Bundle) Index() (*BundleIndex, error) {
+	return NewBundleIndex(b, defaultMapper)
+}
+
+// NewBundleIndex builds an index over the bundle's entries, decoding those
+// that have not been decoded yet with the types registered with mapper, and
+// those of other types as GenericResource
+func NewBundleIndex(b *Bundle, mapper *ResourceMapper) (*BundleIndex, error) {
+	mapper = mapper.WithGenericFallback()
+	idx := &BundleIndex{
+		byURL:    make(map[string]int),
+		byTypeID: make(map[string]int),
+		position: make(map[Resource]int),
+	}
+
+	for i := range b.Entry {
+		entry := &b.Entry[i]
+		if entry.resource == nil && entry.mapper == nil {
+			entry.mapper = mapper
+		}
+		resource, err := entry.GetResource()
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal bundle entry %d: %w", i, err)
+		}
+		if resource == nil {
+			continue
+		}
+
+		data := entry.Resource
+		if data == nil {
+			if data, err = json.Marshal(resource); err != nil {
+				return nil, fmt.Errorf("failed to marshal bundle entry %d: %w", i, err)
+			}
+		}
+		var tree map[string]interface{}
+		if err := json.Unmarshal(data, &tree); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal bundle entry %d: %w", i, err)
+		}
+		delete(tree, "contained")
+
+		resourceType, id, version := entryIdentity(entry)
+		ie := indexedEntry{entry: entry, resource: resource, version: version}
+		collectPathReferences(tree, "", &ie.refs)
+
+		pos := len(idx.entries)
+		idx.entries = append(idx.entries, ie)
+		idx.position[resource] = pos
+		if _, exists := idx.byURL[entry.FullURL]; entry.FullURL != "" && !exists {
+			idx.byURL[entry.FullURL] = pos
+		}
+		if resourceType != "" && id != "" {
+			if _, exists := idx.byTypeID[resourceType+"/"+id]; !exists {
+				idx.byTypeID[resourceType+"/"+id] = pos
+			}
+		}
+	}
+	return idx, nil
+}
+
+// collectPathReferences records every reference in a decoded JSON tree
+// together with the dotted path of the element holding it
+func collectPathReferences(node interface{}, path string, refs *[]pathReference) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if s, ok := v["reference"].(string); ok {
+			if ref, err := ParseReference(s); err == nil {
+				*refs = append(*refs, pathReference{path: path, ref: ref})
+			}
+		}
+		for key, child := range v {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			collectPathReferences(child, childPath, refs)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectPathReferences(child, path, refs)
+		}
+	}
+}
+
+// mode returns the search mode of an entry, treating entries without one as matches
+func (e indexedEntry) mode() string {
+	if e.entry.Search == nil || e.entry.Search.Mode == "" {
+		return SearchModeMatch
+	}
+	return e.entry.Search.Mode
+}
+
+// resourcesWithMode returns the resources of entries with the given search mode
+func (idx *BundleIndex) resourcesWithMode(mode string) []Resource {
+	var resources []Resource
+	for _, e := range idx.entries {
+		if e.mode() == mode {
+			resources = append(resources, e.resource)
+		}
+	}
+	return resources
+}
+
+// Matches returns the resources that matched the search, in bundle order
+func (idx *BundleIndex) Matches() []Resource {
+	return idx.resourcesWithMode(SearchModeMatch)
+}
+
+// Includes returns the resources added by _include or _revinclude, in bundle order
+func (idx *BundleIndex) Includes() []Resource {
+	return idx.resourcesWithMode(SearchModeInclude)
+}
+
+// Entry returns the bundle entry a resource from the index was decoded from
+func (idx *BundleIndex) Entry(resource Resource) *BundleEntry {
+	pos, ok := idx.position[resource]
+	if !ok {
+		return nil
+	}
+	return idx.entries[pos].entry
+}
+
+// lookup finds the position of the entry a reference points to
+func (idx *BundleIndex) lookup(ref *ParsedReference, referrerFullURL string) (int, bool) {
+	target := bundleTarget(ref, referrerFullURL)
+	if target != "" {
+		if pos, ok := idx.byURL[target]; ok {
+			return pos, true
+		}
+		if ref.Version != "" {
+			pos, ok := idx.byURL[strings.TrimSuffix(target, "/_history/"+ref.Version)]
+			if ok && idx.entries[pos].version == ref.Version {
+				return pos, true
+			}
+		}
+		return 0, false
+	}
+
+	if ref.Kind != ReferenceKindRelative {
+		return 0, false
+	}
+	pos, ok := idx.byTypeID[ref.ResourceType+"/"+ref.ID]
+	if !ok || (ref.Version != "" && idx.entries[pos].version != ref.Version) {
+		return 0, false
+	}
+	return pos, true
+}
+
+// Resolve returns the resource in the bundle that ref, found in from, points to
+func (idx *BundleIndex) Resolve(from Resource, ref Reference) (Resource, bool) {
+	parsed, err := ref.Parse()
+	if err != nil {
+		return nil, false
+	}
+	var referrerFullURL string
+	if entry := idx.Entry(from); entry != nil {
+		referrerFullURL = entry.FullURL
+	}
+	pos, ok := idx.lookup(parsed, referrerFullURL)
+	if !ok {
+		return nil, false
+	}
+	return idx.entries[pos].resource, true
+}
+
+// trimPath removes a leading resource type from an element path, so that
+// "Observation.subject" and "subject" are equivalent
+func trimPath(resourceType, path string) string {
+	if rest, ok := strings.CutPrefix(path, resourceType+"."); ok {
+		return rest
+	}
+	return path
+}
+
+// Referenced returns the resources in the bundle referenced from the element
+// at path in from, e.g. Referenced(observation, "subject")
+func (idx *BundleIndex) Referenced(from Resource, path string) []Resource {
+	pos, ok := idx.position[from]
+	if !ok {
+		return nil
+	}
+	e := idx.entries[pos]
+	path = trimPath(from.GetResourceType(), path)
+
+	var resources []Resource
+	for _, pr := range e.refs {
+		if pr.path != path {
+			continue
+		}
+		if target, ok := idx.lookup(pr.ref, e.entry.FullURL); ok {
+			resources = append(resources, idx.entries[target].resource)
+		}
+	}
+	return resources
+}
+
+// ReferencedBy returns the resources in the bundle that reference target,
+// e.g. ReferencedBy(patient, "Encounter", "subject"). An empty resourceType
+// or path matches any.
+func (idx *BundleIndex) ReferencedBy(target Resource, resourceType, path string) []Resource {
+	targetPos, ok := idx.position[target]
+	if !ok {
+		return nil
+	}
+
+	var resources []Resource
+	for _, e := range idx.entries {
+		if resourceType != "" && e.resource.GetResourceType() != resourceType {
+			continue
+		}
+		wantPath := trimPath(e.resource.GetResourceType(), path)
+		for _, pr := range e.refs {
+			if path != "" && pr.path != wantPath {
+				continue
+			}
+			if pos, ok := idx.lookup(pr.ref, e.entry.FullURL); ok && pos == targetPos {
+				resources = append(resources, e.resource)
+				break
+			}
+		}
+	}
+	return resources
+}
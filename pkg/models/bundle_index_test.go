@@ -0,0 +1,105 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const includeBundle = `{
+	"resourceType": "Bundle",
+	"type": "searchset",
+	"entry": [
+		{
+			"fullUrl": "http://example.org/fhir/Observation/o1",
+			"resource": {"resourceType": "Observation", "id": "o1", "subject": {"reference": "Patient/p1"}, "performer": [{"reference": "Practitioner/x"}, {"reference": "http://example.org/fhir/Patient/p2"}]},
+			"search": {"mode": "match"}
+		},
+		{
+			"fullUrl": "http://example.org/fhir/Observation/o2",
+			"resource": {"resourceType": "Observation", "id": "o2", "subject": {"reference": "Patient/p2"}},
+			"search": {"mode": "match"}
+		},
+		{
+			"fullUrl": "http://example.org/fhir/Patient/p1",
+			"resource": {"resourceType": "Patient", "id": "p1"},
+			"search": {"mode": "include"}
+		},
+		{
+			"fullUrl": "http://example.org/fhir/Patient/p2",
+			"resource": {"resourceType": "Patient", "id": "p2"},
+			"search": {"mode": "include"}
+		},
+		{
+			"fullUrl": "http://example.org/fhir/Encounter/e1",
+			"resource": {"resourceType": "Encounter", "id": "e1", "subject": {"reference": "Patient/p1"}},
+			"search": {"mode": "include"}
+		}
+	]
+}`
+
+func ids(resources []Resource) []string {
+	var out []string
+	for _, r := range resources {
+		out = append(out, r.GetResourceType()+"/"+r.(baseResource).resourceBase().ID)
+	}
+	return out
+}
+
+func equalIDs(got []Resource, want ...string) bool {
+	g := ids(got)
+	if len(g) != len(want) {
+		return false
+	}
+	for i := range g {
+		if g[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBundleIndex(t *testing.T) {
+	var bundle Bundle
+	if err := json.Unmarshal([]byte(includeBundle), &bundle); err != nil {
+		t.Fatalf("Failed to unmarshal bundle: %v", err)
+	}
+	idx, err := bundle.Index()
+	if err != nil {
+		t.Fatalf("Failed to index bundle: %v", err)
+	}
+
+	matches := idx.Matches()
+	if !equalIDs(matches, "Observation/o1", "Observation/o2") {
+		t.Errorf("Matches = %v", ids(matches))
+	}
+	if includes := idx.Includes(); !equalIDs(includes, "Patient/p1", "Patient/p2", "Encounter/e1") {
+		t.Errorf("Includes = %v", ids(includes))
+	}
+
+	o1 := matches[0]
+	subject := idx.Referenced(o1, "Observation.subject")
+	if !equalIDs(subject, "Patient/p1") {
+		t.Fatalf("Referenced(o1, subject) = %v", ids(subject))
+	}
+	if _, ok := subject[0].(*Patient); !ok {
+		t.Errorf("Expected *Patient, got %T", subject[0])
+	}
+	if performers := idx.Referenced(o1, "performer"); !equalIDs(performers, "Patient/p2") {
+		t.Errorf("Referenced(o1, performer) = %v", ids(performers))
+	}
+
+	p1 := subject[0]
+	if encounters := idx.ReferencedBy(p1, "Encounter", "subject"); !equalIDs(encounters, "Encounter/e1") {
+		t.Errorf("ReferencedBy(p1, Encounter) = %v", ids(encounters))
+	}
+	if all := idx.ReferencedBy(p1, "", ""); !equalIDs(all, "Observation/o1", "Encounter/e1") {
+		t.Errorf("ReferencedBy(p1) = %v", ids(all))
+	}
+
+	if resolved, ok := idx.Resolve(matches[1], Reference{Reference: "Patient/p2"}); !ok || resolved.(baseResource).resourceBase().ID != "p2" {
+		t.Errorf("Resolve(Patient/p2) = %v, %v", resolved, ok)
+	}
+	if _, ok := idx.Resolve(o1, Reference{Reference: "Practitioner/x"}); ok {
+		t.Error("Expected Practitioner/x not to resolve")
+	}
+}
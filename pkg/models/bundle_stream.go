@@ -0,0 +1,202 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// BundleDecoder reads a Bundle from a stream one entry at a time, so that
+// large bundles can be processed without holding the whole response in memory
+type BundleDecoder struct {
+	dec    *json.Decoder
+	mapper *ResourceMapper
+
+	// members holds the bundle's members other than entry, in stream order
+	members map[string]json.RawMessage
+
+	started  bool
+	inEntry  bool
+	finished bool
+	count    int
+	err      error
+}
+
+// NewBundleDecoder creates a decoder reading a Bundle from r. Entries are
+// decoded lazily with the types registered with mapper, or the standard
+// types if it is nil; entries of other types are decoded as GenericResource.
+func NewBundleDecoder(r io.Reader, mapper *ResourceMapper) *BundleDecoder {
+	if mapper == nil {
+		mapper = defaultMapper
+	}
+	return &BundleDecoder{
+		dec:     json.NewDecoder(r),
+		mapper:  mapper.WithGenericFallback(),
+		members: make(map[string]json.RawMessage),
+	}
+}
+
+// Next returns the next entry of the bundle. It returns io.EOF once all
+// entries have been read and the rest of the bundle has been consumed.
+func (d *BundleDecoder) Next() (*BundleEntry, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	entry, err := d.next()
+	if err != nil {
+		d.err = err
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (d *BundleDecoder) next() (*BundleEntry, error) {
+	if !d.started {
+		if err := d.expectDelim('{'); err != nil {
+			return nil, err
+		}
+		d.started = true
+	}
+
+	for {
+		if d.inEntry {
+			if d.dec.More() {
+				entry := &BundleEntry{}
+				if err := d.dec.Decode(entry); err != nil {
+					return nil, fmt.Errorf("failed to decode bundle entry %d: %w", d.count, err)
+				}
+				entry.mapper = d.mapper
+				d.count++
+				return entry, nil
+			}
+			if err := d.expectDelim(']'); err != nil {
+				return nil, err
+			}
+			d.inEntry = false
+		}
+
+		if !d.dec.More() {
+			if err := d.expectDelim('}'); err != nil {
+				return nil, err
+			}
+			d.finished = true
+			if err := d.checkResourceType(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+
+		name, err := d.memberName()
+		if err != nil {
+			return nil, err
+		}
+		if name == "entry" {
+			if err := d.checkResourceType(); err != nil {
+				return nil, err
+			}
+			if err := d.expectDelim('['); err != nil {
+				return nil, err
+			}
+			d.inEntry = true
+			continue
+		}
+
+		var value json.RawMessage
+		if err := d.dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("failed to decode bundle member %q: %w", name, err)
+		}
+		d.members[name] = value
+	}
+}
+
+// memberName reads the name of the next member of the bundle object
+func (d *BundleDecoder) memberName() (string, error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return "", fmt.Errorf("failed to read bundle: %w", err)
+	}
+	name, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("failed to read bundle: unexpected %v", tok)
+	}
+	return name, nil
+}
+
+// expectDelim reads the next token and checks it is the given delimiter
+func (d *BundleDecoder) expectDelim(delim json.Delim) error {
+	tok, err := d.dec.Token()
+	if err == io.EOF {
+		return fmt.Errorf("failed to read bundle: %w", io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+	if tok != delim {
+		return fmt.Errorf("failed to read bundle: expected %v, got %v", delim, tok)
+	}
+	return nil
+}
+
+// checkResourceType rejects streams that are not Bundles, once resourceType
+// has been seen or the bundle has ended
+func (d *BundleDecoder) checkResourceType() error {
+	raw, ok := d.members["resourceType"]
+	if !ok {
+		if d.finished {
+			return fmt.Errorf("failed to read bundle: resourceType is missing")
+		}
+		return nil
+	}
+	var resourceType string
+	if err := json.Unmarshal(raw, &resourceType); err != nil || resourceType != "Bundle" {
+		return fmt.Errorf("failed to read bundle: resourceType is %s, not Bundle", raw)
+	}
+	return nil
+}
+
+// Bundle returns the bundle's members other than its entries. Members that
+// follow the entries in the stream are only included once Next has returned
+// io.EOF.
+func (d *BundleDecoder) Bundle() (*Bundle, error) {
+	data, err := json.Marshal(d.members)
+	if err != nil {
+		return nil, err
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bundle: %w", err)
+	}
+	return &bundle, nil
+}
+
+// Count returns the number of entries read so far
+func (d *BundleDecoder) Count() int {
+	return d.count
+}
+
+// DecodeBundle reads a whole Bundle from r, decoding each entry's resource
+// once with mapper
+func (m *ResourceMapper) DecodeBundle(r io.Reader) (*Bundle, error) {
+	d := NewBundleDecoder(r, m)
+	var entries []BundleEntry
+	for {
+		entry, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := entry.GetResource(); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal bundle entry %d: %w", len(entries), err)
+		}
+		entries = append(entries, *entry)
+	}
+
+	bundle, err := d.Bundle()
+	if err != nil {
+		return nil, err
+	}
+	bundle.Entry = entries
+	return bundle, nil
+}
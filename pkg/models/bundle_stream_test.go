@@ -0,0 +1,87 @@
+package models
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBundleDecoder(t *testing.T) {
+	data := `{
+		"resourceType": "Bundle",
+		"type": "searchset",
+		"entry": [
+			{"fullUrl": "http://example.com/Patient/1", "resource": {"resourceType": "Patient", "id": "1"}},
+			{"fullUrl": "http://example.com/Basic/2", "resource": {"resourceType": "Basic", "id": "2"}, "search": {"mode": "include"}}
+		],
+		"total": 2,
+		"link": [{"relation": "next", "url": "http://example.com/Patient?page=2"}]
+	}`
+
+	d := NewBundleDecoder(strings.NewReader(data), nil)
+	var got []string
+	for {
+		entry, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		resource, err := entry.GetResource()
+		if err != nil {
+			t.Fatalf("GetResource failed: %v", err)
+		}
+		got = append(got, resource.GetResourceType())
+	}
+	if strings.Join(got, ",") != "Patient,Basic" || d.Count() != 2 {
+		t.Errorf("Expected Patient,Basic, got %v", got)
+	}
+	if _, err := d.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last entry, got %v", err)
+	}
+
+	bundle, err := d.Bundle()
+	if err != nil {
+		t.Fatalf("Bundle failed: %v", err)
+	}
+	if bundle.Type != "searchset" || bundle.Total == nil || *bundle.Total != 2 || len(bundle.Link) != 1 {
+		t.Errorf("Unexpected bundle members: %+v", bundle)
+	}
+	if len(bundle.Entry) != 0 {
+		t.Errorf("Expected no entries in Bundle(), got %d", len(bundle.Entry))
+	}
+}
+
+func TestBundleDecoderErrors(t *testing.T) {
+	tests := map[string]string{
+		"not a bundle": `{"resourceType": "Patient", "entry": []}`,
+		"no type":      `{"type": "searchset"}`,
+		"truncated":    `{"resourceType": "Bundle", "entry": [{"fullUrl": "a"}`,
+		"not object":   `[]`,
+	}
+	for name, data := range tests {
+		d := NewBundleDecoder(strings.NewReader(data), nil)
+		var err error
+		for err == nil {
+			_, err = d.Next()
+		}
+		if err == io.EOF {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDecodeBundle(t *testing.T) {
+	bundle, err := NewResourceMapper().DecodeBundle(strings.NewReader(
+		`{"resourceType": "Bundle", "type": "history", "entry": [{"resource": {"resourceType": "Patient", "id": "1"}}]}`))
+	if err != nil {
+		t.Fatalf("DecodeBundle failed: %v", err)
+	}
+	if bundle.Type != "history" || len(bundle.Entry) != 1 {
+		t.Fatalf("Unexpected bundle: %+v", bundle)
+	}
+	if _, ok := bundle.Entry[0].resource.(*Patient); !ok {
+		t.Errorf("Expected the entry to be decoded, got %T", bundle.Entry[0].resource)
+	}
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+)
+
+// CodeSystem represents a FHIR CodeSystem resource, which declares a code
+// system and usually lists its concepts
+type CodeSystem struct {
+	Base
+	URL              string               `json:"url,omitempty"`
+	Identifier       []Identifier         `json:"identifier,omitempty"`
+	Version          string               `json:"version,omitempty"`
+	Name             string               `json:"name,omitempty"`
+	Title            string               `json:"title,omitempty"`
+	Status           string               `json:"status"`
+	Experimental     *bool                `json:"experimental,omitempty"`
+	Date             *fhir.DateTime       `json:"date,omitempty"`
+	Publisher        string               `json:"publisher,omitempty"`
+	Description      string               `json:"description,omitempty"`
+	CaseSensitive    *bool                `json:"caseSensitive,omitempty"`
+	ValueSet         string               `json:"valueSet,omitempty"`
+	HierarchyMeaning string               `json:"hierarchyMeaning,omitempty"`
+	Content          string               `json:"content"`
+	Supplements      string               `json:"supplements,omitempty"`
+	Count            *int                 `json:"count,omitempty"`
+	Property         []CodeSystemProperty `json:"property,omitempty"`
+	Concept          []CodeSystemConcept  `json:"concept,omitempty"`
+}
+
+// CodeSystemProperty declares a property that concepts in the code system may have
+type CodeSystemProperty struct {
+	Code        string                     `json:"code"`
+	URI         string                     `json:"uri,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Type        string                     `json:"type"`
+	Unknown     map[string]json.RawMessage `json:"-"`
+	Primitives  PrimitiveElements          `json:"-"`
+}
+
+// CodeSystemConcept is a concept in a code system. Concept nests the
+// concepts below it in the hierarchy.
+type CodeSystemConcept struct {
+	Code        string                      `json:"code"`
+	Display     string                      `json:"display,omitempty"`
+	Definition  string                      `json:"definition,omitempty"`
+	Designation []ValueSetDesignation       `json:"designation,omitempty"`
+	Property    []CodeSystemConceptProperty `json:"property,omitempty"`
+	Concept     []CodeSystemConcept         `json:"concept,omitempty"`
+	Unknown     map[string]json.RawMessage  `json:"-"`
+	Primitives  PrimitiveElements           `json:"-"`
+}
+
+// CodeSystemConceptProperty is the value of a property for a concept
+type CodeSystemConceptProperty struct {
+	Code          string                     `json:"code"`
+	ValueCode     *string                    `json:"valueCode,omitempty"`
+	ValueCoding   *Coding                    `json:"valueCoding,omitempty"`
+	ValueString   *string                    `json:"valueString,omitempty"`
+	ValueInteger  *int                       `json:"valueInteger,omitempty"`
+	ValueBoolean  *bool                      `json:"valueBoolean,omitempty"`
+	ValueDateTime *fhir.DateTime             `json:"valueDateTime,omitempty"`
+	ValueDecimal  *fhir.Decimal              `json:"valueDecimal,omitempty"`
+	Unknown       map[string]json.RawMessage `json:"-"`
+	Primitives    PrimitiveElements          `json:"-"`
+}
+
+// NewCodeSystem creates a new CodeSystem with the required fields
+func NewCodeSystem() *CodeSystem {
+	return &CodeSystem{
+		Base: Base{
+			ResourceType: ResourceTypeCodeSystem,
+		},
+		Status:  "active",
+		Content: "complete",
+	}
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for CodeSystem, retaining primitive extensions and unrecognised members
+func (c *CodeSystem) UnmarshalJSON(data []byte) error {
+	type Alias CodeSystem
+	return decodeElement(data, (*Alias)(c), &c.Unknown, &c.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for CodeSystem, re-emitting primitive extensions and unrecognised members
+func (c CodeSystem) MarshalJSON() ([]byte, error) {
+	type Alias CodeSystem
+	return encodeElement(Alias(c), c.Unknown, c.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for CodeSystemProperty, retaining primitive extensions and unrecognised members
+func (p *CodeSystemProperty) UnmarshalJSON(data []byte) error {
+	type Alias CodeSystemProperty
+	return decodeElement(data, (*Alias)(p), &p.Unknown, &p.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for CodeSystemProperty, re-emitting primitive extensions and unrecognised members
+func (p CodeSystemProperty) MarshalJSON() ([]byte, error) {
+	type Alias CodeSystemProperty
+	return encodeElement(Alias(p), p.Unknown, p.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for CodeSystemConcept, retaining primitive extensions and unrecognised members
+func (c *CodeSystemConcept) UnmarshalJSON(data []byte) error {
+	type Alias CodeSystemConcept
+	return decodeElement(data, (*Alias)(c), &c.Unknown, &c.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for CodeSystemConcept, re-emitting primitive extensions and unrecognised members
+func (c CodeSystemConcept) MarshalJSON() ([]byte, error) {
+	type Alias CodeSystemConcept
+	return encodeElement(Alias(c), c.Unknown, c.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for CodeSystemConceptProperty, retaining primitive extensions and unrecognised members
+func (p *CodeSystemConceptProperty) UnmarshalJSON(data []byte) error {
+	type Alias CodeSystemConceptProperty
+	return decodeElement(data, (*Alias)(p), &p.Unknown, &p.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for CodeSystemConceptProperty, re-emitting primitive extensions and unrecognised members
+func (p CodeSystemConceptProperty) MarshalJSON() ([]byte, error) {
+	type Alias CodeSystemConceptProperty
+	return encodeElement(Alias(p), p.Unknown, p.Primitives)
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// defaultMapper decodes contained resources, and the resources of Bundle
+// entries and Parameters read without a mapper. Types it does not know are
+// decoded as GenericResource, so that the container can still be read; a
+// ResourceMapper with further registrations upgrades contained resources
+// when it decodes the container.
+var defaultMapper = NewResourceMapper().WithGenericFallback()
+
+// ContainedResources holds the resources contained in another resource
+type ContainedResources []Resource
+
+// UnmarshalJSON decodes each contained resource through the resource mapper
+func (c *ContainedResources) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*c = nil
+		return nil
+	}
+
+	resources := make(ContainedResources, 0, len(raw))
+	for i, r := range raw {
+		resource, err := defaultMapper.UnmarshalResource(r)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal contained resource %d: %w", i, err)
+		}
+		resources = append(resources, resource)
+	}
+	*c = resources
+	return nil
+}
+
+// ContainedOf returns the resources contained in the given resource
+func ContainedOf(resource Resource) []Resource {
+	r, ok := resource.(baseResource)
+	if !ok {
+		return nil
+	}
+	return r.resourceBase().Contained
+}
+
+// ResolveContained returns the resource that a local reference such as
+// "#med1" points to within container. The reference "#" resolves to the
+// container itself.
+func ResolveContained(container Resource, reference string) (Resource, bool) {
+	id, ok := strings.CutPrefix(reference, "#")
+	if !ok {
+		return nil, false
+	}
+	if id == "" {
+		return container, container != nil
+	}
+
+	for _, contained := range ContainedOf(container) {
+		if r, ok := contained.(baseResource); ok && r.resourceBase().ID == id {
+			return contained, true
+		}
+	}
+	return nil, false
+}
+
+// ResolveReference resolves ref against the resources contained in container
+func ResolveReference(container Resource, ref Reference) (Resource, bool) {
+	return ResolveContained(container, ref.Reference)
+}
+
+// ValidateContained checks the FHIR rules for contained resources: each must
+// have an id that is unique within the container, must not itself contain
+// resources or carry a version or last-updated time, and must be referenced
+// from the container or from another contained resource (or refer to the
+// container with "#").
+func ValidateContained(container Resource) error {
+	contained := ContainedOf(container)
+	if len(contained) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(container)
+	if err != nil {
+		return fmt.Errorf("failed to marshal container: %w", err)
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return fmt.Errorf("failed to unmarshal container: %w", err)
+	}
+
+	// References from the container itself, excluding its contained resources
+	containedTrees, _ := tree["contained"].([]interface{})
+	delete(tree, "contained")
+	referenced := make(map[string]bool)
+	collectReferences(tree, referenced)
+
+	// References made by each contained resource
+	containedRefs := make([]map[string]bool, len(containedTrees))
+	for i, ct := range containedTrees {
+		containedRefs[i] = make(map[string]bool)
+		collectReferences(ct, containedRefs[i])
+	}
+
+	var errs []error
+	seen := make(map[string]bool)
+	for i, resource := range contained {
+		r, ok := resource.(baseResource)
+		if !ok {
+			continue
+		}
+		base := r.resourceBase()
+		label := fmt.Sprintf("contained resource %d (%s)", i, base.ResourceType)
+
+		if base.ID == "" {
+			errs = append(errs, fmt.Errorf("%s has no id", label))
+			continue
+		}
+		if seen[base.ID] {
+			errs = append(errs, fmt.Errorf("%s has duplicate id %q", label, base.ID))
+		}
+		seen[base.ID] = true
+
+		if len(base.Contained) > 0 {
+			errs = append(errs, fmt.Errorf("%s must not contain other resources", label))
+		}
+		if base.Meta != nil && (base.Meta.VersionID != "" || base.Meta.LastUpdated != nil) {
+			errs = append(errs, fmt.Errorf("%s must not have meta.versionId or meta.lastUpdated", label))
+		}
+
+		ref := "#" + base.ID
+		found := referenced[ref] || (i < len(containedRefs) && containedRefs[i]["#"])
+		for j, refs := range containedRefs {
+			if found {
+				break
+			}
+			found = j != i && refs[ref]
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("%s %q is not referenced from its container", label, base.ID))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// collectReferences adds every local reference found in a decoded JSON tree to refs
+func collectReferences(node interface{}, refs map[string]bool) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if s, ok := child.(string); ok && key == "reference" && strings.HasPrefix(s, "#") {
+				refs[s] = true
+				continue
+			}
+			collectReferences(child, refs)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectReferences(child, refs)
+		}
+	}
+}
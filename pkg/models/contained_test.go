@@ -0,0 +1,125 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const patientWithContained = `{
+	"resourceType": "Patient",
+	"id": "p1",
+	"contained": [
+		{"resourceType": "Practitioner", "id": "gp", "name": [{"family": "Careful"}]},
+		{"resourceType": "Patient", "id": "mother", "name": [{"family": "Doe"}]}
+	],
+	"generalPractitioner": [{"reference": "#gp"}],
+	"link": [{"other": {"reference": "#mother"}, "type": "seealso"}]
+}`
+
+func TestUnsupportedResourceType(t *testing.T) {
+	data := []byte(`{"resourceType": "Observation", "id": "o1", "status": "final"}`)
+	if _, err := NewResourceMapper().UnmarshalResource(data); err == nil || !strings.Contains(err.Error(), "unsupported resource type: Observation") {
+		t.Errorf("Expected an unsupported resource type error, got %v", err)
+	}
+
+	m := NewResourceMapper()
+	m.SetGenericFallback(true)
+	for _, mapper := range []*ResourceMapper{m, NewResourceMapper().WithGenericFallback()} {
+		resource, err := mapper.UnmarshalResource(data)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal with the generic fallback: %v", err)
+		}
+		if generic, ok := resource.(*GenericResource); !ok || generic.ResourceType != "Observation" {
+			t.Errorf("Expected a GenericResource, got %T", resource)
+		}
+	}
+
+	// Bundle entries of unregistered types are still read
+	bundle, err := NewResourceMapper().UnmarshalBundle([]byte(`{"resourceType": "Bundle", "type": "searchset", "entry": [{"resource": ` + string(data) + `}]}`))
+	if err != nil {
+		t.Fatalf("Failed to unmarshal bundle: %v", err)
+	}
+	if resource, _ := bundle.Entry[0].GetResource(); resource.GetResourceType() != "Observation" {
+		t.Errorf("Unexpected entry %v", resource)
+	}
+}
+
+func TestContainedResourcesAreTyped(t *testing.T) {
+	resource, err := NewResourceMapper().UnmarshalResource([]byte(patientWithContained))
+	if err != nil {
+		t.Fatalf("Failed to unmarshal patient: %v", err)
+	}
+	patient := resource.(*Patient)
+
+	if len(patient.Contained) != 2 {
+		t.Fatalf("Expected 2 contained resources, got %d", len(patient.Contained))
+	}
+	if _, ok := patient.Contained[0].(*GenericResource); !ok {
+		t.Errorf("Expected unregistered Practitioner to decode as GenericResource, got %T", patient.Contained[0])
+	}
+	if _, ok := patient.Contained[1].(*Patient); !ok {
+		t.Errorf("Expected contained Patient to decode as *Patient, got %T", patient.Contained[1])
+	}
+
+	mother, ok := ResolveReference(patient, patient.Link[0].Other)
+	if !ok {
+		t.Fatal("Expected #mother to resolve")
+	}
+	if mother.(*Patient).Name[0].Family != "Doe" {
+		t.Errorf("Expected contained mother with family Doe, got %+v", mother)
+	}
+	if self, ok := ResolveContained(patient, "#"); !ok || self != Resource(patient) {
+		t.Error("Expected # to resolve to the container")
+	}
+	if _, ok := ResolveContained(patient, "#missing"); ok {
+		t.Error("Expected #missing not to resolve")
+	}
+	if _, ok := ResolveContained(patient, "Patient/mother"); ok {
+		t.Error("Expected a non-local reference not to resolve")
+	}
+
+	// Contained resources, typed or generic, are written back unchanged
+	data, err := json.Marshal(patient)
+	if err != nil {
+		t.Fatalf("Failed to marshal patient: %v", err)
+	}
+	if !strings.Contains(string(data), `{"resourceType":"Practitioner","id":"gp","name":[{"family":"Careful"}]}`) {
+		t.Errorf("Expected contained Practitioner to round-trip, got %s", data)
+	}
+}
+
+func TestContainedUsesMapperRegistrations(t *testing.T) {
+	mapper := NewResourceMapper()
+	mapper.RegisterResource("Practitioner", func() Resource { return &Patient{} })
+
+	resource, err := mapper.UnmarshalResource([]byte(patientWithContained))
+	if err != nil {
+		t.Fatalf("Failed to unmarshal patient: %v", err)
+	}
+	if _, ok := ContainedOf(resource)[0].(*Patient); !ok {
+		t.Errorf("Expected contained resource to use the mapper's registration, got %T", ContainedOf(resource)[0])
+	}
+}
+
+func TestValidateContained(t *testing.T) {
+	var patient Patient
+	if err := json.Unmarshal([]byte(patientWithContained), &patient); err != nil {
+		t.Fatalf("Failed to unmarshal patient: %v", err)
+	}
+	if err := ValidateContained(&patient); err != nil {
+		t.Errorf("Expected valid contained resources, got %v", err)
+	}
+
+	patient.Link = nil
+	err := ValidateContained(&patient)
+	if err == nil || !strings.Contains(err.Error(), `"mother" is not referenced`) {
+		t.Errorf("Expected unreferenced contained error, got %v", err)
+	}
+
+	orphan := NewPatient()
+	orphan.Contained = []Resource{&GenericResource{Base: Base{ResourceType: "Medication"}}}
+	if err := ValidateContained(orphan); err == nil || !strings.Contains(err.Error(), "has no id") {
+		t.Errorf("Expected missing id error, got %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+// GenericResource holds a resource of a type that has no dedicated model.
+// Everything other than the Base elements is kept in Unknown, so the
+// resource is written back out unchanged.
+type GenericResource struct {
+	Base
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for GenericResource
+func (g *GenericResource) UnmarshalJSON(data []byte) error {
+	type Alias GenericResource
+	return decodeElement(data, (*Alias)(g), &g.Unknown, &g.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for GenericResource
+func (g GenericResource) MarshalJSON() ([]byte, error) {
+	type Alias GenericResource
+	return encodeElement(Alias(g), g.Unknown, g.Primitives)
+}
@@ -0,0 +1,245 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// jsonField describes a JSON member decoded by a model struct
+type jsonField struct {
+	// index is the path of the struct field, as for reflect.Value.FieldByIndex
+	index []int
+
+	// repeating is true when the member is a JSON array
+	repeating bool
+}
+
+// jsonFieldCache caches the JSON members of struct types used by the element codec
+var jsonFieldCache sync.Map
+
+// jsonFields returns the JSON members that the given struct type (or pointer
+// to struct) decodes, including members of embedded structs
+func jsonFields(t reflect.Type) map[string]jsonField {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if cached, ok := jsonFieldCache.Load(t); ok {
+		return cached.(map[string]jsonField)
+	}
+
+	fields := make(map[string]jsonField)
+	collectJSONFields(t, nil, fields)
+	jsonFieldCache.Store(t, fields)
+	return fields
+}
+
+// collectJSONFields adds the members of t, whose fields are at index within
+// the outer struct, to fields. Direct fields are added before those of
+// embedded structs so that shallower fields shadow deeper ones, matching
+// encoding/json.
+func collectJSONFields(t reflect.Type, index []int, fields map[string]jsonField) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded = append(embedded, f)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = jsonField{
+			index:     append(append([]int(nil), index...), i),
+			repeating: ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8,
+		}
+	}
+
+	for _, f := range embedded {
+		et := f.Type
+		for et.Kind() == reflect.Ptr {
+			et = et.Elem()
+		}
+		inner := make(map[string]jsonField)
+		collectJSONFields(et, append(append([]int(nil), index...), f.Index...), inner)
+		for name, f := range inner {
+			if _, ok := fields[name]; !ok {
+				fields[name] = f
+			}
+		}
+	}
+}
+
+// decodeElement unmarshals data into v, which must be a pointer to an alias of
+// a model struct. The data is split into its members once; members v has a
+// field for are decoded into the field, members it has no field for are
+// stored in unknown, and "_name" siblings of v's fields are decoded into
+// primitives.
+func decodeElement(data []byte, v interface{}, unknown *map[string]json.RawMessage, primitives *PrimitiveElements) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+
+	target := reflect.ValueOf(v).Elem()
+	known := jsonFields(target.Type())
+	*unknown = nil
+	*primitives = nil
+	for name, raw := range members {
+		if field, ok := known[name]; ok {
+			if err := json.Unmarshal(raw, fieldByIndex(target, field.index).Addr().Interface()); err != nil {
+				return err
+			}
+			continue
+		}
+		if field, ok := known[strings.TrimPrefix(name, "_")]; ok && strings.HasPrefix(name, "_") {
+			if elements, ok := decodePrimitiveElements(raw, field.repeating); ok {
+				if *primitives == nil {
+					*primitives = make(PrimitiveElements)
+				}
+				(*primitives)[name[1:]] = elements
+				continue
+			}
+		}
+		if *unknown == nil {
+			*unknown = make(map[string]json.RawMessage)
+		}
+		(*unknown)[name] = raw
+	}
+	return nil
+}
+
+// fieldByIndex returns the field of v at index, allocating the embedded
+// structs on the way that are nil pointers
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// decodePrimitiveElements decodes the value of a "_name" member. It reports
+// false when the value is not of the shape FHIR prescribes, in which case the
+// member is treated as unknown and preserved verbatim.
+func decodePrimitiveElements(raw json.RawMessage, repeating bool) ([]*Element, bool) {
+	if repeating {
+		var elements []*Element
+		if err := json.Unmarshal(raw, &elements); err != nil {
+			return nil, false
+		}
+		return elements, true
+	}
+
+	var element Element
+	if err := json.Unmarshal(raw, &element); err != nil {
+		return nil, false
+	}
+	return []*Element{&element}, true
+}
+
+// encodeElement marshals v, an alias of a model struct, and appends the
+// "_name" siblings for primitives and the unknown members captured when the
+// element was decoded
+func encodeElement(v interface{}, unknown map[string]json.RawMessage, primitives PrimitiveElements) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || (len(unknown) == 0 && len(primitives) == 0) {
+		return data, err
+	}
+
+	known := jsonFields(reflect.TypeOf(v))
+	extra := make(map[string]json.RawMessage, len(unknown)+len(primitives))
+	for name, raw := range unknown {
+		if _, ok := known[name]; !ok && len(raw) > 0 {
+			extra[name] = raw
+		}
+	}
+	for name, elements := range primitives {
+		raw, err := encodePrimitiveElements(elements, known[name].repeating)
+		if err != nil {
+			return nil, err
+		}
+		if raw != nil {
+			extra["_"+name] = raw
+		}
+	}
+	if len(extra) == 0 {
+		return data, nil
+	}
+
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.Grow(len(data) + 64*len(names))
+	buf.Write(data[:len(data)-1])
+	empty := bytes.Equal(bytes.TrimSpace(data), []byte("{}"))
+	for _, name := range names {
+		if !empty {
+			buf.WriteByte(',')
+		}
+		empty = false
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(extra[name])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// encodePrimitiveElements encodes the value of a "_name" member, returning nil
+// when no element carries an id or extensions
+func encodePrimitiveElements(elements []*Element, repeating bool) (json.RawMessage, error) {
+	last := -1
+	for i, element := range elements {
+		if !element.IsEmpty() {
+			last = i
+		}
+	}
+	if last < 0 {
+		return nil, nil
+	}
+
+	if !repeating {
+		if elements[0].IsEmpty() {
+			return nil, nil
+		}
+		return json.Marshal(elements[0])
+	}
+
+	aligned := make([]*Element, last+1)
+	for i, element := range elements[:last+1] {
+		if !element.IsEmpty() {
+			aligned[i] = element
+		}
+	}
+	return json.Marshal(aligned)
+}
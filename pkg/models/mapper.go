@@ -7,7 +7,8 @@ import (
 
 // ResourceMapper handles conversion between JSON and FHIR resource structs
 type ResourceMapper struct {
-	typeRegistry map[ResourceType]func() Resource
+	typeRegistry    map[ResourceType]func() Resource
+	genericFallback bool
 }
 
 // NewResourceMapper creates a new resource mapper with default resource types
@@ -18,6 +19,13 @@ func NewResourceMapper() *ResourceMapper {
 
 	// Register default resource types
 	m.RegisterResource(ResourceTypePatient, func() Resource { return NewPatient() })
+	m.RegisterResource(ResourceTypeOperationOutcome, func() Resource { return NewOperationOutcome() })
+	m.RegisterResource(ResourceTypeParameters, func() Resource { return NewParameters() })
+	m.RegisterResource(ResourceTypeStructureDefinition, func() Resource { return NewStructureDefinition() })
+	m.RegisterResource(ResourceTypeCodeSystem, func() Resource { return NewCodeSystem() })
+	m.RegisterResource(ResourceTypeValueSet, func() Resource { return NewValueSet() })
+	m.RegisterResource(ResourceTypeBinary, func() Resource { return NewBinary("", nil) })
+	m.RegisterResource(ResourceTypeBundle, func() Resource { return &Bundle{Base: Base{ResourceType: ResourceTypeBundle}} })
 	// Add more resource types as they are implemented
 
 	return m
@@ -28,7 +36,25 @@ func (m *ResourceMapper) RegisterResource(resourceType ResourceType, factory fun
 	m.typeRegistry[resourceType] = factory
 }
 
-// UnmarshalResource converts JSON data to a FHIR resource
+// SetGenericFallback sets whether resources of unregistered types are
+// decoded as *GenericResource, keeping all of their content, instead of
+// being refused as unsupported
+func (m *ResourceMapper) SetGenericFallback(enabled bool) {
+	m.genericFallback = enabled
+}
+
+// WithGenericFallback returns a mapper with the same registrations that
+// decodes resources of unregistered types as *GenericResource
+func (m *ResourceMapper) WithGenericFallback() *ResourceMapper {
+	if m.genericFallback {
+		return m
+	}
+	return &ResourceMapper{typeRegistry: m.typeRegistry, genericFallback: true}
+}
+
+// UnmarshalResource converts JSON data to a FHIR resource. Resources of
+// unregistered types are refused unless SetGenericFallback is enabled; the
+// entries of a Bundle are decoded with the fallback either way.
 func (m *ResourceMapper) UnmarshalResource(data []byte) (Resource, error) {
 	// First unmarshal just the resource type
 	var typeHolder struct {
@@ -39,10 +65,17 @@ func (m *ResourceMapper) UnmarshalResource(data []byte) (Resource, error) {
 		return nil, fmt.Errorf("failed to determine resource type: %w", err)
 	}
 
+	if typeHolder.ResourceType == "" {
+		return nil, fmt.Errorf("failed to determine resource type: resourceType is missing")
+	}
+
 	// Get the factory function for this resource type
 	factory, ok := m.typeRegistry[typeHolder.ResourceType]
 	if !ok {
-		return nil, fmt.Errorf("unsupported resource type: %s", typeHolder.ResourceType)
+		if !m.genericFallback {
+			return nil, fmt.Errorf("unsupported resource type: %s", typeHolder.ResourceType)
+		}
+		factory = func() Resource { return &GenericResource{} }
 	}
 
 	// Create a new instance of the resource
@@ -53,35 +86,84 @@ func (m *ResourceMapper) UnmarshalResource(data []byte) (Resource, error) {
 		return nil, fmt.Errorf("failed to unmarshal resource: %w", err)
 	}
 
+	if err := m.typeContained(resource); err != nil {
+		return nil, err
+	}
+
+	// The entries of a Bundle are decoded with this mapper's registrations
+	if bundle, ok := resource.(*Bundle); ok {
+		entries := m.WithGenericFallback()
+		for i := range bundle.Entry {
+			bundle.Entry[i].mapper = entries
+		}
+	}
+
 	return resource, nil
 }
 
-// UnmarshalBundle converts a FHIR Bundle JSON to a Bundle struct with typed resources
-func (m *ResourceMapper) UnmarshalBundle(data []byte) (*Bundle, error) {
-	var bundle Bundle
-	if err := json.Unmarshal(data, &bundle); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal bundle: %w", err)
+// typeContained re-decodes contained resources that were decoded generically
+// but whose type is registered with this mapper
+func (m *ResourceMapper) typeContained(resource Resource) error {
+	r, ok := resource.(baseResource)
+	if !ok {
+		return nil
 	}
 
-	// Process each entry in the bundle
-	for i, entry := range bundle.Entry {
-		if entry.Resource == nil {
+	contained := r.resourceBase().Contained
+	for i, c := range contained {
+		generic, ok := c.(*GenericResource)
+		if !ok {
+			continue
+		}
+		if _, registered := m.typeRegistry[generic.ResourceType]; !registered {
 			continue
 		}
 
-		// Convert the raw resource to a typed resource
-		resource, err := m.UnmarshalResource(entry.Resource)
+		data, err := json.Marshal(generic)
 		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal bundle entry %d: %w", i, err)
+			return fmt.Errorf("failed to marshal contained resource %d: %w", i, err)
 		}
-
-		// Re-marshal the typed resource
-		typedJSON, err := json.Marshal(resource)
+		typed, err := m.UnmarshalResource(data)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal typed resource %d: %w", i, err)
+			return fmt.Errorf("failed to unmarshal contained resource %d: %w", i, err)
 		}
+		contained[i] = typed
+	}
+	return nil
+}
+
+// UnmarshalBundle converts a FHIR Bundle JSON to a Bundle struct with typed
+// resources. Each entry's resource is decoded once and is available from
+// BundleEntry.GetResource.
+func (m *ResourceMapper) UnmarshalBundle(data []byte) (*Bundle, error) {
+	bundle, err := m.UnmarshalBundleLazy(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range bundle.Entry {
+		if _, err := bundle.Entry[i].GetResource(); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal bundle entry %d: %w", i, err)
+		}
+	}
+
+	return bundle, nil
+}
+
+// UnmarshalBundleLazy converts a FHIR Bundle JSON to a Bundle struct whose
+// entries are decoded with this mapper's registrations on the first call to
+// BundleEntry.GetResource, so that entries that are never looked at cost
+// nothing to decode. Entries of unregistered types are decoded as
+// *GenericResource.
+func (m *ResourceMapper) UnmarshalBundleLazy(data []byte) (*Bundle, error) {
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bundle: %w", err)
+	}
 
-		bundle.Entry[i].Resource = typedJSON
+	entries := m.WithGenericFallback()
+	for i := range bundle.Entry {
+		bundle.Entry[i].mapper = entries
 	}
 
 	return &bundle, nil
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// OperationOutcome represents a FHIR OperationOutcome resource
+type OperationOutcome struct {
+	Base
+	Issue []OperationOutcomeIssue `json:"issue"`
+}
+
+// OperationOutcomeIssue represents a single error, warning or information message
+type OperationOutcomeIssue struct {
+	Severity    string                     `json:"severity"`
+	Code        string                     `json:"code"`
+	Details     *CodeableConcept           `json:"details,omitempty"`
+	Diagnostics string                     `json:"diagnostics,omitempty"`
+	Location    []string                   `json:"location,omitempty"`
+	Expression  []string                   `json:"expression,omitempty"`
+	Unknown     map[string]json.RawMessage `json:"-"`
+	Primitives  PrimitiveElements          `json:"-"`
+}
+
+// NewOperationOutcome creates a new OperationOutcome with the required fields
+func NewOperationOutcome() *OperationOutcome {
+	return &OperationOutcome{
+		Base: Base{
+			ResourceType: ResourceTypeOperationOutcome,
+		},
+	}
+}
+
+// HasErrors reports whether any issue has severity error or fatal
+func (o *OperationOutcome) HasErrors() bool {
+	for _, issue := range o.Issue {
+		if issue.Severity == "error" || issue.Severity == "fatal" {
+			return true
+		}
+	}
+	return false
+}
+
+// Message describes the issue in a single line
+func (i OperationOutcomeIssue) Message() string {
+	msg := i.Severity + " " + i.Code
+	switch {
+	case i.Diagnostics != "":
+		msg += ": " + i.Diagnostics
+	case i.Details != nil && i.Details.Text != "":
+		msg += ": " + i.Details.Text
+	}
+	if len(i.Expression) > 0 {
+		msg += fmt.Sprintf(" (at %s)", strings.Join(i.Expression, ", "))
+	} else if len(i.Location) > 0 {
+		msg += fmt.Sprintf(" (at %s)", strings.Join(i.Location, ", "))
+	}
+	return msg
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for OperationOutcome, retaining primitive extensions and unrecognised members
+func (o *OperationOutcome) UnmarshalJSON(data []byte) error {
+	type Alias OperationOutcome
+	return decodeElement(data, (*Alias)(o), &o.Unknown, &o.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for OperationOutcome, re-emitting primitive extensions and unrecognised members
+func (o OperationOutcome) MarshalJSON() ([]byte, error) {
+	type Alias OperationOutcome
+	return encodeElement(Alias(o), o.Unknown, o.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for OperationOutcomeIssue, retaining primitive extensions and unrecognised members
+func (i *OperationOutcomeIssue) UnmarshalJSON(data []byte) error {
+	type Alias OperationOutcomeIssue
+	return decodeElement(data, (*Alias)(i), &i.Unknown, &i.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for OperationOutcomeIssue, re-emitting primitive extensions and unrecognised members
+func (i OperationOutcomeIssue) MarshalJSON() ([]byte, error) {
+	type Alias OperationOutcomeIssue
+	return encodeElement(Alias(i), i.Unknown, i.Primitives)
+}
@@ -0,0 +1,343 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+)
+
+// Parameters represents a FHIR Parameters resource, used as the input and
+// output of operations
+type Parameters struct {
+	Base
+	Parameter []ParametersParameter `json:"parameter,omitempty"`
+}
+
+// ParametersParameter represents a single named parameter. Only one of the
+// value fields, Resource or Part is set. Value types without a field here are
+// kept in Unknown.
+type ParametersParameter struct {
+	Name                 string                     `json:"name"`
+	ValueString          *string                    `json:"valueString,omitempty"`
+	ValueCode            *string                    `json:"valueCode,omitempty"`
+	ValueURI             *string                    `json:"valueUri,omitempty"`
+	ValueURL             *string                    `json:"valueUrl,omitempty"`
+	ValueCanonical       *string                    `json:"valueCanonical,omitempty"`
+	ValueID              *string                    `json:"valueId,omitempty"`
+	ValueBoolean         *bool                      `json:"valueBoolean,omitempty"`
+	ValueInteger         *int                       `json:"valueInteger,omitempty"`
+	ValueDecimal         *fhir.Decimal              `json:"valueDecimal,omitempty"`
+	ValueDate            *fhir.Date                 `json:"valueDate,omitempty"`
+	ValueDateTime        *fhir.DateTime             `json:"valueDateTime,omitempty"`
+	ValueInstant         *fhir.Instant              `json:"valueInstant,omitempty"`
+	ValueCoding          *Coding                    `json:"valueCoding,omitempty"`
+	ValueCodeableConcept *CodeableConcept           `json:"valueCodeableConcept,omitempty"`
+	ValueIdentifier      *Identifier                `json:"valueIdentifier,omitempty"`
+	ValueReference       *Reference                 `json:"valueReference,omitempty"`
+	ValuePeriod          *Period                    `json:"valuePeriod,omitempty"`
+	ValueQuantity        *Quantity                  `json:"valueQuantity,omitempty"`
+	Resource             json.RawMessage            `json:"resource,omitempty"`
+	Part                 []ParametersParameter      `json:"part,omitempty"`
+	Unknown              map[string]json.RawMessage `json:"-"`
+	Primitives           PrimitiveElements          `json:"-"`
+
+	// resource is the typed form of Resource, written in its place when marshaled
+	resource Resource
+}
+
+// NewParameters creates a new Parameters with the required fields
+func NewParameters() *Parameters {
+	return &Parameters{
+		Base: Base{
+			ResourceType: ResourceTypeParameters,
+		},
+	}
+}
+
+// add appends a parameter and returns p for chaining
+func (p *Parameters) add(param ParametersParameter) *Parameters {
+	p.Parameter = append(p.Parameter, param)
+	return p
+}
+
+// AddString adds a string parameter
+func (p *Parameters) AddString(name, value string) *Parameters {
+	return p.add(ParametersParameter{Name: name, ValueString: &value})
+}
+
+// AddCode adds a code parameter
+func (p *Parameters) AddCode(name, value string) *Parameters {
+	return p.add(ParametersParameter{Name: name, ValueCode: &value})
+}
+
+// AddURI adds a uri parameter
+func (p *Parameters) AddURI(name, value string) *Parameters {
+	return p.add(ParametersParameter{Name: name, ValueURI: &value})
+}
+
+// AddCanonical adds a canonical parameter
+func (p *Parameters) AddCanonical(name, value string) *Parameters {
+	return p.add(ParametersParameter{Name: name, ValueCanonical: &value})
+}
+
+// AddBoolean adds a boolean parameter
+func (p *Parameters) AddBoolean(name string, value bool) *Parameters {
+	return p.add(ParametersParameter{Name: name, ValueBoolean: &value})
+}
+
+// AddInteger adds an integer parameter
+func (p *Parameters) AddInteger(name string, value int) *Parameters {
+	return p.add(ParametersParameter{Name: name, ValueInteger: &value})
+}
+
+// AddDecimal adds a decimal parameter
+func (p *Parameters) AddDecimal(name string, value fhir.Decimal) *Parameters {
+	return p.add(ParametersParameter{Name: name, ValueDecimal: &value})
+}
+
+// AddDate adds a date parameter
+func (p *Parameters) AddDate(name string, value fhir.Date) *Parameters {
+	return p.add(ParametersParameter{Name: name, ValueDate: &value})
+}
+
+// AddDateTime adds a dateTime parameter
+func (p *Parameters) AddDateTime(name string, value fhir.DateTime) *Parameters {
+	return p.add(ParametersParameter{Name: name, ValueDateTime: &value})
+}
+
+// AddInstant adds an instant parameter
+func (p *Parameters) AddInstant(name string, value fhir.Instant) *Parameters {
+	return p.add(ParametersParameter{Name: name, ValueInstant: &value})
+}
+
+// AddCoding adds a Coding parameter
+func (p *Parameters) AddCoding(name string, value Coding) *Parameters {
+	return p.add(ParametersParameter{Name: name, ValueCoding: &value})
+}
+
+// AddCodeableConcept adds a CodeableConcept parameter
+func (p *Parameters) AddCodeableConcept(name string, value CodeableConcept) *Parameters {
+	return p.add(ParametersParameter{Name: name, ValueCodeableConcept: &value})
+}
+
+// AddIdentifier adds an Identifier parameter
+func (p *Parameters) AddIdentifier(name string, value Identifier) *Parameters {
+	return p.add(ParametersParameter{Name: name, ValueIdentifier: &value})
+}
+
+// AddReference adds a Reference parameter
+func (p *Parameters) AddReference(name string, value Reference) *Parameters {
+	return p.add(ParametersParameter{Name: name, ValueReference: &value})
+}
+
+// AddResource adds a resource parameter
+func (p *Parameters) AddResource(name string, resource Resource) *Parameters {
+	return p.add(ParametersParameter{Name: name, resource: resource})
+}
+
+// AddPart adds a parameter made up of the parameters in parts
+func (p *Parameters) AddPart(name string, parts *Parameters) *Parameters {
+	return p.add(ParametersParameter{Name: name, Part: parts.Parameter})
+}
+
+// Get returns the first parameter with the given name, or nil
+func (p *Parameters) Get(name string) *ParametersParameter {
+	for i := range p.Parameter {
+		if p.Parameter[i].Name == name {
+			return &p.Parameter[i]
+		}
+	}
+	return nil
+}
+
+// GetAll returns all parameters with the given name
+func (p *Parameters) GetAll(name string) []*ParametersParameter {
+	var params []*ParametersParameter
+	for i := range p.Parameter {
+		if p.Parameter[i].Name == name {
+			params = append(params, &p.Parameter[i])
+		}
+	}
+	return params
+}
+
+// GetString returns the value of the first parameter with the given name
+// that has a string-like value (string, code, uri, url, canonical or id)
+func (p *Parameters) GetString(name string) (string, bool) {
+	for _, param := range p.GetAll(name) {
+		if s, ok := param.StringValue(); ok {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// GetBoolean returns the boolean value of the first parameter with the given name
+func (p *Parameters) GetBoolean(name string) (bool, bool) {
+	if param := p.Get(name); param != nil && param.ValueBoolean != nil {
+		return *param.ValueBoolean, true
+	}
+	return false, false
+}
+
+// GetInteger returns the integer value of the first parameter with the given name
+func (p *Parameters) GetInteger(name string) (int, bool) {
+	if param := p.Get(name); param != nil && param.ValueInteger != nil {
+		return *param.ValueInteger, true
+	}
+	return 0, false
+}
+
+// GetDecimal returns the decimal value of the first parameter with the given name
+func (p *Parameters) GetDecimal(name string) (fhir.Decimal, bool) {
+	if param := p.Get(name); param != nil && param.ValueDecimal != nil {
+		return *param.ValueDecimal, true
+	}
+	return fhir.Decimal{}, false
+}
+
+// GetCoding returns the Coding value of the first parameter with the given name
+func (p *Parameters) GetCoding(name string) (*Coding, bool) {
+	if param := p.Get(name); param != nil && param.ValueCoding != nil {
+		return param.ValueCoding, true
+	}
+	return nil, false
+}
+
+// GetCodeableConcept returns the CodeableConcept value of the first parameter with the given name
+func (p *Parameters) GetCodeableConcept(name string) (*CodeableConcept, bool) {
+	if param := p.Get(name); param != nil && param.ValueCodeableConcept != nil {
+		return param.ValueCodeableConcept, true
+	}
+	return nil, false
+}
+
+// GetResource returns the resource of the first parameter with the given
+// name, decoded to its typed struct. It returns nil if there is no such
+// parameter or it has no resource.
+func (p *Parameters) GetResource(name string) (Resource, error) {
+	param := p.Get(name)
+	if param == nil {
+		return nil, nil
+	}
+	return param.GetResource()
+}
+
+// GetParts returns the parts of the first parameter with the given name as
+// a Parameters, so that the same getters can be used on them
+func (p *Parameters) GetParts(name string) (*Parameters, bool) {
+	param := p.Get(name)
+	if param == nil || len(param.Part) == 0 {
+		return nil, false
+	}
+	return param.Parts(), true
+}
+
+// StringValue returns the parameter's value if it is string-like (string,
+// code, uri, url, canonical or id)
+func (p *ParametersParameter) StringValue() (string, bool) {
+	for _, v := range []*string{p.ValueString, p.ValueCode, p.ValueURI, p.ValueURL, p.ValueCanonical, p.ValueID} {
+		if v != nil {
+			return *v, true
+		}
+	}
+	return "", false
+}
+
+// GetResource returns the parameter's resource decoded to its typed struct,
+// decoding it on first use. It returns nil if the parameter has no resource.
+func (p *ParametersParameter) GetResource() (Resource, error) {
+	if p.resource != nil || p.Resource == nil {
+		return p.resource, nil
+	}
+	resource, err := defaultMapper.UnmarshalResource(p.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal parameter %q resource: %w", p.Name, err)
+	}
+	p.resource = resource
+	return resource, nil
+}
+
+// SetResource sets the parameter's resource, replacing any JSON in Resource
+func (p *ParametersParameter) SetResource(resource Resource) {
+	p.resource = resource
+	p.Resource = nil
+}
+
+// Parts returns the parameter's parts as a Parameters
+func (p *ParametersParameter) Parts() *Parameters {
+	return &Parameters{Parameter: p.Part}
+}
+
+// PrimitiveValue returns the parameter's value in its string form, as it
+// appears in a URL, if it has a primitive value
+func (p *ParametersParameter) PrimitiveValue() (string, bool) {
+	if s, ok := p.StringValue(); ok {
+		return s, true
+	}
+	switch {
+	case p.ValueBoolean != nil:
+		return strconv.FormatBool(*p.ValueBoolean), true
+	case p.ValueInteger != nil:
+		return strconv.Itoa(*p.ValueInteger), true
+	case p.ValueDecimal != nil:
+		return p.ValueDecimal.String(), true
+	case p.ValueDate != nil:
+		return p.ValueDate.String(), true
+	case p.ValueDateTime != nil:
+		return p.ValueDateTime.String(), true
+	case p.ValueInstant != nil:
+		return p.ValueInstant.String(), true
+	}
+	return "", false
+}
+
+// Query encodes the parameters as URL query parameters, for invoking an
+// operation with GET. It fails if any parameter has a complex value, a
+// resource or parts, since those can only be sent in a request body.
+func (p *Parameters) Query() (url.Values, error) {
+	q := url.Values{}
+	for _, param := range p.Parameter {
+		value, ok := param.PrimitiveValue()
+		if !ok {
+			return nil, fmt.Errorf("parameter %q cannot be sent in a URL", param.Name)
+		}
+		q.Add(param.Name, value)
+	}
+	return q, nil
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Parameters, retaining primitive extensions and unrecognised members
+func (p *Parameters) UnmarshalJSON(data []byte) error {
+	type Alias Parameters
+	return decodeElement(data, (*Alias)(p), &p.Unknown, &p.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for Parameters, re-emitting primitive extensions and unrecognised members
+func (p Parameters) MarshalJSON() ([]byte, error) {
+	type Alias Parameters
+	return encodeElement(Alias(p), p.Unknown, p.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ParametersParameter, retaining primitive extensions and unrecognised members
+func (p *ParametersParameter) UnmarshalJSON(data []byte) error {
+	type Alias ParametersParameter
+	p.resource = nil
+	return decodeElement(data, (*Alias)(p), &p.Unknown, &p.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ParametersParameter, re-emitting primitive extensions and unrecognised members
+func (p ParametersParameter) MarshalJSON() ([]byte, error) {
+	type Alias ParametersParameter
+	if p.resource != nil {
+		data, err := json.Marshal(p.resource)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal parameter %q resource: %w", p.Name, err)
+		}
+		p.Resource = data
+	}
+	return encodeElement(Alias(p), p.Unknown, p.Primitives)
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+)
+
+func TestParametersBuilder(t *testing.T) {
+	patient := NewPatient()
+	patient.ID = "p1"
+
+	params := NewParameters().
+		AddCode("mode", "full").
+		AddInteger("count", 3).
+		AddDecimal("threshold", fhir.MustParseDecimal("0.80")).
+		AddResource("resource", patient).
+		AddPart("input", NewParameters().AddCode("type", "Patient").AddURI("url", "https://example.org/p.ndjson"))
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded Parameters
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if mode, ok := decoded.GetString("mode"); !ok || mode != "full" {
+		t.Errorf("Expected mode full, got %q", mode)
+	}
+	if count, ok := decoded.GetInteger("count"); !ok || count != 3 {
+		t.Errorf("Expected count 3, got %d", count)
+	}
+	if threshold, ok := decoded.GetDecimal("threshold"); !ok || threshold.String() != "0.80" {
+		t.Errorf("Expected threshold 0.80, got %s", threshold)
+	}
+	if _, ok := decoded.GetBoolean("count"); ok {
+		t.Error("Expected no boolean value for count")
+	}
+
+	resource, err := decoded.GetResource("resource")
+	if err != nil {
+		t.Fatalf("GetResource failed: %v", err)
+	}
+	if p, ok := resource.(*Patient); !ok || p.ID != "p1" {
+		t.Errorf("Expected Patient p1, got %+v", resource)
+	}
+
+	input, ok := decoded.GetParts("input")
+	if !ok {
+		t.Fatal("Expected input parts")
+	}
+	if url, _ := input.GetString("url"); url != "https://example.org/p.ndjson" {
+		t.Errorf("Unexpected input url %q", url)
+	}
+
+	if _, err := decoded.Query(); err == nil {
+		t.Error("Expected parameters with a resource not to encode as a query")
+	}
+	q, err := NewParameters().AddCode("mode", "full").AddInteger("count", 3).Query()
+	if err != nil || q.Encode() != "count=3&mode=full" {
+		t.Errorf("Unexpected query %q: %v", q.Encode(), err)
+	}
+}
@@ -2,7 +2,9 @@ package models
 
 import (
 	"encoding/json"
-	"time"
+	"fmt"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
 )
 
 // Patient represents a FHIR Patient resource
@@ -12,9 +14,9 @@ type Patient struct {
 	Name                 []HumanName      `json:"name,omitempty"`
 	Telecom              []ContactPoint   `json:"telecom,omitempty"`
 	Gender               string           `json:"gender,omitempty"`
-	BirthDate            *time.Time       `json:"birthDate,omitempty"`
+	BirthDate            *fhir.Date       `json:"birthDate,omitempty"`
 	Deceased             *bool            `json:"deceasedBoolean,omitempty"`
-	DeceasedAt           *time.Time       `json:"deceasedDateTime,omitempty"`
+	DeceasedAt           *fhir.DateTime   `json:"deceasedDateTime,omitempty"`
 	Address              []Address        `json:"address,omitempty"`
 	MaritalStatus        *CodeableConcept `json:"maritalStatus,omitempty"`
 	MultipleBirth        *bool            `json:"multipleBirthBoolean,omitempty"`
@@ -38,54 +40,65 @@ func NewPatient() *Patient {
 
 // Address represents a physical address
 type Address struct {
-	Use        string   `json:"use,omitempty"`
-	Type       string   `json:"type,omitempty"`
-	Text       string   `json:"text,omitempty"`
-	Line       []string `json:"line,omitempty"`
-	City       string   `json:"city,omitempty"`
-	District   string   `json:"district,omitempty"`
-	State      string   `json:"state,omitempty"`
-	PostalCode string   `json:"postalCode,omitempty"`
-	Country    string   `json:"country,omitempty"`
-	Period     *Period  `json:"period,omitempty"`
+	Use        string                     `json:"use,omitempty"`
+	Type       string                     `json:"type,omitempty"`
+	Text       string                     `json:"text,omitempty"`
+	Line       []string                   `json:"line,omitempty"`
+	City       string                     `json:"city,omitempty"`
+	District   string                     `json:"district,omitempty"`
+	State      string                     `json:"state,omitempty"`
+	PostalCode string                     `json:"postalCode,omitempty"`
+	Country    string                     `json:"country,omitempty"`
+	Period     *Period                    `json:"period,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
 }
 
 // Attachment represents a file or other attachment
 type Attachment struct {
-	ContentType string     `json:"contentType,omitempty"`
-	Language    string     `json:"language,omitempty"`
-	Data        string     `json:"data,omitempty"`
-	URL         string     `json:"url,omitempty"`
-	Size        *int       `json:"size,omitempty"`
-	Hash        string     `json:"hash,omitempty"`
-	Title       string     `json:"title,omitempty"`
-	Creation    *time.Time `json:"creation,omitempty"`
+	ContentType string                     `json:"contentType,omitempty"`
+	Language    string                     `json:"language,omitempty"`
+	Data        string                     `json:"data,omitempty"`
+	URL         string                     `json:"url,omitempty"`
+	Size        *int                       `json:"size,omitempty"`
+	Hash        string                     `json:"hash,omitempty"`
+	Title       string                     `json:"title,omitempty"`
+	Creation    *fhir.DateTime             `json:"creation,omitempty"`
+	Unknown     map[string]json.RawMessage `json:"-"`
+	Primitives  PrimitiveElements          `json:"-"`
 }
 
 // PatientContact represents a patient's contact person
 type PatientContact struct {
-	Relationship []CodeableConcept `json:"relationship,omitempty"`
-	Name         *HumanName        `json:"name,omitempty"`
-	Telecom      []ContactPoint    `json:"telecom,omitempty"`
-	Address      *Address          `json:"address,omitempty"`
-	Gender       string            `json:"gender,omitempty"`
-	Organization *Reference        `json:"organization,omitempty"`
-	Period       *Period           `json:"period,omitempty"`
+	Relationship []CodeableConcept          `json:"relationship,omitempty"`
+	Name         *HumanName                 `json:"name,omitempty"`
+	Telecom      []ContactPoint             `json:"telecom,omitempty"`
+	Address      *Address                   `json:"address,omitempty"`
+	Gender       string                     `json:"gender,omitempty"`
+	Organization *Reference                 `json:"organization,omitempty"`
+	Period       *Period                    `json:"period,omitempty"`
+	Unknown      map[string]json.RawMessage `json:"-"`
+	Primitives   PrimitiveElements          `json:"-"`
 }
 
 // Communication represents a patient's language preferences
 type Communication struct {
-	Language  CodeableConcept `json:"language"`
-	Preferred *bool           `json:"preferred,omitempty"`
+	Language   CodeableConcept            `json:"language"`
+	Preferred  *bool                      `json:"preferred,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
 }
 
 // PatientLink represents a link to another patient record
 type PatientLink struct {
-	Other Reference `json:"other"`
-	Type  string    `json:"type"`
+	Other      Reference                  `json:"other"`
+	Type       string                     `json:"type"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
 }
 
-// Bundle represents a collection of resources
+// Bundle represents a collection of resources. Its unknown members and
+// primitive extensions are kept in the Unknown and Primitives of Base.
 type Bundle struct {
 	Base
 	Type  string        `json:"type"`
@@ -96,21 +109,62 @@ type Bundle struct {
 
 // BundleLink represents a navigation link
 type BundleLink struct {
-	Relation string `json:"relation"`
-	URL      string `json:"url"`
+	Relation   string                     `json:"relation"`
+	URL        string                     `json:"url"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
 }
 
-// BundleEntry represents a single entry in a bundle
+// BundleEntry represents a single entry in a bundle.
+//
+// Resource holds the entry's resource as JSON. Entries decoded by a
+// ResourceMapper also carry the typed resource, available from GetResource;
+// other entries decode it on first use. Once an entry has a typed resource,
+// that is what is written when the entry is marshaled, so changes made to it
+// are kept. An entry is not safe for concurrent first use of GetResource.
 type BundleEntry struct {
-	FullURL  string          `json:"fullUrl,omitempty"`
-	Resource json.RawMessage `json:"resource,omitempty"`
-	Search   *BundleSearch   `json:"search,omitempty"`
+	FullURL    string                     `json:"fullUrl,omitempty"`
+	Resource   json.RawMessage            `json:"resource,omitempty"`
+	Search     *BundleSearch              `json:"search,omitempty"`
+	Request    *BundleRequest             `json:"request,omitempty"`
+	Response   *BundleResponse            `json:"response,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
+
+	resource Resource
+	mapper   *ResourceMapper
 }
 
 // BundleSearch represents search information for a bundle entry
 type BundleSearch struct {
-	Mode  string   `json:"mode,omitempty"`
-	Score *float64 `json:"score,omitempty"`
+	Extension  []Extension                `json:"extension,omitempty"`
+	Mode       string                     `json:"mode,omitempty"`
+	Score      *fhir.Decimal              `json:"score,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
+}
+
+// BundleRequest represents the request to perform for a batch or transaction entry
+type BundleRequest struct {
+	Method          string                     `json:"method"`
+	URL             string                     `json:"url"`
+	IfNoneMatch     string                     `json:"ifNoneMatch,omitempty"`
+	IfModifiedSince *fhir.Instant              `json:"ifModifiedSince,omitempty"`
+	IfMatch         string                     `json:"ifMatch,omitempty"`
+	IfNoneExist     string                     `json:"ifNoneExist,omitempty"`
+	Unknown         map[string]json.RawMessage `json:"-"`
+	Primitives      PrimitiveElements          `json:"-"`
+}
+
+// BundleResponse represents the outcome of a batch or transaction entry
+type BundleResponse struct {
+	Status       string                     `json:"status"`
+	Location     string                     `json:"location,omitempty"`
+	Etag         string                     `json:"etag,omitempty"`
+	LastModified *fhir.Instant              `json:"lastModified,omitempty"`
+	Outcome      json.RawMessage            `json:"outcome,omitempty"`
+	Unknown      map[string]json.RawMessage `json:"-"`
+	Primitives   PrimitiveElements          `json:"-"`
 }
 
 // GetTypedResource converts a raw resource to its typed struct using the resource mapper
@@ -118,64 +172,237 @@ func (b *Bundle) GetTypedResource(data json.RawMessage) (Resource, error) {
 	if data == nil {
 		return nil, nil
 	}
-
-	// Create a new resource mapper
-	mapper := NewResourceMapper()
-	return mapper.UnmarshalResource(data)
+	return defaultMapper.UnmarshalResource(data)
 }
 
-// UnmarshalJSON implements custom JSON unmarshaling for Patient
-func (p *Patient) UnmarshalJSON(data []byte) error {
-	type Alias Patient
-	aux := struct {
-		*Alias
-		BirthDate  string `json:"birthDate,omitempty"`
-		DeceasedAt string `json:"deceasedDateTime,omitempty"`
-	}{
-		Alias: (*Alias)(p),
+// GetResource returns the entry's resource as its typed struct, decoding
+// Resource on first use. It returns nil for entries without a resource.
+func (b *BundleEntry) GetResource() (Resource, error) {
+	if b.resource != nil || b.Resource == nil {
+		return b.resource, nil
 	}
 
-	if err := json.Unmarshal(data, &aux); err != nil {
-		return err
+	mapper := b.mapper
+	if mapper == nil {
+		mapper = defaultMapper
 	}
+	resource, err := mapper.UnmarshalResource(b.Resource)
+	if err != nil {
+		return nil, err
+	}
+	b.resource = resource
+	return resource, nil
+}
 
-	if aux.BirthDate != "" {
-		t, err := time.Parse("2006-01-02", aux.BirthDate)
+// SetResource sets the entry's typed resource, replacing any JSON in Resource
+func (b *BundleEntry) SetResource(resource Resource) {
+	b.resource = resource
+	b.Resource = nil
+}
+
+// Resources returns the typed resources of all entries that have one, in bundle order
+func (b *Bundle) Resources() ([]Resource, error) {
+	resources := make([]Resource, 0, len(b.Entry))
+	for i := range b.Entry {
+		resource, err := b.Entry[i].GetResource()
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("failed to unmarshal bundle entry %d: %w", i, err)
+		}
+		if resource != nil {
+			resources = append(resources, resource)
 		}
-		p.BirthDate = &t
 	}
+	return resources, nil
+}
 
-	if aux.DeceasedAt != "" {
-		t, err := time.Parse(time.RFC3339, aux.DeceasedAt)
-		if err != nil {
-			return err
+// GetExtension returns the first extension with the given URL, or nil
+func (b *BundleSearch) GetExtension(url string) *Extension {
+	if b == nil {
+		return nil
+	}
+	for i := range b.Extension {
+		if b.Extension[i].URL == url {
+			return &b.Extension[i]
 		}
-		p.DeceasedAt = &t
 	}
-
 	return nil
 }
 
+// MatchGrade returns the code of the entry's match-grade extension, which
+// $match servers use to say how confident they are in a candidate: certain,
+// probable, possible or certainly-not. It is empty if there is none.
+func (b *BundleSearch) MatchGrade() string {
+	if ext := b.GetExtension(ExtensionMatchGrade); ext != nil && ext.ValueCode != nil {
+		return *ext.ValueCode
+	}
+	return ""
+}
+
+// LinkURL returns the URL of the bundle's link with the given relation, or
+// an empty string if it has none
+func (b *Bundle) LinkURL(relation string) string {
+	for _, link := range b.Link {
+		if link.Relation == relation {
+			return link.URL
+		}
+	}
+	return ""
+}
+
+// NextLink returns the URL of the next page of a paged bundle, or an empty
+// string on the last page
+func (b *Bundle) NextLink() string {
+	return b.LinkURL("next")
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Patient
+func (p *Patient) UnmarshalJSON(data []byte) error {
+	type Alias Patient
+	return decodeElement(data, (*Alias)(p), &p.Unknown, &p.Primitives)
+}
+
 // MarshalJSON implements custom JSON marshaling for Patient
 func (p Patient) MarshalJSON() ([]byte, error) {
 	type Alias Patient
-	aux := struct {
-		*Alias
-		BirthDate  string `json:"birthDate,omitempty"`
-		DeceasedAt string `json:"deceasedDateTime,omitempty"`
-	}{
-		Alias: (*Alias)(&p),
-	}
+	return encodeElement(Alias(p), p.Unknown, p.Primitives)
+}
 
-	if p.BirthDate != nil {
-		aux.BirthDate = p.BirthDate.Format("2006-01-02")
-	}
+// UnmarshalJSON implements custom JSON unmarshaling for Address, retaining primitive extensions and unrecognised members
+func (a *Address) UnmarshalJSON(data []byte) error {
+	type Alias Address
+	return decodeElement(data, (*Alias)(a), &a.Unknown, &a.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for Address, re-emitting primitive extensions and unrecognised members
+func (a Address) MarshalJSON() ([]byte, error) {
+	type Alias Address
+	return encodeElement(Alias(a), a.Unknown, a.Primitives)
+}
 
-	if p.DeceasedAt != nil {
-		aux.DeceasedAt = p.DeceasedAt.Format(time.RFC3339)
+// UnmarshalJSON implements custom JSON unmarshaling for Attachment, retaining primitive extensions and unrecognised members
+func (a *Attachment) UnmarshalJSON(data []byte) error {
+	type Alias Attachment
+	return decodeElement(data, (*Alias)(a), &a.Unknown, &a.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for Attachment, re-emitting primitive extensions and unrecognised members
+func (a Attachment) MarshalJSON() ([]byte, error) {
+	type Alias Attachment
+	return encodeElement(Alias(a), a.Unknown, a.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for PatientContact, retaining primitive extensions and unrecognised members
+func (p *PatientContact) UnmarshalJSON(data []byte) error {
+	type Alias PatientContact
+	return decodeElement(data, (*Alias)(p), &p.Unknown, &p.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for PatientContact, re-emitting primitive extensions and unrecognised members
+func (p PatientContact) MarshalJSON() ([]byte, error) {
+	type Alias PatientContact
+	return encodeElement(Alias(p), p.Unknown, p.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Communication, retaining primitive extensions and unrecognised members
+func (c *Communication) UnmarshalJSON(data []byte) error {
+	type Alias Communication
+	return decodeElement(data, (*Alias)(c), &c.Unknown, &c.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for Communication, re-emitting primitive extensions and unrecognised members
+func (c Communication) MarshalJSON() ([]byte, error) {
+	type Alias Communication
+	return encodeElement(Alias(c), c.Unknown, c.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for PatientLink, retaining primitive extensions and unrecognised members
+func (p *PatientLink) UnmarshalJSON(data []byte) error {
+	type Alias PatientLink
+	return decodeElement(data, (*Alias)(p), &p.Unknown, &p.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for PatientLink, re-emitting primitive extensions and unrecognised members
+func (p PatientLink) MarshalJSON() ([]byte, error) {
+	type Alias PatientLink
+	return encodeElement(Alias(p), p.Unknown, p.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Bundle, retaining primitive extensions and unrecognised members
+func (b *Bundle) UnmarshalJSON(data []byte) error {
+	type Alias Bundle
+	return decodeElement(data, (*Alias)(b), &b.Unknown, &b.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for Bundle, re-emitting primitive extensions and unrecognised members
+func (b Bundle) MarshalJSON() ([]byte, error) {
+	type Alias Bundle
+	return encodeElement(Alias(b), b.Unknown, b.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for BundleLink, retaining primitive extensions and unrecognised members
+func (b *BundleLink) UnmarshalJSON(data []byte) error {
+	type Alias BundleLink
+	return decodeElement(data, (*Alias)(b), &b.Unknown, &b.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for BundleLink, re-emitting primitive extensions and unrecognised members
+func (b BundleLink) MarshalJSON() ([]byte, error) {
+	type Alias BundleLink
+	return encodeElement(Alias(b), b.Unknown, b.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for BundleEntry, retaining primitive extensions and unrecognised members
+func (b *BundleEntry) UnmarshalJSON(data []byte) error {
+	type Alias BundleEntry
+	b.resource, b.mapper = nil, nil
+	return decodeElement(data, (*Alias)(b), &b.Unknown, &b.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for BundleEntry, re-emitting primitive extensions and unrecognised members
+func (b BundleEntry) MarshalJSON() ([]byte, error) {
+	type Alias BundleEntry
+	if b.resource != nil {
+		data, err := json.Marshal(b.resource)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal bundle entry resource: %w", err)
+		}
+		b.Resource = data
 	}
+	return encodeElement(Alias(b), b.Unknown, b.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for BundleSearch, retaining primitive extensions and unrecognised members
+func (b *BundleSearch) UnmarshalJSON(data []byte) error {
+	type Alias BundleSearch
+	return decodeElement(data, (*Alias)(b), &b.Unknown, &b.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for BundleSearch, re-emitting primitive extensions and unrecognised members
+func (b BundleSearch) MarshalJSON() ([]byte, error) {
+	type Alias BundleSearch
+	return encodeElement(Alias(b), b.Unknown, b.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for BundleRequest, retaining primitive extensions and unrecognised members
+func (b *BundleRequest) UnmarshalJSON(data []byte) error {
+	type Alias BundleRequest
+	return decodeElement(data, (*Alias)(b), &b.Unknown, &b.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for BundleRequest, re-emitting primitive extensions and unrecognised members
+func (b BundleRequest) MarshalJSON() ([]byte, error) {
+	type Alias BundleRequest
+	return encodeElement(Alias(b), b.Unknown, b.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for BundleResponse, retaining primitive extensions and unrecognised members
+func (b *BundleResponse) UnmarshalJSON(data []byte) error {
+	type Alias BundleResponse
+	return decodeElement(data, (*Alias)(b), &b.Unknown, &b.Primitives)
+}
 
-	return json.Marshal(aux)
+// MarshalJSON implements custom JSON marshaling for BundleResponse, re-emitting primitive extensions and unrecognised members
+func (b BundleResponse) MarshalJSON() ([]byte, error) {
+	type Alias BundleResponse
+	return encodeElement(Alias(b), b.Unknown, b.Primitives)
 }
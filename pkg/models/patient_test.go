@@ -2,8 +2,12 @@ package models
 
 import (
 	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
 )
 
 func TestPatientUnmarshalJSON(t *testing.T) {
@@ -72,14 +76,14 @@ func TestPatientUnmarshalJSON(t *testing.T) {
 	}
 
 	// Verify birth date
-	expectedBirthDate := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	expectedBirthDate := fhir.NewDate(2000, time.January, 1)
 	if patient.BirthDate == nil || !patient.BirthDate.Equal(expectedBirthDate) {
 		t.Errorf("Expected birth date %v, got %v", expectedBirthDate, patient.BirthDate)
 	}
 
 	// Verify deceased date
 	expectedDeceasedDate := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
-	if patient.DeceasedAt == nil || !patient.DeceasedAt.Equal(expectedDeceasedDate) {
+	if patient.DeceasedAt == nil || !patient.DeceasedAt.Time().Equal(expectedDeceasedDate) {
 		t.Errorf("Expected deceased date %v, got %v", expectedDeceasedDate, patient.DeceasedAt)
 	}
 
@@ -178,8 +182,326 @@ func TestBundleUnmarshalWithTypedResources(t *testing.T) {
 		t.Errorf("Expected given name John, got %v", patient.Name[0].Given)
 	}
 
-	expectedBirthDate := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	expectedBirthDate := fhir.NewDate(2000, time.January, 1)
 	if patient.BirthDate == nil || !patient.BirthDate.Equal(expectedBirthDate) {
 		t.Errorf("Expected birth date %v, got %v", expectedBirthDate, patient.BirthDate)
 	}
 }
+
+func TestPatientRoundTripPreservesUnknownElements(t *testing.T) {
+	jsonData := `{
+		"resourceType": "Patient",
+		"id": "123",
+		"identifier": [{"system": "urn:oid:1.2.36.146.595.217.0.1", "value": "12345"}],
+		"name": [
+			{
+				"family": "Doe",
+				"given": ["John"],
+				"extension": [{"url": "http://example.org/name-source", "valueString": "import"}]
+			}
+		],
+		"birthDate": "2000-01-01",
+		"_birthDate": {"extension": [{"url": "http://example.org/birth-time", "valueDateTime": "2000-01-01T08:30:00Z"}]},
+		"vendorData": {"score": 42}
+	}`
+
+	var patient Patient
+	if err := json.Unmarshal([]byte(jsonData), &patient); err != nil {
+		t.Fatalf("Failed to unmarshal patient: %v", err)
+	}
+
+	if _, ok := patient.Unknown["identifier"]; !ok {
+		t.Error("Expected identifier to be retained as an unknown member")
+	}
+	if _, ok := patient.Name[0].Unknown["extension"]; !ok {
+		t.Error("Expected name extension to be retained as an unknown member")
+	}
+
+	// Modify a known field, as a read-modify-write would
+	patient.Gender = "male"
+
+	data, err := json.Marshal(patient)
+	if err != nil {
+		t.Fatalf("Failed to marshal patient: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Failed to unmarshal marshaled patient: %v", err)
+	}
+	var want map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonData), &want); err != nil {
+		t.Fatalf("Failed to unmarshal input: %v", err)
+	}
+	want["gender"] = "male"
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Round trip mismatch:\ngot  %s\nwant %s", data, jsonData)
+	}
+}
+
+func TestBundleRoundTripPreservesUnknownElements(t *testing.T) {
+	jsonData := `{
+		"resourceType": "Bundle",
+		"type": "collection",
+		"_type": {"extension": [{"url": "http://example.org/type-source", "valueString": "import"}]},
+		"timestamp": "2024-01-01T00:00:00Z",
+		"entry": [{"fullUrl": "urn:uuid:1", "resource": {"resourceType": "Patient", "id": "1"}}]
+	}`
+
+	var bundle Bundle
+	if err := json.Unmarshal([]byte(jsonData), &bundle); err != nil {
+		t.Fatalf("Failed to unmarshal bundle: %v", err)
+	}
+	if _, ok := bundle.Base.Unknown["timestamp"]; !ok {
+		t.Error("Expected timestamp to be retained as an unknown member of Base")
+	}
+	if bundle.Base.Primitives["type"] == nil {
+		t.Error("Expected the extension of type to be retained in Base")
+	}
+
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("Failed to marshal bundle: %v", err)
+	}
+	var got, want map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Failed to unmarshal marshaled bundle: %v", err)
+	}
+	if err := json.Unmarshal([]byte(jsonData), &want); err != nil {
+		t.Fatalf("Failed to unmarshal input: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Round trip mismatch:\ngot  %s\nwant %s", data, jsonData)
+	}
+}
+
+func TestUnknownMembersDoNotOverrideKnownFields(t *testing.T) {
+	coding := Coding{
+		System: "http://loinc.org",
+		Code:   "1234-5",
+		Unknown: map[string]json.RawMessage{
+			"code":        json.RawMessage(`"stale"`),
+			"vendorFlag":  json.RawMessage(`true`),
+			"emptyMember": nil,
+		},
+	}
+
+	data, err := json.Marshal(coding)
+	if err != nil {
+		t.Fatalf("Failed to marshal coding: %v", err)
+	}
+	want := `{"system":"http://loinc.org","code":"1234-5","vendorFlag":true}`
+	if string(data) != want {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+}
+
+func TestPatientPrimitiveExtensions(t *testing.T) {
+	jsonData := `{
+		"resourceType": "Patient",
+		"id": "123",
+		"_id": {"id": "id-element"},
+		"name": [
+			{
+				"family": "Doe",
+				"given": ["John", "Middle", "Third"],
+				"_given": [null, {"extension": [{"url": "http://example.org/name-qualifier", "valueCode": "MID"}]}]
+			}
+		],
+		"_gender": {
+			"extension": [{"url": "http://hl7.org/fhir/StructureDefinition/data-absent-reason", "valueCode": "asked-declined"}]
+		}
+	}`
+
+	var patient Patient
+	if err := json.Unmarshal([]byte(jsonData), &patient); err != nil {
+		t.Fatalf("Failed to unmarshal patient: %v", err)
+	}
+
+	if len(patient.Unknown) != 0 {
+		t.Errorf("Expected no unknown members, got %v", patient.Unknown)
+	}
+	if got := patient.Primitives.Get("id"); got == nil || got.ID != "id-element" {
+		t.Errorf("Expected _id element with id id-element, got %+v", got)
+	}
+
+	absent := patient.Primitives.Get("gender").GetExtension("http://hl7.org/fhir/StructureDefinition/data-absent-reason")
+	if absent == nil || absent.ValueCode == nil || *absent.ValueCode != "asked-declined" {
+		t.Errorf("Expected data-absent-reason asked-declined on gender, got %+v", absent)
+	}
+
+	name := patient.Name[0]
+	if name.Primitives.GetAt("given", 0) != nil {
+		t.Error("Expected no element data for first given name")
+	}
+	if el := name.Primitives.GetAt("given", 1); el == nil || len(el.Extension) != 1 {
+		t.Errorf("Expected one extension on second given name, got %+v", el)
+	}
+	if name.Primitives.GetAt("given", 2) != nil {
+		t.Error("Expected no element data for third given name")
+	}
+
+	// Add element data to the last given name and check the aligned output
+	code := "THIRD"
+	patient.Name[0].Primitives.SetAt("given", 2, &Element{
+		Extension: []Extension{{URL: "http://example.org/name-qualifier", ValueCode: &code}},
+	})
+
+	data, err := json.Marshal(patient)
+	if err != nil {
+		t.Fatalf("Failed to marshal patient: %v", err)
+	}
+
+	var out struct {
+		Gender *string `json:"gender"`
+		Name   []struct {
+			Given []*Element `json:"_given"`
+		} `json:"name"`
+		GenderElement *Element `json:"_gender"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Failed to unmarshal marshaled patient: %v", err)
+	}
+	if out.Gender != nil {
+		t.Errorf("Expected gender value to stay absent, got %q", *out.Gender)
+	}
+	if out.GenderElement == nil || len(out.GenderElement.Extension) != 1 {
+		t.Errorf("Expected _gender to be written, got %s", data)
+	}
+	given := out.Name[0].Given
+	if len(given) != 3 || given[0] != nil || given[1] == nil || given[2] == nil {
+		t.Errorf("Expected _given aligned as [null, {...}, {...}], got %s", data)
+	}
+}
+
+func TestPatientPartialDatesRoundTrip(t *testing.T) {
+	jsonData := `{"resourceType":"Patient","meta":{"lastUpdated":"2023-04-05T06:07:08.120+01:00"},"birthDate":"1980-07","deceasedDateTime":"2021"}`
+
+	var patient Patient
+	if err := json.Unmarshal([]byte(jsonData), &patient); err != nil {
+		t.Fatalf("Failed to unmarshal patient with partial dates: %v", err)
+	}
+	if patient.BirthDate == nil || patient.BirthDate.Precision() != fhir.PrecisionMonth {
+		t.Errorf("Expected month-precision birth date, got %v", patient.BirthDate)
+	}
+
+	data, err := json.Marshal(patient)
+	if err != nil {
+		t.Fatalf("Failed to marshal patient: %v", err)
+	}
+	if string(data) != jsonData {
+		t.Errorf("Round trip mismatch:\ngot  %s\nwant %s", data, jsonData)
+	}
+}
+
+func TestBundleDecimalsKeepLexicalForm(t *testing.T) {
+	jsonData := `{"resourceType":"Bundle","type":"searchset","entry":[{"search":{"mode":"match","score":0.80}}],"extension":[{"url":"http://example.org/lab-value","valueQuantity":{"value":0.10,"unit":"mmol/L"}}]}`
+
+	var bundle Bundle
+	if err := json.Unmarshal([]byte(jsonData), &bundle); err != nil {
+		t.Fatalf("Failed to unmarshal bundle: %v", err)
+	}
+	if score := bundle.Entry[0].Search.Score; score == nil || score.String() != "0.80" {
+		t.Errorf("Expected score 0.80, got %v", score)
+	}
+	if value := bundle.Extension[0].ValueQuantity.Value; value == nil || value.String() != "0.10" {
+		t.Errorf("Expected quantity value 0.10, got %v", value)
+	}
+
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("Failed to marshal bundle: %v", err)
+	}
+	if !strings.Contains(string(data), `"score":0.80`) || !strings.Contains(string(data), `"value":0.10`) {
+		t.Errorf("Expected decimals to keep trailing zeros, got %s", data)
+	}
+}
+
+func TestUnmarshalBundleDecodesEntriesOnce(t *testing.T) {
+	data := []byte(`{
+		"resourceType": "Bundle",
+		"type": "searchset",
+		"entry": [
+			{"fullUrl": "http://example.com/Patient/1", "resource": {"resourceType": "Patient", "id": "1", "gender": "female"}},
+			{"fullUrl": "http://example.com/Basic/2", "resource": {"resourceType": "Basic", "id": "2"}}
+		]
+	}`)
+
+	bundle, err := NewResourceMapper().UnmarshalBundle(data)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal bundle: %v", err)
+	}
+
+	first, err := bundle.Entry[0].GetResource()
+	if err != nil {
+		t.Fatalf("Failed to get resource: %v", err)
+	}
+	again, _ := bundle.Entry[0].GetResource()
+	if first != again {
+		t.Error("Expected the decoded resource to be reused")
+	}
+	patient, ok := first.(*Patient)
+	if !ok {
+		t.Fatalf("Expected *Patient, got %T", first)
+	}
+
+	// Changes to the typed resource are what gets marshaled
+	patient.Gender = "male"
+	out, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("Failed to marshal bundle: %v", err)
+	}
+	if !strings.Contains(string(out), `"gender":"male"`) {
+		t.Errorf("Expected modified resource in output, got %s", out)
+	}
+
+	resources, err := bundle.Resources()
+	if err != nil || len(resources) != 2 {
+		t.Fatalf("Resources() = %v, %v", resources, err)
+	}
+	if _, ok := resources[1].(*GenericResource); !ok {
+		t.Errorf("Expected *GenericResource, got %T", resources[1])
+	}
+}
+
+func TestUnmarshalBundleLazyUsesMapper(t *testing.T) {
+	type custom struct{ GenericResource }
+
+	mapper := NewResourceMapper()
+	mapper.RegisterResource("Basic", func() Resource { return &custom{} })
+
+	bundle, err := mapper.UnmarshalBundleLazy([]byte(`{
+		"resourceType": "Bundle",
+		"type": "collection",
+		"entry": [{"resource": {"resourceType": "Basic", "id": "b"}}]
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to unmarshal bundle: %v", err)
+	}
+	if bundle.Entry[0].resource != nil {
+		t.Fatal("Expected the entry not to be decoded yet")
+	}
+
+	resource, err := bundle.Entry[0].GetResource()
+	if err != nil {
+		t.Fatalf("Failed to get resource: %v", err)
+	}
+	if _, ok := resource.(*custom); !ok {
+		t.Errorf("Expected the mapper's registered type, got %T", resource)
+	}
+}
+
+func TestBundleEntrySetResource(t *testing.T) {
+	entry := BundleEntry{FullURL: "urn:uuid:1", Resource: json.RawMessage(`{"resourceType":"Basic"}`)}
+	entry.SetResource(&Patient{Base: Base{ResourceType: ResourceTypePatient, ID: "p"}})
+
+	out, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("Failed to marshal entry: %v", err)
+	}
+	want := `{"fullUrl":"urn:uuid:1","resource":{"resourceType":"Patient","id":"p"}}`
+	if string(out) != want {
+		t.Errorf("Expected %s, got %s", want, out)
+	}
+}
@@ -1,7 +1,13 @@
 package r4
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/mapper"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/validation"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/version"
 )
 
 // Patient extends the base Patient model with R4-specific fields and validations
@@ -9,27 +15,28 @@ type Patient struct {
 	models.Patient
 }
 
-// Validate performs R4-specific validation rules
-func (p *Patient) Validate() error {
-	// Add R4-specific validation rules here
-	// For example:
-	// - Check if required fields are present
-	// - Validate field formats
-	// - Check value sets
-	return nil
+// Validate checks the patient with v, which must be loaded with the R4 core
+// StructureDefinitions and any profiles in its meta.profile, returning a
+// *validation.Error listing the errors found
+func (p *Patient) Validate(ctx context.Context, v *validation.Validator) error {
+	return validation.Check(ctx, v, &p.Patient)
 }
 
-// ToR5 converts an R4 Patient to R5 format
+// ToR5 converts the patient to R5
 func (p *Patient) ToR5() (*models.Patient, error) {
-	// For now, we'll just return the base patient since we haven't
-	// implemented any R4-specific fields yet
-	return &p.Patient, nil
+	result, err := mapper.NewMapper().MapResource(&p.Patient, version.R4, version.R5)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert patient to R5: %w", err)
+	}
+	return result.(*models.Patient), nil
 }
 
-// FromR5 converts an R5 Patient to R4 format
+// FromR5 sets the patient from an R5 Patient
 func (p *Patient) FromR5(r5Patient *models.Patient) error {
-	// For now, we'll just copy the base patient since we haven't
-	// implemented any R4-specific fields yet
-	p.Patient = *r5Patient
+	result, err := mapper.NewMapper().MapResource(r5Patient, version.R5, version.R4)
+	if err != nil {
+		return fmt.Errorf("failed to convert patient from R5: %w", err)
+	}
+	p.Patient = *result.(*models.Patient)
 	return nil
 }
@@ -1,10 +1,14 @@
 package r4
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/validation"
 )
 
 func TestR4PatientMappingAndValidation(t *testing.T) {
@@ -30,9 +34,16 @@ func TestR4PatientMappingAndValidation(t *testing.T) {
 
 	r4Patient := &Patient{Patient: basePatient}
 
-	if err := r4Patient.Validate(); err != nil {
+	v := validation.NewValidator()
+	if err := v.LoadDir("../../validation/testdata"); err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+	if err := r4Patient.Validate(context.Background(), v); err != nil {
 		t.Errorf("R4 Patient validation failed: %v", err)
 	}
+	if err := r4Patient.Validate(context.Background(), nil); !errors.Is(err, validation.ErrNoDefinition) {
+		t.Errorf("Expected ErrNoDefinition without a validator, got %v", err)
+	}
 
 	if r4Patient.ID != "r4-123" {
 		t.Errorf("Expected ID r4-123, got %s", r4Patient.ID)
@@ -44,3 +55,43 @@ func TestR4PatientMappingAndValidation(t *testing.T) {
 		t.Errorf("Expected family name Smith, got %+v", r4Patient.Name)
 	}
 }
+
+func TestR4PatientConversion(t *testing.T) {
+	var basePatient models.Patient
+	if err := json.Unmarshal([]byte(`{
+		"resourceType": "Patient",
+		"id": "r4-123",
+		"identifier": [{"system": "http://example.org/mrn", "value": "12345"}],
+		"name": [{"family": "Smith", "given": ["Alice"]}],
+		"gender": "female",
+		"contained": [{"resourceType": "Encounter", "id": "e1", "status": "finished", "period": {"start": "2024-01-01"}}]
+	}`), &basePatient); err != nil {
+		t.Fatalf("Failed to unmarshal base patient: %v", err)
+	}
+	r4Patient := &Patient{Patient: basePatient}
+
+	r5Patient, err := r4Patient.ToR5()
+	if err != nil {
+		t.Fatalf("ToR5 failed: %v", err)
+	}
+	if r5Patient == &r4Patient.Patient || r5Patient.Gender != "female" || r5Patient.Unknown["identifier"] == nil {
+		t.Errorf("unexpected R5 patient %+v", r5Patient)
+	}
+	data, err := json.Marshal(r5Patient)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"actualPeriod":{"start":"2024-01-01"}`) || !strings.Contains(string(data), `"status":"completed"`) {
+		t.Errorf("expected the contained encounter to be converted: %s", data)
+	}
+
+	var back Patient
+	if err := back.FromR5(r5Patient); err != nil {
+		t.Fatalf("FromR5 failed: %v", err)
+	}
+	original, _ := json.Marshal(&r4Patient.Patient)
+	roundTripped, _ := json.Marshal(&back.Patient)
+	if string(original) != string(roundTripped) {
+		t.Errorf("round trip changed the patient:\n%s\n%s", original, roundTripped)
+	}
+}
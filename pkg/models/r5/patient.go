@@ -1,7 +1,13 @@
 package r5
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/mapper"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/validation"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/version"
 )
 
 // Patient extends the base Patient model with R5-specific fields and validations
@@ -12,27 +18,28 @@ type Patient struct {
 	// NewInR5Field *string `json:"newInR5Field,omitempty"`
 }
 
-// Validate performs R5-specific validation rules
-func (p *Patient) Validate() error {
-	// Add R5-specific validation rules here
-	// For example:
-	// - Check if required fields are present
-	// - Validate field formats
-	// - Check value sets
-	return nil
+// Validate checks the patient with v, which must be loaded with the R5 core
+// StructureDefinitions and any profiles in its meta.profile, returning a
+// *validation.Error listing the errors found
+func (p *Patient) Validate(ctx context.Context, v *validation.Validator) error {
+	return validation.Check(ctx, v, &p.Patient)
 }
 
-// ToR4 converts an R5 Patient to R4 format
+// ToR4 converts the patient to R4
 func (p *Patient) ToR4() (*models.Patient, error) {
-	// For now, we'll just return the base patient since we haven't
-	// implemented any R5-specific fields yet
-	return &p.Patient, nil
+	result, err := mapper.NewMapper().MapResource(&p.Patient, version.R5, version.R4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert patient to R4: %w", err)
+	}
+	return result.(*models.Patient), nil
 }
 
-// FromR4 converts an R4 Patient to R5 format
+// FromR4 sets the patient from an R4 Patient
 func (p *Patient) FromR4(r4Patient *models.Patient) error {
-	// For now, we'll just copy the base patient since we haven't
-	// implemented any R4-specific fields yet
-	p.Patient = *r4Patient
+	result, err := mapper.NewMapper().MapResource(r4Patient, version.R4, version.R5)
+	if err != nil {
+		return fmt.Errorf("failed to convert patient from R4: %w", err)
+	}
+	p.Patient = *result.(*models.Patient)
 	return nil
 }
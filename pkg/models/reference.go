@@ -0,0 +1,138 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ReferenceKind classifies the form of a reference
+type ReferenceKind string
+
+const (
+	// ReferenceKindLocal is a reference to a contained resource, e.g. "#med1"
+	ReferenceKindLocal ReferenceKind = "local"
+	// ReferenceKindRelative is a reference relative to the server base, e.g. "Patient/123"
+	ReferenceKindRelative ReferenceKind = "relative"
+	// ReferenceKindAbsolute is a RESTful URL, e.g. "http://example.org/fhir/Patient/123"
+	ReferenceKindAbsolute ReferenceKind = "absolute"
+	// ReferenceKindURN is a urn:uuid or urn:oid, typically resolved within a Bundle
+	ReferenceKindURN ReferenceKind = "urn"
+	// ReferenceKindLogical is a reference by business identifier only
+	ReferenceKindLogical ReferenceKind = "logical"
+	// ReferenceKindOther is any other URL, such as a non-RESTful endpoint
+	ReferenceKindOther ReferenceKind = "other"
+)
+
+// restfulReference matches the [type]/[id](/_history/[vid]) tail of a RESTful reference
+var restfulReference = regexp.MustCompile(
+	`(?:^|/)([A-Z][A-Za-z]+)/([A-Za-z0-9\-.]{1,64})(?:/_history/([A-Za-z0-9\-.]{1,64}))?$`)
+
+// ParsedReference is the decomposed form of a Reference
+type ParsedReference struct {
+	Kind ReferenceKind
+
+	// Raw is the reference string as given
+	Raw string
+
+	// BaseURL is the server base of an absolute reference, without a trailing slash
+	BaseURL string
+
+	// ResourceType, ID and Version identify the target of relative and
+	// absolute references. ResourceType is also set for logical references
+	// when Reference.Type is given.
+	ResourceType string
+	ID           string
+	Version      string
+
+	// Identifier is set for logical references
+	Identifier *Identifier
+}
+
+// ParseReference parses a reference string in any of the forms FHIR allows
+func ParseReference(ref string) (*ParsedReference, error) {
+	p := &ParsedReference{Raw: ref}
+
+	switch {
+	case ref == "":
+		return nil, fmt.Errorf("empty reference")
+	case strings.HasPrefix(ref, "#"):
+		p.Kind = ReferenceKindLocal
+		p.ID = ref[1:]
+		return p, nil
+	case strings.HasPrefix(ref, "urn:uuid:"), strings.HasPrefix(ref, "urn:oid:"):
+		p.Kind = ReferenceKindURN
+		return p, nil
+	}
+
+	m := restfulReference.FindStringSubmatchIndex(ref)
+	if m == nil {
+		if strings.Contains(ref, "://") {
+			p.Kind = ReferenceKindOther
+			return p, nil
+		}
+		return nil, fmt.Errorf("invalid reference %q", ref)
+	}
+
+	p.ResourceType = ref[m[2]:m[3]]
+	p.ID = ref[m[4]:m[5]]
+	if m[6] >= 0 {
+		p.Version = ref[m[6]:m[7]]
+	}
+
+	if m[0] == 0 {
+		p.Kind = ReferenceKindRelative
+		return p, nil
+	}
+	if !strings.Contains(ref[:m[0]], "://") {
+		return nil, fmt.Errorf("invalid reference %q", ref)
+	}
+	p.Kind = ReferenceKindAbsolute
+	p.BaseURL = ref[:m[0]]
+	return p, nil
+}
+
+// Parse parses the reference, falling back to its identifier for logical references
+func (r Reference) Parse() (*ParsedReference, error) {
+	if r.Reference == "" {
+		if r.Identifier == nil {
+			return nil, fmt.Errorf("reference has neither a reference nor an identifier")
+		}
+		return &ParsedReference{
+			Kind:         ReferenceKindLogical,
+			ResourceType: r.Type,
+			Identifier:   r.Identifier,
+		}, nil
+	}
+
+	p, err := ParseReference(r.Reference)
+	if err != nil {
+		return nil, err
+	}
+	if p.ResourceType == "" {
+		p.ResourceType = r.Type
+	}
+	return p, nil
+}
+
+// RelativeURL returns the [type]/[id] form of the reference, with the
+// _history suffix when it is versioned. It is empty for references that do
+// not name a resource type and id.
+func (p *ParsedReference) RelativeURL() string {
+	if p.ResourceType == "" || p.ID == "" || p.Kind == ReferenceKindLocal {
+		return ""
+	}
+	url := p.ResourceType + "/" + p.ID
+	if p.Version != "" {
+		url += "/_history/" + p.Version
+	}
+	return url
+}
+
+// String returns the reference in its original form
+func (p *ParsedReference) String() string {
+	if p.Kind == ReferenceKindLogical && p.Identifier != nil {
+		return fmt.Sprintf("%s?identifier=%s|%s", p.ResourceType, p.Identifier.System, p.Identifier.Value)
+	}
+	return p.Raw
+}
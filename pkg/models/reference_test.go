@@ -0,0 +1,60 @@
+package models
+
+import "testing"
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		ref          string
+		kind         ReferenceKind
+		baseURL      string
+		resourceType string
+		id           string
+		version      string
+	}{
+		{"#med1", ReferenceKindLocal, "", "", "med1", ""},
+		{"Patient/123", ReferenceKindRelative, "", "Patient", "123", ""},
+		{"Patient/123/_history/2", ReferenceKindRelative, "", "Patient", "123", "2"},
+		{"http://example.org/fhir/Patient/123", ReferenceKindAbsolute, "http://example.org/fhir", "Patient", "123", ""},
+		{"https://example.org/FHIR/R4/Observation/a.b-c/_history/7", ReferenceKindAbsolute, "https://example.org/FHIR/R4", "Observation", "a.b-c", "7"},
+		{"urn:uuid:04121321-4af5-424c-a0e1-ed3aab1c349d", ReferenceKindURN, "", "", "", ""},
+		{"http://example.org/some/endpoint", ReferenceKindOther, "", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		p, err := ParseReference(tt.ref)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", tt.ref, err)
+			continue
+		}
+		if p.Kind != tt.kind || p.BaseURL != tt.baseURL || p.ResourceType != tt.resourceType || p.ID != tt.id || p.Version != tt.version {
+			t.Errorf("ParseReference(%q) = %+v", tt.ref, p)
+		}
+	}
+
+	for _, invalid := range []string{"", "patient/123", "foo/Patient/123", "Patient"} {
+		if _, err := ParseReference(invalid); err == nil {
+			t.Errorf("Expected error parsing %q", invalid)
+		}
+	}
+}
+
+func TestReferenceParseLogical(t *testing.T) {
+	ref := Reference{
+		Type:       "Patient",
+		Identifier: &Identifier{System: "http://hospital.example.org/mrn", Value: "12345"},
+	}
+	p, err := ref.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse logical reference: %v", err)
+	}
+	if p.Kind != ReferenceKindLogical || p.ResourceType != "Patient" || p.Identifier.Value != "12345" {
+		t.Errorf("Unexpected parsed reference %+v", p)
+	}
+	if p.RelativeURL() != "" {
+		t.Errorf("Expected no relative URL for a logical reference, got %q", p.RelativeURL())
+	}
+
+	if _, err := (Reference{Display: "Dr. No"}).Parse(); err == nil {
+		t.Error("Expected error for a reference with only a display")
+	}
+}
@@ -0,0 +1,230 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// StructureDefinition represents a FHIR StructureDefinition resource, which
+// defines a resource, datatype, extension or profile
+type StructureDefinition struct {
+	Base
+	URL            string                          `json:"url"`
+	Version        string                          `json:"version,omitempty"`
+	Name           string                          `json:"name"`
+	Title          string                          `json:"title,omitempty"`
+	Status         string                          `json:"status"`
+	FHIRVersion    string                          `json:"fhirVersion,omitempty"`
+	Kind           string                          `json:"kind"`
+	Abstract       bool                            `json:"abstract"`
+	Type           string                          `json:"type"`
+	BaseDefinition string                          `json:"baseDefinition,omitempty"`
+	Derivation     string                          `json:"derivation,omitempty"`
+	Snapshot       *StructureDefinitionElementList `json:"snapshot,omitempty"`
+	Differential   *StructureDefinitionElementList `json:"differential,omitempty"`
+}
+
+// StructureDefinitionElementList holds the elements of a snapshot or differential
+type StructureDefinitionElementList struct {
+	Element    []ElementDefinition        `json:"element"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
+}
+
+// ElementDefinition defines an element in a resource or datatype, or a
+// constraint on it in a profile. Its fixed[x] and pattern[x] values are kept
+// in Unknown and returned by Fixed and Pattern.
+type ElementDefinition struct {
+	ID               string                        `json:"id,omitempty"`
+	Path             string                        `json:"path"`
+	SliceName        string                        `json:"sliceName,omitempty"`
+	Short            string                        `json:"short,omitempty"`
+	Min              *int                          `json:"min,omitempty"`
+	Max              string                        `json:"max,omitempty"`
+	ContentReference string                        `json:"contentReference,omitempty"`
+	Type             []ElementDefinitionType       `json:"type,omitempty"`
+	Constraint       []ElementDefinitionConstraint `json:"constraint,omitempty"`
+	MustSupport      *bool                         `json:"mustSupport,omitempty"`
+	Slicing          *ElementDefinitionSlicing     `json:"slicing,omitempty"`
+	Binding          *ElementDefinitionBinding     `json:"binding,omitempty"`
+	Unknown          map[string]json.RawMessage    `json:"-"`
+	Primitives       PrimitiveElements             `json:"-"`
+}
+
+// ElementDefinitionType is a type an element may have
+type ElementDefinitionType struct {
+	Code          string                     `json:"code"`
+	Profile       []string                   `json:"profile,omitempty"`
+	TargetProfile []string                   `json:"targetProfile,omitempty"`
+	Unknown       map[string]json.RawMessage `json:"-"`
+	Primitives    PrimitiveElements          `json:"-"`
+}
+
+// ElementDefinitionConstraint is an invariant that must hold for an element,
+// expressed in FHIRPath
+type ElementDefinitionConstraint struct {
+	Key        string                     `json:"key"`
+	Severity   string                     `json:"severity"`
+	Human      string                     `json:"human"`
+	Expression string                     `json:"expression,omitempty"`
+	Source     string                     `json:"source,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
+}
+
+// ElementDefinitionSlicing describes how the repetitions of an element are divided into slices
+type ElementDefinitionSlicing struct {
+	Discriminator []ElementDefinitionDiscriminator `json:"discriminator,omitempty"`
+	Ordered       *bool                            `json:"ordered,omitempty"`
+	Rules         string                           `json:"rules"`
+	Unknown       map[string]json.RawMessage       `json:"-"`
+	Primitives    PrimitiveElements                `json:"-"`
+}
+
+// ElementDefinitionDiscriminator identifies the slice a repetition belongs to
+type ElementDefinitionDiscriminator struct {
+	Type       string                     `json:"type"`
+	Path       string                     `json:"path"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
+}
+
+// ElementDefinitionBinding binds a coded element to a value set
+type ElementDefinitionBinding struct {
+	Strength   string                     `json:"strength"`
+	ValueSet   string                     `json:"valueSet,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
+}
+
+// NewStructureDefinition creates a new StructureDefinition with the required fields
+func NewStructureDefinition() *StructureDefinition {
+	return &StructureDefinition{
+		Base: Base{
+			ResourceType: ResourceTypeStructureDefinition,
+		},
+		Status: "active",
+	}
+}
+
+// Name returns the element's name, the last part of its path
+func (e *ElementDefinition) Name() string {
+	return e.Path[strings.LastIndex(e.Path, ".")+1:]
+}
+
+// Fixed returns the element's fixed[x] value and its type suffix, such as
+// "Uri" for fixedUri, or nil if it has none
+func (e *ElementDefinition) Fixed() (json.RawMessage, string) {
+	return e.choiceValue("fixed")
+}
+
+// Pattern returns the element's pattern[x] value and its type suffix, or nil if it has none
+func (e *ElementDefinition) Pattern() (json.RawMessage, string) {
+	return e.choiceValue("pattern")
+}
+
+// choiceValue returns the value of the element's prefix[x] member
+func (e *ElementDefinition) choiceValue(prefix string) (json.RawMessage, string) {
+	for name, value := range e.Unknown {
+		if suffix := strings.TrimPrefix(name, prefix); suffix != name && suffix != "" && suffix[0] >= 'A' && suffix[0] <= 'Z' {
+			return value, suffix
+		}
+	}
+	return nil, ""
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for StructureDefinition, retaining primitive extensions and unrecognised members
+func (s *StructureDefinition) UnmarshalJSON(data []byte) error {
+	type Alias StructureDefinition
+	return decodeElement(data, (*Alias)(s), &s.Unknown, &s.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for StructureDefinition, re-emitting primitive extensions and unrecognised members
+func (s StructureDefinition) MarshalJSON() ([]byte, error) {
+	type Alias StructureDefinition
+	return encodeElement(Alias(s), s.Unknown, s.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for StructureDefinitionElementList, retaining primitive extensions and unrecognised members
+func (l *StructureDefinitionElementList) UnmarshalJSON(data []byte) error {
+	type Alias StructureDefinitionElementList
+	return decodeElement(data, (*Alias)(l), &l.Unknown, &l.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for StructureDefinitionElementList, re-emitting primitive extensions and unrecognised members
+func (l StructureDefinitionElementList) MarshalJSON() ([]byte, error) {
+	type Alias StructureDefinitionElementList
+	return encodeElement(Alias(l), l.Unknown, l.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ElementDefinition, retaining primitive extensions and unrecognised members
+func (e *ElementDefinition) UnmarshalJSON(data []byte) error {
+	type Alias ElementDefinition
+	return decodeElement(data, (*Alias)(e), &e.Unknown, &e.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ElementDefinition, re-emitting primitive extensions and unrecognised members
+func (e ElementDefinition) MarshalJSON() ([]byte, error) {
+	type Alias ElementDefinition
+	return encodeElement(Alias(e), e.Unknown, e.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ElementDefinitionType, retaining primitive extensions and unrecognised members
+func (t *ElementDefinitionType) UnmarshalJSON(data []byte) error {
+	type Alias ElementDefinitionType
+	return decodeElement(data, (*Alias)(t), &t.Unknown, &t.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ElementDefinitionType, re-emitting primitive extensions and unrecognised members
+func (t ElementDefinitionType) MarshalJSON() ([]byte, error) {
+	type Alias ElementDefinitionType
+	return encodeElement(Alias(t), t.Unknown, t.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ElementDefinitionConstraint, retaining primitive extensions and unrecognised members
+func (c *ElementDefinitionConstraint) UnmarshalJSON(data []byte) error {
+	type Alias ElementDefinitionConstraint
+	return decodeElement(data, (*Alias)(c), &c.Unknown, &c.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ElementDefinitionConstraint, re-emitting primitive extensions and unrecognised members
+func (c ElementDefinitionConstraint) MarshalJSON() ([]byte, error) {
+	type Alias ElementDefinitionConstraint
+	return encodeElement(Alias(c), c.Unknown, c.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ElementDefinitionSlicing, retaining primitive extensions and unrecognised members
+func (s *ElementDefinitionSlicing) UnmarshalJSON(data []byte) error {
+	type Alias ElementDefinitionSlicing
+	return decodeElement(data, (*Alias)(s), &s.Unknown, &s.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ElementDefinitionSlicing, re-emitting primitive extensions and unrecognised members
+func (s ElementDefinitionSlicing) MarshalJSON() ([]byte, error) {
+	type Alias ElementDefinitionSlicing
+	return encodeElement(Alias(s), s.Unknown, s.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ElementDefinitionDiscriminator, retaining primitive extensions and unrecognised members
+func (d *ElementDefinitionDiscriminator) UnmarshalJSON(data []byte) error {
+	type Alias ElementDefinitionDiscriminator
+	return decodeElement(data, (*Alias)(d), &d.Unknown, &d.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ElementDefinitionDiscriminator, re-emitting primitive extensions and unrecognised members
+func (d ElementDefinitionDiscriminator) MarshalJSON() ([]byte, error) {
+	type Alias ElementDefinitionDiscriminator
+	return encodeElement(Alias(d), d.Unknown, d.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ElementDefinitionBinding, retaining primitive extensions and unrecognised members
+func (b *ElementDefinitionBinding) UnmarshalJSON(data []byte) error {
+	type Alias ElementDefinitionBinding
+	return decodeElement(data, (*Alias)(b), &b.Unknown, &b.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ElementDefinitionBinding, re-emitting primitive extensions and unrecognised members
+func (b ElementDefinitionBinding) MarshalJSON() ([]byte, error) {
+	type Alias ElementDefinitionBinding
+	return encodeElement(Alias(b), b.Unknown, b.Primitives)
+}
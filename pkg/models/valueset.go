@@ -0,0 +1,208 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+)
+
+// ValueSet represents a FHIR ValueSet resource, a set of codes drawn from
+// one or more code systems
+type ValueSet struct {
+	Base
+	URL          string             `json:"url,omitempty"`
+	Identifier   []Identifier       `json:"identifier,omitempty"`
+	Version      string             `json:"version,omitempty"`
+	Name         string             `json:"name,omitempty"`
+	Title        string             `json:"title,omitempty"`
+	Status       string             `json:"status"`
+	Experimental *bool              `json:"experimental,omitempty"`
+	Date         *fhir.DateTime     `json:"date,omitempty"`
+	Publisher    string             `json:"publisher,omitempty"`
+	Description  string             `json:"description,omitempty"`
+	Immutable    *bool              `json:"immutable,omitempty"`
+	Compose      *ValueSetCompose   `json:"compose,omitempty"`
+	Expansion    *ValueSetExpansion `json:"expansion,omitempty"`
+}
+
+// ValueSetCompose holds the rules that define a value set's content
+type ValueSetCompose struct {
+	LockedDate *fhir.Date                 `json:"lockedDate,omitempty"`
+	Inactive   *bool                      `json:"inactive,omitempty"`
+	Include    []ValueSetInclude          `json:"include"`
+	Exclude    []ValueSetInclude          `json:"exclude,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
+}
+
+// ValueSetInclude selects codes from a code system or other value sets
+type ValueSetInclude struct {
+	System     string                     `json:"system,omitempty"`
+	Version    string                     `json:"version,omitempty"`
+	Concept    []ValueSetConcept          `json:"concept,omitempty"`
+	Filter     []ValueSetFilter           `json:"filter,omitempty"`
+	ValueSet   []string                   `json:"valueSet,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
+}
+
+// ValueSetConcept is a code listed explicitly in an include or exclude
+type ValueSetConcept struct {
+	Code        string                     `json:"code"`
+	Display     string                     `json:"display,omitempty"`
+	Designation []ValueSetDesignation      `json:"designation,omitempty"`
+	Unknown     map[string]json.RawMessage `json:"-"`
+	Primitives  PrimitiveElements          `json:"-"`
+}
+
+// ValueSetDesignation is an additional representation of a concept, such as
+// a display in another language
+type ValueSetDesignation struct {
+	Language   string                     `json:"language,omitempty"`
+	Use        *Coding                    `json:"use,omitempty"`
+	Value      string                     `json:"value"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
+}
+
+// ValueSetFilter selects codes by a property of the code system, such as
+// concepts that are a descendant of another (op "is-a")
+type ValueSetFilter struct {
+	Property   string                     `json:"property"`
+	Op         string                     `json:"op"`
+	Value      string                     `json:"value"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
+}
+
+// ValueSetExpansion is the list of codes a value set contains at a point in time
+type ValueSetExpansion struct {
+	Identifier string                     `json:"identifier,omitempty"`
+	Timestamp  *fhir.DateTime             `json:"timestamp,omitempty"`
+	Total      *int                       `json:"total,omitempty"`
+	Offset     *int                       `json:"offset,omitempty"`
+	Contains   []ValueSetContains         `json:"contains,omitempty"`
+	Unknown    map[string]json.RawMessage `json:"-"`
+	Primitives PrimitiveElements          `json:"-"`
+}
+
+// ValueSetContains is a code in an expansion. Hierarchical expansions nest
+// codes in Contains.
+type ValueSetContains struct {
+	System      string                     `json:"system,omitempty"`
+	Abstract    *bool                      `json:"abstract,omitempty"`
+	Inactive    *bool                      `json:"inactive,omitempty"`
+	Version     string                     `json:"version,omitempty"`
+	Code        string                     `json:"code,omitempty"`
+	Display     string                     `json:"display,omitempty"`
+	Designation []ValueSetDesignation      `json:"designation,omitempty"`
+	Contains    []ValueSetContains         `json:"contains,omitempty"`
+	Unknown     map[string]json.RawMessage `json:"-"`
+	Primitives  PrimitiveElements          `json:"-"`
+}
+
+// NewValueSet creates a new ValueSet with the required fields
+func NewValueSet() *ValueSet {
+	return &ValueSet{
+		Base: Base{
+			ResourceType: ResourceTypeValueSet,
+		},
+		Status: "active",
+	}
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ValueSet, retaining primitive extensions and unrecognised members
+func (v *ValueSet) UnmarshalJSON(data []byte) error {
+	type Alias ValueSet
+	return decodeElement(data, (*Alias)(v), &v.Unknown, &v.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ValueSet, re-emitting primitive extensions and unrecognised members
+func (v ValueSet) MarshalJSON() ([]byte, error) {
+	type Alias ValueSet
+	return encodeElement(Alias(v), v.Unknown, v.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ValueSetCompose, retaining primitive extensions and unrecognised members
+func (c *ValueSetCompose) UnmarshalJSON(data []byte) error {
+	type Alias ValueSetCompose
+	return decodeElement(data, (*Alias)(c), &c.Unknown, &c.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ValueSetCompose, re-emitting primitive extensions and unrecognised members
+func (c ValueSetCompose) MarshalJSON() ([]byte, error) {
+	type Alias ValueSetCompose
+	return encodeElement(Alias(c), c.Unknown, c.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ValueSetInclude, retaining primitive extensions and unrecognised members
+func (i *ValueSetInclude) UnmarshalJSON(data []byte) error {
+	type Alias ValueSetInclude
+	return decodeElement(data, (*Alias)(i), &i.Unknown, &i.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ValueSetInclude, re-emitting primitive extensions and unrecognised members
+func (i ValueSetInclude) MarshalJSON() ([]byte, error) {
+	type Alias ValueSetInclude
+	return encodeElement(Alias(i), i.Unknown, i.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ValueSetConcept, retaining primitive extensions and unrecognised members
+func (c *ValueSetConcept) UnmarshalJSON(data []byte) error {
+	type Alias ValueSetConcept
+	return decodeElement(data, (*Alias)(c), &c.Unknown, &c.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ValueSetConcept, re-emitting primitive extensions and unrecognised members
+func (c ValueSetConcept) MarshalJSON() ([]byte, error) {
+	type Alias ValueSetConcept
+	return encodeElement(Alias(c), c.Unknown, c.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ValueSetDesignation, retaining primitive extensions and unrecognised members
+func (d *ValueSetDesignation) UnmarshalJSON(data []byte) error {
+	type Alias ValueSetDesignation
+	return decodeElement(data, (*Alias)(d), &d.Unknown, &d.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ValueSetDesignation, re-emitting primitive extensions and unrecognised members
+func (d ValueSetDesignation) MarshalJSON() ([]byte, error) {
+	type Alias ValueSetDesignation
+	return encodeElement(Alias(d), d.Unknown, d.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ValueSetFilter, retaining primitive extensions and unrecognised members
+func (f *ValueSetFilter) UnmarshalJSON(data []byte) error {
+	type Alias ValueSetFilter
+	return decodeElement(data, (*Alias)(f), &f.Unknown, &f.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ValueSetFilter, re-emitting primitive extensions and unrecognised members
+func (f ValueSetFilter) MarshalJSON() ([]byte, error) {
+	type Alias ValueSetFilter
+	return encodeElement(Alias(f), f.Unknown, f.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ValueSetExpansion, retaining primitive extensions and unrecognised members
+func (e *ValueSetExpansion) UnmarshalJSON(data []byte) error {
+	type Alias ValueSetExpansion
+	return decodeElement(data, (*Alias)(e), &e.Unknown, &e.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ValueSetExpansion, re-emitting primitive extensions and unrecognised members
+func (e ValueSetExpansion) MarshalJSON() ([]byte, error) {
+	type Alias ValueSetExpansion
+	return encodeElement(Alias(e), e.Unknown, e.Primitives)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for ValueSetContains, retaining primitive extensions and unrecognised members
+func (c *ValueSetContains) UnmarshalJSON(data []byte) error {
+	type Alias ValueSetContains
+	return decodeElement(data, (*Alias)(c), &c.Unknown, &c.Primitives)
+}
+
+// MarshalJSON implements custom JSON marshaling for ValueSetContains, re-emitting primitive extensions and unrecognised members
+func (c ValueSetContains) MarshalJSON() ([]byte, error) {
+	type Alias ValueSetContains
+	return encodeElement(Alias(c), c.Unknown, c.Primitives)
+}
@@ -0,0 +1,221 @@
+package ndjson
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+// gzipMagic starts every gzip stream and can never start an NDJSON line
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// LineError is an error decoding a single line of an NDJSON stream
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Reader decodes FHIR resources from NDJSON, one resource per line. Input
+// compressed with gzip is detected and decompressed automatically.
+type Reader struct {
+	src    *bufio.Reader
+	lines  *bufio.Reader
+	gz     *gzip.Reader
+	mapper *models.ResourceMapper
+
+	line       int
+	skipErrors bool
+	errs       []*LineError
+}
+
+// NewReader creates a reader decoding resources from r with mapper. If
+// mapper is nil, resources of the standard types are decoded to their structs
+// and those of other types as *models.GenericResource.
+func NewReader(r io.Reader, mapper *models.ResourceMapper) *Reader {
+	if mapper == nil {
+		mapper = models.NewResourceMapper().WithGenericFallback()
+	}
+	return &Reader{
+		src:    bufio.NewReader(r),
+		mapper: mapper,
+	}
+}
+
+// SkipErrors sets whether lines that fail to decode are skipped rather than
+// ending the read. Skipped lines are available from Errors.
+func (r *Reader) SkipErrors(skip bool) {
+	r.skipErrors = skip
+}
+
+// Errors returns the errors for the lines skipped so far
+func (r *Reader) Errors() []*LineError {
+	return r.errs
+}
+
+// Line returns the number of the last line read, counting from 1
+func (r *Reader) Line() int {
+	return r.line
+}
+
+// init detects gzip input on the first read
+func (r *Reader) init() error {
+	if r.lines != nil {
+		return nil
+	}
+	if magic, _ := r.src.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(r.src)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		r.gz = gz
+		r.lines = bufio.NewReader(gz)
+		return nil
+	}
+	r.lines = r.src
+	return nil
+}
+
+// Read returns the next resource. Blank lines are ignored. It returns io.EOF
+// at the end of the input, and a *LineError for a line that cannot be decoded.
+func (r *Reader) Read() (models.Resource, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+
+	for {
+		data, err := r.lines.ReadBytes('\n')
+		if len(data) == 0 && err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("failed to read line %d: %w", r.line+1, err)
+		}
+		r.line++
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+
+		resource, decodeErr := r.mapper.UnmarshalResource(data)
+		if decodeErr == nil {
+			return resource, nil
+		}
+		lineErr := &LineError{Line: r.line, Err: decodeErr}
+		if !r.skipErrors {
+			return nil, lineErr
+		}
+		r.errs = append(r.errs, lineErr)
+	}
+}
+
+// ReadAll reads all remaining resources
+func (r *Reader) ReadAll() ([]models.Resource, error) {
+	var resources []models.Resource
+	for {
+		resource, err := r.Read()
+		if err == io.EOF {
+			return resources, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+}
+
+// Close releases the gzip stream, if any. It does not close the underlying reader.
+func (r *Reader) Close() error {
+	if r.gz != nil {
+		return r.gz.Close()
+	}
+	return nil
+}
+
+// Writer encodes FHIR resources as NDJSON, one resource per line
+type Writer struct {
+	buf   *bufio.Writer
+	gz    *gzip.Writer
+	count int
+}
+
+// NewWriter creates a writer writing uncompressed NDJSON to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{buf: bufio.NewWriter(w)}
+}
+
+// NewGzipWriter creates a writer writing gzip-compressed NDJSON to w
+func NewGzipWriter(w io.Writer) *Writer {
+	gz := gzip.NewWriter(w)
+	return &Writer{buf: bufio.NewWriter(gz), gz: gz}
+}
+
+// Write writes a resource as a single line
+func (w *Writer) Write(resource models.Resource) error {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return fmt.Errorf("failed to marshal resource %d: %w", w.count+1, err)
+	}
+	return w.writeLine(data)
+}
+
+// WriteRaw writes a resource that is already JSON, compacting it onto a single line
+func (w *Writer) WriteRaw(data json.RawMessage) error {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return fmt.Errorf("failed to compact resource %d: %w", w.count+1, err)
+	}
+	return w.writeLine(compact.Bytes())
+}
+
+func (w *Writer) writeLine(data []byte) error {
+	if _, err := w.buf.Write(data); err != nil {
+		return err
+	}
+	if err := w.buf.WriteByte('\n'); err != nil {
+		return err
+	}
+	w.count++
+	return nil
+}
+
+// Count returns the number of resources written
+func (w *Writer) Count() int {
+	return w.count
+}
+
+// Flush writes any buffered data to the underlying writer
+func (w *Writer) Flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Flush()
+	}
+	return nil
+}
+
+// Close flushes the writer and ends the gzip stream, if any. It does not
+// close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package ndjson
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+const input = `{"resourceType":"Patient","id":"1"}
+{"resourceType":"Basic","id":"2"}
+
+{"id":"3"}
+{"resourceType":"Patient","id":"4"}
+`
+
+func TestReader(t *testing.T) {
+	r := NewReader(strings.NewReader(input), nil)
+
+	first, err := r.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if p, ok := first.(*models.Patient); !ok || p.ID != "1" {
+		t.Errorf("Expected Patient 1, got %#v", first)
+	}
+	if _, err := r.Read(); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	_, err = r.Read()
+	var lineErr *LineError
+	if !errors.As(err, &lineErr) || lineErr.Line != 4 {
+		t.Fatalf("Expected an error on line 4, got %v", err)
+	}
+}
+
+func TestReaderSkipErrors(t *testing.T) {
+	r := NewReader(strings.NewReader(input), nil)
+	r.SkipErrors(true)
+
+	resources, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	if len(resources) != 3 {
+		t.Errorf("Expected 3 resources, got %d", len(resources))
+	}
+	if errs := r.Errors(); len(errs) != 1 || errs[0].Line != 4 {
+		t.Errorf("Expected one error on line 4, got %v", errs)
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	for _, compressed := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		if compressed {
+			w = NewGzipWriter(&buf)
+		}
+
+		if err := w.Write(&models.Patient{Base: models.Base{ResourceType: models.ResourceTypePatient, ID: "a"}}); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if err := w.WriteRaw([]byte("{\n  \"resourceType\": \"Basic\",\n  \"id\": \"b\"\n}")); err != nil {
+			t.Fatalf("WriteRaw failed: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if w.Count() != 2 {
+			t.Errorf("Expected count 2, got %d", w.Count())
+		}
+		if !compressed && strings.Count(buf.String(), "\n") != 2 {
+			t.Errorf("Expected two lines, got %q", buf.String())
+		}
+
+		r := NewReader(&buf, nil)
+		resources, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("ReadAll failed (gzip %v): %v", compressed, err)
+		}
+		if len(resources) != 2 || resources[1].GetResourceType() != "Basic" {
+			t.Errorf("Unexpected resources (gzip %v): %v", compressed, resources)
+		}
+		if _, err := r.Read(); err != io.EOF {
+			t.Errorf("Expected io.EOF, got %v", err)
+		}
+		r.Close()
+	}
+}
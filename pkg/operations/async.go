@@ -0,0 +1,330 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+// AcceptedError is returned by the synchronous methods when the server
+// chose to process the request asynchronously. The request's result can be
+// fetched with the job returned by Job.
+type AcceptedError struct {
+	// StatusURL is the Content-Location of the status endpoint
+	StatusURL string
+
+	op *HTTPOperation
+}
+
+func (e *AcceptedError) Error() string {
+	return "request accepted for asynchronous processing, status at " + e.StatusURL
+}
+
+// Job returns a handle for polling the accepted request
+func (e *AcceptedError) Job() *AsyncJob {
+	return e.op.Job(e.StatusURL)
+}
+
+// AsyncStatus is the result of a single status request for an asynchronous job
+type AsyncStatus struct {
+	// Complete is true once the job has finished and Body holds its result
+	Complete bool
+
+	// Progress is the server's X-Progress description of an in-progress job
+	Progress string
+
+	// RetryAfter is the delay the server asked for before the next poll, or zero
+	RetryAfter time.Duration
+
+	// Body is the completion response
+	Body []byte
+}
+
+// AsyncJob is a request the server is processing asynchronously
+type AsyncJob struct {
+	op *HTTPOperation
+
+	// StatusURL is the Content-Location of the job's status endpoint, which
+	// may be relative to the base URL. It is empty when the server completed
+	// the request straight away.
+	StatusURL string
+
+	// PollInterval is the first wait between status requests when the server
+	// sends no Retry-After. It doubles on each poll up to MaxPollInterval.
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+
+	// OnProgress is called with the X-Progress header of each in-progress status response
+	OnProgress func(progress string)
+
+	// result holds the response of a request completed without a status endpoint
+	result []byte
+}
+
+// defaultPollInterval is the first wait between status requests of a job
+// whose PollInterval is not set
+const defaultPollInterval = time.Second
+
+// Job returns a handle for a job started earlier, from its status URL
+func (o *HTTPOperation) Job(statusURL string) *AsyncJob {
+	return &AsyncJob{
+		op:              o,
+		StatusURL:       statusURL,
+		PollInterval:    defaultPollInterval,
+		MaxPollInterval: time.Minute,
+	}
+}
+
+// SendAsync sends a request with Prefer: respond-async and returns a handle
+// for its job. path is relative to the base URL and may include a query.
+// Servers that complete the request straight away return a job that is
+// already complete.
+func (o *HTTPOperation) SendAsync(ctx context.Context, method, path string, body interface{}) (*AsyncJob, error) {
+	if err := o.checkPath(path); err != nil {
+		return nil, err
+	}
+	req, err := o.newRequest(ctx, method, o.buildURL(path), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Prefer", "respond-async")
+
+	resp, err := o.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		location := resp.Header.Get("Content-Location")
+		if location == "" {
+			return nil, fmt.Errorf("asynchronous response has no Content-Location")
+		}
+		return o.Job(location), nil
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		job := o.Job("")
+		job.result = respBody
+		return job, nil
+	default:
+		return nil, statusError(resp)
+	}
+}
+
+// OperationAsync starts a custom operation asynchronously. path is the
+// operation's path relative to the base URL, e.g. "Patient/123/$everything".
+// A nil input sends a GET.
+func (o *HTTPOperation) OperationAsync(ctx context.Context, path string, input interface{}) (*AsyncJob, error) {
+	method := http.MethodPost
+	if input == nil {
+		method = http.MethodGet
+	}
+	return o.SendAsync(ctx, method, path, input)
+}
+
+// TransactionAsync starts a batch or transaction asynchronously
+func (o *HTTPOperation) TransactionAsync(ctx context.Context, bundle interface{}) (*AsyncJob, error) {
+	if err := o.checkBundle(bundle); err != nil {
+		return nil, err
+	}
+	return o.SendAsync(ctx, http.MethodPost, "", bundle)
+}
+
+// statusURL resolves StatusURL against the base URL
+func (j *AsyncJob) statusURL() (string, error) {
+	base, err := url.Parse(strings.TrimSuffix(j.op.baseURL, "/") + "/")
+	if err != nil {
+		return "", fmt.Errorf("failed to parse base URL: %w", err)
+	}
+	ref, err := url.Parse(j.StatusURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse status URL: %w", err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// Status requests the job's status once
+func (j *AsyncJob) Status(ctx context.Context) (*AsyncStatus, error) {
+	if j.StatusURL == "" {
+		return &AsyncStatus{Complete: true, Body: j.result}, nil
+	}
+
+	statusURL, err := j.statusURL()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/fhir+json, application/json")
+
+	resp, err := j.op.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusAccepted, http.StatusTooManyRequests:
+		return &AsyncStatus{
+			Progress:   resp.Header.Get("X-Progress"),
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}, nil
+	case http.StatusOK:
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		return &AsyncStatus{Complete: true, Body: body}, nil
+	default:
+		return nil, statusError(resp)
+	}
+}
+
+// Poll polls the job's status until it completes, honouring Retry-After and
+// backing off otherwise, and returns the completion response body
+func (j *AsyncJob) Poll(ctx context.Context) ([]byte, error) {
+	interval := j.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	for {
+		status, err := j.Status(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if status.Complete {
+			return status.Body, nil
+		}
+		if j.OnProgress != nil && status.Progress != "" {
+			j.OnProgress(status.Progress)
+		}
+
+		wait := status.RetryAfter
+		if wait == 0 {
+			wait = interval
+			interval *= 2
+			if j.MaxPollInterval > 0 && interval > j.MaxPollInterval {
+				interval = j.MaxPollInterval
+			}
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Wait polls until the job completes and returns its result, usually a
+// batch-response Bundle or an OperationOutcome
+func (j *AsyncJob) Wait(ctx context.Context) (models.Resource, error) {
+	body, err := j.Poll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return nil, nil
+	}
+	return j.op.mapper.UnmarshalResource(body)
+}
+
+// WaitBundle polls until the job completes and returns its result Bundle.
+// An OperationOutcome result is returned as an error.
+func (j *AsyncJob) WaitBundle(ctx context.Context) (*models.Bundle, error) {
+	resource, err := j.Wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch r := resource.(type) {
+	case *models.Bundle:
+		return r, nil
+	case *models.OperationOutcome:
+		return nil, &OutcomeError{Outcome: r}
+	case nil:
+		return nil, fmt.Errorf("asynchronous job completed without a result")
+	default:
+		return nil, fmt.Errorf("asynchronous job returned %s, not Bundle", resource.GetResourceType())
+	}
+}
+
+// Cancel asks the server to cancel the job, or to discard its result once it has been fetched
+func (j *AsyncJob) Cancel(ctx context.Context) error {
+	if j.StatusURL == "" {
+		return nil
+	}
+
+	statusURL, err := j.statusURL()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, statusURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := j.op.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return statusError(resp)
+	}
+	return nil
+}
+
+// OutcomeError is an OperationOutcome returned in place of the expected result
+type OutcomeError struct {
+	Outcome *models.OperationOutcome
+}
+
+func (e *OutcomeError) Error() string {
+	if len(e.Outcome.Issue) == 0 {
+		return "server returned an OperationOutcome"
+	}
+	msg := e.Outcome.Issue[0].Message()
+	if n := len(e.Outcome.Issue) - 1; n > 0 {
+		msg += fmt.Sprintf(" (and %d more issues)", n)
+	}
+	return msg
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,194 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const batchResponse = `{"resourceType":"Bundle","type":"batch-response","entry":[` +
+	`{"resource":{"resourceType":"Patient","id":"1"},"response":{"status":"200 OK"}}]}`
+
+// fakeAsyncServer accepts requests asynchronously and completes them on the second poll
+type fakeAsyncServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	polls    int
+	canceled bool
+	result   string
+}
+
+func newFakeAsyncServer(t *testing.T, result string) *fakeAsyncServer {
+	f := &fakeAsyncServer{result: result}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/status" && r.Method == http.MethodDelete:
+			f.canceled = true
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == "/status":
+			f.polls++
+			if f.polls == 1 {
+				w.Header().Set("X-Progress", "reindexing")
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			io.WriteString(w, f.result)
+		case r.Header.Get("Prefer") == "respond-async" || r.URL.Path == "/Patient/1/$everything":
+			w.Header().Set("Content-Location", f.URL+"/status")
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			io.WriteString(w, f.result)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestOperationAsync(t *testing.T) {
+	server := newFakeAsyncServer(t, batchResponse)
+	op := NewHTTPOperation(server.Client(), server.URL)
+	ctx := context.Background()
+
+	job, err := op.OperationAsync(ctx, "$reindex", nil)
+	if err != nil {
+		t.Fatalf("OperationAsync failed: %v", err)
+	}
+	job.PollInterval = time.Millisecond
+	var progress []string
+	job.OnProgress = func(p string) { progress = append(progress, p) }
+
+	bundle, err := job.WaitBundle(ctx)
+	if err != nil {
+		t.Fatalf("WaitBundle failed: %v", err)
+	}
+	if bundle.Type != "batch-response" || len(bundle.Entry) != 1 || bundle.Entry[0].Response.Status != "200 OK" {
+		t.Errorf("Unexpected result %+v", bundle)
+	}
+	if server.polls != 2 || len(progress) != 1 || progress[0] != "reindexing" {
+		t.Errorf("Expected two polls reporting progress once, got %d and %v", server.polls, progress)
+	}
+
+	if err := job.Cancel(ctx); err != nil || !server.canceled {
+		t.Errorf("Cancel failed: %v", err)
+	}
+}
+
+func TestAcceptedError(t *testing.T) {
+	server := newFakeAsyncServer(t, batchResponse)
+	op := NewHTTPOperation(server.Client(), server.URL)
+	ctx := context.Background()
+
+	_, err := op.OperationStream(ctx, "Patient/1/$everything", nil)
+	var accepted *AcceptedError
+	if !errors.As(err, &accepted) || accepted.StatusURL != server.URL+"/status" {
+		t.Fatalf("Expected an AcceptedError, got %v", err)
+	}
+
+	job := accepted.Job()
+	job.PollInterval = time.Millisecond
+	if _, err := job.WaitBundle(ctx); err != nil {
+		t.Errorf("WaitBundle failed: %v", err)
+	}
+}
+
+func TestRelativeStatusURL(t *testing.T) {
+	for _, location := range []string{"status/1", "/fhir/status/1"} {
+		var canceled bool
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/fhir/status/1" && r.Method == http.MethodDelete:
+				canceled = true
+				w.WriteHeader(http.StatusAccepted)
+			case r.URL.Path == "/fhir/status/1":
+				io.WriteString(w, batchResponse)
+			case r.URL.Path == "/fhir/$reindex":
+				w.Header().Set("Content-Location", location)
+				w.WriteHeader(http.StatusAccepted)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		op := NewHTTPOperation(server.Client(), server.URL+"/fhir")
+		ctx := context.Background()
+
+		job, err := op.OperationAsync(ctx, "$reindex", nil)
+		if err != nil {
+			t.Fatalf("%s: OperationAsync failed: %v", location, err)
+		}
+		if _, err := job.WaitBundle(ctx); err != nil {
+			t.Errorf("%s: WaitBundle failed: %v", location, err)
+		}
+		if err := job.Cancel(ctx); err != nil || !canceled {
+			t.Errorf("%s: Cancel failed: %v", location, err)
+		}
+		server.Close()
+	}
+}
+
+func TestPollWithoutInterval(t *testing.T) {
+	var mu sync.Mutex
+	var polls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	job := NewHTTPOperation(server.Client(), server.URL).Job(server.URL + "/status")
+	job.PollInterval = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := job.Poll(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to pass, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if polls != 1 {
+		t.Errorf("Expected a single poll before the default interval passed, got %d", polls)
+	}
+}
+
+func TestSendAsyncCompletedSynchronously(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"not-supported","diagnostics":"no async"}]}`)
+	}))
+	defer server.Close()
+	op := NewHTTPOperation(server.Client(), server.URL)
+
+	job, err := op.TransactionAsync(context.Background(), map[string]string{"resourceType": "Bundle"})
+	if err != nil {
+		t.Fatalf("TransactionAsync failed: %v", err)
+	}
+	if job.StatusURL != "" {
+		t.Errorf("Expected a completed job, got status URL %q", job.StatusURL)
+	}
+
+	_, err = job.WaitBundle(context.Background())
+	var outcome *OutcomeError
+	if !errors.As(err, &outcome) || outcome.Error() != "error not-supported: no async" {
+		t.Errorf("Expected an OutcomeError, got %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if d := retryAfter("120"); d != 2*time.Minute {
+		t.Errorf("Expected 2m, got %v", d)
+	}
+	if d := retryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); d < 59*time.Minute {
+		t.Errorf("Expected about an hour, got %v", d)
+	}
+	if d := retryAfter("soon"); d != 0 {
+		t.Errorf("Expected 0, got %v", d)
+	}
+}
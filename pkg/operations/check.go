@@ -0,0 +1,89 @@
+package operations
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// checkPath checks the resource types of a path relative to the base URL:
+// the type it starts with, if any, and those listed in its _type and
+// _typeFilter parameters, as in a bulk export
+func (o *HTTPOperation) checkPath(path string) error {
+	if o.version == nil {
+		return nil
+	}
+	path, query, _ := strings.Cut(path, "?")
+	if err := o.checkResourceType(pathResourceType(path)); err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		// The server reports malformed queries
+		return nil
+	}
+	for _, types := range values["_type"] {
+		for _, resourceType := range strings.Split(types, ",") {
+			if err := o.checkResourceType(strings.TrimSpace(resourceType)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, filter := range values["_typeFilter"] {
+		resourceType, _, _ := strings.Cut(filter, "?")
+		if err := o.checkResourceType(resourceType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkBundle checks the resource types of the entries of a batch or
+// transaction Bundle, both of their resources and of their request URLs
+func (o *HTTPOperation) checkBundle(bundle interface{}) error {
+	if o.version == nil {
+		return nil
+	}
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	var b struct {
+		Entry []struct {
+			Resource struct {
+				ResourceType string `json:"resourceType"`
+			} `json:"resource"`
+			Request struct {
+				URL string `json:"url"`
+			} `json:"request"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		// The server reports bodies that are not Bundles
+		return nil
+	}
+	for i, entry := range b.Entry {
+		if err := o.checkResourceType(entry.Resource.ResourceType); err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+		path := strings.TrimPrefix(entry.Request.URL, o.baseURL+"/")
+		if strings.Contains(path, "://") {
+			continue
+		}
+		if err := o.checkPath(path); err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// pathResourceType returns the resource type a relative path starts with,
+// or "" if it starts with something else, such as $export or metadata
+func pathResourceType(path string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if segment == "" || segment[0] < 'A' || segment[0] > 'Z' {
+		return ""
+	}
+	return segment
+}
@@ -0,0 +1,152 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+// EverythingRequest holds the optional parameters of a Patient $everything request
+type EverythingRequest struct {
+	// Start and End limit the record to care dates in the range
+	Start *fhir.Date
+	End   *fhir.Date
+
+	// Since limits the record to resources changed after the instant
+	Since *fhir.Instant
+
+	// Types limits the record to resources of the given types
+	Types []string
+
+	// Count is the page size to ask the server for, or zero for its default
+	Count int
+}
+
+// parameters builds the operation's input parameters
+func (r *EverythingRequest) parameters() *models.Parameters {
+	params := models.NewParameters()
+	if r == nil {
+		return params
+	}
+	if r.Start != nil {
+		params.AddDate("start", *r.Start)
+	}
+	if r.End != nil {
+		params.AddDate("end", *r.End)
+	}
+	if r.Since != nil {
+		params.AddInstant("_since", *r.Since)
+	}
+	if len(r.Types) > 0 {
+		params.AddString("_type", strings.Join(r.Types, ","))
+	}
+	if r.Count > 0 {
+		params.AddInteger("_count", r.Count)
+	}
+	return params
+}
+
+// EverythingResult is a patient's record returned by $everything
+type EverythingResult struct {
+	// Resources holds the record's resources keyed by resource type, in the
+	// order the server returned them
+	Resources map[string][]models.Resource
+
+	// Outcomes holds OperationOutcomes the server returned alongside the
+	// record, such as warnings that it was truncated
+	Outcomes []*models.OperationOutcome
+
+	// Pages is the number of pages fetched
+	Pages int
+}
+
+// Types returns the resource types in the record, sorted
+func (r *EverythingResult) Types() []string {
+	types := make([]string, 0, len(r.Resources))
+	for t := range r.Resources {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Patient returns the Patient the record belongs to, if the server included it
+func (r *EverythingResult) Patient() *models.Patient {
+	for _, resource := range r.Resources[string(models.ResourceTypePatient)] {
+		if patient, ok := resource.(*models.Patient); ok {
+			return patient
+		}
+	}
+	return nil
+}
+
+// add adds the resources of one page, skipping any already seen on an earlier page
+func (r *EverythingResult) add(bundle *models.Bundle, seen map[string]bool) error {
+	for i := range bundle.Entry {
+		entry := &bundle.Entry[i]
+		resource, err := entry.GetResource()
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal bundle entry %d: %w", i, err)
+		}
+		if resource == nil {
+			continue
+		}
+		if outcome, ok := resource.(*models.OperationOutcome); ok &&
+			(entry.Search == nil || entry.Search.Mode == models.SearchModeOutcome) {
+			r.Outcomes = append(r.Outcomes, outcome)
+			continue
+		}
+
+		resourceType := resource.GetResourceType()
+		if withID, ok := resource.(interface{ GetID() string }); ok && withID.GetID() != "" {
+			key := resourceType + "/" + withID.GetID()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		r.Resources[resourceType] = append(r.Resources[resourceType], resource)
+	}
+	return nil
+}
+
+// Everything fetches a patient's record with Patient/[id]/$everything,
+// following next links until the last page, and returns the resources
+// grouped by type. req may be nil. Resources of types the operation's mapper
+// does not register are returned as *models.GenericResource.
+func (o *HTTPOperation) Everything(ctx context.Context, patientID string, req *EverythingRequest) (*EverythingResult, error) {
+	bundle, err := o.InvokeBundle(ctx, &Invocation{
+		Name:         "everything",
+		ResourceType: string(models.ResourceTypePatient),
+		ID:           patientID,
+		Parameters:   req.parameters(),
+		UseGET:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EverythingResult{Resources: make(map[string][]models.Resource)}
+	seen := make(map[string]bool)
+	fetched := make(map[string]bool)
+	for bundle != nil {
+		result.Pages++
+		if err := result.add(bundle, seen); err != nil {
+			return nil, fmt.Errorf("page %d: %w", result.Pages, err)
+		}
+
+		next := bundle.NextLink()
+		if next == "" || fetched[next] {
+			break
+		}
+		fetched[next] = true
+		if bundle, err = o.NextPage(ctx, bundle); err != nil {
+			return nil, fmt.Errorf("failed to fetch page %d: %w", result.Pages+1, err)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+func TestEverything(t *testing.T) {
+	var firstQuery string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		switch r.URL.Path {
+		case "/Patient/p1/$everything":
+			firstQuery = r.URL.RawQuery
+			io.WriteString(w, `{"resourceType":"Bundle","type":"searchset",`+
+				`"link":[{"relation":"next","url":"`+server.URL+`/page2"}],"entry":[`+
+				`{"resource":{"resourceType":"Patient","id":"p1"}},`+
+				`{"resource":{"resourceType":"Condition","id":"c1"}}]}`)
+		case "/page2":
+			io.WriteString(w, `{"resourceType":"Bundle","type":"searchset","entry":[`+
+				`{"resource":{"resourceType":"Condition","id":"c1"}},`+
+				`{"resource":{"resourceType":"Condition","id":"c2"}},`+
+				`{"resource":{"resourceType":"OperationOutcome","issue":[{"severity":"warning","code":"incomplete"}]},"search":{"mode":"outcome"}}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	op := NewHTTPOperation(server.Client(), server.URL)
+
+	start, _ := fhir.ParseDate("2020-01-01")
+	result, err := op.Everything(context.Background(), "p1", &EverythingRequest{
+		Start: &start,
+		Types: []string{"Patient", "Condition"},
+		Count: 2,
+	})
+	if err != nil {
+		t.Fatalf("Everything failed: %v", err)
+	}
+
+	if want := "_count=2&_type=Patient%2CCondition&start=2020-01-01"; firstQuery != want {
+		t.Errorf("Expected query %q, got %q", want, firstQuery)
+	}
+	if result.Pages != 2 {
+		t.Errorf("Expected 2 pages, got %d", result.Pages)
+	}
+	if types := result.Types(); len(types) != 2 || types[0] != "Condition" || types[1] != "Patient" {
+		t.Errorf("Unexpected types %v", types)
+	}
+	if n := len(result.Resources["Condition"]); n != 2 {
+		t.Errorf("Expected 2 distinct conditions, got %d", n)
+	}
+	if patient := result.Patient(); patient == nil || patient.ID != "p1" {
+		t.Errorf("Expected patient p1, got %+v", patient)
+	}
+	if len(result.Outcomes) != 1 {
+		t.Errorf("Expected 1 outcome, got %d", len(result.Outcomes))
+	}
+	if _, ok := result.Resources[string(models.ResourceTypeOperationOutcome)]; ok {
+		t.Error("Expected outcomes to be kept apart from the record")
+	}
+}
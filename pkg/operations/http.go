@@ -4,20 +4,31 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/search"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/version"
 )
 
+// ErrBodyTooLarge is returned when a response body exceeds the limit set with SetMaxBodySize
+var ErrBodyTooLarge = errors.New("response body exceeds maximum size")
+
+// ErrUnsupportedResourceType is returned, before a request is sent, for
+// resource types that the FHIR version set with SetVersion does not define
+var ErrUnsupportedResourceType = errors.New("resource type not defined in FHIR version")
+
 // HTTPOperation implements the Operation interface using HTTP
 type HTTPOperation struct {
-	client  *http.Client
-	baseURL string
-	headers map[string]string
-	mapper  *models.ResourceMapper
+	client      *http.Client
+	baseURL     string
+	headers     map[string]string
+	mapper      *models.ResourceMapper
+	maxBodySize int64
+	version     version.VersionManager
 }
 
 // NewHTTPOperation creates a new HTTP operation handler
@@ -34,8 +45,8 @@ func NewHTTPOperation(client *http.Client, baseURL string) *HTTPOperation {
 	}
 }
 
-// doRequest performs an HTTP request and returns the response
-func (o *HTTPOperation) doRequest(ctx context.Context, method, url string, body interface{}) (json.RawMessage, error) {
+// newRequest creates a request to url with body encoded as FHIR JSON if it is not nil
+func (o *HTTPOperation) newRequest(ctx context.Context, method, url string, body interface{}) (*http.Request, error) {
 	var reqBody io.Reader
 	if body != nil {
 		jsonData, err := json.Marshal(body)
@@ -49,20 +60,62 @@ func (o *HTTPOperation) doRequest(ctx context.Context, method, url string, body
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-
-	// Set headers
-	req.Header.Set("Accept", "application/fhir+json")
 	if body != nil {
 		req.Header.Set("Content-Type", "application/fhir+json")
 	}
-	for k, v := range o.headers {
-		req.Header.Set(k, v)
+	return req, nil
+}
+
+// send performs an HTTP request and returns the response if its status is
+// successful. The caller must close the response body, which is limited to
+// the maximum body size. A 202 Accepted with a status endpoint is returned
+// as an *AcceptedError.
+func (o *HTTPOperation) send(ctx context.Context, method, url string, body interface{}) (*http.Response, error) {
+	req, err := o.newRequest(ctx, method, url, body)
+	if err != nil {
+		return nil, err
 	}
 
-	resp, err := o.client.Do(req)
+	resp, err := o.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
+	if o.maxBodySize > 0 {
+		if resp.ContentLength > o.maxBodySize {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w: content length %d exceeds %d bytes", ErrBodyTooLarge, resp.ContentLength, o.maxBodySize)
+		}
+		resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: o.maxBodySize}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, statusError(resp)
+	}
+	if location := resp.Header.Get("Content-Location"); resp.StatusCode == http.StatusAccepted && location != "" {
+		resp.Body.Close()
+		return nil, &AcceptedError{StatusURL: location, op: o}
+	}
+
+	return resp, nil
+}
+
+// statusError describes an unsuccessful response, including its body, which
+// is typically an OperationOutcome
+func statusError(resp *http.Response) error {
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	return fmt.Errorf("server returned error status %d: %s", resp.StatusCode, string(respBody))
+}
+
+// doRequest performs an HTTP request and returns the response
+func (o *HTTPOperation) doRequest(ctx context.Context, method, url string, body interface{}) (json.RawMessage, error) {
+	resp, err := o.send(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
@@ -70,15 +123,47 @@ func (o *HTTPOperation) doRequest(ctx context.Context, method, url string, body
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("server returned error status %d: %s", resp.StatusCode, string(respBody))
+	return respBody, nil
+}
+
+// limitedBody fails with ErrBodyTooLarge once more than remaining bytes are read
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrBodyTooLarge
 	}
+	// Read one byte past the limit so that a body of exactly the limit is allowed
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.ReadCloser.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), ErrBodyTooLarge
+	}
+	return n, err
+}
 
-	return respBody, nil
+// bundleRequest performs an HTTP request and decodes the Bundle response as it is read
+func (o *HTTPOperation) bundleRequest(ctx context.Context, method, url string, body interface{}) (*models.Bundle, error) {
+	resp, err := o.send(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return o.mapper.DecodeBundle(resp.Body)
 }
 
 // Read retrieves a resource by ID and returns a typed resource
 func (o *HTTPOperation) Read(ctx context.Context, resourceType, id string) (models.Resource, error) {
+	if err := o.checkResourceType(resourceType); err != nil {
+		return nil, err
+	}
 	url := o.buildURL(resourceType, id)
 	data, err := o.doRequest(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -89,6 +174,9 @@ func (o *HTTPOperation) Read(ctx context.Context, resourceType, id string) (mode
 
 // Vread retrieves a specific version of a resource and returns a typed resource
 func (o *HTTPOperation) Vread(ctx context.Context, resourceType, id, versionId string) (models.Resource, error) {
+	if err := o.checkResourceType(resourceType); err != nil {
+		return nil, err
+	}
 	url := o.buildURL(resourceType, id, "_history", versionId)
 	data, err := o.doRequest(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -99,6 +187,9 @@ func (o *HTTPOperation) Vread(ctx context.Context, resourceType, id, versionId s
 
 // Create creates a new resource and returns the typed created resource
 func (o *HTTPOperation) Create(ctx context.Context, resourceType string, resource interface{}) (models.Resource, error) {
+	if err := o.checkResourceType(resourceType); err != nil {
+		return nil, err
+	}
 	url := o.buildURL(resourceType)
 	data, err := o.doRequest(ctx, http.MethodPost, url, resource)
 	if err != nil {
@@ -109,6 +200,9 @@ func (o *HTTPOperation) Create(ctx context.Context, resourceType string, resourc
 
 // Update updates an existing resource and returns the typed updated resource
 func (o *HTTPOperation) Update(ctx context.Context, resourceType, id string, resource interface{}) (models.Resource, error) {
+	if err := o.checkResourceType(resourceType); err != nil {
+		return nil, err
+	}
 	url := o.buildURL(resourceType, id)
 	data, err := o.doRequest(ctx, http.MethodPut, url, resource)
 	if err != nil {
@@ -119,6 +213,9 @@ func (o *HTTPOperation) Update(ctx context.Context, resourceType, id string, res
 
 // Patch patches an existing resource and returns the typed patched resource
 func (o *HTTPOperation) Patch(ctx context.Context, resourceType, id string, patchBody interface{}) (models.Resource, error) {
+	if err := o.checkResourceType(resourceType); err != nil {
+		return nil, err
+	}
 	url := o.buildURL(resourceType, id)
 	data, err := o.doRequest(ctx, http.MethodPatch, url, patchBody)
 	if err != nil {
@@ -129,6 +226,9 @@ func (o *HTTPOperation) Patch(ctx context.Context, resourceType, id string, patc
 
 // Delete deletes a resource
 func (o *HTTPOperation) Delete(ctx context.Context, resourceType, id string) error {
+	if err := o.checkResourceType(resourceType); err != nil {
+		return err
+	}
 	url := o.buildURL(resourceType, id)
 	_, err := o.doRequest(ctx, http.MethodDelete, url, nil)
 	return err
@@ -136,38 +236,45 @@ func (o *HTTPOperation) Delete(ctx context.Context, resourceType, id string) err
 
 // Search searches for resources and returns a typed Bundle
 func (o *HTTPOperation) Search(ctx context.Context, resourceType string, params *search.Parameters) (*models.Bundle, error) {
+	if err := o.checkResourceType(resourceType); err != nil {
+		return nil, err
+	}
 	url := o.buildURL(resourceType)
 	if params != nil {
 		url += "?" + params.Encode()
 	}
-	data, err := o.doRequest(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-	return o.mapper.UnmarshalBundle(data)
+	return o.bundleRequest(ctx, http.MethodGet, url, nil)
 }
 
 // History gets the history of a resource and returns a typed Bundle
 func (o *HTTPOperation) History(ctx context.Context, resourceType, id string, params *search.Parameters) (*models.Bundle, error) {
+	if err := o.checkResourceType(resourceType); err != nil {
+		return nil, err
+	}
 	url := o.buildURL(resourceType, id, "_history")
 	if params != nil {
 		url += "?" + params.Encode()
 	}
-	data, err := o.doRequest(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
+	return o.bundleRequest(ctx, http.MethodGet, url, nil)
+}
+
+// NextPage fetches the page after bundle by following its next link. It
+// returns nil on the last page.
+func (o *HTTPOperation) NextPage(ctx context.Context, bundle *models.Bundle) (*models.Bundle, error) {
+	next := bundle.NextLink()
+	if next == "" {
+		return nil, nil
 	}
-	return o.mapper.UnmarshalBundle(data)
+	return o.bundleRequest(ctx, http.MethodGet, next, nil)
 }
 
 // Transaction executes a batch of operations and returns a typed Bundle
 func (o *HTTPOperation) Transaction(ctx context.Context, bundle interface{}) (*models.Bundle, error) {
-	url := o.buildURL()
-	data, err := o.doRequest(ctx, http.MethodPost, url, bundle)
-	if err != nil {
+	if err := o.checkBundle(bundle); err != nil {
 		return nil, err
 	}
-	return o.mapper.UnmarshalBundle(data)
+	url := o.buildURL()
+	return o.bundleRequest(ctx, http.MethodPost, url, bundle)
 }
 
 // Capabilities retrieves the server's capability statement as a typed Resource
@@ -201,6 +308,64 @@ func (o *HTTPOperation) buildURL(parts ...string) string {
 	return url
 }
 
+// BaseURL returns the server base URL requests are made against
+func (o *HTTPOperation) BaseURL() string {
+	return o.baseURL
+}
+
+// HTTPClient returns the HTTP client requests are sent with
+func (o *HTTPOperation) HTTPClient() *http.Client {
+	return o.client
+}
+
+// Do sends req with the operation's custom headers, defaulting Accept to
+// application/fhir+json, and returns the response whatever its status. It is
+// for interactions the typed methods do not cover; the response body is not
+// limited and the caller must close it.
+func (o *HTTPOperation) Do(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/fhir+json")
+	}
+	for k, v := range o.headers {
+		req.Header.Set(k, v)
+	}
+	return o.client.Do(req)
+}
+
+// SetMaxBodySize limits the size of response bodies. Responses larger than
+// n bytes fail with ErrBodyTooLarge. Zero, the default, means no limit.
+func (o *HTTPOperation) SetMaxBodySize(n int64) {
+	o.maxBodySize = n
+}
+
+// SetMapper sets the mapper that decodes responses, e.g. one with further
+// resource types registered or with its generic fallback enabled to read
+// types the models do not define
+func (o *HTTPOperation) SetMapper(mapper *models.ResourceMapper) {
+	o.mapper = mapper
+}
+
+// SetVersion sets the FHIR version of the server. Requests for resource
+// types the version does not define, including those in the entries of
+// batches and transactions and in the _type of bulk exports, then fail
+// with ErrUnsupportedResourceType without being sent.
+func (o *HTTPOperation) SetVersion(v version.VersionManager) {
+	o.version = v
+}
+
+// checkResourceType checks that the version set with SetVersion defines a
+// resource type. An empty type, as for system-level history, is allowed.
+func (o *HTTPOperation) checkResourceType(resourceType string) error {
+	if o.version == nil || resourceType == "" || o.version.IsSupported(resourceType) {
+		return nil
+	}
+	v := o.version.GetVersion()
+	if equivalent := o.version.GetBaseResource(resourceType); equivalent != resourceType {
+		return fmt.Errorf("%w: %s is not defined in %s, which has %s instead", ErrUnsupportedResourceType, resourceType, v, equivalent)
+	}
+	return fmt.Errorf("%w: %s is not defined in %s", ErrUnsupportedResourceType, resourceType, v)
+}
+
 // SetHeader sets a custom header for all operations
 func (o *HTTPOperation) SetHeader(key, value string) {
 	o.headers[key] = value
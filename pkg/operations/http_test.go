@@ -0,0 +1,78 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/version"
+)
+
+func TestUnsupportedResourceType(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/fhir+json")
+		w.Write([]byte(`{"resourceType":"Bundle","type":"searchset"}`))
+	}))
+	defer server.Close()
+	op := NewHTTPOperation(server.Client(), server.URL)
+	r4, _ := version.NewVersionManager(version.R4)
+	op.SetVersion(r4)
+	ctx := context.Background()
+
+	_, err := op.Search(ctx, "SubscriptionTopic", nil)
+	if !errors.Is(err, ErrUnsupportedResourceType) {
+		t.Fatalf("expected ErrUnsupportedResourceType, got %v", err)
+	}
+	_, err = op.Read(ctx, "DeviceUsage", "d1")
+	if !errors.Is(err, ErrUnsupportedResourceType) || !strings.Contains(err.Error(), "which has DeviceUseStatement instead") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := op.Invoke(ctx, &Invocation{Name: "events", ResourceType: "SubscriptionTopic"}); !errors.Is(err, ErrUnsupportedResourceType) {
+		t.Errorf("expected ErrUnsupportedResourceType for Invoke, got %v", err)
+	}
+	transactions := map[string]interface{}{
+		"entry resource": map[string]interface{}{"resourceType": "Bundle", "type": "transaction", "entry": []interface{}{
+			map[string]interface{}{"resource": map[string]interface{}{"resourceType": "SubscriptionTopic"}, "request": map[string]interface{}{"method": "POST", "url": "SubscriptionTopic"}},
+		}},
+		"request URL": map[string]interface{}{"resourceType": "Bundle", "type": "batch", "entry": []interface{}{
+			map[string]interface{}{"request": map[string]interface{}{"method": "GET", "url": server.URL + "/DeviceUsage?patient=p1"}},
+		}},
+	}
+	for name, bundle := range transactions {
+		if _, err := op.Transaction(ctx, bundle); !errors.Is(err, ErrUnsupportedResourceType) {
+			t.Errorf("%s: expected ErrUnsupportedResourceType for Transaction, got %v", name, err)
+		}
+		if _, err := op.TransactionAsync(ctx, bundle); !errors.Is(err, ErrUnsupportedResourceType) {
+			t.Errorf("%s: expected ErrUnsupportedResourceType for TransactionAsync, got %v", name, err)
+		}
+	}
+	for _, path := range []string{"SubscriptionTopic/$events", "$export?_type=Patient,SubscriptionTopic", "$export?_typeFilter=DeviceUsage%3Fstatus%3Dactive"} {
+		if _, err := op.SendAsync(ctx, http.MethodGet, path, nil); !errors.Is(err, ErrUnsupportedResourceType) {
+			t.Errorf("%s: expected ErrUnsupportedResourceType for SendAsync, got %v", path, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("%d requests were sent for unsupported types", requests)
+	}
+
+	if _, err := op.Search(ctx, "Patient", nil); err != nil {
+		t.Errorf("Search failed: %v", err)
+	}
+	if _, err := op.History(ctx, "", "", nil); err != nil {
+		t.Errorf("system-level History failed: %v", err)
+	}
+	if _, err := op.Transaction(ctx, map[string]interface{}{"resourceType": "Bundle", "type": "batch", "entry": []interface{}{
+		map[string]interface{}{"request": map[string]interface{}{"method": "GET", "url": "Patient?name=x"}},
+		map[string]interface{}{"request": map[string]interface{}{"method": "GET", "url": "metadata"}},
+	}}); err != nil {
+		t.Errorf("Transaction failed: %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+}
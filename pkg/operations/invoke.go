@@ -0,0 +1,153 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+// Invocation describes a call of a named operation at system, type or instance level
+type Invocation struct {
+	// Name is the operation's name, with or without the leading $
+	Name string
+
+	// ResourceType and ID select the type or instance the operation is
+	// invoked on. Leaving both empty invokes the operation at system level.
+	ResourceType string
+	ID           string
+
+	// Parameters are the operation's input parameters, which may be nil
+	Parameters *models.Parameters
+
+	// Resource is sent as the request body in place of Parameters, for
+	// operations that take a single resource
+	Resource models.Resource
+
+	// UseGET sends Parameters in the query string, for operations that do
+	// not change state. Only parameters with primitive values can be sent
+	// this way.
+	UseGET bool
+}
+
+// path returns the operation's path relative to the base URL
+func (inv *Invocation) path() (string, error) {
+	name := strings.TrimPrefix(inv.Name, "$")
+	if name == "" {
+		return "", fmt.Errorf("operation name is required")
+	}
+	if inv.ID != "" && inv.ResourceType == "" {
+		return "", fmt.Errorf("operation %s: resource type is required for an instance-level invocation", name)
+	}
+
+	var parts []string
+	if inv.ResourceType != "" {
+		parts = append(parts, inv.ResourceType)
+	}
+	if inv.ID != "" {
+		parts = append(parts, inv.ID)
+	}
+	return strings.Join(append(parts, "$"+name), "/"), nil
+}
+
+// Invoke calls an operation and returns its result, which is nil if the
+// server returned no content. Results that are not FHIR JSON, such as a
+// document from Binary/$read, are returned as a *models.Binary holding the
+// response body, and resources of types the operation's mapper does not
+// register as a *models.GenericResource.
+func (o *HTTPOperation) Invoke(ctx context.Context, inv *Invocation) (models.Resource, error) {
+	path, err := inv.path()
+	if err != nil {
+		return nil, err
+	}
+	if err := o.checkResourceType(inv.ResourceType); err != nil {
+		return nil, err
+	}
+
+	method := http.MethodPost
+	var body interface{}
+	switch {
+	case inv.UseGET:
+		method = http.MethodGet
+		if inv.Resource != nil {
+			return nil, fmt.Errorf("operation %s: a resource cannot be sent with GET", inv.Name)
+		}
+		if inv.Parameters != nil && len(inv.Parameters.Parameter) > 0 {
+			q, err := inv.Parameters.Query()
+			if err != nil {
+				return nil, fmt.Errorf("operation %s: %w", inv.Name, err)
+			}
+			path += "?" + q.Encode()
+		}
+	case inv.Resource != nil:
+		body = inv.Resource
+	case inv.Parameters != nil:
+		body = inv.Parameters
+	default:
+		body = models.NewParameters()
+	}
+
+	resp, err := o.send(ctx, method, o.buildURL(path), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && !strings.HasSuffix(mediaType, "json") {
+		return models.NewBinary(contentType, data), nil
+	}
+	return o.mapper.WithGenericFallback().UnmarshalResource(data)
+}
+
+// InvokeParameters calls an operation and returns its output parameters. An
+// operation that returned a single resource directly, as operations with a
+// single "return" output may, has it wrapped as the "return" parameter.
+func (o *HTTPOperation) InvokeParameters(ctx context.Context, inv *Invocation) (*models.Parameters, error) {
+	resource, err := o.Invoke(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	switch r := resource.(type) {
+	case *models.Parameters:
+		return r, nil
+	case *models.OperationOutcome:
+		if r.HasErrors() {
+			return nil, &OutcomeError{Outcome: r}
+		}
+	case nil:
+		return models.NewParameters(), nil
+	}
+	return models.NewParameters().AddResource("return", resource), nil
+}
+
+// InvokeBundle calls an operation that returns a Bundle, such as
+// $everything. An OperationOutcome result is returned as an error.
+func (o *HTTPOperation) InvokeBundle(ctx context.Context, inv *Invocation) (*models.Bundle, error) {
+	resource, err := o.Invoke(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	switch r := resource.(type) {
+	case *models.Bundle:
+		return r, nil
+	case *models.OperationOutcome:
+		return nil, &OutcomeError{Outcome: r}
+	case nil:
+		return nil, fmt.Errorf("operation %s returned no content", inv.Name)
+	default:
+		return nil, fmt.Errorf("operation %s returned %s, not Bundle", inv.Name, resource.GetResourceType())
+	}
+}
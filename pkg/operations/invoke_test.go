@@ -0,0 +1,134 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+func TestInvoke(t *testing.T) {
+	var gotMethod, gotURL string
+	var gotBody models.Parameters
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotURL = r.Method, r.URL.String()
+		gotBody = models.Parameters{}
+		if r.Body != nil {
+			json.NewDecoder(r.Body).Decode(&gotBody)
+		}
+		switch r.URL.Path {
+		case "/Patient/123/$everything":
+			w.Header().Set("Content-Type", "application/fhir+json")
+			io.WriteString(w, searchBundle)
+		case "/Binary/b1/$read":
+			w.Header().Set("Content-Type", "application/pdf")
+			io.WriteString(w, "%PDF-1.7")
+		case "/ValueSet/$validate-code":
+			w.Header().Set("Content-Type", "application/fhir+json")
+			io.WriteString(w, `{"resourceType":"Parameters","parameter":[{"name":"result","valueBoolean":true},{"name":"display","valueString":"Male"}]}`)
+		case "/$convert":
+			w.Header().Set("Content-Type", "application/fhir+json")
+			io.WriteString(w, `{"resourceType":"Patient","id":"converted"}`)
+		case "/Observation/$lastn":
+			w.Header().Set("Content-Type", "application/fhir+json")
+			io.WriteString(w, `{"resourceType":"Observation","id":"o1","status":"final"}`)
+		case "/$fail":
+			w.Header().Set("Content-Type", "application/fhir+json")
+			io.WriteString(w, `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"not-found"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	op := NewHTTPOperation(server.Client(), server.URL)
+	ctx := context.Background()
+
+	bundle, err := op.InvokeBundle(ctx, &Invocation{
+		Name:         "everything",
+		ResourceType: "Patient",
+		ID:           "123",
+		Parameters:   models.NewParameters().AddInteger("_count", 50),
+		UseGET:       true,
+	})
+	if err != nil {
+		t.Fatalf("InvokeBundle failed: %v", err)
+	}
+	if gotMethod != http.MethodGet || gotURL != "/Patient/123/$everything?_count=50" || len(bundle.Entry) != 2 {
+		t.Errorf("Unexpected %s %s returning %d entries", gotMethod, gotURL, len(bundle.Entry))
+	}
+
+	out, err := op.InvokeParameters(ctx, &Invocation{
+		Name:         "$validate-code",
+		ResourceType: "ValueSet",
+		Parameters: models.NewParameters().
+			AddURI("url", "http://hl7.org/fhir/ValueSet/administrative-gender").
+			AddCoding("coding", models.Coding{System: "http://hl7.org/fhir/administrative-gender", Code: "male"}),
+	})
+	if err != nil {
+		t.Fatalf("InvokeParameters failed: %v", err)
+	}
+	if gotMethod != http.MethodPost || gotURL != "/ValueSet/$validate-code" {
+		t.Errorf("Unexpected request %s %s", gotMethod, gotURL)
+	}
+	if coding, ok := gotBody.GetCoding("coding"); !ok || coding.Code != "male" {
+		t.Errorf("Unexpected request body %+v", gotBody)
+	}
+	if result, ok := out.GetBoolean("result"); !ok || !result {
+		t.Errorf("Expected result true, got %+v", out)
+	}
+	if display, _ := out.GetString("display"); display != "Male" {
+		t.Errorf("Expected display Male, got %q", display)
+	}
+
+	out, err = op.InvokeParameters(ctx, &Invocation{Name: "convert"})
+	if err != nil {
+		t.Fatalf("InvokeParameters failed: %v", err)
+	}
+	if r, err := out.GetResource("return"); err != nil || r.(*models.Patient).ID != "converted" {
+		t.Errorf("Expected the returned resource to be wrapped, got %v, %v", r, err)
+	}
+
+	resource, err := op.Invoke(ctx, &Invocation{Name: "read", ResourceType: "Binary", ID: "b1", UseGET: true})
+	if err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+	if binary, ok := resource.(*models.Binary); !ok || binary.ContentType != "application/pdf" || string(binary.Data) != "%PDF-1.7" {
+		t.Errorf("Expected a Binary, got %+v", resource)
+	}
+
+	// Results of types the mapper does not register are still returned
+	resource, err = op.Invoke(ctx, &Invocation{Name: "lastn", ResourceType: "Observation", UseGET: true})
+	if err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+	if generic, ok := resource.(*models.GenericResource); !ok || generic.ResourceType != "Observation" || generic.ID != "o1" {
+		t.Errorf("Expected a GenericResource, got %+v", resource)
+	}
+
+	_, err = op.InvokeBundle(ctx, &Invocation{Name: "fail"})
+	var outcome *OutcomeError
+	if !errors.As(err, &outcome) {
+		t.Errorf("Expected an OutcomeError, got %v", err)
+	}
+}
+
+func TestInvokeRejectsComplexGET(t *testing.T) {
+	op := NewHTTPOperation(nil, "http://example.org")
+	_, err := op.Invoke(context.Background(), &Invocation{
+		Name:       "lookup",
+		Parameters: models.NewParameters().AddCoding("coding", models.Coding{Code: "x"}),
+		UseGET:     true,
+	})
+	if err == nil {
+		t.Error("Expected a Coding parameter to be refused for GET")
+	}
+
+	if _, err := op.Invoke(context.Background(), &Invocation{Name: "everything", ID: "1"}); err == nil {
+		t.Error("Expected an instance invocation without a resource type to fail")
+	}
+}
@@ -0,0 +1,77 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+// MatchCandidate is a patient returned by $match
+type MatchCandidate struct {
+	Patient *models.Patient
+
+	// Score is the server's confidence in the match, from 0 to 1, or nil if
+	// it gave none
+	Score *fhir.Decimal
+
+	// Grade is the match-grade code: certain, probable, possible or
+	// certainly-not. It is empty if the server gave none.
+	Grade string
+}
+
+// Match looks up candidate matches for patient with Patient/$match. When
+// onlyCertainMatches is set, the server returns only candidates it is
+// certain of; count limits the number of candidates if it is positive.
+// Candidates are returned with the highest score first, those without a
+// score last.
+func (o *HTTPOperation) Match(ctx context.Context, patient *models.Patient, onlyCertainMatches bool, count int) ([]MatchCandidate, error) {
+	params := models.NewParameters().
+		AddResource("resource", patient).
+		AddBoolean("onlyCertainMatches", onlyCertainMatches)
+	if count > 0 {
+		params.AddInteger("count", count)
+	}
+
+	bundle, err := o.InvokeBundle(ctx, &Invocation{
+		Name:         "match",
+		ResourceType: string(models.ResourceTypePatient),
+		Parameters:   params,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []MatchCandidate
+	for i := range bundle.Entry {
+		entry := &bundle.Entry[i]
+		if entry.Search != nil && entry.Search.Mode != "" && entry.Search.Mode != models.SearchModeMatch {
+			continue
+		}
+		resource, err := entry.GetResource()
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal bundle entry %d: %w", i, err)
+		}
+		candidate, ok := resource.(*models.Patient)
+		if !ok {
+			continue
+		}
+		match := MatchCandidate{Patient: candidate}
+		if entry.Search != nil {
+			match.Score = entry.Search.Score
+			match.Grade = entry.Search.MatchGrade()
+		}
+		candidates = append(candidates, match)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i].Score, candidates[j].Score
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.Cmp(*b) > 0
+	})
+	return candidates, nil
+}
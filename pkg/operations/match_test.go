@@ -0,0 +1,63 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+const matchBundle = `{"resourceType":"Bundle","type":"searchset","entry":[
+	{"resource":{"resourceType":"Patient","id":"low"},"search":{"mode":"match","score":0.4,
+		"extension":[{"url":"http://hl7.org/fhir/StructureDefinition/match-grade","valueCode":"possible"}]}},
+	{"resource":{"resourceType":"Patient","id":"high"},"search":{"mode":"match","score":0.95,
+		"extension":[{"url":"http://hl7.org/fhir/StructureDefinition/match-grade","valueCode":"certain"}]}},
+	{"resource":{"resourceType":"Organization","id":"org"},"search":{"mode":"include"}}]}`
+
+func TestMatch(t *testing.T) {
+	var received models.Parameters
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/Patient/$match" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/fhir+json")
+		io.WriteString(w, matchBundle)
+	}))
+	defer server.Close()
+	op := NewHTTPOperation(server.Client(), server.URL)
+
+	patient := models.NewPatient()
+	birthDate, _ := fhir.ParseDate("1970-01-01")
+	patient.BirthDate = &birthDate
+	candidates, err := op.Match(context.Background(), patient, true, 5)
+	if err != nil {
+		t.Fatalf("Match failed: %v", err)
+	}
+
+	if certain, ok := received.GetBoolean("onlyCertainMatches"); !ok || !certain {
+		t.Errorf("Expected onlyCertainMatches true, got %+v", received)
+	}
+	if count, _ := received.GetInteger("count"); count != 5 {
+		t.Errorf("Expected count 5, got %d", count)
+	}
+	if r, err := received.GetResource("resource"); err != nil || r.(*models.Patient).BirthDate.String() != "1970-01-01" {
+		t.Errorf("Expected the patient to be sent, got %v, %v", r, err)
+	}
+
+	if len(candidates) != 2 {
+		t.Fatalf("Expected 2 candidates, got %d", len(candidates))
+	}
+	if c := candidates[0]; c.Patient.ID != "high" || c.Grade != "certain" || c.Score.String() != "0.95" {
+		t.Errorf("Unexpected first candidate %+v", c)
+	}
+	if c := candidates[1]; c.Patient.ID != "low" || c.Grade != "possible" {
+		t.Errorf("Unexpected second candidate %+v", c)
+	}
+}
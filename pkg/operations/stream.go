@@ -0,0 +1,80 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/search"
+)
+
+// BundleStream is a Bundle response that is read one entry at a time as it
+// arrives. It must be closed once the caller is done with it.
+type BundleStream struct {
+	*models.BundleDecoder
+	body io.ReadCloser
+}
+
+// Close closes the underlying response body
+func (s *BundleStream) Close() error {
+	return s.body.Close()
+}
+
+// streamRequest performs an HTTP request and returns a stream over the Bundle response
+func (o *HTTPOperation) streamRequest(ctx context.Context, method, url string, body interface{}) (*BundleStream, error) {
+	resp, err := o.send(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	return &BundleStream{
+		BundleDecoder: models.NewBundleDecoder(resp.Body, o.mapper),
+		body:          resp.Body,
+	}, nil
+}
+
+// SearchStream searches for resources and streams the resulting Bundle
+func (o *HTTPOperation) SearchStream(ctx context.Context, resourceType string, params *search.Parameters) (*BundleStream, error) {
+	if err := o.checkResourceType(resourceType); err != nil {
+		return nil, err
+	}
+	url := o.buildURL(resourceType)
+	if params != nil {
+		url += "?" + params.Encode()
+	}
+	return o.streamRequest(ctx, http.MethodGet, url, nil)
+}
+
+// HistoryStream gets the history of a resource and streams the resulting Bundle
+func (o *HTTPOperation) HistoryStream(ctx context.Context, resourceType, id string, params *search.Parameters) (*BundleStream, error) {
+	if err := o.checkResourceType(resourceType); err != nil {
+		return nil, err
+	}
+	url := o.buildURL(resourceType, id, "_history")
+	if params != nil {
+		url += "?" + params.Encode()
+	}
+	return o.streamRequest(ctx, http.MethodGet, url, nil)
+}
+
+// TransactionStream executes a batch of operations and streams the response Bundle
+func (o *HTTPOperation) TransactionStream(ctx context.Context, bundle interface{}) (*BundleStream, error) {
+	if err := o.checkBundle(bundle); err != nil {
+		return nil, err
+	}
+	return o.streamRequest(ctx, http.MethodPost, o.buildURL(), bundle)
+}
+
+// OperationStream executes a custom operation that returns a Bundle, such
+// as $everything, and streams the result. path is the operation's path
+// relative to the base URL, e.g. "Patient/123/$everything".
+func (o *HTTPOperation) OperationStream(ctx context.Context, path string, input interface{}) (*BundleStream, error) {
+	if err := o.checkPath(path); err != nil {
+		return nil, err
+	}
+	method := http.MethodPost
+	if input == nil {
+		method = http.MethodGet
+	}
+	return o.streamRequest(ctx, method, o.buildURL(path), input)
+}
@@ -0,0 +1,77 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const searchBundle = `{"resourceType":"Bundle","type":"searchset","entry":[` +
+	`{"resource":{"resourceType":"Patient","id":"1"}},` +
+	`{"resource":{"resourceType":"Patient","id":"2"}}]}`
+
+func newBundleServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		io.WriteString(w, searchBundle)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSearchStream(t *testing.T) {
+	op := NewHTTPOperation(nil, newBundleServer(t).URL)
+
+	stream, err := op.SearchStream(context.Background(), "Patient", nil)
+	if err != nil {
+		t.Fatalf("SearchStream failed: %v", err)
+	}
+	defer stream.Close()
+
+	var ids []string
+	for {
+		entry, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if !strings.Contains(string(entry.Resource), `"Patient"`) {
+			t.Errorf("Unexpected entry %s", entry.Resource)
+		}
+		ids = append(ids, entry.FullURL)
+	}
+	if len(ids) != 2 {
+		t.Errorf("Expected 2 entries, got %d", len(ids))
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	op := NewHTTPOperation(nil, newBundleServer(t).URL)
+
+	op.SetMaxBodySize(int64(len(searchBundle)))
+	if _, err := op.Search(context.Background(), "Patient", nil); err != nil {
+		t.Fatalf("Expected a body of exactly the limit to be accepted: %v", err)
+	}
+
+	op.SetMaxBodySize(int64(len(searchBundle)) - 1)
+	if _, err := op.Search(context.Background(), "Patient", nil); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Expected ErrBodyTooLarge, got %v", err)
+	}
+
+	stream, err := op.SearchStream(context.Background(), "Patient", nil)
+	if err == nil {
+		defer stream.Close()
+		for err == nil {
+			_, err = stream.Next()
+		}
+	}
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Expected ErrBodyTooLarge from the stream, got %v", err)
+	}
+}
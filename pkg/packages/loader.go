@@ -0,0 +1,341 @@
+package packages
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/terminology"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/validation"
+)
+
+var (
+	// ErrPackageNotFound is returned when a package or dependency is not in the cache
+	ErrPackageNotFound = errors.New("package not found")
+
+	// ErrResourceNotFound is returned when no loaded package has a canonical resource
+	ErrResourceNotFound = errors.New("resource not found")
+)
+
+// DefaultCacheDir returns the package cache shared by FHIR tools, ~/.fhir/packages
+func DefaultCacheDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".fhir", "packages")
+	}
+	return filepath.Join(home, ".fhir", "packages")
+}
+
+// Loader loads FHIR packages and their dependencies and indexes the
+// canonical resources in them by URL and version
+type Loader struct {
+	// CacheDir is the package cache that packages and dependencies are
+	// looked up in. It holds unpacked packages in name#version folders,
+	// as FHIR tools leave them, or name#version.tgz and name-version.tgz
+	// archives.
+	CacheDir string
+
+	mu       sync.RWMutex
+	packages map[string]*Package
+	order    []*Package
+
+	// canonicals holds the resources with each URL, latest version first
+	canonicals map[string][]*Resource
+}
+
+// NewLoader creates a loader over the default package cache
+func NewLoader() *Loader {
+	return &Loader{
+		CacheDir:   DefaultCacheDir(),
+		packages:   make(map[string]*Package),
+		canonicals: make(map[string][]*Resource),
+	}
+}
+
+// Load loads a package from the cache with its dependencies. version may
+// be exact, a wildcard such as 4.0.x, or "latest" for the latest cached
+// version.
+func (l *Loader) Load(name, version string) (*Package, error) {
+	return l.load(name, version, nil)
+}
+
+// LoadFile loads a package from a .tgz archive. Its dependencies are
+// loaded from the cache.
+func (l *Loader) LoadFile(path string) (*Package, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	p, err := readArchive(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return l.add(p, nil)
+}
+
+// LoadDir loads an unpacked package from its directory, or the directory
+// holding its package folder. Its dependencies are loaded from the cache.
+func (l *Loader) LoadDir(dir string) (*Package, error) {
+	p, err := readDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dir, err)
+	}
+	return l.add(p, nil)
+}
+
+// load finds a package in the cache and adds it. chain holds the packages
+// depending on it, to report dependency cycles.
+func (l *Loader) load(name, version string, chain []string) (*Package, error) {
+	l.mu.RLock()
+	for _, p := range l.order {
+		if p.Name == name && matchVersion(version, p.Version) {
+			l.mu.RUnlock()
+			return p, nil
+		}
+	}
+	l.mu.RUnlock()
+
+	p, err := l.find(name, version)
+	if err != nil {
+		return nil, err
+	}
+	return l.add(p, chain)
+}
+
+// add indexes a package after loading its dependencies
+func (l *Loader) add(p *Package, chain []string) (*Package, error) {
+	l.mu.RLock()
+	loaded, ok := l.packages[p.ID()]
+	l.mu.RUnlock()
+	if ok {
+		return loaded, nil
+	}
+	for _, id := range chain {
+		if id == p.ID() {
+			return nil, fmt.Errorf("dependency cycle: %s -> %s", strings.Join(chain, " -> "), id)
+		}
+	}
+
+	chain = append(chain, p.ID())
+	for _, name := range sortedKeys(p.Manifest.Dependencies) {
+		dep, err := l.load(name, p.Manifest.Dependencies[name], chain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load dependency of %s: %w", p.ID(), err)
+		}
+		p.Dependencies = append(p.Dependencies, dep)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if loaded, ok := l.packages[p.ID()]; ok {
+		return loaded, nil
+	}
+	l.packages[p.ID()] = p
+	l.order = append(l.order, p)
+	for _, r := range p.Resources {
+		if r.URL == "" {
+			continue
+		}
+		list := l.canonicals[r.URL]
+		i := 0
+		for i < len(list) && compareVersions(list[i].Version, r.Version) >= 0 {
+			i++
+		}
+		list = append(list, nil)
+		copy(list[i+1:], list[i:])
+		list[i] = r
+		l.canonicals[r.URL] = list
+	}
+	return p, nil
+}
+
+// find reads the latest cached package matching name and version
+func (l *Loader) find(name, version string) (*Package, error) {
+	entries, err := os.ReadDir(l.CacheDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list %s: %w", l.CacheDir, err)
+	}
+
+	var best, bestPath string
+	for _, entry := range entries {
+		var v string
+		switch file := entry.Name(); {
+		case entry.IsDir():
+			v, _ = strings.CutPrefix(file, name+"#")
+		case strings.HasSuffix(file, ".tgz"):
+			file = strings.TrimSuffix(file, ".tgz")
+			if after, ok := strings.CutPrefix(file, name+"#"); ok {
+				v = after
+			} else {
+				v, _ = strings.CutPrefix(file, name+"-")
+			}
+		}
+		if !validVersion(v) || !matchVersion(version, v) {
+			continue
+		}
+		if best == "" || compareVersions(v, best) > 0 {
+			best, bestPath = v, filepath.Join(l.CacheDir, entry.Name())
+		}
+	}
+	if best == "" {
+		return nil, fmt.Errorf("%s#%s: %w", name, version, ErrPackageNotFound)
+	}
+
+	var p *Package
+	if strings.HasSuffix(bestPath, ".tgz") {
+		f, err := os.Open(bestPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", bestPath, err)
+		}
+		defer f.Close()
+		p, err = readArchive(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", bestPath, err)
+		}
+	} else if p, err = readDir(bestPath); err != nil {
+		return nil, fmt.Errorf("%s: %w", bestPath, err)
+	}
+	if p.Name != name {
+		return nil, fmt.Errorf("%s holds package %s, not %s", bestPath, p.ID(), name)
+	}
+	return p, nil
+}
+
+// validVersion reports whether v is a package version such as 4.0.1 or
+// 6.1.0-snapshot1, so that the names of other packages in the cache, such
+// as the bar-1.0.0 of foo-bar-1.0.0.tgz, are not read as versions of foo
+func validVersion(v string) bool {
+	number, _, _ := strings.Cut(v, "-")
+	if number == "" {
+		return false
+	}
+	for _, part := range strings.Split(number, ".") {
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Packages returns the loaded packages, each after its dependencies
+func (l *Loader) Packages() []*Package {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]*Package(nil), l.order...)
+}
+
+// Package returns a loaded package by name and version, or nil
+func (l *Loader) Package(name, version string) *Package {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.packages[name+"#"+version]
+}
+
+// Resource returns the resource with a canonical URL, which may carry a
+// |version. Without a version the latest loaded version is returned. It
+// returns nil if no loaded package has the resource.
+func (l *Loader) Resource(canonical string) *Resource {
+	url, version, _ := strings.Cut(canonical, "|")
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	list := l.canonicals[url]
+	if version == "" {
+		if len(list) == 0 {
+			return nil
+		}
+		return list[0]
+	}
+	for _, r := range list {
+		if r.Version == version {
+			return r
+		}
+	}
+	// A version of major.minor matches any patch release
+	for _, r := range list {
+		if strings.HasPrefix(r.Version, version+".") {
+			return r
+		}
+	}
+	return nil
+}
+
+// Resources returns the loaded resources of a type, such as
+// "SearchParameter", in package load order. All resources are returned if
+// resourceType is empty.
+func (l *Loader) Resources(resourceType string) []*Resource {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var result []*Resource
+	for _, p := range l.order {
+		for _, r := range p.Resources {
+			if resourceType == "" || r.ResourceType == resourceType {
+				result = append(result, r)
+			}
+		}
+	}
+	return result
+}
+
+// StructureDefinition returns the StructureDefinition with a canonical URL
+func (l *Loader) StructureDefinition(canonical string) (*models.StructureDefinition, error) {
+	r := l.Resource(canonical)
+	if r == nil || r.ResourceType != string(models.ResourceTypeStructureDefinition) {
+		return nil, fmt.Errorf("StructureDefinition %s: %w", canonical, ErrResourceNotFound)
+	}
+	sd := models.NewStructureDefinition()
+	if err := r.Decode(sd); err != nil {
+		return nil, err
+	}
+	return sd, nil
+}
+
+// StructureDefinitions returns the loaded StructureDefinitions
+func (l *Loader) StructureDefinitions() ([]*models.StructureDefinition, error) {
+	var result []*models.StructureDefinition
+	for _, r := range l.Resources(string(models.ResourceTypeStructureDefinition)) {
+		sd := models.NewStructureDefinition()
+		if err := r.Decode(sd); err != nil {
+			return nil, err
+		}
+		result = append(result, sd)
+	}
+	return result, nil
+}
+
+// AddTo adds the loaded StructureDefinitions that have snapshots to a validator
+func (l *Loader) AddTo(v *validation.Validator) error {
+	for _, r := range l.Resources(string(models.ResourceTypeStructureDefinition)) {
+		if err := v.Load(r.Data); err != nil {
+			return fmt.Errorf("%s %s: %w", r.Package.ID(), r.Filename, err)
+		}
+	}
+	return nil
+}
+
+// AddTerminology adds the loaded CodeSystems and ValueSets to a local terminology service
+func (l *Loader) AddTerminology(t *terminology.Local) error {
+	for _, r := range l.Resources("") {
+		if r.ResourceType != string(models.ResourceTypeCodeSystem) && r.ResourceType != string(models.ResourceTypeValueSet) {
+			continue
+		}
+		if err := t.Load(r.Data); err != nil {
+			return fmt.Errorf("%s %s: %w", r.Package.ID(), r.Filename, err)
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,306 @@
+package packages
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/terminology"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/validation"
+)
+
+const coreManifest = `{"name": "example.core", "version": "1.0.1", "fhirVersions": ["4.0.1"]}`
+
+// coreFiles is a small stand-in for a core package
+var coreFiles = map[string]string{
+	"StructureDefinition-Basic.json": `{
+		"resourceType": "StructureDefinition", "id": "Basic", "url": "http://example.org/StructureDefinition/Basic",
+		"version": "1.0.1", "name": "Basic", "status": "active", "kind": "resource", "abstract": false, "type": "Basic",
+		"derivation": "specialization",
+		"snapshot": {"element": [
+			{"id": "Basic", "path": "Basic", "min": 0, "max": "*"},
+			{"id": "Basic.id", "path": "Basic.id", "min": 0, "max": "1", "type": [{"code": "http://hl7.org/fhirpath/System.String"}]},
+			{"id": "Basic.code", "path": "Basic.code", "min": 1, "max": "1", "type": [{"code": "code"}]}
+		]}
+	}`,
+	"CodeSystem-colors.json": `{
+		"resourceType": "CodeSystem", "id": "colors", "url": "http://example.org/CodeSystem/colors", "version": "1.0.1",
+		"status": "active", "content": "complete", "concept": [{"code": "red"}, {"code": "blue"}]
+	}`,
+	"ValueSet-colors.json": `{
+		"resourceType": "ValueSet", "id": "colors", "url": "http://example.org/ValueSet/colors", "version": "1.0.1",
+		"status": "active", "compose": {"include": [{"system": "http://example.org/CodeSystem/colors"}]}
+	}`,
+	".index.json": `{"index-version": 1, "files": []}`,
+}
+
+const igManifest = `{"name": "example.ig", "version": "2.0.0", "dependencies": {"example.core": "1.0.x"}}`
+
+var igFiles = map[string]string{
+	"StructureDefinition-basic-profile.json": `{
+		"resourceType": "StructureDefinition", "id": "basic-profile", "url": "http://example.org/StructureDefinition/basic-profile",
+		"version": "2.0.0", "name": "BasicProfile", "status": "active", "kind": "resource", "type": "Basic",
+		"derivation": "constraint", "baseDefinition": "http://example.org/StructureDefinition/Basic"
+	}`,
+	"SearchParameter-basic-code.json": `{
+		"resourceType": "SearchParameter", "id": "basic-code", "url": "http://example.org/SearchParameter/basic-code",
+		"name": "code", "status": "active", "code": "code", "base": ["Basic"], "type": "token", "expression": "Basic.code"
+	}`,
+	"ig-r4.json": `{"not": "a resource"}`,
+}
+
+// writeArchive writes a package as a .tgz
+func writeArchive(t *testing.T, path, manifest string, files map[string]string) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	add := func(name, content string) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("package/package.json", manifest)
+	for name, content := range files {
+		add("package/"+name, content)
+	}
+	add("package/example/Basic-example.json", `{"resourceType": "Basic", "id": "example"}`)
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeDir writes an unpacked package in the cache layout
+func writeDir(t *testing.T, dir, manifest string, files map[string]string) {
+	folder := filepath.Join(dir, "package")
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files["package.json"] = manifest
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(folder, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newTestLoader(t *testing.T) *Loader {
+	l := NewLoader()
+	l.CacheDir = t.TempDir()
+	writeDir(t, filepath.Join(l.CacheDir, "example.core#1.0.1"), coreManifest, copyFiles(coreFiles))
+	writeDir(t, filepath.Join(l.CacheDir, "example.core#1.0.0"),
+		strings.Replace(coreManifest, "1.0.1", "1.0.0", 1), map[string]string{
+			"CodeSystem-colors.json": strings.Replace(coreFiles["CodeSystem-colors.json"], "1.0.1", "1.0.0", 1),
+		})
+	return l
+}
+
+func copyFiles(files map[string]string) map[string]string {
+	result := make(map[string]string, len(files))
+	for k, v := range files {
+		result[k] = v
+	}
+	return result
+}
+
+func TestLoadFromCache(t *testing.T) {
+	l := newTestLoader(t)
+	writeArchive(t, filepath.Join(l.CacheDir, "example.ig#2.0.0.tgz"), igManifest, igFiles)
+
+	p, err := l.Load("example.ig", "2.0.0")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if p.ID() != "example.ig#2.0.0" {
+		t.Errorf("ID() = %s", p.ID())
+	}
+	if len(p.Resources) != 2 {
+		t.Errorf("got %d resources, want 2 (examples and non-resources are skipped)", len(p.Resources))
+	}
+	if len(p.Dependencies) != 1 || p.Dependencies[0].ID() != "example.core#1.0.1" {
+		t.Fatalf("dependencies = %v, want the latest 1.0.x core", p.Dependencies)
+	}
+
+	packages := l.Packages()
+	if len(packages) != 2 || packages[0].Name != "example.core" {
+		t.Errorf("packages should be listed after their dependencies")
+	}
+	if l.Package("example.core", "1.0.1") == nil {
+		t.Error("expected the dependency to be loaded")
+	}
+	if got := len(l.Resources("SearchParameter")); got != 1 {
+		t.Errorf("got %d search parameters, want 1", got)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	l := newTestLoader(t)
+	path := filepath.Join(t.TempDir(), "example.ig-2.0.0.tgz")
+	writeArchive(t, path, igManifest, igFiles)
+
+	if _, err := l.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	r := l.Resource("http://example.org/StructureDefinition/basic-profile")
+	if r == nil {
+		t.Fatal("profile not indexed")
+	}
+	if r.Package.Name != "example.ig" || r.Type != "Basic" || r.Canonical() != "http://example.org/StructureDefinition/basic-profile|2.0.0" {
+		t.Errorf("unexpected resource %+v", r)
+	}
+	sd, err := l.StructureDefinition("http://example.org/StructureDefinition/basic-profile|2.0.0")
+	if err != nil {
+		t.Fatalf("StructureDefinition failed: %v", err)
+	}
+	if sd.BaseDefinition != "http://example.org/StructureDefinition/Basic" {
+		t.Errorf("BaseDefinition = %s", sd.BaseDefinition)
+	}
+	if _, err := l.StructureDefinition("http://example.org/StructureDefinition/missing"); !errors.Is(err, ErrResourceNotFound) {
+		t.Errorf("expected ErrResourceNotFound, got %v", err)
+	}
+}
+
+func TestResourceVersions(t *testing.T) {
+	l := newTestLoader(t)
+	if _, err := l.Load("example.core", "1.0.0"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if _, err := l.Load("example.core", "1.0.1"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	tests := map[string]string{
+		"http://example.org/CodeSystem/colors":        "1.0.1",
+		"http://example.org/CodeSystem/colors|1.0.0":  "1.0.0",
+		"http://example.org/CodeSystem/colors|1.0":    "1.0.1",
+		"http://example.org/CodeSystem/colors|1.0.1":  "1.0.1",
+		"http://example.org/CodeSystem/colors|2.0.0":  "",
+		"http://example.org/CodeSystem/unknown|1.0.1": "",
+	}
+	for canonical, want := range tests {
+		var got string
+		if r := l.Resource(canonical); r != nil {
+			got = r.Version
+		}
+		if got != want {
+			t.Errorf("Resource(%s) has version %q, want %q", canonical, got, want)
+		}
+	}
+}
+
+func TestLoadLatest(t *testing.T) {
+	l := newTestLoader(t)
+	p, err := l.Load("example.core", "latest")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if p.Version != "1.0.1" {
+		t.Errorf("Version = %s, want 1.0.1", p.Version)
+	}
+}
+
+func TestLoadSkipsOtherPackages(t *testing.T) {
+	l := newTestLoader(t)
+	writeArchive(t, filepath.Join(l.CacheDir, "example.tools-extra-2.0.0.tgz"),
+		`{"name": "example.tools-extra", "version": "2.0.0"}`, map[string]string{})
+	if _, err := l.Load("example.tools", ""); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("expected ErrPackageNotFound, got %v", err)
+	}
+
+	// An archive named for the package holding another is refused
+	writeArchive(t, filepath.Join(l.CacheDir, "example.other-3.0.0.tgz"),
+		`{"name": "example.core", "version": "3.0.0"}`, map[string]string{})
+	if _, err := l.Load("example.other", "3.0.0"); err == nil {
+		t.Error("expected an error for an archive holding another package")
+	}
+}
+
+func TestMissingDependency(t *testing.T) {
+	l := NewLoader()
+	l.CacheDir = t.TempDir()
+	dir := filepath.Join(t.TempDir(), "ig")
+	writeDir(t, dir, igManifest, copyFiles(igFiles))
+
+	_, err := l.LoadDir(dir)
+	if !errors.Is(err, ErrPackageNotFound) {
+		t.Fatalf("expected ErrPackageNotFound, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "example.core#1.0.x") {
+		t.Errorf("error should name the dependency: %v", err)
+	}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	l := NewLoader()
+	l.CacheDir = t.TempDir()
+	writeDir(t, filepath.Join(l.CacheDir, "a#1.0.0"), `{"name": "a", "version": "1.0.0", "dependencies": {"b": "1.0.0"}}`, map[string]string{})
+	writeDir(t, filepath.Join(l.CacheDir, "b#1.0.0"), `{"name": "b", "version": "1.0.0", "dependencies": {"a": "1.0.0"}}`, map[string]string{})
+
+	if _, err := l.Load("a", "1.0.0"); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected a dependency cycle error, got %v", err)
+	}
+}
+
+func TestAddTo(t *testing.T) {
+	l := newTestLoader(t)
+	if _, err := l.Load("example.core", "1.0.1"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	local := terminology.NewLocal()
+	if err := l.AddTerminology(local); err != nil {
+		t.Fatalf("AddTerminology failed: %v", err)
+	}
+	result, err := local.ValidateCode(context.Background(), &terminology.ValidateCodeRequest{
+		ValueSet: "http://example.org/ValueSet/colors",
+		System:   "http://example.org/CodeSystem/colors",
+		Code:     "red",
+	})
+	if err != nil || !result.Result {
+		t.Errorf("expected red to be in the value set: %+v, %v", result, err)
+	}
+
+	v := validation.NewValidator()
+	if err := l.AddTo(v); err != nil {
+		t.Fatalf("AddTo failed: %v", err)
+	}
+	outcome, err := v.ValidateJSON(context.Background(), []byte(`{"resourceType": "Basic", "id": "b1"}`))
+	if err != nil {
+		t.Fatalf("ValidateJSON failed: %v", err)
+	}
+	if len(outcome.Issue) == 0 || outcome.Issue[0].Severity != "error" {
+		t.Errorf("expected the missing code to be reported, got %+v", outcome.Issue)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.0.10", "1.0.9", 1},
+		{"4.0.1", "5.0.0", -1},
+		{"1.0.0-ballot", "1.0.0", -1},
+		{"1.0", "1.0.0", 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
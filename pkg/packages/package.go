@@ -0,0 +1,241 @@
+package packages
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Manifest is the package.json of a FHIR package
+type Manifest struct {
+	Name         string            `json:"name"`
+	Version      string            `json:"version"`
+	Description  string            `json:"description,omitempty"`
+	Canonical    string            `json:"canonical,omitempty"`
+	URL          string            `json:"url,omitempty"`
+	Type         string            `json:"type,omitempty"`
+	FHIRVersions []string          `json:"fhirVersions,omitempty"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+}
+
+// Package is a loaded FHIR package
+type Package struct {
+	Manifest
+
+	// Resources holds the conformance resources in the package folder, in
+	// file name order. Examples and other subfolders are not read.
+	Resources []*Resource
+
+	// Dependencies holds the packages this one depends on, as resolved
+	Dependencies []*Package
+}
+
+// ID returns the package's name#version
+func (p *Package) ID() string {
+	return p.Name + "#" + p.Version
+}
+
+// Resource is a resource read from a package
+type Resource struct {
+	ResourceType string
+	ID           string
+	URL          string
+	Version      string
+
+	// Type and Kind are the type and kind of a StructureDefinition
+	Type string
+	Kind string
+
+	// Package is the package the resource was read from
+	Package *Package
+
+	// Filename is the name of the resource's file within the package folder
+	Filename string
+
+	// Data is the resource's JSON
+	Data json.RawMessage
+}
+
+// Canonical returns the resource's url|version, or its url if it has no version
+func (r *Resource) Canonical() string {
+	if r.Version == "" {
+		return r.URL
+	}
+	return r.URL + "|" + r.Version
+}
+
+// Decode unmarshals the resource's JSON into v, such as a *models.StructureDefinition
+func (r *Resource) Decode(v interface{}) error {
+	if err := json.Unmarshal(r.Data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal %s %s: %w", r.ResourceType, r.Filename, err)
+	}
+	return nil
+}
+
+// errNotPackage is returned when a directory or archive has no package.json
+var errNotPackage = errors.New("no package/package.json")
+
+// readArchive reads a package from a gzipped tarball, as published to a
+// package registry
+func readArchive(r io.Reader) (*Package, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress package: %w", err)
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read package: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		dir, file := path.Split(name)
+		if dir != "package/" || !strings.HasSuffix(file, ".json") {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		files[file] = data
+	}
+	return newPackage(files)
+}
+
+// readDir reads an unpacked package. dir may be the package's folder or
+// the directory containing it, as in the package cache.
+func readDir(dir string) (*Package, error) {
+	if _, err := os.Stat(filepath.Join(dir, "package", "package.json")); err == nil {
+		dir = filepath.Join(dir, "package")
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	files := make(map[string][]byte)
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		files[filepath.Base(p)] = data
+	}
+	return newPackage(files)
+}
+
+// newPackage builds a package from the JSON files in its package folder
+func newPackage(files map[string][]byte) (*Package, error) {
+	manifest, ok := files["package.json"]
+	if !ok {
+		return nil, errNotPackage
+	}
+	p := &Package{}
+	if err := json.Unmarshal(manifest, &p.Manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal package.json: %w", err)
+	}
+	if p.Name == "" || p.Version == "" {
+		return nil, fmt.Errorf("package.json has no name or version")
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		if name != "package.json" && !strings.HasPrefix(name, ".") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var header struct {
+			ResourceType string `json:"resourceType"`
+			ID           string `json:"id"`
+			URL          string `json:"url"`
+			Version      string `json:"version"`
+			Type         string `json:"type"`
+			Kind         string `json:"kind"`
+		}
+		// Files that are not resources, such as an ig-r4.json, are skipped
+		if err := json.Unmarshal(files[name], &header); err != nil || header.ResourceType == "" {
+			continue
+		}
+		p.Resources = append(p.Resources, &Resource{
+			ResourceType: header.ResourceType,
+			ID:           header.ID,
+			URL:          header.URL,
+			Version:      header.Version,
+			Type:         header.Type,
+			Kind:         header.Kind,
+			Package:      p,
+			Filename:     name,
+			Data:         files[name],
+		})
+	}
+	return p, nil
+}
+
+// compareVersions orders two semantic versions. Pre-release versions sort
+// before the release they precede.
+func compareVersions(a, b string) int {
+	a, aPre, _ := strings.Cut(a, "-")
+	b, bPre, _ := strings.Cut(b, "-")
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	case aPre < bPre:
+		return -1
+	}
+	return 1
+}
+
+// matchVersion reports whether version satisfies a dependency's version,
+// which may be exact, a wildcard such as 4.0.x, or "latest"
+func matchVersion(want, version string) bool {
+	switch want {
+	case "", "latest", "current", "*":
+		return true
+	case version:
+		return true
+	}
+	prefix, ok := strings.CutSuffix(want, ".x")
+	if !ok {
+		prefix, ok = strings.CutSuffix(want, ".*")
+	}
+	return ok && strings.HasPrefix(version, prefix+".")
+}
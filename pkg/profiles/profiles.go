@@ -0,0 +1,230 @@
+package profiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhirpath"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+// WithProfile returns a copy of meta that claims conformance to profile,
+// leaving meta itself unchanged. A claim to any version of the profile is kept.
+func WithProfile(meta *models.Meta, profile string) *models.Meta {
+	if HasProfile(meta, profile) {
+		return meta
+	}
+	result := &models.Meta{}
+	if meta != nil {
+		*result = *meta
+	}
+	result.Profile = append(append([]string(nil), result.Profile...), profile)
+	return result
+}
+
+// HasProfile reports whether meta claims conformance to any version of profile
+func HasProfile(meta *models.Meta, profile string) bool {
+	if meta == nil {
+		return false
+	}
+	url, _, _ := strings.Cut(profile, "|")
+	for _, p := range meta.Profile {
+		if claimed, _, _ := strings.Cut(p, "|"); claimed == url {
+			return true
+		}
+	}
+	return false
+}
+
+// FindExtension returns the first extension with the given URL, or nil
+func FindExtension(extensions []models.Extension, url string) *models.Extension {
+	for i := range extensions {
+		if extensions[i].URL == url {
+			return &extensions[i]
+		}
+	}
+	return nil
+}
+
+// FindExtensions returns the extensions with the given URL
+func FindExtensions(extensions []models.Extension, url string) []*models.Extension {
+	var result []*models.Extension
+	for i := range extensions {
+		if extensions[i].URL == url {
+			result = append(result, &extensions[i])
+		}
+	}
+	return result
+}
+
+// SetExtensions replaces the extensions with the given URL by values, at
+// the position of the first of them, and returns the updated list. Passing
+// no values removes them.
+func SetExtensions(extensions []models.Extension, url string, values ...models.Extension) []models.Extension {
+	result := make([]models.Extension, 0, len(extensions)+len(values))
+	inserted := false
+	for _, ext := range extensions {
+		if ext.URL != url {
+			result = append(result, ext)
+			continue
+		}
+		if !inserted {
+			result = append(result, values...)
+			inserted = true
+		}
+	}
+	if !inserted {
+		result = append(result, values...)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// Slice returns the items of a repeating element that belong to a slice,
+// identified by a JSON pattern of the values its discriminators require
+func Slice[T any](items []T, pattern string) []*T {
+	var result []*T
+	for i := range items {
+		if Matches(items[i], pattern) {
+			result = append(result, &items[i])
+		}
+	}
+	return result
+}
+
+// Matches reports whether v has at least the content of pattern, a JSON
+// object: every member of the pattern, and a match for every item of a
+// pattern array
+func Matches(v interface{}, pattern string) bool {
+	value, err := toJSON(v)
+	if err != nil {
+		return false
+	}
+	var p interface{}
+	if err := json.Unmarshal([]byte(pattern), &p); err != nil {
+		return false
+	}
+	return matchesPattern(value, p)
+}
+
+// Apply sets the values a slice's pattern requires on item, so that it
+// belongs to the slice
+func Apply[T any](item *T, pattern string) error {
+	value, err := toJSON(*item)
+	if err != nil {
+		return err
+	}
+	var p interface{}
+	if err := json.Unmarshal([]byte(pattern), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal pattern: %w", err)
+	}
+	data, err := json.Marshal(merge(value, p))
+	if err != nil {
+		return fmt.Errorf("failed to marshal %T: %w", *item, err)
+	}
+	var result T
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("failed to unmarshal %T: %w", *item, err)
+	}
+	*item = result
+	return nil
+}
+
+// MissingMustSupport returns the FHIRPath paths of the elements in paths
+// that resource has no value for
+func MissingMustSupport(resource models.Resource, paths []string) []string {
+	var missing []string
+	for _, path := range paths {
+		result, err := fhirpath.Evaluate(resource, path)
+		if err != nil || len(result) == 0 {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
+
+// toJSON returns the decoded JSON of v
+func toJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %T: %w", v, err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %T: %w", v, err)
+	}
+	return value, nil
+}
+
+// matchesPattern reports whether value has at least the content of pattern
+func matchesPattern(value, pattern interface{}) bool {
+	switch p := pattern.(type) {
+	case map[string]interface{}:
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, item := range p {
+			if !matchesPattern(v[key], item) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		v, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range p {
+			found := false
+			for _, candidate := range v {
+				if matchesPattern(candidate, item) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(value, pattern)
+	}
+}
+
+// merge adds the content of pattern to value
+func merge(value, pattern interface{}) interface{} {
+	switch p := pattern.(type) {
+	case map[string]interface{}:
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			v = make(map[string]interface{})
+		}
+		for key, item := range p {
+			v[key] = merge(v[key], item)
+		}
+		return v
+	case []interface{}:
+		v, _ := value.([]interface{})
+		for _, item := range p {
+			found := false
+			for _, candidate := range v {
+				if matchesPattern(candidate, item) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				v = append(v, item)
+			}
+		}
+		return v
+	default:
+		return pattern
+	}
+}
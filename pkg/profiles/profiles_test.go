@@ -0,0 +1,126 @@
+package profiles
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+const testProfile = "http://example.org/fhir/StructureDefinition/test-patient"
+
+func TestWithProfile(t *testing.T) {
+	meta := WithProfile(nil, testProfile)
+	if meta == nil || !reflect.DeepEqual(meta.Profile, []string{testProfile}) {
+		t.Fatalf("WithProfile(nil) = %+v", meta)
+	}
+
+	original := &models.Meta{Profile: []string{"http://example.org/other"}}
+	meta = WithProfile(original, testProfile)
+	if len(original.Profile) != 1 {
+		t.Errorf("WithProfile changed the original meta: %v", original.Profile)
+	}
+	if len(meta.Profile) != 2 || meta.Profile[1] != testProfile {
+		t.Errorf("Profile = %v", meta.Profile)
+	}
+
+	versioned := &models.Meta{Profile: []string{testProfile + "|1.0.0"}}
+	if got := WithProfile(versioned, testProfile); got != versioned {
+		t.Errorf("expected a claim to a version of the profile to be kept, got %v", got.Profile)
+	}
+	if HasProfile(versioned, "http://example.org/other") {
+		t.Error("HasProfile matched a different profile")
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	code := func(url, value string) models.Extension {
+		return models.Extension{URL: url, ValueCode: &value}
+	}
+	exts := []models.Extension{code("a", "1"), code("b", "2"), code("a", "3"), code("c", "4")}
+
+	if ext := FindExtension(exts, "a"); ext == nil || *ext.ValueCode != "1" {
+		t.Errorf("FindExtension = %+v", ext)
+	}
+	if got := FindExtensions(exts, "a"); len(got) != 2 || *got[1].ValueCode != "3" {
+		t.Errorf("FindExtensions returned %d extensions", len(got))
+	}
+
+	exts = SetExtensions(exts, "a", code("a", "5"))
+	var urls []string
+	for _, ext := range exts {
+		urls = append(urls, ext.URL+"="+*ext.ValueCode)
+	}
+	if want := []string{"a=5", "b=2", "c=4"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("SetExtensions = %v, want %v", urls, want)
+	}
+
+	exts = SetExtensions(exts, "d", code("d", "6"))
+	if len(exts) != 4 || exts[3].URL != "d" {
+		t.Errorf("expected a new extension to be appended, got %+v", exts)
+	}
+	if exts = SetExtensions(SetExtensions(exts[:1], "a"), "a"); exts != nil {
+		t.Errorf("expected removing the only extension to leave nil, got %+v", exts)
+	}
+}
+
+func TestSlice(t *testing.T) {
+	const phone = `{"system": "phone"}`
+	telecom := []models.ContactPoint{
+		{System: "email", Value: "amy@example.org"},
+		{System: "phone", Value: "555-0100"},
+		{System: "phone", Value: "555-0199", Use: "work"},
+	}
+	got := Slice(telecom, phone)
+	if len(got) != 2 || got[0].Value != "555-0100" {
+		t.Fatalf("Slice = %+v", got)
+	}
+	got[0].Use = "home"
+	if telecom[1].Use != "home" {
+		t.Error("expected Slice to return pointers into the items")
+	}
+
+	concept := models.CodeableConcept{Coding: []models.Coding{
+		{System: "http://loinc.org", Code: "8867-4"},
+		{System: "http://snomed.info/sct", Code: "364075005"},
+	}}
+	if !Matches(concept, `{"coding": [{"system": "http://snomed.info/sct", "code": "364075005"}]}`) {
+		t.Error("expected a pattern array item to match any coding")
+	}
+	if Matches(concept, `{"coding": [{"system": "http://snomed.info/sct", "code": "8867-4"}]}`) {
+		t.Error("expected a pattern item to match within one coding")
+	}
+}
+
+func TestApply(t *testing.T) {
+	item := models.ContactPoint{Value: "555-0100"}
+	if err := Apply(&item, `{"system": "phone"}`); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if item.System != "phone" || item.Value != "555-0100" {
+		t.Errorf("Apply = %+v", item)
+	}
+
+	concept := models.CodeableConcept{Coding: []models.Coding{{System: "http://loinc.org", Code: "8867-4"}}}
+	pattern := `{"coding": [{"system": "http://snomed.info/sct", "code": "364075005"}]}`
+	if err := Apply(&concept, pattern); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(concept.Coding) != 2 || !Matches(concept, pattern) {
+		t.Errorf("expected the coding to be added, got %+v", concept.Coding)
+	}
+	if err := Apply(&concept, pattern); err != nil || len(concept.Coding) != 2 {
+		t.Errorf("expected applying a pattern twice to change nothing, got %+v", concept.Coding)
+	}
+}
+
+func TestMissingMustSupport(t *testing.T) {
+	patient := models.NewPatient()
+	patient.Gender = "female"
+	patient.Name = []models.HumanName{{Given: []string{"Amy"}}}
+
+	got := MissingMustSupport(patient, []string{"Patient.name", "Patient.name.family", "Patient.name.given", "Patient.gender", "Patient.birthDate"})
+	if want := []string{"Patient.name.family", "Patient.birthDate"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MissingMustSupport = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,151 @@
+// Code generated by cmd/generator. DO NOT EDIT.
+
+package uscore
+
+import (
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+// BirthsexURL is the canonical URL of the US Core Birth Sex Extension
+const BirthsexURL = "http://hl7.org/fhir/us/core/StructureDefinition/us-core-birthsex"
+
+// EthnicityURL is the canonical URL of the US Core Ethnicity Extension
+const EthnicityURL = "http://hl7.org/fhir/us/core/StructureDefinition/us-core-ethnicity"
+
+// Ethnicity is the value of the US Core Ethnicity Extension
+type Ethnicity struct {
+	OmbCategory *models.Coding
+	Detailed    []models.Coding
+	Text        string
+}
+
+// ethnicityFromExtension reads the value of a US Core Ethnicity Extension
+func ethnicityFromExtension(ext *models.Extension) *Ethnicity {
+	v := &Ethnicity{}
+	for _, part := range ext.Extension {
+		switch part.URL {
+		case "ombCategory":
+			if part.ValueCoding != nil {
+				v.OmbCategory = part.ValueCoding
+			}
+		case "detailed":
+			if part.ValueCoding != nil {
+				v.Detailed = append(v.Detailed, *part.ValueCoding)
+			}
+		case "text":
+			if part.ValueString != nil {
+				v.Text = *part.ValueString
+			}
+		}
+	}
+	return v
+}
+
+// Extension returns the value as a US Core Ethnicity Extension
+func (v *Ethnicity) Extension() models.Extension {
+	ext := models.Extension{URL: EthnicityURL}
+	if v.OmbCategory != nil {
+		ext.Extension = append(ext.Extension, models.Extension{URL: "ombCategory", ValueCoding: v.OmbCategory})
+	}
+	for i := range v.Detailed {
+		value := v.Detailed[i]
+		ext.Extension = append(ext.Extension, models.Extension{URL: "detailed", ValueCoding: &value})
+	}
+	if v.Text != "" {
+		value := v.Text
+		ext.Extension = append(ext.Extension, models.Extension{URL: "text", ValueString: &value})
+	}
+	return ext
+}
+
+// GenderIdentityURL is the canonical URL of the US Core Gender Identity Extension
+const GenderIdentityURL = "http://hl7.org/fhir/us/core/StructureDefinition/us-core-genderIdentity"
+
+// RaceURL is the canonical URL of the US Core Race Extension
+const RaceURL = "http://hl7.org/fhir/us/core/StructureDefinition/us-core-race"
+
+// Race is the value of the US Core Race Extension
+type Race struct {
+	OmbCategory []models.Coding
+	Detailed    []models.Coding
+	Text        string
+}
+
+// raceFromExtension reads the value of a US Core Race Extension
+func raceFromExtension(ext *models.Extension) *Race {
+	v := &Race{}
+	for _, part := range ext.Extension {
+		switch part.URL {
+		case "ombCategory":
+			if part.ValueCoding != nil {
+				v.OmbCategory = append(v.OmbCategory, *part.ValueCoding)
+			}
+		case "detailed":
+			if part.ValueCoding != nil {
+				v.Detailed = append(v.Detailed, *part.ValueCoding)
+			}
+		case "text":
+			if part.ValueString != nil {
+				v.Text = *part.ValueString
+			}
+		}
+	}
+	return v
+}
+
+// Extension returns the value as a US Core Race Extension
+func (v *Race) Extension() models.Extension {
+	ext := models.Extension{URL: RaceURL}
+	for i := range v.OmbCategory {
+		value := v.OmbCategory[i]
+		ext.Extension = append(ext.Extension, models.Extension{URL: "ombCategory", ValueCoding: &value})
+	}
+	for i := range v.Detailed {
+		value := v.Detailed[i]
+		ext.Extension = append(ext.Extension, models.Extension{URL: "detailed", ValueCoding: &value})
+	}
+	if v.Text != "" {
+		value := v.Text
+		ext.Extension = append(ext.Extension, models.Extension{URL: "text", ValueString: &value})
+	}
+	return ext
+}
+
+// TribalAffiliationURL is the canonical URL of the US Core Tribal Affiliation Extension
+const TribalAffiliationURL = "http://hl7.org/fhir/us/core/StructureDefinition/us-core-tribal-affiliation"
+
+// TribalAffiliation is the value of the US Core Tribal Affiliation Extension
+type TribalAffiliation struct {
+	TribalAffiliation *models.CodeableConcept
+	IsEnrolled        *bool
+}
+
+// tribalAffiliationFromExtension reads the value of a US Core Tribal Affiliation Extension
+func tribalAffiliationFromExtension(ext *models.Extension) *TribalAffiliation {
+	v := &TribalAffiliation{}
+	for _, part := range ext.Extension {
+		switch part.URL {
+		case "tribalAffiliation":
+			if part.ValueCodeableConcept != nil {
+				v.TribalAffiliation = part.ValueCodeableConcept
+			}
+		case "isEnrolled":
+			if part.ValueBoolean != nil {
+				v.IsEnrolled = part.ValueBoolean
+			}
+		}
+	}
+	return v
+}
+
+// Extension returns the value as a US Core Tribal Affiliation Extension
+func (v *TribalAffiliation) Extension() models.Extension {
+	ext := models.Extension{URL: TribalAffiliationURL}
+	if v.TribalAffiliation != nil {
+		ext.Extension = append(ext.Extension, models.Extension{URL: "tribalAffiliation", ValueCodeableConcept: v.TribalAffiliation})
+	}
+	if v.IsEnrolled != nil {
+		ext.Extension = append(ext.Extension, models.Extension{URL: "isEnrolled", ValueBoolean: v.IsEnrolled})
+	}
+	return ext
+}
@@ -0,0 +1,3 @@
+package uscore
+
+//go:generate go run ../../../cmd/generator -profiles uscore -input testdata -output .
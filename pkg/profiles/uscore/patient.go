@@ -0,0 +1,164 @@
+// Code generated by cmd/generator from http://hl7.org/fhir/us/core/StructureDefinition/us-core-patient|6.1.0. DO NOT EDIT.
+
+package uscore
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/profiles"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/validation"
+)
+
+// PatientProfile is the canonical URL of the US Core Patient Profile
+const PatientProfile = "http://hl7.org/fhir/us/core/StructureDefinition/us-core-patient"
+
+// Patient is a models.Patient conforming to the US Core Patient Profile. It claims the
+// profile in meta.profile when marshaled.
+type Patient struct {
+	models.Patient
+}
+
+// NewPatient creates a Patient that claims the profile
+func NewPatient() *Patient {
+	p := &Patient{Patient: *models.NewPatient()}
+	p.Meta = profiles.WithProfile(p.Meta, PatientProfile)
+	return p
+}
+
+// PatientMustSupport lists the must-support elements of the profile as FHIRPath
+var PatientMustSupport = []string{
+	"Patient.identifier",
+	"Patient.identifier.system",
+	"Patient.identifier.value",
+	"Patient.name",
+	"Patient.name.family",
+	"Patient.name.given",
+	"Patient.telecom",
+	"Patient.telecom.system",
+	"Patient.telecom.value",
+	"Patient.telecom.use",
+	"Patient.gender",
+	"Patient.birthDate",
+	"Patient.address",
+	"Patient.address.line",
+	"Patient.address.city",
+	"Patient.address.state",
+	"Patient.address.postalCode",
+	"Patient.communication",
+	"Patient.communication.language",
+}
+
+// MarshalJSON implements custom JSON marshaling, adding the profile to meta.profile
+func (p Patient) MarshalJSON() ([]byte, error) {
+	p.Meta = profiles.WithProfile(p.Meta, PatientProfile)
+	return json.Marshal(p.Patient)
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for Patient
+func (p *Patient) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &p.Patient)
+}
+
+// Validate checks the patient against the profile with v, which must be loaded
+// with the core StructureDefinitions and the profiles and extensions of
+// this package, returning a *validation.Error listing the errors found
+func (p *Patient) Validate(ctx context.Context, v *validation.Validator) error {
+	return validation.Check(ctx, v, p)
+}
+
+// MissingMustSupport returns the must-support elements the patient has no value for
+func (p *Patient) MissingMustSupport() []string {
+	return profiles.MissingMustSupport(p, PatientMustSupport)
+}
+
+// Race returns the value of the race extension, or nil if it is absent
+func (p *Patient) Race() *Race {
+	if ext := profiles.FindExtension(p.Extension, RaceURL); ext != nil {
+		return raceFromExtension(ext)
+	}
+	return nil
+}
+
+// SetRace sets the race extension, removing it if value is nil
+func (p *Patient) SetRace(value *Race) {
+	if value == nil {
+		p.Extension = profiles.SetExtensions(p.Extension, RaceURL)
+		return
+	}
+	p.Extension = profiles.SetExtensions(p.Extension, RaceURL, value.Extension())
+}
+
+// Ethnicity returns the value of the ethnicity extension, or nil if it is absent
+func (p *Patient) Ethnicity() *Ethnicity {
+	if ext := profiles.FindExtension(p.Extension, EthnicityURL); ext != nil {
+		return ethnicityFromExtension(ext)
+	}
+	return nil
+}
+
+// SetEthnicity sets the ethnicity extension, removing it if value is nil
+func (p *Patient) SetEthnicity(value *Ethnicity) {
+	if value == nil {
+		p.Extension = profiles.SetExtensions(p.Extension, EthnicityURL)
+		return
+	}
+	p.Extension = profiles.SetExtensions(p.Extension, EthnicityURL, value.Extension())
+}
+
+// TribalAffiliation returns the values of the tribalAffiliation extensions
+func (p *Patient) TribalAffiliation() []TribalAffiliation {
+	var values []TribalAffiliation
+	for _, ext := range profiles.FindExtensions(p.Extension, TribalAffiliationURL) {
+		values = append(values, *tribalAffiliationFromExtension(ext))
+	}
+	return values
+}
+
+// SetTribalAffiliation replaces the tribalAffiliation extensions with one for each value
+func (p *Patient) SetTribalAffiliation(values ...TribalAffiliation) {
+	exts := make([]models.Extension, len(values))
+	for i := range values {
+		exts[i] = values[i].Extension()
+	}
+	p.Extension = profiles.SetExtensions(p.Extension, TribalAffiliationURL, exts...)
+}
+
+// Birthsex returns the value of the birthsex extension, or "" if it is absent
+func (p *Patient) Birthsex() string {
+	if ext := profiles.FindExtension(p.Extension, BirthsexURL); ext != nil && ext.ValueCode != nil {
+		return *ext.ValueCode
+	}
+	return ""
+}
+
+// SetBirthsex sets the birthsex extension, removing it if value is ""
+func (p *Patient) SetBirthsex(value string) {
+	if value == "" {
+		p.Extension = profiles.SetExtensions(p.Extension, BirthsexURL)
+		return
+	}
+	p.Extension = profiles.SetExtensions(p.Extension, BirthsexURL, models.Extension{URL: BirthsexURL, ValueCode: &value})
+}
+
+// GenderIdentity returns the values of the genderIdentity extensions
+func (p *Patient) GenderIdentity() []models.CodeableConcept {
+	var values []models.CodeableConcept
+	for _, ext := range profiles.FindExtensions(p.Extension, GenderIdentityURL) {
+		if ext.ValueCodeableConcept != nil {
+			values = append(values, *ext.ValueCodeableConcept)
+		}
+	}
+	return values
+}
+
+// SetGenderIdentity replaces the genderIdentity extensions with one for each value
+func (p *Patient) SetGenderIdentity(values ...models.CodeableConcept) {
+	exts := make([]models.Extension, len(values))
+	for i := range values {
+		value := values[i]
+		exts[i] = models.Extension{URL: GenderIdentityURL, ValueCodeableConcept: &value}
+	}
+	p.Extension = profiles.SetExtensions(p.Extension, GenderIdentityURL, exts...)
+}
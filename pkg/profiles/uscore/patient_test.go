@@ -0,0 +1,194 @@
+package uscore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhirpath"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/terminology"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/validation"
+)
+
+const ombSystem = "urn:oid:2.16.840.1.113883.6.238"
+
+const testPatient = `{
+	"resourceType": "Patient",
+	"id": "example",
+	"extension": [
+		{
+			"url": "http://hl7.org/fhir/us/core/StructureDefinition/us-core-race",
+			"extension": [
+				{"url": "ombCategory", "valueCoding": {"system": "urn:oid:2.16.840.1.113883.6.238", "code": "2106-3", "display": "White"}},
+				{"url": "ombCategory", "valueCoding": {"system": "urn:oid:2.16.840.1.113883.6.238", "code": "1002-5", "display": "American Indian or Alaska Native"}},
+				{"url": "text", "valueString": "Mixed"}
+			]
+		},
+		{"url": "http://hl7.org/fhir/us/core/StructureDefinition/us-core-birthsex", "valueCode": "F"}
+	],
+	"identifier": [{"system": "http://hospital.smarthealthit.org", "value": "1032702"}],
+	"name": [{"family": "Shaw", "given": ["Amy", "V."]}],
+	"gender": "female",
+	"birthDate": "1987-02-20"
+}`
+
+func decodePatient(t *testing.T, data string) *Patient {
+	p := &Patient{}
+	if err := json.Unmarshal([]byte(data), p); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	return p
+}
+
+func TestNewPatientClaimsProfile(t *testing.T) {
+	p := NewPatient()
+	if p.ResourceType != models.ResourceTypePatient {
+		t.Errorf("ResourceType = %s", p.ResourceType)
+	}
+	if p.Meta == nil || !reflect.DeepEqual(p.Meta.Profile, []string{PatientProfile}) {
+		t.Errorf("Meta = %+v", p.Meta)
+	}
+
+	// A patient built without NewPatient claims the profile when marshaled
+	var q Patient
+	q.ResourceType = models.ResourceTypePatient
+	q.Gender = "male"
+	data, err := json.Marshal(q)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"profile":["`+PatientProfile+`"]`) {
+		t.Errorf("expected meta.profile in %s", data)
+	}
+	if q.Meta != nil {
+		t.Error("Marshal changed the patient")
+	}
+}
+
+func TestPatientExtensions(t *testing.T) {
+	p := decodePatient(t, testPatient)
+
+	race := p.Race()
+	if race == nil {
+		t.Fatal("Race() = nil")
+	}
+	if len(race.OmbCategory) != 2 || race.OmbCategory[1].Code != "1002-5" || race.Text != "Mixed" {
+		t.Errorf("Race() = %+v", race)
+	}
+	if p.Ethnicity() != nil {
+		t.Errorf("Ethnicity() = %+v, want nil", p.Ethnicity())
+	}
+	if got := p.Birthsex(); got != "F" {
+		t.Errorf("Birthsex() = %q", got)
+	}
+
+	p.SetEthnicity(&Ethnicity{
+		OmbCategory: &models.Coding{System: ombSystem, Code: "2186-5", Display: "Not Hispanic or Latino"},
+		Text:        "Not Hispanic or Latino",
+	})
+	p.SetBirthsex("")
+	p.SetGenderIdentity(models.CodeableConcept{Text: "non-binary"})
+	enrolled := true
+	p.SetTribalAffiliation(TribalAffiliation{
+		TribalAffiliation: &models.CodeableConcept{Text: "Cherokee Nation"},
+		IsEnrolled:        &enrolled,
+	})
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	q := decodePatient(t, string(data))
+	if q.Birthsex() != "" {
+		t.Errorf("expected birthsex to be removed, got %q", q.Birthsex())
+	}
+	if e := q.Ethnicity(); e == nil || e.OmbCategory == nil || e.OmbCategory.Code != "2186-5" || e.Text != "Not Hispanic or Latino" {
+		t.Errorf("Ethnicity() = %+v", e)
+	}
+	if got := q.GenderIdentity(); len(got) != 1 || got[0].Text != "non-binary" {
+		t.Errorf("GenderIdentity() = %+v", got)
+	}
+	if got := q.TribalAffiliation(); len(got) != 1 || got[0].IsEnrolled == nil || !*got[0].IsEnrolled {
+		t.Errorf("TribalAffiliation() = %+v", got)
+	}
+	if r := q.Race(); r == nil || len(r.OmbCategory) != 2 {
+		t.Errorf("expected race to be kept, got %+v", r)
+	}
+	if q.Extension[0].URL != RaceURL {
+		t.Errorf("expected race to keep its position, got %s first", q.Extension[0].URL)
+	}
+	if !strings.Contains(string(data), `"identifier":[{"system":"http://hospital.smarthealthit.org"`) {
+		t.Errorf("expected elements without fields to be kept in %s", data)
+	}
+}
+
+func TestMissingMustSupport(t *testing.T) {
+	p := decodePatient(t, testPatient)
+	got := p.MissingMustSupport()
+	for _, path := range []string{"Patient.identifier", "Patient.name.family", "Patient.gender", "Patient.birthDate"} {
+		for _, missing := range got {
+			if missing == path {
+				t.Errorf("%s reported missing", path)
+			}
+		}
+	}
+	for _, path := range []string{"Patient.telecom", "Patient.address", "Patient.communication.language"} {
+		found := false
+		for _, missing := range got {
+			found = found || missing == path
+		}
+		if !found {
+			t.Errorf("expected %s to be reported missing, got %v", path, got)
+		}
+	}
+}
+
+func TestPatientValidate(t *testing.T) {
+	p := decodePatient(t, testPatient)
+	p.Gender = "woman"
+	if err := p.Validate(context.Background(), nil); !errors.Is(err, validation.ErrNoDefinition) {
+		t.Errorf("expected ErrNoDefinition without a validator, got %v", err)
+	}
+
+	v := validation.NewValidator()
+	for _, dir := range []string{"../../validation/testdata", "testdata"} {
+		if err := v.LoadDir(dir); err != nil {
+			t.Fatalf("LoadDir failed: %v", err)
+		}
+	}
+	local := terminology.NewLocal()
+	if err := local.LoadDir("../../validation/testdata"); err != nil {
+		t.Fatalf("terminology LoadDir failed: %v", err)
+	}
+	v.Terminology = local
+	v.Invariants = fhirpath.NewEvaluator()
+
+	// The base Extension definition in the validation testdata has no
+	// nested extensions, so leave race out
+	p.Gender = "female"
+	p.SetRace(nil)
+	if err := p.Validate(context.Background(), v); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+
+	p = NewPatient()
+	p.Gender = "woman"
+	err := p.Validate(context.Background(), v)
+	var verr *validation.Error
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	for _, text := range []string{"woman", "Patient.identifier", "us-core-6"} {
+		found := false
+		for _, issue := range verr.Outcome.Issue {
+			found = found || strings.Contains(issue.Diagnostics, text) || (len(issue.Expression) > 0 && issue.Expression[0] == text)
+		}
+		if !found {
+			t.Errorf("expected an issue mentioning %s, got %+v", text, verr.Outcome.Issue)
+		}
+	}
+}
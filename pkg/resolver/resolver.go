@@ -0,0 +1,280 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/operations"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/search"
+)
+
+// Scope describes where a reference was found, so that it can be resolved
+// against the surrounding data before going to the server
+type Scope struct {
+	// Bundle is the bundle the referring resource was read from
+	Bundle *models.Bundle
+
+	// FullURL is the fullUrl of the referring entry in Bundle
+	FullURL string
+
+	// Container is the resource whose contained resources local references resolve against
+	Container models.Resource
+}
+
+// Resolver follows references to the resources they point to, looking in the
+// referring resource's container and bundle first, then reading from the
+// server. Resources read from the server are cached.
+type Resolver struct {
+	op      operations.Operation
+	baseURL string
+
+	mu    sync.Mutex
+	cache map[string]models.Resource
+}
+
+// NewResolver creates a resolver that reads from op. baseURL is the server's
+// base URL, used to recognise absolute references that point to the server.
+func NewResolver(op operations.Operation, baseURL string) *Resolver {
+	return &Resolver{
+		op:      op,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		cache:   make(map[string]models.Resource),
+	}
+}
+
+// ClearCache discards all cached resources
+func (r *Resolver) ClearCache() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache = make(map[string]models.Resource)
+}
+
+// Resolve returns the resource that ref points to. scope may be nil.
+func (r *Resolver) Resolve(ctx context.Context, ref models.Reference, scope *Scope) (models.Resource, error) {
+	resources, err := r.ResolveAll(ctx, []models.Reference{ref}, scope)
+	if err != nil {
+		return nil, err
+	}
+	return resources[0], nil
+}
+
+// ResolveAll resolves several references, returning the resources in the
+// same order. References that must be read from the server are fetched
+// together in a single batch Bundle.
+func (r *Resolver) ResolveAll(ctx context.Context, refs []models.Reference, scope *Scope) ([]models.Resource, error) {
+	resources := make([]models.Resource, len(refs))
+	pending := make(map[string][]int)
+	var order []string
+
+	for i, ref := range refs {
+		parsed, err := ref.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("reference %d: %w", i, err)
+		}
+
+		if resource, ok := r.resolveLocally(parsed, scope); ok {
+			resources[i] = resource
+			continue
+		}
+
+		switch parsed.Kind {
+		case models.ReferenceKindLocal:
+			return nil, fmt.Errorf("reference %d: contained resource %q not found", i, parsed.Raw)
+		case models.ReferenceKindURN:
+			return nil, fmt.Errorf("reference %d: %q not found in bundle", i, parsed.Raw)
+		case models.ReferenceKindOther:
+			return nil, fmt.Errorf("reference %d: cannot resolve non-RESTful reference %q", i, parsed.Raw)
+		case models.ReferenceKindAbsolute:
+			if parsed.BaseURL != r.baseURL {
+				return nil, fmt.Errorf("reference %d: %q is on another server", i, parsed.Raw)
+			}
+		case models.ReferenceKindLogical:
+			resource, err := r.resolveLogical(ctx, parsed)
+			if err != nil {
+				return nil, fmt.Errorf("reference %d: %w", i, err)
+			}
+			resources[i] = resource
+			continue
+		}
+
+		key := parsed.RelativeURL()
+		if resource, ok := r.cached(key); ok {
+			resources[i] = resource
+			continue
+		}
+		if _, ok := pending[key]; !ok {
+			order = append(order, key)
+		}
+		pending[key] = append(pending[key], i)
+	}
+
+	if len(order) == 0 {
+		return resources, nil
+	}
+
+	fetched, err := r.fetch(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	for key, indexes := range pending {
+		for _, i := range indexes {
+			resources[i] = fetched[key]
+		}
+	}
+	return resources, nil
+}
+
+// resolveLocally looks for the target of a reference in the scope
+func (r *Resolver) resolveLocally(ref *models.ParsedReference, scope *Scope) (models.Resource, bool) {
+	if scope == nil {
+		return nil, false
+	}
+
+	if ref.Kind == models.ReferenceKindLocal {
+		if scope.Container == nil {
+			return nil, false
+		}
+		return models.ResolveContained(scope.Container, ref.Raw)
+	}
+
+	if scope.Bundle == nil {
+		return nil, false
+	}
+	entry := scope.Bundle.FindEntry(ref, scope.FullURL)
+	if entry == nil {
+		return nil, false
+	}
+	resource, err := entry.GetResource()
+	if err != nil || resource == nil {
+		return nil, false
+	}
+	return resource, true
+}
+
+// resolveLogical finds the single resource with the reference's identifier
+func (r *Resolver) resolveLogical(ctx context.Context, ref *models.ParsedReference) (models.Resource, error) {
+	if ref.ResourceType == "" {
+		return nil, fmt.Errorf("logical reference has no type")
+	}
+
+	key := ref.String()
+	if resource, ok := r.cached(key); ok {
+		return resource, nil
+	}
+
+	token := ref.Identifier.Value
+	if ref.Identifier.System != "" {
+		token = ref.Identifier.System + "|" + token
+	}
+	bundle, err := r.op.Search(ctx, ref.ResourceType, search.NewParameters().Add("identifier", token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for %s: %w", key, err)
+	}
+
+	var matches []models.BundleEntry
+	for _, entry := range bundle.Entry {
+		if entry.Search == nil || entry.Search.Mode == "" || entry.Search.Mode == "match" {
+			matches = append(matches, entry)
+		}
+	}
+	if len(matches) != 1 {
+		return nil, fmt.Errorf("%s matched %d resources, expected 1", key, len(matches))
+	}
+
+	resource, err := matches[0].GetResource()
+	if err != nil {
+		return nil, err
+	}
+	r.store(key, resource)
+	return resource, nil
+}
+
+// fetch reads resources from the server by relative URL. A single resource
+// is read directly; several are read in one batch.
+func (r *Resolver) fetch(ctx context.Context, urls []string) (map[string]models.Resource, error) {
+	if len(urls) == 1 {
+		resource, err := r.read(ctx, urls[0])
+		if err != nil {
+			return nil, err
+		}
+		r.store(urls[0], resource)
+		return map[string]models.Resource{urls[0]: resource}, nil
+	}
+
+	batch := &models.Bundle{
+		Base: models.Base{ResourceType: "Bundle"},
+		Type: "batch",
+	}
+	for _, url := range urls {
+		batch.Entry = append(batch.Entry, models.BundleEntry{
+			Request: &models.BundleRequest{Method: "GET", URL: url},
+		})
+	}
+
+	response, err := r.op.Transaction(ctx, batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute batch read: %w", err)
+	}
+	if len(response.Entry) != len(urls) {
+		return nil, fmt.Errorf("batch response has %d entries, expected %d", len(response.Entry), len(urls))
+	}
+
+	resources := make(map[string]models.Resource, len(urls))
+	for i, entry := range response.Entry {
+		if entry.Response != nil && !strings.HasPrefix(entry.Response.Status, "2") {
+			return nil, fmt.Errorf("failed to read %s: %s", urls[i], entry.Response.Status)
+		}
+		resource, err := entry.GetResource()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", urls[i], err)
+		}
+		if resource == nil {
+			return nil, fmt.Errorf("failed to read %s: no resource returned", urls[i])
+		}
+		r.store(urls[i], resource)
+		resources[urls[i]] = resource
+	}
+	return resources, nil
+}
+
+// read reads a single resource by relative URL
+func (r *Resolver) read(ctx context.Context, url string) (models.Resource, error) {
+	ref, err := models.ParseReference(url)
+	if err != nil {
+		return nil, err
+	}
+	if ref.Version != "" {
+		return r.op.Vread(ctx, ref.ResourceType, ref.ID, ref.Version)
+	}
+	return r.op.Read(ctx, ref.ResourceType, ref.ID)
+}
+
+func (r *Resolver) cached(key string) (models.Resource, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	resource, ok := r.cache[key]
+	return resource, ok
+}
+
+func (r *Resolver) store(key string, resource models.Resource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache[key] = resource
+}
+
+// ResolveAs resolves ref and asserts the result to the type T, such as *models.Patient
+func ResolveAs[T models.Resource](ctx context.Context, r *Resolver, ref models.Reference, scope *Scope) (T, error) {
+	var zero T
+	resource, err := r.Resolve(ctx, ref, scope)
+	if err != nil {
+		return zero, err
+	}
+	typed, ok := resource.(T)
+	if !ok {
+		return zero, fmt.Errorf("reference %q resolved to %s, not %T", ref.Reference, resource.GetResourceType(), zero)
+	}
+	return typed, nil
+}
@@ -0,0 +1,177 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/operations"
+)
+
+// fakeServer serves Patient reads, identifier searches and batch reads
+type fakeServer struct {
+	reads   int32
+	batches int32
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/fhir+json")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/Patient" && r.URL.Query().Get("identifier") != "":
+		fmt.Fprintf(w, `{"resourceType":"Bundle","type":"searchset","entry":[{"resource":%s,"search":{"mode":"match"}}]}`, patientJSON("mrn-match"))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/Patient/"):
+		atomic.AddInt32(&f.reads, 1)
+		id := strings.Split(strings.TrimPrefix(r.URL.Path, "/Patient/"), "/")[0]
+		fmt.Fprint(w, patientJSON(id))
+	case r.Method == http.MethodPost && r.URL.Path == "/":
+		atomic.AddInt32(&f.batches, 1)
+		var batch models.Bundle
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var entries []string
+		for _, entry := range batch.Entry {
+			id := strings.Split(strings.TrimPrefix(entry.Request.URL, "Patient/"), "/")[0]
+			if id == "missing" {
+				entries = append(entries, `{"response":{"status":"404 Not Found"}}`)
+				continue
+			}
+			entries = append(entries, fmt.Sprintf(`{"resource":%s,"response":{"status":"200 OK"}}`, patientJSON(id)))
+		}
+		fmt.Fprintf(w, `{"resourceType":"Bundle","type":"batch-response","entry":[%s]}`, strings.Join(entries, ","))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func patientJSON(id string) string {
+	return fmt.Sprintf(`{"resourceType":"Patient","id":%q,"name":[{"family":"Family-%s"}]}`, id, id)
+}
+
+func newTestResolver(t *testing.T) (*Resolver, *fakeServer, string) {
+	fake := &fakeServer{}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	op := operations.NewHTTPOperation(server.Client(), server.URL)
+	return NewResolver(op, server.URL), fake, server.URL
+}
+
+func TestResolveFromServerWithCaching(t *testing.T) {
+	r, fake, baseURL := newTestResolver(t)
+	ctx := context.Background()
+
+	patient, err := ResolveAs[*models.Patient](ctx, r, models.Reference{Reference: "Patient/1"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to resolve reference: %v", err)
+	}
+	if patient.ID != "1" {
+		t.Errorf("Expected patient 1, got %s", patient.ID)
+	}
+
+	// An absolute reference to the same server hits the cache
+	if _, err := r.Resolve(ctx, models.Reference{Reference: baseURL + "/Patient/1"}, nil); err != nil {
+		t.Fatalf("Failed to resolve absolute reference: %v", err)
+	}
+	if fake.reads != 1 {
+		t.Errorf("Expected 1 server read, got %d", fake.reads)
+	}
+
+	if _, err := r.Resolve(ctx, models.Reference{Reference: "http://elsewhere.example.org/fhir/Patient/1"}, nil); err == nil {
+		t.Error("Expected error resolving a reference to another server")
+	}
+}
+
+func TestResolveAllBatchesServerReads(t *testing.T) {
+	r, fake, _ := newTestResolver(t)
+
+	refs := []models.Reference{
+		{Reference: "Patient/a"},
+		{Reference: "Patient/b"},
+		{Reference: "Patient/a"},
+		{Reference: "Patient/c/_history/3"},
+	}
+	resources, err := r.ResolveAll(context.Background(), refs, nil)
+	if err != nil {
+		t.Fatalf("Failed to resolve references: %v", err)
+	}
+	if fake.batches != 1 || fake.reads != 0 {
+		t.Errorf("Expected a single batch and no direct reads, got %d batches and %d reads", fake.batches, fake.reads)
+	}
+	for i, want := range []string{"a", "b", "a", "c"} {
+		if got := resources[i].(*models.Patient).ID; got != want {
+			t.Errorf("Resource %d: expected %s, got %s", i, want, got)
+		}
+	}
+
+	_, err = r.ResolveAll(context.Background(), []models.Reference{{Reference: "Patient/x"}, {Reference: "Patient/missing"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected batch entry error, got %v", err)
+	}
+}
+
+func TestResolveAgainstBundleAndContained(t *testing.T) {
+	r, fake, _ := newTestResolver(t)
+	ctx := context.Background()
+
+	bundleJSON := `{
+		"resourceType": "Bundle",
+		"type": "transaction",
+		"entry": [
+			{"fullUrl": "urn:uuid:61ebe359-bfdc-4613-8bf2-c5e300945f0a", "resource": {"resourceType": "Patient", "id": "new"}},
+			{"fullUrl": "http://example.org/fhir/Patient/7", "resource": {"resourceType": "Patient", "id": "7", "meta": {"versionId": "2"}}}
+		]
+	}`
+	var bundle models.Bundle
+	if err := json.Unmarshal([]byte(bundleJSON), &bundle); err != nil {
+		t.Fatalf("Failed to unmarshal bundle: %v", err)
+	}
+	scope := &Scope{Bundle: &bundle, FullURL: "http://example.org/fhir/Observation/1"}
+
+	byURN, err := r.Resolve(ctx, models.Reference{Reference: "urn:uuid:61ebe359-bfdc-4613-8bf2-c5e300945f0a"}, scope)
+	if err != nil || byURN.(*models.Patient).ID != "new" {
+		t.Errorf("Expected urn reference to resolve within bundle, got %v, %v", byURN, err)
+	}
+
+	relative, err := r.Resolve(ctx, models.Reference{Reference: "Patient/7/_history/2"}, scope)
+	if err != nil || relative.(*models.Patient).ID != "7" {
+		t.Errorf("Expected versioned relative reference to resolve within bundle, got %v, %v", relative, err)
+	}
+
+	container := models.NewPatient()
+	container.Contained = []models.Resource{&models.Patient{Base: models.Base{ResourceType: "Patient", ID: "mum"}}}
+	local, err := r.Resolve(ctx, models.Reference{Reference: "#mum"}, &Scope{Container: container})
+	if err != nil || local.(*models.Patient).ID != "mum" {
+		t.Errorf("Expected local reference to resolve to contained resource, got %v, %v", local, err)
+	}
+
+	if fake.reads != 0 || fake.batches != 0 {
+		t.Errorf("Expected no server traffic, got %d reads and %d batches", fake.reads, fake.batches)
+	}
+}
+
+func TestResolveLogicalReference(t *testing.T) {
+	r, _, _ := newTestResolver(t)
+
+	ref := models.Reference{
+		Type:       "Patient",
+		Identifier: &models.Identifier{System: "http://hospital.example.org/mrn", Value: "12345"},
+	}
+	resource, err := r.Resolve(context.Background(), ref, nil)
+	if err != nil {
+		t.Fatalf("Failed to resolve logical reference: %v", err)
+	}
+	if resource.(*models.Patient).ID != "mrn-match" {
+		t.Errorf("Expected mrn-match, got %s", resource.(*models.Patient).ID)
+	}
+
+	if _, err := ResolveAs[*models.Bundle](context.Background(), r, ref, nil); err == nil {
+		t.Error("Expected type mismatch error")
+	}
+}
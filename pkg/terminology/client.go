@@ -0,0 +1,230 @@
+package terminology
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/operations"
+)
+
+// Client performs terminology operations against a terminology server
+type Client struct {
+	op *operations.HTTPOperation
+
+	// CacheExpansions keeps the results of Expand, so that repeated
+	// expansions of a value set are served locally
+	CacheExpansions bool
+
+	// CacheTTL is how long a cached expansion is used for. Zero keeps
+	// expansions until ClearCache is called.
+	CacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedExpansion
+}
+
+// cachedExpansion is an expansion kept by the client
+type cachedExpansion struct {
+	valueSet *models.ValueSet
+	expires  time.Time
+}
+
+// NewClient creates a terminology client sending requests through op
+func NewClient(op *operations.HTTPOperation) *Client {
+	return &Client{
+		op:              op,
+		CacheExpansions: true,
+		cache:           make(map[string]cachedExpansion),
+	}
+}
+
+// ClearCache discards all cached expansions
+func (c *Client) ClearCache() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache = make(map[string]cachedExpansion)
+}
+
+// Lookup returns the details of a code with CodeSystem/$lookup
+func (c *Client) Lookup(ctx context.Context, req *LookupRequest) (*LookupResult, error) {
+	out, err := c.op.InvokeParameters(ctx, &operations.Invocation{
+		Name:         "lookup",
+		ResourceType: "CodeSystem",
+		Parameters:   req.parameters(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s|%s: %w", req.System, req.Code, err)
+	}
+
+	result := &LookupResult{}
+	result.Name, _ = out.GetString("name")
+	result.Version, _ = out.GetString("version")
+	result.Display, _ = out.GetString("display")
+	for _, param := range out.GetAll("designation") {
+		result.Designations = append(result.Designations, designation(param.Parts()))
+	}
+	for _, param := range out.GetAll("property") {
+		result.Properties = append(result.Properties, property(param.Parts()))
+	}
+	return result, nil
+}
+
+// designation decodes the parts of a designation output parameter
+func designation(parts *models.Parameters) models.ValueSetDesignation {
+	var d models.ValueSetDesignation
+	d.Language, _ = parts.GetString("language")
+	d.Use, _ = parts.GetCoding("use")
+	d.Value, _ = parts.GetString("value")
+	return d
+}
+
+// property decodes the parts of a property output parameter
+func property(parts *models.Parameters) Property {
+	var p Property
+	p.Code, _ = parts.GetString("code")
+	p.Description, _ = parts.GetString("description")
+	if value := parts.Get("value"); value != nil {
+		if value.ValueCoding != nil {
+			p.Coding = value.ValueCoding
+		} else {
+			p.Value, _ = value.PrimitiveValue()
+		}
+	}
+	for _, sub := range parts.GetAll("subproperty") {
+		p.Subproperties = append(p.Subproperties, property(sub.Parts()))
+	}
+	return p
+}
+
+// ValidateCode checks a code with ValueSet/$validate-code, or with
+// CodeSystem/$validate-code when the request names no value set
+func (c *Client) ValidateCode(ctx context.Context, req *ValidateCodeRequest) (*ValidateCodeResult, error) {
+	resourceType := "ValueSet"
+	if req.ValueSet == "" {
+		resourceType = "CodeSystem"
+	}
+	out, err := c.op.InvokeParameters(ctx, &operations.Invocation{
+		Name:         "validate-code",
+		ResourceType: resourceType,
+		Parameters:   req.parameters(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate code: %w", err)
+	}
+
+	result, ok := out.GetBoolean("result")
+	if !ok {
+		return nil, fmt.Errorf("failed to validate code: response has no result")
+	}
+	message, _ := out.GetString("message")
+	display, _ := out.GetString("display")
+	return &ValidateCodeResult{Result: result, Message: message, Display: display}, nil
+}
+
+// Expand returns a value set expanded with ValueSet/$expand. Expansions are
+// cached when CacheExpansions is set; the returned value set may be shared
+// with later callers and must not be modified.
+func (c *Client) Expand(ctx context.Context, req *ExpandRequest) (*models.ValueSet, error) {
+	key := req.cacheKey()
+	if c.CacheExpansions {
+		if vs := c.cached(key); vs != nil {
+			return vs, nil
+		}
+	}
+
+	resource, err := c.op.Invoke(ctx, &operations.Invocation{
+		Name:         "expand",
+		ResourceType: "ValueSet",
+		Parameters:   req.parameters(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand %s: %w", req.URL, err)
+	}
+	vs, ok := resource.(*models.ValueSet)
+	if !ok {
+		if outcome, ok := resource.(*models.OperationOutcome); ok {
+			return nil, fmt.Errorf("failed to expand %s: %w", req.URL, &operations.OutcomeError{Outcome: outcome})
+		}
+		return nil, fmt.Errorf("failed to expand %s: server returned %T, not ValueSet", req.URL, resource)
+	}
+
+	if c.CacheExpansions {
+		c.store(key, vs)
+	}
+	return vs, nil
+}
+
+// cached returns the cached expansion for key if it has not expired
+func (c *Client) cached(key string) *models.ValueSet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.cache[key]
+	if !ok {
+		return nil
+	}
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		delete(c.cache, key)
+		return nil
+	}
+	return entry.valueSet
+}
+
+// store caches an expansion under key
+func (c *Client) store(key string, vs *models.ValueSet) {
+	entry := cachedExpansion{valueSet: vs}
+	if c.CacheTTL > 0 {
+		entry.expires = time.Now().Add(c.CacheTTL)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache[key] = entry
+}
+
+// Translate maps a code with ConceptMap/$translate
+func (c *Client) Translate(ctx context.Context, req *TranslateRequest) (*TranslateResult, error) {
+	out, err := c.op.InvokeParameters(ctx, &operations.Invocation{
+		Name:         "translate",
+		ResourceType: "ConceptMap",
+		Parameters:   req.parameters(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to translate code: %w", err)
+	}
+
+	result := &TranslateResult{}
+	result.Result, _ = out.GetBoolean("result")
+	result.Message, _ = out.GetString("message")
+	for _, param := range out.GetAll("match") {
+		parts := param.Parts()
+		var match TranslateMatch
+		if match.Equivalence, _ = parts.GetString("equivalence"); match.Equivalence == "" {
+			match.Equivalence, _ = parts.GetString("relationship")
+		}
+		match.Concept, _ = parts.GetCoding("concept")
+		if match.Source, _ = parts.GetString("source"); match.Source == "" {
+			match.Source, _ = parts.GetString("originMap")
+		}
+		result.Matches = append(result.Matches, match)
+	}
+	return result, nil
+}
+
+// Subsumes tests whether code A subsumes code B with CodeSystem/$subsumes
+func (c *Client) Subsumes(ctx context.Context, req *SubsumesRequest) (SubsumesOutcome, error) {
+	out, err := c.op.InvokeParameters(ctx, &operations.Invocation{
+		Name:         "subsumes",
+		ResourceType: "CodeSystem",
+		Parameters:   req.parameters(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to test subsumption: %w", err)
+	}
+	outcome, ok := out.GetString("outcome")
+	if !ok {
+		return "", fmt.Errorf("failed to test subsumption: response has no outcome")
+	}
+	return SubsumesOutcome(outcome), nil
+}
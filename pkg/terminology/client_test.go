@@ -0,0 +1,159 @@
+package terminology
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/operations"
+)
+
+var _ Service = (*Client)(nil)
+
+const (
+	lookupResponse = `{"resourceType":"Parameters","parameter":[
+		{"name":"name","valueString":"SNOMED CT"},
+		{"name":"display","valueString":"Diabetes mellitus"},
+		{"name":"designation","part":[{"name":"language","valueCode":"en"},{"name":"value","valueString":"Diabetes"}]},
+		{"name":"property","part":[{"name":"code","valueCode":"parent"},{"name":"value","valueCode":"362969004"}]},
+		{"name":"property","part":[{"name":"code","valueCode":"inactive"},{"name":"value","valueBoolean":false}]}]}`
+
+	translateResponse = `{"resourceType":"Parameters","parameter":[
+		{"name":"result","valueBoolean":true},
+		{"name":"match","part":[{"name":"equivalence","valueCode":"equivalent"},
+			{"name":"concept","valueCoding":{"system":"http://hl7.org/fhir/sid/icd-10","code":"E11"}},
+			{"name":"source","valueUri":"http://example.org/ConceptMap/snomed-icd"}]}]}`
+)
+
+// fakeTerminologyServer answers terminology operations and records the requests
+type fakeTerminologyServer struct {
+	*httptest.Server
+	requests map[string][]*models.Parameters
+}
+
+func newFakeTerminologyServer(t *testing.T) *fakeTerminologyServer {
+	f := &fakeTerminologyServer{requests: make(map[string][]*models.Parameters)}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params models.Parameters
+		json.NewDecoder(r.Body).Decode(&params)
+		f.requests[r.URL.Path] = append(f.requests[r.URL.Path], &params)
+
+		w.Header().Set("Content-Type", "application/fhir+json")
+		switch r.URL.Path {
+		case "/CodeSystem/$lookup":
+			io.WriteString(w, lookupResponse)
+		case "/ValueSet/$validate-code":
+			io.WriteString(w, `{"resourceType":"Parameters","parameter":[{"name":"result","valueBoolean":false},{"name":"message","valueString":"Unknown code"}]}`)
+		case "/ValueSet/$expand":
+			offset, _ := params.GetInteger("offset")
+			codes := []string{"a", "b", "c"}
+			count, ok := params.GetInteger("count")
+			if !ok || offset+count > len(codes) {
+				count = len(codes) - offset
+			}
+			vs := models.NewValueSet()
+			total := len(codes)
+			vs.Expansion = &models.ValueSetExpansion{Total: &total, Offset: &offset}
+			for _, code := range codes[offset : offset+count] {
+				vs.Expansion.Contains = append(vs.Expansion.Contains, models.ValueSetContains{System: "http://example.org/cs", Code: code})
+			}
+			json.NewEncoder(w).Encode(vs)
+		case "/ConceptMap/$translate":
+			io.WriteString(w, translateResponse)
+		case "/CodeSystem/$subsumes":
+			io.WriteString(w, `{"resourceType":"Parameters","parameter":[{"name":"outcome","valueCode":"subsumes"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestClient(t *testing.T) {
+	server := newFakeTerminologyServer(t)
+	c := NewClient(operations.NewHTTPOperation(server.Client(), server.URL))
+	ctx := context.Background()
+
+	lookup, err := c.Lookup(ctx, &LookupRequest{System: "http://snomed.info/sct", Code: "73211009", Properties: []string{"parent"}})
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if lookup.Display != "Diabetes mellitus" || lookup.Name != "SNOMED CT" || len(lookup.Designations) != 1 ||
+		lookup.Designations[0].Value != "Diabetes" {
+		t.Errorf("Unexpected lookup result %+v", lookup)
+	}
+	if p := lookup.Property("parent"); p == nil || p.Value != "362969004" {
+		t.Errorf("Unexpected parent property %+v", p)
+	}
+	if p := lookup.Property("inactive"); p == nil || p.Value != "false" {
+		t.Errorf("Unexpected inactive property %+v", p)
+	}
+	if code, _ := server.requests["/CodeSystem/$lookup"][0].GetString("code"); code != "73211009" {
+		t.Errorf("Expected the code to be sent, got %q", code)
+	}
+
+	validation, err := c.ValidateCode(ctx, &ValidateCodeRequest{
+		ValueSet: "http://example.org/vs",
+		Coding:   &models.Coding{System: "http://example.org/cs", Code: "x"},
+	})
+	if err != nil {
+		t.Fatalf("ValidateCode failed: %v", err)
+	}
+	if validation.Result || validation.Message != "Unknown code" {
+		t.Errorf("Unexpected validation result %+v", validation)
+	}
+
+	translation, err := c.Translate(ctx, &TranslateRequest{System: "http://snomed.info/sct", Code: "44054006", TargetSystem: "http://hl7.org/fhir/sid/icd-10"})
+	if err != nil {
+		t.Fatalf("Translate failed: %v", err)
+	}
+	if !translation.Result || len(translation.Matches) != 1 || translation.Matches[0].Equivalence != "equivalent" ||
+		translation.Matches[0].Concept.Code != "E11" {
+		t.Errorf("Unexpected translation %+v", translation)
+	}
+
+	outcome, err := c.Subsumes(ctx, &SubsumesRequest{System: "http://snomed.info/sct", CodeA: "73211009", CodeB: "44054006"})
+	if err != nil || outcome != Subsumes {
+		t.Errorf("Expected subsumes, got %q: %v", outcome, err)
+	}
+}
+
+func TestExpandCachesAndPages(t *testing.T) {
+	server := newFakeTerminologyServer(t)
+	c := NewClient(operations.NewHTTPOperation(server.Client(), server.URL))
+	ctx := context.Background()
+
+	req := &ExpandRequest{URL: "http://example.org/vs", Filter: "diab"}
+	for i := 0; i < 2; i++ {
+		vs, err := c.Expand(ctx, req)
+		if err != nil {
+			t.Fatalf("Expand failed: %v", err)
+		}
+		if len(vs.Expansion.Contains) != 3 {
+			t.Errorf("Expected 3 codes, got %d", len(vs.Expansion.Contains))
+		}
+	}
+	if n := len(server.requests["/ValueSet/$expand"]); n != 1 {
+		t.Errorf("Expected the second expansion to be cached, got %d requests", n)
+	}
+	if filter, _ := server.requests["/ValueSet/$expand"][0].GetString("filter"); filter != "diab" {
+		t.Errorf("Expected the filter to be sent, got %q", filter)
+	}
+
+	c.ClearCache()
+	codes, err := ExpandAll(ctx, c, &ExpandRequest{URL: "http://example.org/vs"}, 2)
+	if err != nil {
+		t.Fatalf("ExpandAll failed: %v", err)
+	}
+	if len(codes) != 3 || codes[2].Code != "c" {
+		t.Errorf("Unexpected codes %+v", codes)
+	}
+	if n := len(server.requests["/ValueSet/$expand"]); n != 3 {
+		t.Errorf("Expected two pages to be requested, got %d requests", n-1)
+	}
+}
@@ -0,0 +1,787 @@
+package terminology
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+var (
+	// ErrNotFound is returned when a code system, value set or code is not known
+	ErrNotFound = errors.New("not found")
+
+	// ErrNotSupported is returned for operations or filters the local service does not implement
+	ErrNotSupported = errors.New("not supported by the local terminology service")
+)
+
+// Local is an in-memory terminology service over CodeSystem and ValueSet
+// resources loaded from files, for tests and deployments that cannot reach
+// a terminology server. It expands compose rules that include or exclude
+// whole code systems, listed concepts, other value sets and property
+// filters such as is-a. It does not support concept maps, so Translate
+// always fails.
+type Local struct {
+	mu          sync.RWMutex
+	codeSystems map[string]*localCodeSystem
+	valueSets   map[string]*models.ValueSet
+	expansions  map[string][]models.ValueSetContains
+}
+
+// localCodeSystem is a code system indexed by code
+type localCodeSystem struct {
+	*models.CodeSystem
+	concepts map[string]*localConcept
+
+	// order holds the concepts in the order they are defined
+	order []*localConcept
+}
+
+// localConcept is a concept with its place in the hierarchy
+type localConcept struct {
+	*models.CodeSystemConcept
+	parents  []string
+	children []string
+}
+
+// NewLocal creates an empty local terminology service
+func NewLocal() *Local {
+	return &Local{
+		codeSystems: make(map[string]*localCodeSystem),
+		valueSets:   make(map[string]*models.ValueSet),
+		expansions:  make(map[string][]models.ValueSetContains),
+	}
+}
+
+// AddCodeSystem adds a code system, replacing any earlier one with the same URL and version
+func (l *Local) AddCodeSystem(cs *models.CodeSystem) {
+	indexed := &localCodeSystem{CodeSystem: cs, concepts: make(map[string]*localConcept)}
+	indexed.index(cs.Concept, "")
+	indexed.linkProperties()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.codeSystems[cs.URL] = indexed
+	if cs.Version != "" {
+		l.codeSystems[cs.URL+"|"+cs.Version] = indexed
+	}
+	l.expansions = make(map[string][]models.ValueSetContains)
+}
+
+// AddValueSet adds a value set, replacing any earlier one with the same URL and version
+func (l *Local) AddValueSet(vs *models.ValueSet) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.valueSets[vs.URL] = vs
+	if vs.Version != "" {
+		l.valueSets[vs.URL+"|"+vs.Version] = vs
+	}
+	l.expansions = make(map[string][]models.ValueSetContains)
+}
+
+// Load adds the CodeSystem and ValueSet resources in a JSON document, which
+// may be a single resource or a Bundle of them. Other resources are ignored.
+func (l *Local) Load(data []byte) error {
+	var typeHolder struct {
+		ResourceType models.ResourceType `json:"resourceType"`
+	}
+	if err := json.Unmarshal(data, &typeHolder); err != nil {
+		return fmt.Errorf("failed to determine resource type: %w", err)
+	}
+
+	switch typeHolder.ResourceType {
+	case models.ResourceTypeCodeSystem:
+		cs := models.NewCodeSystem()
+		if err := json.Unmarshal(data, cs); err != nil {
+			return fmt.Errorf("failed to unmarshal CodeSystem: %w", err)
+		}
+		l.AddCodeSystem(cs)
+	case models.ResourceTypeValueSet:
+		vs := models.NewValueSet()
+		if err := json.Unmarshal(data, vs); err != nil {
+			return fmt.Errorf("failed to unmarshal ValueSet: %w", err)
+		}
+		l.AddValueSet(vs)
+	case models.ResourceTypeBundle:
+		var bundle models.Bundle
+		if err := json.Unmarshal(data, &bundle); err != nil {
+			return fmt.Errorf("failed to unmarshal Bundle: %w", err)
+		}
+		for i, entry := range bundle.Entry {
+			if entry.Resource == nil {
+				continue
+			}
+			if err := l.Load(entry.Resource); err != nil {
+				return fmt.Errorf("bundle entry %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadFile adds the terminology resources in a JSON file
+func (l *Local) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := l.Load(data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadDir adds the terminology resources in every .json file in dir, such
+// as the package directory of an unpacked FHIR package
+func (l *Local) LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	for _, path := range paths {
+		if err := l.LoadFile(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// index adds concepts and their nested concepts below parent
+func (cs *localCodeSystem) index(concepts []models.CodeSystemConcept, parent string) {
+	for i := range concepts {
+		concept := cs.concept(concepts[i].Code)
+		concept.CodeSystemConcept = &concepts[i]
+		cs.order = append(cs.order, concept)
+		if parent != "" {
+			cs.link(parent, concept.Code)
+		}
+		cs.index(concepts[i].Concept, concept.Code)
+	}
+}
+
+// linkProperties adds the hierarchy given by parent and child properties
+// that refer to concepts in the code system
+func (cs *localCodeSystem) linkProperties() {
+	for _, concept := range cs.order {
+		for _, p := range concept.Property {
+			if p.ValueCode == nil || cs.concepts[*p.ValueCode] == nil {
+				continue
+			}
+			switch p.Code {
+			case "parent":
+				cs.link(*p.ValueCode, concept.Code)
+			case "child":
+				cs.link(concept.Code, *p.ValueCode)
+			}
+		}
+	}
+}
+
+// concept returns the indexed concept for code, creating it if needed
+func (cs *localCodeSystem) concept(code string) *localConcept {
+	concept, ok := cs.concepts[code]
+	if !ok {
+		concept = &localConcept{CodeSystemConcept: &models.CodeSystemConcept{Code: code}}
+		cs.concepts[code] = concept
+	}
+	return concept
+}
+
+// link records that child is directly below parent
+func (cs *localCodeSystem) link(parent, child string) {
+	p, c := cs.concept(parent), cs.concept(child)
+	for _, existing := range c.parents {
+		if existing == parent {
+			return
+		}
+	}
+	c.parents = append(c.parents, parent)
+	p.children = append(p.children, child)
+}
+
+// related returns the codes reachable from code by repeatedly following next,
+// not including code itself
+func (cs *localCodeSystem) related(code string, next func(*localConcept) []string) map[string]bool {
+	found := make(map[string]bool)
+	queue := []string{code}
+	for len(queue) > 0 {
+		concept, ok := cs.concepts[queue[0]]
+		queue = queue[1:]
+		if !ok {
+			continue
+		}
+		for _, c := range next(concept) {
+			if !found[c] && c != code {
+				found[c] = true
+				queue = append(queue, c)
+			}
+		}
+	}
+	return found
+}
+
+// descendants returns the codes below code in the hierarchy
+func (cs *localCodeSystem) descendants(code string) map[string]bool {
+	return cs.related(code, func(c *localConcept) []string { return c.children })
+}
+
+// ancestors returns the codes above code in the hierarchy
+func (cs *localCodeSystem) ancestors(code string) map[string]bool {
+	return cs.related(code, func(c *localConcept) []string { return c.parents })
+}
+
+// propertyValue returns a concept property's value in string form
+func propertyValue(p models.CodeSystemConceptProperty) string {
+	switch {
+	case p.ValueCode != nil:
+		return *p.ValueCode
+	case p.ValueString != nil:
+		return *p.ValueString
+	case p.ValueCoding != nil:
+		return p.ValueCoding.Code
+	case p.ValueBoolean != nil:
+		return fmt.Sprint(*p.ValueBoolean)
+	case p.ValueInteger != nil:
+		return fmt.Sprint(*p.ValueInteger)
+	case p.ValueDecimal != nil:
+		return p.ValueDecimal.String()
+	case p.ValueDateTime != nil:
+		return p.ValueDateTime.String()
+	}
+	return ""
+}
+
+// property returns the value of the concept's first property with the given code
+func (c *localConcept) property(code string) (string, bool) {
+	for _, p := range c.Property {
+		if p.Code == code {
+			return propertyValue(p), true
+		}
+	}
+	return "", false
+}
+
+// inactive reports whether the concept is marked inactive or retired
+func (c *localConcept) inactive() bool {
+	if v, _ := c.property("inactive"); v == "true" {
+		return true
+	}
+	status, _ := c.property("status")
+	return status == "retired" || status == "inactive"
+}
+
+// codeSystem returns the code system with the given URL, preferring the given version
+func (l *Local) codeSystem(url, version string) *localCodeSystem {
+	if version != "" {
+		if cs, ok := l.codeSystems[url+"|"+version]; ok {
+			return cs
+		}
+	}
+	return l.codeSystems[url]
+}
+
+// valueSet returns the value set with the given canonical, which may carry a |version
+func (l *Local) valueSet(canonical, version string) *models.ValueSet {
+	if version == "" {
+		if i := strings.LastIndex(canonical, "|"); i >= 0 {
+			canonical, version = canonical[:i], canonical[i+1:]
+		}
+	}
+	if version != "" {
+		if vs, ok := l.valueSets[canonical+"|"+version]; ok {
+			return vs
+		}
+	}
+	return l.valueSets[canonical]
+}
+
+// Lookup returns the details of a code in a loaded code system
+func (l *Local) Lookup(ctx context.Context, req *LookupRequest) (*LookupResult, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	cs := l.codeSystem(req.System, req.Version)
+	if cs == nil {
+		return nil, fmt.Errorf("code system %s: %w", req.System, ErrNotFound)
+	}
+	concept, ok := cs.concepts[req.Code]
+	if !ok {
+		return nil, fmt.Errorf("code %s in %s: %w", req.Code, req.System, ErrNotFound)
+	}
+
+	result := &LookupResult{
+		Name:         cs.Name,
+		Version:      cs.Version,
+		Display:      concept.Display,
+		Designations: concept.Designation,
+	}
+	if req.DisplayLanguage != "" {
+		for _, d := range concept.Designation {
+			if d.Language == req.DisplayLanguage {
+				result.Display = d.Value
+				break
+			}
+		}
+	}
+
+	wanted := func(code string) bool {
+		if len(req.Properties) == 0 {
+			return true
+		}
+		for _, p := range req.Properties {
+			if p == code {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range concept.Property {
+		if wanted(p.Code) && p.Code != "parent" && p.Code != "child" {
+			property := Property{Code: p.Code, Coding: p.ValueCoding}
+			if p.ValueCoding == nil {
+				property.Value = propertyValue(p)
+			}
+			result.Properties = append(result.Properties, property)
+		}
+	}
+	if wanted("parent") {
+		for _, parent := range concept.parents {
+			result.Properties = append(result.Properties, Property{Code: "parent", Value: parent})
+		}
+	}
+	if wanted("child") {
+		for _, child := range concept.children {
+			result.Properties = append(result.Properties, Property{Code: "child", Value: child})
+		}
+	}
+	return result, nil
+}
+
+// ValidateCode checks a code against a loaded value set, or against its
+// code system when the request names no value set. A code without a system
+// is in a value set if any of its code systems has it. A display that matches
+// neither the concept's display nor its designations makes the code invalid.
+func (l *Local) ValidateCode(ctx context.Context, req *ValidateCodeRequest) (*ValidateCodeResult, error) {
+	var codings []models.Coding
+	if req.Code != "" {
+		codings = append(codings, models.Coding{System: req.System, Code: req.Code, Display: req.Display})
+	}
+	if req.Coding != nil {
+		codings = append(codings, *req.Coding)
+	}
+	if req.CodeableConcept != nil {
+		codings = append(codings, req.CodeableConcept.Coding...)
+	}
+	if len(codings) == 0 {
+		return nil, fmt.Errorf("no code to validate")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var contains []models.ValueSetContains
+	if req.ValueSet != "" {
+		var err error
+		if contains, err = l.expandCached(req.ValueSet, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	var messages []string
+	for _, coding := range codings {
+		var display string
+		var found bool
+		if req.ValueSet != "" {
+			for _, c := range contains {
+				if (coding.System == "" || c.System == coding.System) && c.Code == coding.Code {
+					display, found = c.Display, true
+					break
+				}
+			}
+			if !found {
+				messages = append(messages, fmt.Sprintf("code %s|%s is not in value set %s", coding.System, coding.Code, req.ValueSet))
+				continue
+			}
+		} else {
+			cs := l.codeSystem(coding.System, req.Version)
+			if cs == nil {
+				return nil, fmt.Errorf("code system %s: %w", coding.System, ErrNotFound)
+			}
+			concept, ok := cs.concepts[coding.Code]
+			if !ok {
+				messages = append(messages, fmt.Sprintf("code %s is not in code system %s", coding.Code, coding.System))
+				continue
+			}
+			display = concept.Display
+		}
+
+		if cs := l.codeSystem(coding.System, req.Version); cs != nil {
+			if concept, ok := cs.concepts[coding.Code]; ok && display == "" {
+				display = concept.Display
+			}
+			if coding.Display != "" && !l.displayMatches(cs, coding.Code, coding.Display) {
+				messages = append(messages, fmt.Sprintf("display %q does not match %q for code %s|%s", coding.Display, display, coding.System, coding.Code))
+				continue
+			}
+		}
+		return &ValidateCodeResult{Result: true, Display: display}, nil
+	}
+	return &ValidateCodeResult{Message: strings.Join(messages, "; ")}, nil
+}
+
+// displayMatches reports whether display is the concept's display or one of its designations
+func (l *Local) displayMatches(cs *localCodeSystem, code, display string) bool {
+	concept, ok := cs.concepts[code]
+	if !ok || concept.Display == "" || strings.EqualFold(concept.Display, display) {
+		return true
+	}
+	for _, d := range concept.Designation {
+		if strings.EqualFold(d.Value, display) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand expands a loaded value set. Value sets without compose rules are
+// served from the expansion they were loaded with.
+func (l *Local) Expand(ctx context.Context, req *ExpandRequest) (*models.ValueSet, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	vs := l.valueSet(req.URL, req.Version)
+	if vs == nil {
+		return nil, fmt.Errorf("value set %s: %w", req.URL, ErrNotFound)
+	}
+	all, err := l.expandCached(req.URL, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := strings.ToLower(req.Filter)
+	var contains []models.ValueSetContains
+	for _, c := range all {
+		if req.ActiveOnly && c.Inactive != nil && *c.Inactive {
+			continue
+		}
+		if filter != "" && !strings.Contains(strings.ToLower(c.Display), filter) && !strings.Contains(strings.ToLower(c.Code), filter) {
+			continue
+		}
+		if !req.IncludeDesignations {
+			c.Designation = nil
+		}
+		contains = append(contains, c)
+	}
+
+	total := len(contains)
+	offset := req.Offset
+	if offset > total {
+		offset = total
+	}
+	contains = contains[offset:]
+	if req.Count > 0 && req.Count < len(contains) {
+		contains = contains[:req.Count]
+	}
+
+	timestamp := fhir.NewDateTime(time.Now())
+	result := models.NewValueSet()
+	result.URL, result.Version, result.Name, result.Title, result.Status = vs.URL, vs.Version, vs.Name, vs.Title, vs.Status
+	result.Expansion = &models.ValueSetExpansion{Timestamp: &timestamp, Total: &total, Contains: contains}
+	if offset > 0 {
+		result.Expansion.Offset = &offset
+	}
+	return result, nil
+}
+
+// expandCached returns the full expansion of a value set, computing it on
+// first use. The caller must hold the write lock.
+func (l *Local) expandCached(canonical, version string) ([]models.ValueSetContains, error) {
+	key := canonical + "|" + version
+	if contains, ok := l.expansions[key]; ok {
+		return contains, nil
+	}
+	vs := l.valueSet(canonical, version)
+	if vs == nil {
+		return nil, fmt.Errorf("value set %s: %w", canonical, ErrNotFound)
+	}
+	contains, err := l.expand(vs, make(map[*models.ValueSet]bool))
+	if err != nil {
+		return nil, err
+	}
+	l.expansions[key] = contains
+	return contains, nil
+}
+
+// codeKey identifies a code in an expansion
+type codeKey struct {
+	system, code string
+}
+
+// expand computes the codes in vs. seen guards against value sets that include themselves.
+func (l *Local) expand(vs *models.ValueSet, seen map[*models.ValueSet]bool) ([]models.ValueSetContains, error) {
+	if seen[vs] {
+		return nil, fmt.Errorf("value set %s includes itself", vs.URL)
+	}
+	seen[vs] = true
+	defer delete(seen, vs)
+
+	if vs.Compose == nil {
+		if vs.Expansion == nil {
+			return nil, fmt.Errorf("value set %s has neither compose rules nor an expansion", vs.URL)
+		}
+		var flat []models.ValueSetContains
+		flatten(vs.Expansion.Contains, &flat)
+		return flat, nil
+	}
+
+	var result []models.ValueSetContains
+	included := make(map[codeKey]bool)
+	for i, include := range vs.Compose.Include {
+		codes, err := l.include(include, seen)
+		if err != nil {
+			return nil, fmt.Errorf("value set %s include %d: %w", vs.URL, i, err)
+		}
+		for _, c := range codes {
+			key := codeKey{c.System, c.Code}
+			if !included[key] {
+				included[key] = true
+				result = append(result, c)
+			}
+		}
+	}
+
+	excluded := make(map[codeKey]bool)
+	for i, exclude := range vs.Compose.Exclude {
+		codes, err := l.include(exclude, seen)
+		if err != nil {
+			return nil, fmt.Errorf("value set %s exclude %d: %w", vs.URL, i, err)
+		}
+		for _, c := range codes {
+			excluded[codeKey{c.System, c.Code}] = true
+		}
+	}
+	if len(excluded) == 0 {
+		return result, nil
+	}
+	kept := result[:0]
+	for _, c := range result {
+		if !excluded[codeKey{c.System, c.Code}] {
+			kept = append(kept, c)
+		}
+	}
+	return kept, nil
+}
+
+// flatten appends the codes of a hierarchical expansion to flat
+func flatten(contains []models.ValueSetContains, flat *[]models.ValueSetContains) {
+	for _, c := range contains {
+		nested := c.Contains
+		c.Contains = nil
+		if c.Code != "" {
+			*flat = append(*flat, c)
+		}
+		flatten(nested, flat)
+	}
+}
+
+// include returns the codes selected by an include or exclude rule
+func (l *Local) include(rule models.ValueSetInclude, seen map[*models.ValueSet]bool) ([]models.ValueSetContains, error) {
+	var codes []models.ValueSetContains
+	if rule.System != "" {
+		var err error
+		if codes, err = l.systemCodes(rule); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, canonical := range rule.ValueSet {
+		vs := l.valueSet(canonical, "")
+		if vs == nil {
+			return nil, fmt.Errorf("value set %s: %w", canonical, ErrNotFound)
+		}
+		nested, err := l.expand(vs, seen)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 && rule.System == "" {
+			codes = nested
+			continue
+		}
+		in := make(map[codeKey]bool, len(nested))
+		for _, c := range nested {
+			in[codeKey{c.System, c.Code}] = true
+		}
+		kept := codes[:0]
+		for _, c := range codes {
+			if in[codeKey{c.System, c.Code}] {
+				kept = append(kept, c)
+			}
+		}
+		codes = kept
+	}
+	return codes, nil
+}
+
+// systemCodes returns the codes a rule selects from its code system
+func (l *Local) systemCodes(rule models.ValueSetInclude) ([]models.ValueSetContains, error) {
+	cs := l.codeSystem(rule.System, rule.Version)
+
+	if len(rule.Concept) > 0 {
+		codes := make([]models.ValueSetContains, 0, len(rule.Concept))
+		for _, listed := range rule.Concept {
+			c := models.ValueSetContains{System: rule.System, Version: rule.Version, Code: listed.Code, Display: listed.Display, Designation: listed.Designation}
+			if cs != nil {
+				if concept, ok := cs.concepts[listed.Code]; ok {
+					if c.Display == "" {
+						c.Display = concept.Display
+					}
+					if concept.inactive() {
+						inactive := true
+						c.Inactive = &inactive
+					}
+				}
+			}
+			codes = append(codes, c)
+		}
+		return codes, nil
+	}
+
+	if cs == nil {
+		return nil, fmt.Errorf("code system %s: %w", rule.System, ErrNotFound)
+	}
+	selected := make(map[string]bool, len(cs.order))
+	for _, concept := range cs.order {
+		selected[concept.Code] = true
+	}
+	for _, filter := range rule.Filter {
+		matched, err := cs.filter(filter)
+		if err != nil {
+			return nil, err
+		}
+		for code := range selected {
+			if !matched[code] {
+				delete(selected, code)
+			}
+		}
+	}
+
+	var codes []models.ValueSetContains
+	for _, concept := range cs.order {
+		if !selected[concept.Code] {
+			continue
+		}
+		c := models.ValueSetContains{System: cs.URL, Version: cs.Version, Code: concept.Code, Display: concept.Display, Designation: concept.Designation}
+		if concept.inactive() {
+			inactive := true
+			c.Inactive = &inactive
+		}
+		codes = append(codes, c)
+	}
+	return codes, nil
+}
+
+// filter returns the codes that satisfy a compose filter
+func (cs *localCodeSystem) filter(f models.ValueSetFilter) (map[string]bool, error) {
+	all := func(keep func(*localConcept) bool) map[string]bool {
+		matched := make(map[string]bool)
+		for _, concept := range cs.order {
+			if keep(concept) {
+				matched[concept.Code] = true
+			}
+		}
+		return matched
+	}
+	hierarchical := f.Property == "concept" || f.Property == "code"
+	value := func(c *localConcept) (string, bool) {
+		if hierarchical {
+			return c.Code, true
+		}
+		return c.property(f.Property)
+	}
+
+	switch f.Op {
+	case "is-a":
+		matched := cs.descendants(f.Value)
+		matched[f.Value] = true
+		return matched, nil
+	case "descendent-of":
+		return cs.descendants(f.Value), nil
+	case "is-not-a":
+		excluded := cs.descendants(f.Value)
+		return all(func(c *localConcept) bool { return c.Code != f.Value && !excluded[c.Code] }), nil
+	case "generalizes":
+		matched := cs.ancestors(f.Value)
+		matched[f.Value] = true
+		return matched, nil
+	case "=":
+		return all(func(c *localConcept) bool { v, ok := value(c); return ok && v == f.Value }), nil
+	case "in", "not-in":
+		values := make(map[string]bool)
+		for _, v := range strings.Split(f.Value, ",") {
+			values[strings.TrimSpace(v)] = true
+		}
+		return all(func(c *localConcept) bool { v, ok := value(c); return (ok && values[v]) == (f.Op == "in") }), nil
+	case "regex":
+		re, err := regexp.Compile("^(?:" + f.Value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex filter %q: %w", f.Value, err)
+		}
+		return all(func(c *localConcept) bool { v, ok := value(c); return ok && re.MatchString(v) }), nil
+	case "exists":
+		return all(func(c *localConcept) bool { _, ok := value(c); return ok == (f.Value == "true") }), nil
+	default:
+		return nil, fmt.Errorf("filter op %q: %w", f.Op, ErrNotSupported)
+	}
+}
+
+// Translate is not supported by the local service
+func (l *Local) Translate(ctx context.Context, req *TranslateRequest) (*TranslateResult, error) {
+	return nil, fmt.Errorf("$translate: %w", ErrNotSupported)
+}
+
+// Subsumes tests the relationship between two codes in a loaded code system's hierarchy
+func (l *Local) Subsumes(ctx context.Context, req *SubsumesRequest) (SubsumesOutcome, error) {
+	system, codeA, codeB := req.System, req.CodeA, req.CodeB
+	if req.CodingA != nil {
+		system, codeA = req.CodingA.System, req.CodingA.Code
+	}
+	if req.CodingB != nil {
+		if system != "" && req.CodingB.System != system {
+			return NotSubsumed, nil
+		}
+		system, codeB = req.CodingB.System, req.CodingB.Code
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	cs := l.codeSystem(system, req.Version)
+	if cs == nil {
+		return "", fmt.Errorf("code system %s: %w", system, ErrNotFound)
+	}
+	for _, code := range []string{codeA, codeB} {
+		if _, ok := cs.concepts[code]; !ok {
+			return "", fmt.Errorf("code %s in %s: %w", code, system, ErrNotFound)
+		}
+	}
+
+	switch {
+	case codeA == codeB:
+		return Equivalent, nil
+	case cs.descendants(codeA)[codeB]:
+		return Subsumes, nil
+	case cs.descendants(codeB)[codeA]:
+		return SubsumedBy, nil
+	default:
+		return NotSubsumed, nil
+	}
+}
@@ -0,0 +1,148 @@
+package terminology
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+var _ Service = (*Local)(nil)
+
+const (
+	conditionsSystem = "http://example.org/fhir/CodeSystem/conditions"
+	diabetesValueSet = "http://example.org/fhir/ValueSet/diabetes"
+)
+
+func newTestLocal(t *testing.T) *Local {
+	l := NewLocal()
+	if err := l.LoadDir("testdata"); err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+	return l
+}
+
+func codes(contains []models.ValueSetContains) []string {
+	var result []string
+	for _, c := range contains {
+		result = append(result, c.Code)
+	}
+	return result
+}
+
+func TestLocalExpand(t *testing.T) {
+	l := newTestLocal(t)
+	ctx := context.Background()
+
+	vs, err := l.Expand(ctx, &ExpandRequest{URL: diabetesValueSet})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if got := codes(vs.Expansion.Contains); len(got) != 4 || got[0] != "diabetes" || got[3] != "old-diabetes" {
+		t.Errorf("Expected diabetes and its descendants without gestational, got %v", got)
+	}
+	if *vs.Expansion.Total != 4 {
+		t.Errorf("Expected total 4, got %d", *vs.Expansion.Total)
+	}
+
+	vs, err = l.Expand(ctx, &ExpandRequest{URL: diabetesValueSet, ActiveOnly: true, Filter: "type", Offset: 1, Count: 5})
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if got := codes(vs.Expansion.Contains); len(got) != 1 || got[0] != "type2" || *vs.Expansion.Total != 2 {
+		t.Errorf("Expected the second of two filtered codes, got %v of %d", got, *vs.Expansion.Total)
+	}
+
+	all, err := ExpandAll(ctx, l, &ExpandRequest{URL: "http://example.org/fhir/ValueSet/chronic"}, 2)
+	if err != nil {
+		t.Fatalf("ExpandAll failed: %v", err)
+	}
+	if got := codes(all); len(got) != 5 || got[4] != "asthma" || all[4].Display != "Asthma" {
+		t.Errorf("Expected the nested value set plus asthma, got %v", got)
+	}
+
+	if _, err := l.Expand(ctx, &ExpandRequest{URL: "http://example.org/missing"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestLocalValidateCode(t *testing.T) {
+	l := newTestLocal(t)
+	ctx := context.Background()
+
+	result, err := l.ValidateCode(ctx, &ValidateCodeRequest{ValueSet: diabetesValueSet, System: conditionsSystem, Code: "type1"})
+	if err != nil || !result.Result || result.Display != "Type 1 diabetes mellitus" {
+		t.Errorf("Expected type1 to be valid, got %+v: %v", result, err)
+	}
+
+	result, err = l.ValidateCode(ctx, &ValidateCodeRequest{ValueSet: diabetesValueSet, Coding: &models.Coding{System: conditionsSystem, Code: "asthma"}})
+	if err != nil || result.Result || result.Message == "" {
+		t.Errorf("Expected asthma not to be in the value set, got %+v: %v", result, err)
+	}
+
+	result, err = l.ValidateCode(ctx, &ValidateCodeRequest{ValueSet: diabetesValueSet, Code: "type1"})
+	if err != nil || !result.Result {
+		t.Errorf("Expected a code without a system to match any system of the value set, got %+v: %v", result, err)
+	}
+
+	result, err = l.ValidateCode(ctx, &ValidateCodeRequest{ValueSet: diabetesValueSet, System: "http://example.org/other", Code: "type1"})
+	if err != nil || result.Result {
+		t.Errorf("Expected a code of another system not to be in the value set, got %+v: %v", result, err)
+	}
+
+	result, err = l.ValidateCode(ctx, &ValidateCodeRequest{System: conditionsSystem, Code: "diabetes", Display: "Zuckerkrankheit"})
+	if err != nil || !result.Result {
+		t.Errorf("Expected a designation to be accepted as the display, got %+v: %v", result, err)
+	}
+
+	result, err = l.ValidateCode(ctx, &ValidateCodeRequest{System: conditionsSystem, Code: "diabetes", Display: "Asthma"})
+	if err != nil || result.Result {
+		t.Errorf("Expected a wrong display to be refused, got %+v: %v", result, err)
+	}
+}
+
+func TestLocalLookupAndSubsumes(t *testing.T) {
+	l := newTestLocal(t)
+	ctx := context.Background()
+
+	lookup, err := l.Lookup(ctx, &LookupRequest{System: conditionsSystem, Code: "diabetes", DisplayLanguage: "de"})
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if lookup.Display != "Zuckerkrankheit" || lookup.Version != "1.0.0" {
+		t.Errorf("Unexpected lookup result %+v", lookup)
+	}
+	if p := lookup.Property("parent"); p == nil || p.Value != "disorder" {
+		t.Errorf("Expected parent disorder, got %+v", p)
+	}
+	var children int
+	for _, p := range lookup.Properties {
+		if p.Code == "child" {
+			children++
+		}
+	}
+	if children != 4 {
+		t.Errorf("Expected 4 children, got %d", children)
+	}
+
+	tests := []struct {
+		a, b string
+		want SubsumesOutcome
+	}{
+		{"disorder", "type2", Subsumes},
+		{"gestational", "diabetes", SubsumedBy},
+		{"asthma", "asthma", Equivalent},
+		{"asthma", "type1", NotSubsumed},
+	}
+	for _, tt := range tests {
+		got, err := l.Subsumes(ctx, &SubsumesRequest{System: conditionsSystem, CodeA: tt.a, CodeB: tt.b})
+		if err != nil || got != tt.want {
+			t.Errorf("Subsumes(%s, %s) = %q, %v; want %q", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+
+	if _, err := l.Translate(ctx, &TranslateRequest{}); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,365 @@
+package terminology
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+// Service performs terminology operations. Client implements it against a
+// terminology server and Local in memory, so either can be plugged in where
+// a Service is expected.
+type Service interface {
+	// Lookup returns the details of a code in a code system
+	Lookup(ctx context.Context, req *LookupRequest) (*LookupResult, error)
+
+	// ValidateCode checks a code against a value set or code system
+	ValidateCode(ctx context.Context, req *ValidateCodeRequest) (*ValidateCodeResult, error)
+
+	// Expand returns a value set with its expansion
+	Expand(ctx context.Context, req *ExpandRequest) (*models.ValueSet, error)
+
+	// Translate maps a code to codes in another system using concept maps
+	Translate(ctx context.Context, req *TranslateRequest) (*TranslateResult, error)
+
+	// Subsumes tests the subsumption relationship between two codes
+	Subsumes(ctx context.Context, req *SubsumesRequest) (SubsumesOutcome, error)
+}
+
+// LookupRequest holds the parameters of a CodeSystem $lookup request
+type LookupRequest struct {
+	System  string
+	Code    string
+	Version string
+
+	// DisplayLanguage asks for the display in a language, such as "de"
+	DisplayLanguage string
+
+	// Properties are the properties to return. Servers choose when empty.
+	Properties []string
+}
+
+// parameters builds the operation's input parameters
+func (r *LookupRequest) parameters() *models.Parameters {
+	params := models.NewParameters().AddURI("system", r.System).AddCode("code", r.Code)
+	if r.Version != "" {
+		params.AddString("version", r.Version)
+	}
+	if r.DisplayLanguage != "" {
+		params.AddCode("displayLanguage", r.DisplayLanguage)
+	}
+	for _, property := range r.Properties {
+		params.AddCode("property", property)
+	}
+	return params
+}
+
+// LookupResult holds the details of a code returned by $lookup
+type LookupResult struct {
+	// Name and Version identify the code system
+	Name    string
+	Version string
+
+	Display      string
+	Designations []models.ValueSetDesignation
+	Properties   []Property
+}
+
+// Property returns the first property with the given code, or nil
+func (r *LookupResult) Property(code string) *Property {
+	for i := range r.Properties {
+		if r.Properties[i].Code == code {
+			return &r.Properties[i]
+		}
+	}
+	return nil
+}
+
+// Property is a property of a code, such as its parent or its status
+type Property struct {
+	Code        string
+	Description string
+
+	// Value is the property's value in string form when it is primitive;
+	// Coding is set instead when its value is a Coding
+	Value  string
+	Coding *models.Coding
+
+	// Subproperties holds the parts of a property with no value of its own
+	Subproperties []Property
+}
+
+// ValidateCodeRequest holds the parameters of a $validate-code request.
+// The code is checked against ValueSet if it is set, otherwise against the
+// code system. It may be given as System and Code, as a Coding or as a
+// CodeableConcept.
+type ValidateCodeRequest struct {
+	// ValueSet is the canonical URL of the value set to check against
+	ValueSet string
+
+	System  string
+	Code    string
+	Version string
+
+	// Display is checked against the code's display if it is set
+	Display string
+
+	Coding          *models.Coding
+	CodeableConcept *models.CodeableConcept
+}
+
+// system returns the code system of the code being checked
+func (r *ValidateCodeRequest) system() string {
+	if r.System == "" && r.Coding != nil {
+		return r.Coding.System
+	}
+	return r.System
+}
+
+// parameters builds the operation's input parameters
+func (r *ValidateCodeRequest) parameters() *models.Parameters {
+	params := models.NewParameters()
+	if r.ValueSet != "" {
+		params.AddURI("url", r.ValueSet)
+	} else if system := r.system(); system != "" {
+		params.AddURI("url", system)
+	}
+	if r.Code != "" {
+		params.AddCode("code", r.Code)
+	}
+	if r.System != "" && r.ValueSet != "" {
+		params.AddURI("system", r.System)
+	}
+	if r.Version != "" {
+		params.AddString("version", r.Version)
+	}
+	if r.Display != "" {
+		params.AddString("display", r.Display)
+	}
+	if r.Coding != nil {
+		params.AddCoding("coding", *r.Coding)
+	}
+	if r.CodeableConcept != nil {
+		params.AddCodeableConcept("codeableConcept", *r.CodeableConcept)
+	}
+	return params
+}
+
+// ValidateCodeResult is the outcome of $validate-code
+type ValidateCodeResult struct {
+	// Result is true if the code is valid
+	Result bool
+
+	// Message describes why the code is not valid, or warnings about it
+	Message string
+
+	// Display is the code's display according to the server
+	Display string
+}
+
+// ExpandRequest holds the parameters of a ValueSet $expand request
+type ExpandRequest struct {
+	// URL is the canonical URL of the value set
+	URL     string
+	Version string
+
+	// Filter limits the expansion to codes whose display matches the text
+	Filter string
+
+	// Offset and Count page through the expansion. A Count of zero leaves
+	// the page size to the server.
+	Offset int
+	Count  int
+
+	// ActiveOnly excludes inactive codes
+	ActiveOnly bool
+
+	// IncludeDesignations asks for the designations of each code
+	IncludeDesignations bool
+
+	DisplayLanguage string
+}
+
+// parameters builds the operation's input parameters
+func (r *ExpandRequest) parameters() *models.Parameters {
+	params := models.NewParameters().AddURI("url", r.URL)
+	if r.Version != "" {
+		params.AddString("valueSetVersion", r.Version)
+	}
+	if r.Filter != "" {
+		params.AddString("filter", r.Filter)
+	}
+	if r.Offset > 0 {
+		params.AddInteger("offset", r.Offset)
+	}
+	if r.Count > 0 {
+		params.AddInteger("count", r.Count)
+	}
+	if r.ActiveOnly {
+		params.AddBoolean("activeOnly", true)
+	}
+	if r.IncludeDesignations {
+		params.AddBoolean("includeDesignations", true)
+	}
+	if r.DisplayLanguage != "" {
+		params.AddCode("displayLanguage", r.DisplayLanguage)
+	}
+	return params
+}
+
+// cacheKey identifies the request's expansion in the cache
+func (r *ExpandRequest) cacheKey() string {
+	return fmt.Sprintf("%s|%s|%s|%d|%d|%t|%t|%s", r.URL, r.Version, r.Filter, r.Offset, r.Count,
+		r.ActiveOnly, r.IncludeDesignations, r.DisplayLanguage)
+}
+
+// ExpandAll expands a value set through svc a page of pageSize codes at a
+// time, until the expansion's total is reached or a page comes back empty,
+// and returns all the codes. req's Offset and Count are ignored.
+func ExpandAll(ctx context.Context, svc Service, req *ExpandRequest, pageSize int) ([]models.ValueSetContains, error) {
+	page := *req
+	page.Count = pageSize
+
+	var contains []models.ValueSetContains
+	for {
+		page.Offset = len(contains)
+		vs, err := svc.Expand(ctx, &page)
+		if err != nil {
+			return nil, err
+		}
+		if vs.Expansion == nil || len(vs.Expansion.Contains) == 0 {
+			return contains, nil
+		}
+		contains = append(contains, vs.Expansion.Contains...)
+
+		total := vs.Expansion.Total
+		if pageSize <= 0 || (total != nil && len(contains) >= *total) || len(vs.Expansion.Contains) < pageSize {
+			return contains, nil
+		}
+	}
+}
+
+// TranslateRequest holds the parameters of a ConceptMap $translate request.
+// The code may be given as System and Code or as a Coding.
+type TranslateRequest struct {
+	// ConceptMap is the canonical URL of the concept map to use. Servers
+	// choose suitable maps when it is empty.
+	ConceptMap string
+
+	System  string
+	Code    string
+	Version string
+	Coding  *models.Coding
+
+	// Source and Target are the canonical URLs of the source and target value sets
+	Source string
+	Target string
+
+	// TargetSystem is the code system to translate into
+	TargetSystem string
+
+	// Reverse translates from the target of the map to its source
+	Reverse bool
+}
+
+// parameters builds the operation's input parameters
+func (r *TranslateRequest) parameters() *models.Parameters {
+	params := models.NewParameters()
+	if r.ConceptMap != "" {
+		params.AddURI("url", r.ConceptMap)
+	}
+	if r.Code != "" {
+		params.AddCode("code", r.Code)
+	}
+	if r.System != "" {
+		params.AddURI("system", r.System)
+	}
+	if r.Version != "" {
+		params.AddString("version", r.Version)
+	}
+	if r.Coding != nil {
+		params.AddCoding("coding", *r.Coding)
+	}
+	if r.Source != "" {
+		params.AddURI("source", r.Source)
+	}
+	if r.Target != "" {
+		params.AddURI("target", r.Target)
+	}
+	if r.TargetSystem != "" {
+		params.AddURI("targetsystem", r.TargetSystem)
+	}
+	if r.Reverse {
+		params.AddBoolean("reverse", true)
+	}
+	return params
+}
+
+// TranslateResult is the outcome of $translate
+type TranslateResult struct {
+	// Result is true if at least one match is an equivalence other than
+	// unmatched or disjoint
+	Result  bool
+	Message string
+	Matches []TranslateMatch
+}
+
+// TranslateMatch is a code the source code maps to
+type TranslateMatch struct {
+	// Equivalence is how the match relates to the source code, such as
+	// "equivalent", "wider" or "narrower". R5 servers call it the relationship.
+	Equivalence string
+
+	Concept *models.Coding
+
+	// Source is the canonical URL of the concept map that produced the match
+	Source string
+}
+
+// SubsumesRequest holds the parameters of a CodeSystem $subsumes request.
+// The codes may be given as codes in System or as Codings.
+type SubsumesRequest struct {
+	System  string
+	Version string
+	CodeA   string
+	CodeB   string
+
+	CodingA *models.Coding
+	CodingB *models.Coding
+}
+
+// parameters builds the operation's input parameters
+func (r *SubsumesRequest) parameters() *models.Parameters {
+	params := models.NewParameters()
+	if r.CodeA != "" {
+		params.AddCode("codeA", r.CodeA)
+	}
+	if r.CodeB != "" {
+		params.AddCode("codeB", r.CodeB)
+	}
+	if r.System != "" {
+		params.AddURI("system", r.System)
+	}
+	if r.Version != "" {
+		params.AddString("version", r.Version)
+	}
+	if r.CodingA != nil {
+		params.AddCoding("codingA", *r.CodingA)
+	}
+	if r.CodingB != nil {
+		params.AddCoding("codingB", *r.CodingB)
+	}
+	return params
+}
+
+// SubsumesOutcome is the relationship between code A and code B
+type SubsumesOutcome string
+
+// Subsumption outcomes
+const (
+	Equivalent  SubsumesOutcome = "equivalent"
+	Subsumes    SubsumesOutcome = "subsumes"
+	SubsumedBy  SubsumesOutcome = "subsumed-by"
+	NotSubsumed SubsumesOutcome = "not-subsumed"
+)
@@ -0,0 +1,483 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/terminology"
+)
+
+// member is the value or values of one JSON member that an element matches
+type member struct {
+	// name is the element's name in FHIRPath, e.g. "value.ofType(Quantity)"
+	name string
+
+	// key is the JSON member name, e.g. "valueQuantity"
+	key string
+
+	typeCode string
+	values   []interface{}
+	array    bool
+}
+
+// location returns the FHIRPath location of the member's i'th value below loc
+func (m member) location(loc string, i int) string {
+	if m.array {
+		return fmt.Sprintf("%s.%s[%d]", loc, m.name, i)
+	}
+	return loc + "." + m.name
+}
+
+// validateResource checks a resource against a profile
+func (r *run) validateResource(p *profile, resource map[string]interface{}, loc string) {
+	r.checkInvariants(p.root, resource, resource, loc)
+	r.validateObject(p, p.root, resource, resource, loc)
+}
+
+// validateObject checks the members of obj against the children of element e
+func (r *run) validateObject(p *profile, e *models.ElementDefinition, resource, obj map[string]interface{}, loc string) {
+	groups := p.childrenOf(e)
+	if len(groups) == 0 {
+		return
+	}
+
+	known := map[string]bool{"resourceType": true, "fhir_comments": true}
+	for _, g := range groups {
+		members := r.collect(g.base, obj, loc)
+		count := 0
+		for _, m := range members {
+			known[m.key] = true
+			known["_"+m.key] = true
+			count += len(m.values)
+		}
+
+		name := strings.TrimSuffix(g.base.Name(), "[x]")
+		if min := minOccurs(g.base); count < min {
+			r.issue("error", "required", loc+"."+name, "%s: minimum required = %d, but only found %d", g.base.Path, min, count)
+		}
+		if max := maxOccurs(g.base); max >= 0 && count > max {
+			r.issue("error", "structure", loc+"."+name, "%s: maximum allowed = %d, but found %d", g.base.Path, max, count)
+		}
+
+		for _, m := range members {
+			if m.array && maxOccurs(g.base) == 1 {
+				r.issue("error", "structure", loc+"."+m.name, "%s: must be a single value, not an array", g.base.Path)
+			}
+			for i, value := range m.values {
+				r.validateValue(p, g.base, m.typeCode, resource, value, m.location(loc, i))
+			}
+		}
+		if len(g.slices) > 0 {
+			r.checkSlices(p, g, resource, members, loc)
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !known[key] {
+			r.issue("error", "structure", loc, "Unrecognized element %q", key)
+		}
+	}
+}
+
+// collect returns the members of obj that element e matches. Choice
+// elements match a member per type present, such as valueQuantity.
+func (r *run) collect(e *models.ElementDefinition, obj map[string]interface{}, loc string) []member {
+	name := e.Name()
+	if !strings.HasSuffix(name, "[x]") {
+		m := member{name: name, key: name}
+		if len(e.Type) == 1 {
+			m.typeCode = e.Type[0].Code
+		}
+		m.values, m.array = values(obj, name)
+		if m.values == nil {
+			return nil
+		}
+		return []member{m}
+	}
+
+	prefix := strings.TrimSuffix(name, "[x]")
+	var members []member
+	for key := range obj {
+		suffix := strings.TrimPrefix(key, prefix)
+		if suffix == key || suffix == "" || suffix[0] < 'A' || suffix[0] > 'Z' {
+			continue
+		}
+		m := member{name: prefix + ".ofType(" + suffix + ")", key: key}
+		for _, t := range e.Type {
+			if strings.EqualFold(t.Code, suffix) {
+				m.typeCode = t.Code
+			}
+		}
+		if m.typeCode == "" {
+			r.issue("error", "structure", loc+"."+prefix, "Type %s is not allowed for %s", suffix, e.Path)
+			continue
+		}
+		m.values, m.array = values(obj, key)
+		members = append(members, m)
+	}
+	if len(members) > 1 {
+		r.issue("error", "structure", loc+"."+prefix, "%s: only one type may be given", e.Path)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].key < members[j].key })
+	return members
+}
+
+// values returns the values of member key, and whether it is an array. A
+// primitive with only an id or extensions has a nil value.
+func values(obj map[string]interface{}, key string) ([]interface{}, bool) {
+	value, ok := obj[key]
+	if !ok {
+		value, ok = obj["_"+key]
+		if !ok {
+			return nil, false
+		}
+		if list, isList := value.([]interface{}); isList {
+			return make([]interface{}, len(list)), true
+		}
+		return []interface{}{nil}, false
+	}
+	if list, isList := value.([]interface{}); isList {
+		return list, true
+	}
+	return []interface{}{value}, false
+}
+
+// validateValue checks a single value against element e
+func (r *run) validateValue(p *profile, e *models.ElementDefinition, typeCode string, resource map[string]interface{}, value interface{}, loc string) {
+	if value == nil || !r.checkType(typeCode, value, loc) {
+		return
+	}
+	r.checkFixed(e, value, loc)
+	r.checkReference(e, typeCode, value, loc)
+	r.checkBinding(e, typeCode, value, loc)
+	r.checkInvariants(e, resource, value, loc)
+
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if r.isResource(typeCode) {
+		resourceType, _ := obj["resourceType"].(string)
+		if nested := r.v.profile(resourceType); nested != nil {
+			r.validateResource(nested, obj, loc)
+		}
+		return
+	}
+	if len(p.childrenOf(e)) > 0 {
+		r.validateObject(p, e, resource, obj, loc)
+		return
+	}
+	if dt := r.typeProfile(e, typeCode); dt != nil {
+		r.checkInvariants(dt.root, resource, value, loc)
+		r.validateObject(dt, dt.root, resource, obj, loc)
+	}
+}
+
+// typeProfile returns the definition of the value's type, preferring a
+// profile the element requires, such as an extension's definition
+func (r *run) typeProfile(e *models.ElementDefinition, typeCode string) *profile {
+	for _, t := range e.Type {
+		if t.Code == typeCode && len(t.Profile) > 0 {
+			if p := r.v.profile(t.Profile[0]); p != nil {
+				return p
+			}
+		}
+	}
+	return r.v.profile(typeCode)
+}
+
+// isResource reports whether typeCode is a resource rather than a datatype
+func (r *run) isResource(typeCode string) bool {
+	if typeCode == "Resource" || typeCode == "DomainResource" {
+		return true
+	}
+	p := r.v.profile(typeCode)
+	return p != nil && p.Kind == "resource"
+}
+
+var (
+	idPattern   = regexp.MustCompile(`^[A-Za-z0-9\-.]{1,64}$`)
+	codePattern = regexp.MustCompile(`^[^\s]+( [^\s]+)*$`)
+)
+
+// primitiveChecks check the JSON representation of primitive types,
+// returning a description of the problem or an empty string
+var primitiveChecks = map[string]func(interface{}) string{
+	"boolean": func(v interface{}) string {
+		if _, ok := v.(bool); !ok {
+			return "must be true or false"
+		}
+		return ""
+	},
+	"integer":     checkInteger(-1 << 31),
+	"unsignedInt": checkInteger(0),
+	"positiveInt": checkInteger(1),
+	"decimal": func(v interface{}) string {
+		if _, ok := v.(json.Number); !ok {
+			return "must be a number"
+		}
+		return ""
+	},
+	"string": checkString(nil),
+	"code":   checkString(codePattern),
+	"id":     checkString(idPattern),
+	"date": checkString(nil, func(s string) error {
+		_, err := fhir.ParseDate(s)
+		return err
+	}),
+	"dateTime": checkString(nil, func(s string) error {
+		_, err := fhir.ParseDateTime(s)
+		return err
+	}),
+	"instant": checkString(nil, func(s string) error {
+		_, err := fhir.ParseInstant(s)
+		return err
+	}),
+	"time": checkString(nil, func(s string) error {
+		_, err := fhir.ParseTime(s)
+		return err
+	}),
+}
+
+func init() {
+	for _, t := range []string{"markdown", "uri", "url", "canonical", "oid", "uuid", "base64Binary", "xhtml", "integer64"} {
+		primitiveChecks[t] = checkString(nil)
+	}
+}
+
+// checkInteger checks for a whole number no smaller than min
+func checkInteger(min int64) func(interface{}) string {
+	return func(v interface{}) string {
+		n, ok := v.(json.Number)
+		if !ok {
+			return "must be a number"
+		}
+		i, err := n.Int64()
+		if err != nil {
+			return "must be a whole number"
+		}
+		if i < min {
+			return fmt.Sprintf("must be at least %d", min)
+		}
+		return ""
+	}
+}
+
+// checkString checks for a string matching pattern, if it is not nil, and accepted by parse
+func checkString(pattern *regexp.Regexp, parse ...func(string) error) func(interface{}) string {
+	return func(v interface{}) string {
+		s, ok := v.(string)
+		if !ok {
+			return "must be a string"
+		}
+		if s == "" {
+			return "must not be empty"
+		}
+		if pattern != nil && !pattern.MatchString(s) {
+			return fmt.Sprintf("%q is not valid", s)
+		}
+		for _, p := range parse {
+			if err := p(s); err != nil {
+				return err.Error()
+			}
+		}
+		return ""
+	}
+}
+
+// primitiveType returns the FHIR primitive type for a type code, mapping
+// FHIRPath system types such as http://hl7.org/fhirpath/System.String
+func primitiveType(code string) string {
+	if system := strings.TrimPrefix(code, "http://hl7.org/fhirpath/System."); system != code {
+		return strings.ToLower(system[:1]) + system[1:]
+	}
+	return code
+}
+
+// checkType checks that value has the JSON form of typeCode
+func (r *run) checkType(typeCode string, value interface{}, loc string) bool {
+	if typeCode == "" {
+		return true
+	}
+	if check, ok := primitiveChecks[primitiveType(typeCode)]; ok {
+		if problem := check(value); problem != "" {
+			r.issue("error", "value", loc, "Invalid %s: %s", primitiveType(typeCode), problem)
+			return false
+		}
+		return true
+	}
+	if _, ok := value.(map[string]interface{}); !ok {
+		r.issue("error", "structure", loc, "Expected an object of type %s", typeCode)
+		return false
+	}
+	return true
+}
+
+// decodeValue decodes a fixed or pattern value like the resource being validated
+func decodeValue(raw json.RawMessage) interface{} {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var v interface{}
+	decoder.Decode(&v)
+	return v
+}
+
+// checkFixed checks a value against the element's fixed and pattern values
+func (r *run) checkFixed(e *models.ElementDefinition, value interface{}, loc string) {
+	if raw, _ := e.Fixed(); raw != nil && !reflect.DeepEqual(value, decodeValue(raw)) {
+		r.issue("error", "value", loc, "Value does not match the fixed value %s", raw)
+	}
+	if raw, _ := e.Pattern(); raw != nil && !matchesPattern(value, decodeValue(raw)) {
+		r.issue("error", "value", loc, "Value does not match the pattern %s", raw)
+	}
+}
+
+// matchesPattern reports whether value has at least the content of pattern:
+// every member of a pattern object, and a match for every item of a pattern array
+func matchesPattern(value, pattern interface{}) bool {
+	switch p := pattern.(type) {
+	case map[string]interface{}:
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, item := range p {
+			if !matchesPattern(v[key], item) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		v, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range p {
+			found := false
+			for _, candidate := range v {
+				if matchesPattern(candidate, item) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(value, pattern)
+	}
+}
+
+// checkReference checks that a literal reference points at a resource type
+// the element's target profiles allow
+func (r *run) checkReference(e *models.ElementDefinition, typeCode string, value interface{}, loc string) {
+	if typeCode != "Reference" {
+		return
+	}
+	obj, _ := value.(map[string]interface{})
+	reference, _ := obj["reference"].(string)
+	parsed, err := models.ParseReference(reference)
+	if err != nil || parsed.ResourceType == "" {
+		return
+	}
+
+	var allowed []string
+	for _, t := range e.Type {
+		for _, target := range t.TargetProfile {
+			targetType := target[strings.LastIndex(target, "/")+1:]
+			if p := r.v.profile(target); p != nil {
+				targetType = p.Type
+			}
+			if targetType == "Resource" || targetType == parsed.ResourceType {
+				return
+			}
+			allowed = append(allowed, targetType)
+		}
+	}
+	if len(allowed) > 0 {
+		r.issue("error", "structure", loc, "Reference to %s is not allowed; expected %s", parsed.ResourceType, strings.Join(allowed, " or "))
+	}
+}
+
+// checkBinding checks the code in a value against a required binding
+func (r *run) checkBinding(e *models.ElementDefinition, typeCode string, value interface{}, loc string) {
+	b := e.Binding
+	if b == nil || b.Strength != "required" || b.ValueSet == "" || r.v.Terminology == nil {
+		return
+	}
+
+	req := &terminology.ValidateCodeRequest{ValueSet: b.ValueSet}
+	switch primitiveType(typeCode) {
+	case "code", "string", "uri":
+		req.Code, _ = value.(string)
+	case "Coding":
+		req.Coding = &models.Coding{}
+		if !convert(value, req.Coding) || req.Coding.Code == "" {
+			return
+		}
+	case "CodeableConcept":
+		req.CodeableConcept = &models.CodeableConcept{}
+		if !convert(value, req.CodeableConcept) || len(req.CodeableConcept.Coding) == 0 {
+			return
+		}
+	default:
+		return
+	}
+
+	result, err := r.v.Terminology.ValidateCode(r.ctx, req)
+	if err != nil {
+		r.issue("warning", "not-supported", loc, "Unable to check the binding to %s: %v", b.ValueSet, err)
+		return
+	}
+	if !result.Result {
+		message := result.Message
+		if message == "" {
+			message = "The code is not in the value set " + b.ValueSet
+		}
+		r.issue("error", "code-invalid", loc, "%s", message)
+	}
+}
+
+// convert decodes a JSON tree into a model type
+func convert(value, target interface{}) bool {
+	data, err := json.Marshal(value)
+	return err == nil && json.Unmarshal(data, target) == nil
+}
+
+// checkInvariants evaluates the element's constraints on a value
+func (r *run) checkInvariants(e *models.ElementDefinition, resource map[string]interface{}, value interface{}, loc string) {
+	if r.v.Invariants == nil {
+		return
+	}
+	for _, c := range e.Constraint {
+		if c.Expression == "" {
+			continue
+		}
+		ok, err := r.v.Invariants.EvaluateInvariant(c.Expression, resource, value)
+		if err != nil {
+			r.issue("warning", "processing", loc, "Unable to evaluate invariant %s: %v", c.Key, err)
+			continue
+		}
+		if !ok {
+			severity := c.Severity
+			if severity == "" {
+				severity = "error"
+			}
+			r.issue(severity, "invariant", loc, "Constraint failed: %s: %s", c.Key, c.Human)
+		}
+	}
+}
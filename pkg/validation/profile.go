@@ -0,0 +1,105 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+// profile is a StructureDefinition's snapshot indexed by element id
+type profile struct {
+	*models.StructureDefinition
+
+	root     *models.ElementDefinition
+	byID     map[string]*models.ElementDefinition
+	children map[string][]*elementGroup
+}
+
+// elementGroup is an element with the slices defined on it
+type elementGroup struct {
+	base   *models.ElementDefinition
+	slices []*models.ElementDefinition
+}
+
+// newProfile indexes the snapshot of sd
+func newProfile(sd *models.StructureDefinition) (*profile, error) {
+	if sd.Snapshot == nil || len(sd.Snapshot.Element) == 0 {
+		return nil, fmt.Errorf("StructureDefinition %s has no snapshot", sd.URL)
+	}
+
+	p := &profile{
+		StructureDefinition: sd,
+		root:                &sd.Snapshot.Element[0],
+		byID:                make(map[string]*models.ElementDefinition),
+		children:            make(map[string][]*elementGroup),
+	}
+	for i := range sd.Snapshot.Element {
+		e := &sd.Snapshot.Element[i]
+		id := elementID(e)
+		p.byID[id] = e
+
+		dot := strings.LastIndex(id, ".")
+		if dot < 0 {
+			continue
+		}
+		parent, segment := id[:dot], id[dot+1:]
+		name, _, sliced := strings.Cut(segment, ":")
+
+		groups := p.children[parent]
+		if !sliced {
+			p.children[parent] = append(groups, &elementGroup{base: e})
+			continue
+		}
+		for _, g := range groups {
+			if g.base.Name() == name {
+				g.slices = append(g.slices, e)
+				break
+			}
+		}
+	}
+	return p, nil
+}
+
+// elementID returns the element's id, falling back to its path for
+// definitions that omit ids
+func elementID(e *models.ElementDefinition) string {
+	if e.ID != "" {
+		return e.ID
+	}
+	return e.Path
+}
+
+// childrenOf returns the element groups below the element with the given id,
+// following a contentReference if the element has no children of its own
+func (p *profile) childrenOf(e *models.ElementDefinition) []*elementGroup {
+	if groups := p.children[elementID(e)]; len(groups) > 0 {
+		return groups
+	}
+	if ref := e.ContentReference; ref != "" {
+		if target, ok := p.byID[ref[strings.Index(ref, "#")+1:]]; ok {
+			return p.children[elementID(target)]
+		}
+	}
+	return nil
+}
+
+// maxOccurs returns the element's maximum cardinality, or -1 for unbounded
+func maxOccurs(e *models.ElementDefinition) int {
+	if e.Max == "" || e.Max == "*" {
+		return -1
+	}
+	var n int
+	if _, err := fmt.Sscan(e.Max, &n); err != nil {
+		return -1
+	}
+	return n
+}
+
+// minOccurs returns the element's minimum cardinality
+func minOccurs(e *models.ElementDefinition) int {
+	if e.Min == nil {
+		return 0
+	}
+	return *e.Min
+}
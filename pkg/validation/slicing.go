@@ -0,0 +1,222 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+)
+
+// checkSlices assigns the values of a sliced element to its slices, checks
+// each slice's cardinality and validates the values against their slice
+func (r *run) checkSlices(p *profile, g *elementGroup, resource map[string]interface{}, members []member, loc string) {
+	counts := make([]int, len(g.slices))
+	closed := g.base.Slicing != nil && g.base.Slicing.Rules == "closed"
+
+	for _, m := range members {
+		for i, value := range m.values {
+			if value == nil {
+				continue
+			}
+			matched := -1
+			for s, slice := range g.slices {
+				if r.matchesSlice(p, g.base, slice, m.typeCode, value) {
+					matched = s
+					break
+				}
+			}
+			if matched < 0 {
+				if closed {
+					r.issue("error", "structure", m.location(loc, i), "%s: value does not match any slice and the slicing is closed", g.base.Path)
+				}
+				continue
+			}
+			counts[matched]++
+			r.validateValue(p, g.slices[matched], m.typeCode, resource, value, m.location(loc, i))
+		}
+	}
+
+	name := strings.TrimSuffix(g.base.Name(), "[x]")
+	for s, slice := range g.slices {
+		if min := minOccurs(slice); counts[s] < min {
+			r.issue("error", "required", loc+"."+name, "Slice %s:%s: minimum required = %d, but only found %d", slice.Path, slice.SliceName, min, counts[s])
+		}
+		if max := maxOccurs(slice); max >= 0 && counts[s] > max {
+			r.issue("error", "structure", loc+"."+name, "Slice %s:%s: maximum allowed = %d, but found %d", slice.Path, slice.SliceName, max, counts[s])
+		}
+	}
+}
+
+// matchesSlice reports whether a value satisfies all the discriminators of the slicing
+func (r *run) matchesSlice(p *profile, base, slice *models.ElementDefinition, typeCode string, value interface{}) bool {
+	if base.Slicing == nil || len(base.Slicing.Discriminator) == 0 {
+		return false
+	}
+	for _, d := range base.Slicing.Discriminator {
+		if !r.matchesDiscriminator(p, slice, d, typeCode, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesDiscriminator reports whether a value satisfies a single discriminator of a slice
+func (r *run) matchesDiscriminator(p *profile, slice *models.ElementDefinition, d models.ElementDefinitionDiscriminator, typeCode string, value interface{}) bool {
+	targets, ok := resolvePath(value, d.Path)
+	if !ok {
+		return false
+	}
+
+	switch d.Type {
+	case "value", "pattern":
+		expected, isPattern, ok := r.discriminatorValue(p, slice, d.Path)
+		if !ok {
+			return false
+		}
+		for _, target := range targets {
+			if isPattern && matchesPattern(target, expected) || !isPattern && reflect.DeepEqual(target, expected) {
+				return true
+			}
+		}
+		return false
+
+	case "exists":
+		e := p.byID[elementID(slice)+"."+d.Path]
+		if e == nil {
+			return false
+		}
+		switch {
+		case maxOccurs(e) == 0:
+			return len(targets) == 0
+		case minOccurs(e) > 0:
+			return len(targets) > 0
+		default:
+			return true
+		}
+
+	case "type":
+		e := slice
+		if d.Path != "$this" {
+			e = p.byID[elementID(slice)+"."+d.Path]
+		}
+		if e == nil {
+			return false
+		}
+		for _, target := range targets {
+			actual := typeCode
+			if obj, ok := target.(map[string]interface{}); ok && obj["resourceType"] != nil {
+				actual, _ = obj["resourceType"].(string)
+			}
+			for _, t := range e.Type {
+				if t.Code == actual {
+					return true
+				}
+			}
+		}
+		return false
+
+	default:
+		// Profile discriminators need the target validated against each
+		// slice's profile, which is not supported
+		return false
+	}
+}
+
+// discriminatorValue returns the fixed or pattern value a slice requires at
+// path. Extension slices identified by url are matched against the URL of
+// the extension's definition when the snapshot does not fix it.
+func (r *run) discriminatorValue(p *profile, slice *models.ElementDefinition, path string) (interface{}, bool, bool) {
+	e := slice
+	if path != "$this" {
+		e = p.byID[elementID(slice)+"."+path]
+	}
+	if e != nil {
+		if raw, _ := e.Fixed(); raw != nil {
+			return decodeValue(raw), false, true
+		}
+		if raw, _ := e.Pattern(); raw != nil {
+			return decodeValue(raw), true, true
+		}
+	}
+	if path == "url" {
+		for _, t := range slice.Type {
+			if t.Code == "Extension" && len(t.Profile) > 0 {
+				url := t.Profile[0]
+				if i := strings.Index(url, "|"); i >= 0 {
+					url = url[:i]
+				}
+				return url, false, true
+			}
+		}
+	}
+	return nil, false, false
+}
+
+// resolvePath returns the values at a discriminator path below value. It
+// supports member names, $this and extension('url'). It reports false for
+// paths it cannot follow, such as those using resolve().
+func resolvePath(value interface{}, path string) ([]interface{}, bool) {
+	current := []interface{}{value}
+	if path == "$this" {
+		return current, true
+	}
+
+	for _, segment := range splitPath(path) {
+		var next []interface{}
+		url, isExtension := extensionURL(segment)
+		if !isExtension && strings.Contains(segment, "(") {
+			return nil, false
+		}
+		for _, item := range current {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key := segment
+			if isExtension {
+				key = "extension"
+			}
+			children, _ := values(obj, key)
+			for _, child := range children {
+				if isExtension {
+					ext, _ := child.(map[string]interface{})
+					if ext["url"] != url {
+						continue
+					}
+				}
+				next = append(next, child)
+			}
+		}
+		current = next
+	}
+	return current, true
+}
+
+// splitPath splits a path on the dots that are not inside parentheses
+func splitPath(path string) []string {
+	var segments []string
+	depth, start := 0, 0
+	for i, c := range path {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '.':
+			if depth == 0 {
+				segments = append(segments, path[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(segments, path[start:])
+}
+
+// extensionURL returns the URL of an extension('url') path segment
+func extensionURL(segment string) (string, bool) {
+	if !strings.HasPrefix(segment, "extension(") || !strings.HasSuffix(segment, ")") {
+		return "", false
+	}
+	arg := strings.TrimSuffix(strings.TrimPrefix(segment, "extension("), ")")
+	return strings.Trim(arg, `'"`), true
+}
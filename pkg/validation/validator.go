@@ -0,0 +1,290 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/terminology"
+)
+
+// ErrNoDefinition is returned when no StructureDefinition is loaded for the
+// resource's type or any of the profiles it is validated against
+var ErrNoDefinition = errors.New("no StructureDefinition loaded")
+
+// InvariantEvaluator evaluates the FHIRPath expressions of invariants
+type InvariantEvaluator interface {
+	// EvaluateInvariant evaluates expression with node as its context,
+	// within resource, and reports whether the invariant holds. node and
+	// resource are decoded JSON, as produced by encoding/json with UseNumber.
+	EvaluateInvariant(expression string, resource, node interface{}) (bool, error)
+}
+
+// Validator checks resources against StructureDefinitions: the base
+// definition of the resource's type, the profiles in its meta.profile, and
+// any profiles given to Validate. It checks cardinality, types, fixed and
+// pattern values, slicing, required bindings and invariants, and works
+// entirely from the definitions loaded into it.
+type Validator struct {
+	// Terminology checks codes in elements with required bindings. Bindings
+	// are not checked when it is nil.
+	Terminology terminology.Service
+
+	// Invariants evaluates constraints. Invariants are not checked when it is nil.
+	Invariants InvariantEvaluator
+
+	mu       sync.RWMutex
+	profiles map[string]*profile
+}
+
+// NewValidator creates a validator with no definitions loaded
+func NewValidator() *Validator {
+	return &Validator{profiles: make(map[string]*profile)}
+}
+
+// AddDefinition adds a StructureDefinition, which must have a snapshot
+func (v *Validator) AddDefinition(sd *models.StructureDefinition) error {
+	p, err := newProfile(sd)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.profiles[sd.URL] = p
+	if sd.Version != "" {
+		v.profiles[sd.URL+"|"+sd.Version] = p
+	}
+	if sd.Derivation != "constraint" {
+		v.profiles[sd.Type] = p
+	}
+	return nil
+}
+
+// Load adds the StructureDefinitions in a JSON document, which may be a
+// single resource or a Bundle of them. Other resources and definitions
+// without a snapshot are ignored.
+func (v *Validator) Load(data []byte) error {
+	var typeHolder struct {
+		ResourceType models.ResourceType `json:"resourceType"`
+	}
+	if err := json.Unmarshal(data, &typeHolder); err != nil {
+		return fmt.Errorf("failed to determine resource type: %w", err)
+	}
+
+	switch typeHolder.ResourceType {
+	case models.ResourceTypeStructureDefinition:
+		sd := models.NewStructureDefinition()
+		if err := json.Unmarshal(data, sd); err != nil {
+			return fmt.Errorf("failed to unmarshal StructureDefinition: %w", err)
+		}
+		if sd.Snapshot != nil {
+			return v.AddDefinition(sd)
+		}
+	case models.ResourceTypeBundle:
+		var bundle models.Bundle
+		if err := json.Unmarshal(data, &bundle); err != nil {
+			return fmt.Errorf("failed to unmarshal Bundle: %w", err)
+		}
+		for i, entry := range bundle.Entry {
+			if entry.Resource == nil {
+				continue
+			}
+			if err := v.Load(entry.Resource); err != nil {
+				return fmt.Errorf("bundle entry %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadFile adds the StructureDefinitions in a JSON file
+func (v *Validator) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := v.Load(data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadDir adds the StructureDefinitions in every .json file in dir, such as
+// the package directory of an unpacked FHIR package
+func (v *Validator) LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	for _, path := range paths {
+		if err := v.LoadFile(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// profile returns the definition with the given canonical URL or base type name
+func (v *Validator) profile(key string) *profile {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if p, ok := v.profiles[key]; ok {
+		return p
+	}
+	if i := strings.LastIndex(key, "|"); i >= 0 {
+		return v.profiles[key[:i]]
+	}
+	return nil
+}
+
+// Validate checks resource against its type's base definition, its
+// meta.profile and profiles, and returns the issues found. The outcome has
+// a single information issue if there were none. An error is returned only
+// if the resource could not be validated at all.
+func (v *Validator) Validate(ctx context.Context, resource models.Resource, profiles ...string) (*models.OperationOutcome, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource: %w", err)
+	}
+	return v.ValidateJSON(ctx, data, profiles...)
+}
+
+// ValidateJSON checks a resource given as JSON, like Validate
+func (v *Validator) ValidateJSON(ctx context.Context, data []byte, profiles ...string) (*models.OperationOutcome, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, fmt.Errorf("failed to decode resource: %w", err)
+	}
+	resource, ok := tree.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("resource is not a JSON object")
+	}
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" {
+		return nil, fmt.Errorf("resource has no resourceType")
+	}
+
+	r := &run{v: v, ctx: ctx}
+	checked := 0
+	for _, key := range append([]string{resourceType}, append(metaProfiles(resource), profiles...)...) {
+		p := v.profile(key)
+		if p == nil {
+			if key != resourceType {
+				r.issue("warning", "not-supported", resourceType, "Profile %s is not loaded", key)
+			}
+			continue
+		}
+		if p.Type != resourceType {
+			r.issue("error", "structure", resourceType, "Profile %s is for %s, not %s", key, p.Type, resourceType)
+			continue
+		}
+		r.validateResource(p, resource, resourceType)
+		checked++
+	}
+	if checked == 0 {
+		return nil, fmt.Errorf("%w for %s", ErrNoDefinition, resourceType)
+	}
+
+	outcome := models.NewOperationOutcome()
+	outcome.Issue = r.result()
+	return outcome, nil
+}
+
+// metaProfiles returns the profiles a resource claims in meta.profile
+func metaProfiles(resource map[string]interface{}) []string {
+	meta, _ := resource["meta"].(map[string]interface{})
+	list, _ := meta["profile"].([]interface{})
+	var profiles []string
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			profiles = append(profiles, s)
+		}
+	}
+	return profiles
+}
+
+// run holds the state of a single validation
+type run struct {
+	v      *Validator
+	ctx    context.Context
+	issues []models.OperationOutcomeIssue
+}
+
+// issue records an issue at a FHIRPath location
+func (r *run) issue(severity, code, location, format string, args ...interface{}) {
+	r.issues = append(r.issues, models.OperationOutcomeIssue{
+		Severity:    severity,
+		Code:        code,
+		Diagnostics: fmt.Sprintf(format, args...),
+		Expression:  []string{location},
+	})
+}
+
+// result returns the issues found, without duplicates reported by more than
+// one profile, most severe first
+func (r *run) result() []models.OperationOutcomeIssue {
+	seen := make(map[string]bool)
+	var issues []models.OperationOutcomeIssue
+	for _, issue := range r.issues {
+		key := issue.Severity + "|" + issue.Code + "|" + issue.Expression[0] + "|" + issue.Diagnostics
+		if !seen[key] {
+			seen[key] = true
+			issues = append(issues, issue)
+		}
+	}
+	if len(issues) == 0 {
+		return []models.OperationOutcomeIssue{{Severity: "information", Code: "informational", Diagnostics: "No issues detected"}}
+	}
+
+	rank := map[string]int{"fatal": 0, "error": 1, "warning": 2, "information": 3}
+	sort.SliceStable(issues, func(i, j int) bool { return rank[issues[i].Severity] < rank[issues[j].Severity] })
+	return issues
+}
+
+// Error is returned by the Validate methods of the versioned models when
+// validation finds errors
+type Error struct {
+	Outcome *models.OperationOutcome
+}
+
+func (e *Error) Error() string {
+	var errs []string
+	for _, issue := range e.Outcome.Issue {
+		if issue.Severity == "error" || issue.Severity == "fatal" {
+			errs = append(errs, issue.Message())
+		}
+	}
+	msg := "validation failed: " + errs[0]
+	if len(errs) > 1 {
+		msg += fmt.Sprintf(" (and %d more errors)", len(errs)-1)
+	}
+	return msg
+}
+
+// Check validates resource with v and returns an *Error if it has errors.
+// It returns ErrNoDefinition if v is nil, so that a missing validator is not
+// mistaken for a valid resource.
+func Check(ctx context.Context, v *Validator, resource models.Resource) error {
+	if v == nil {
+		return fmt.Errorf("%w: no validator", ErrNoDefinition)
+	}
+	outcome, err := v.Validate(ctx, resource)
+	if err != nil {
+		return err
+	}
+	if outcome.HasErrors() {
+		return &Error{Outcome: outcome}
+	}
+	return nil
+}
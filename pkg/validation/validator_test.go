@@ -0,0 +1,226 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/terminology"
+)
+
+const testPatientProfile = "http://example.org/fhir/StructureDefinition/test-patient"
+
+// fakeInvariants evaluates invariants by looking up their expression
+type fakeInvariants map[string]func(node interface{}) bool
+
+func (f fakeInvariants) EvaluateInvariant(expression string, resource, node interface{}) (bool, error) {
+	eval, ok := f[expression]
+	if !ok {
+		return false, errors.New("unsupported expression")
+	}
+	return eval(node), nil
+}
+
+func newTestValidator(t *testing.T) *Validator {
+	v := NewValidator()
+	if err := v.LoadDir("testdata"); err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+	local := terminology.NewLocal()
+	if err := local.LoadDir("testdata"); err != nil {
+		t.Fatalf("terminology LoadDir failed: %v", err)
+	}
+	v.Terminology = local
+	return v
+}
+
+func validate(t *testing.T, v *Validator, data string, profiles ...string) *models.OperationOutcome {
+	outcome, err := v.ValidateJSON(context.Background(), []byte(data), profiles...)
+	if err != nil {
+		t.Fatalf("ValidateJSON failed: %v", err)
+	}
+	return outcome
+}
+
+// findIssue returns the first issue at location whose diagnostics contain text
+func findIssue(outcome *models.OperationOutcome, location, text string) *models.OperationOutcomeIssue {
+	for i, issue := range outcome.Issue {
+		if len(issue.Expression) > 0 && issue.Expression[0] == location && strings.Contains(issue.Diagnostics, text) {
+			return &outcome.Issue[i]
+		}
+	}
+	return nil
+}
+
+func TestValidateValidPatient(t *testing.T) {
+	v := newTestValidator(t)
+	outcome := validate(t, v, `{
+		"resourceType": "Patient",
+		"meta": {"profile": ["`+testPatientProfile+`"]},
+		"extension": [{"url": "http://example.org/fhir/StructureDefinition/birthsex", "valueCode": "F"}],
+		"identifier": [
+			{"system": "http://example.org/mrn", "value": "12345"},
+			{"system": "http://example.org/other", "value": "A"}
+		],
+		"active": true,
+		"name": [{"family": "Chalmers", "given": ["Peter", "James"]}],
+		"gender": "female",
+		"birthDate": "1974-12-25",
+		"deceasedBoolean": false,
+		"managingOrganization": {"reference": "Organization/1"}
+	}`)
+
+	if outcome.HasErrors() || len(outcome.Issue) != 1 || outcome.Issue[0].Severity != "information" {
+		t.Errorf("Expected no issues, got %+v", outcome.Issue)
+	}
+}
+
+func TestValidateStructure(t *testing.T) {
+	v := newTestValidator(t)
+	outcome := validate(t, v, `{
+		"resourceType": "Patient",
+		"active": "yes",
+		"name": [{"family": ["Chalmers"], "given": ["Peter"], "nickname": "Pete"}],
+		"birthDate": "25/12/1974",
+		"deceasedString": "no",
+		"managingOrganization": {"reference": "Patient/1"},
+		"colour": "blue"
+	}`)
+
+	tests := []struct {
+		location string
+		text     string
+	}{
+		{"Patient.active", "boolean"},
+		{"Patient.name[0].family", "not an array"},
+		{"Patient.name[0]", `Unrecognized element "nickname"`},
+		{"Patient.birthDate", "date"},
+		{"Patient.deceased", "Type String is not allowed"},
+		{"Patient.managingOrganization", "Patient"},
+		{"Patient", `Unrecognized element "colour"`},
+	}
+	for _, tt := range tests {
+		if issue := findIssue(outcome, tt.location, tt.text); issue == nil || issue.Severity != "error" {
+			t.Errorf("Expected an error at %s mentioning %q, got %+v", tt.location, tt.text, outcome.Issue)
+		}
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	v := newTestValidator(t)
+	outcome := validate(t, v, `{
+		"resourceType": "Patient",
+		"extension": [
+			{"url": "http://example.org/fhir/StructureDefinition/birthsex", "valueCode": "F"},
+			{"url": "http://example.org/fhir/StructureDefinition/birthsex", "valueString": "M"}
+		],
+		"identifier": [{"system": "http://example.org/other", "value": "A"}]
+	}`, testPatientProfile)
+
+	tests := []struct {
+		location string
+		text     string
+	}{
+		{"Patient.name", "minimum required = 1"},
+		{"Patient.identifier", "Slice Patient.identifier:mrn: minimum required = 1"},
+		{"Patient.extension", "Slice Patient.extension:birthsex: maximum allowed = 1"},
+		{"Patient.extension[1].value", "minimum required = 1"},
+	}
+	for _, tt := range tests {
+		if issue := findIssue(outcome, tt.location, tt.text); issue == nil {
+			t.Errorf("Expected an issue at %s mentioning %q, got %+v", tt.location, tt.text, outcome.Issue)
+		}
+	}
+
+	outcome = validate(t, v, `{"resourceType": "Patient", "name": [{"family": "Chalmers"}]}`, "http://example.org/fhir/StructureDefinition/missing")
+	if issue := findIssue(outcome, "Patient", "is not loaded"); issue == nil || issue.Severity != "warning" {
+		t.Errorf("Expected a warning for the missing profile, got %+v", outcome.Issue)
+	}
+	if outcome.HasErrors() {
+		t.Errorf("Expected no errors against the base definition, got %+v", outcome.Issue)
+	}
+}
+
+func TestValidateClosedSlicing(t *testing.T) {
+	v := newTestValidator(t)
+	p := v.profile(testPatientProfile)
+	for _, g := range p.children["Patient"] {
+		if g.base.Name() == "identifier" {
+			g.base.Slicing.Rules = "closed"
+		}
+	}
+
+	outcome := validate(t, v, `{
+		"resourceType": "Patient",
+		"identifier": [
+			{"system": "http://example.org/mrn", "value": "12345"},
+			{"system": "http://example.org/other", "value": "A"}
+		],
+		"name": [{"family": "Chalmers"}]
+	}`, testPatientProfile)
+
+	if issue := findIssue(outcome, "Patient.identifier[1]", "does not match any slice"); issue == nil {
+		t.Errorf("Expected the unsliced identifier to be rejected, got %+v", outcome.Issue)
+	}
+	if issue := findIssue(outcome, "Patient.identifier[0]", ""); issue != nil {
+		t.Errorf("Expected the MRN to match its slice, got %+v", issue)
+	}
+}
+
+func TestValidateBinding(t *testing.T) {
+	v := newTestValidator(t)
+	outcome := validate(t, v, `{"resourceType": "Patient", "gender": "woman"}`)
+	if issue := findIssue(outcome, "Patient.gender", "woman"); issue == nil || issue.Code != "code-invalid" {
+		t.Errorf("Expected an invalid code error, got %+v", outcome.Issue)
+	}
+
+	v.Terminology = nil
+	outcome = validate(t, v, `{"resourceType": "Patient", "gender": "woman"}`)
+	if outcome.HasErrors() {
+		t.Errorf("Expected bindings to be skipped without a terminology service, got %+v", outcome.Issue)
+	}
+}
+
+func TestValidateInvariants(t *testing.T) {
+	v := newTestValidator(t)
+	v.Invariants = fakeInvariants{
+		"name.exists() or identifier.exists()": func(node interface{}) bool {
+			obj := node.(map[string]interface{})
+			return obj["name"] != nil || obj["identifier"] != nil
+		},
+	}
+
+	outcome := validate(t, v, `{"resourceType": "Patient", "active": true}`)
+	if issue := findIssue(outcome, "Patient", "pat-1"); issue == nil || issue.Code != "invariant" {
+		t.Errorf("Expected pat-1 to fail, got %+v", outcome.Issue)
+	}
+
+	outcome = validate(t, v, `{"resourceType": "Patient", "name": [{"family": "Chalmers"}]}`)
+	if outcome.HasErrors() {
+		t.Errorf("Expected pat-1 to hold, got %+v", outcome.Issue)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	patient := models.NewPatient()
+	patient.Gender = "woman"
+
+	if err := Check(context.Background(), nil, patient); !errors.Is(err, ErrNoDefinition) {
+		t.Errorf("Expected ErrNoDefinition for a nil validator, got %v", err)
+	}
+
+	err := Check(context.Background(), newTestValidator(t), patient)
+	var verr *Error
+	if !errors.As(err, &verr) || !verr.Outcome.HasErrors() {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "woman") {
+		t.Errorf("Expected the error to describe the invalid code, got %q", err.Error())
+	}
+
+	if _, err := NewValidator().ValidateJSON(context.Background(), []byte(`{"resourceType": "Patient"}`)); !errors.Is(err, ErrNoDefinition) {
+		t.Errorf("Expected ErrNoDefinition, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package version
+
+import "sort"
+
+// Catalog lists what a FHIR version defines. The catalogs are generated
+// from the versions' core packages.
+type Catalog struct {
+	// FHIRVersion is the version of the specification, e.g. 4.0.1
+	FHIRVersion string
+
+	// ResourceTypes and DataTypes list the concrete resource types and
+	// datatypes, sorted
+	ResourceTypes []string
+	DataTypes     []string
+
+	// SearchParameters holds the search parameters of each resource type,
+	// sorted by code. Those under Resource and DomainResource apply to
+	// every type.
+	SearchParameters map[string][]SearchParameter
+
+	// Interactions lists the RESTful interaction codes, e.g. read and
+	// search-type
+	Interactions []string
+}
+
+// SearchParameter is a search parameter defined by a FHIR version
+type SearchParameter struct {
+	// Code is the name used in URLs, e.g. birthdate
+	Code string
+
+	// Type is the parameter's type, e.g. token or reference
+	Type string
+}
+
+// HasResourceType reports whether the version defines a resource type
+func (c *Catalog) HasResourceType(resourceType string) bool {
+	return contains(c.ResourceTypes, resourceType)
+}
+
+// HasDataType reports whether the version defines a datatype
+func (c *Catalog) HasDataType(dataType string) bool {
+	return contains(c.DataTypes, dataType)
+}
+
+// HasInteraction reports whether the version defines a RESTful interaction
+func (c *Catalog) HasInteraction(code string) bool {
+	for _, interaction := range c.Interactions {
+		if interaction == code {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchParameter returns the search parameter with a code that applies to
+// a resource type, including those of every type such as _id
+func (c *Catalog) SearchParameter(resourceType, code string) (SearchParameter, bool) {
+	for _, base := range []string{resourceType, "DomainResource", "Resource"} {
+		params := c.SearchParameters[base]
+		i := sort.Search(len(params), func(i int) bool { return params[i].Code >= code })
+		if i < len(params) && params[i].Code == code {
+			return params[i], true
+		}
+	}
+	return SearchParameter{}, false
+}
+
+// contains reports whether a sorted list holds s
+func contains(sorted []string, s string) bool {
+	i := sort.SearchStrings(sorted, s)
+	return i < len(sorted) && sorted[i] == s
+}
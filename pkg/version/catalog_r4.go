@@ -0,0 +1,101 @@
+// Code generated by cmd/generator. DO NOT EDIT.
+
+package version
+
+// r4Catalog lists what FHIR 4.0.1 defines
+var r4Catalog = &Catalog{
+	FHIRVersion: "4.0.1",
+	ResourceTypes: []string{
+		"Account", "ActivityDefinition", "AdverseEvent", "AllergyIntolerance", "Appointment",
+		"AppointmentResponse", "AuditEvent", "Basic", "Binary", "BiologicallyDerivedProduct",
+		"BodyStructure", "Bundle", "CapabilityStatement", "CarePlan", "CareTeam", "CatalogEntry",
+		"ChargeItem", "ChargeItemDefinition", "Claim", "ClaimResponse", "ClinicalImpression",
+		"CodeSystem", "Communication", "CommunicationRequest", "CompartmentDefinition",
+		"Composition", "ConceptMap", "Condition", "Consent", "Contract", "Coverage",
+		"CoverageEligibilityRequest", "CoverageEligibilityResponse", "DetectedIssue", "Device",
+		"DeviceDefinition", "DeviceMetric", "DeviceRequest", "DeviceUseStatement",
+		"DiagnosticReport", "DocumentManifest", "DocumentReference", "EffectEvidenceSynthesis",
+		"Encounter", "Endpoint", "EnrollmentRequest", "EnrollmentResponse", "EpisodeOfCare",
+		"EventDefinition", "Evidence", "EvidenceVariable", "ExampleScenario",
+		"ExplanationOfBenefit", "FamilyMemberHistory", "Flag", "Goal", "GraphDefinition", "Group",
+		"GuidanceResponse", "HealthcareService", "ImagingStudy", "Immunization",
+		"ImmunizationEvaluation", "ImmunizationRecommendation", "ImplementationGuide",
+		"InsurancePlan", "Invoice", "Library", "Linkage", "List", "Location", "Measure",
+		"MeasureReport", "Media", "Medication", "MedicationAdministration", "MedicationDispense",
+		"MedicationKnowledge", "MedicationRequest", "MedicationStatement", "MedicinalProduct",
+		"MedicinalProductAuthorization", "MedicinalProductContraindication",
+		"MedicinalProductIndication", "MedicinalProductIngredient", "MedicinalProductInteraction",
+		"MedicinalProductManufactured", "MedicinalProductPackaged",
+		"MedicinalProductPharmaceutical", "MedicinalProductUndesirableEffect", "MessageDefinition",
+		"MessageHeader", "MolecularSequence", "NamingSystem", "NutritionOrder", "Observation",
+		"ObservationDefinition", "OperationDefinition", "OperationOutcome", "Organization",
+		"OrganizationAffiliation", "Parameters", "Patient", "PaymentNotice",
+		"PaymentReconciliation", "Person", "PlanDefinition", "Practitioner", "PractitionerRole",
+		"Procedure", "Provenance", "Questionnaire", "QuestionnaireResponse", "RelatedPerson",
+		"RequestGroup", "ResearchDefinition", "ResearchElementDefinition", "ResearchStudy",
+		"ResearchSubject", "RiskAssessment", "RiskEvidenceSynthesis", "Schedule",
+		"SearchParameter", "ServiceRequest", "Slot", "Specimen", "SpecimenDefinition",
+		"StructureDefinition", "StructureMap", "Subscription", "Substance", "SubstanceNucleicAcid",
+		"SubstancePolymer", "SubstanceProtein", "SubstanceReferenceInformation",
+		"SubstanceSourceMaterial", "SubstanceSpecification", "SupplyDelivery", "SupplyRequest",
+		"Task", "TerminologyCapabilities", "TestReport", "TestScript", "ValueSet",
+		"VerificationResult", "VisionPrescription",
+	},
+	DataTypes: []string{
+		"Address", "Age", "Annotation", "Attachment", "BackboneElement", "CodeableConcept",
+		"Coding", "ContactDetail", "ContactPoint", "Contributor", "Count", "DataRequirement",
+		"Distance", "Dosage", "Duration", "Element", "ElementDefinition", "Expression",
+		"Extension", "HumanName", "Identifier", "MarketingStatus", "Meta", "Money", "Narrative",
+		"ParameterDefinition", "Period", "Population", "ProdCharacteristic", "ProductShelfLife",
+		"Quantity", "Range", "Ratio", "Reference", "RelatedArtifact", "SampledData", "Signature",
+		"SubstanceAmount", "Timing", "TriggerDefinition", "UsageContext", "base64Binary",
+		"boolean", "canonical", "code", "date", "dateTime", "decimal", "id", "instant", "integer",
+		"markdown", "oid", "positiveInt", "string", "time", "unsignedInt", "uri", "url", "uuid",
+		"xhtml",
+	},
+	SearchParameters: map[string][]SearchParameter{
+		"DomainResource": {
+			{Code: "_text", Type: "string"},
+		},
+		"Patient": {
+			{Code: "active", Type: "token"},
+			{Code: "address", Type: "string"},
+			{Code: "address-city", Type: "string"},
+			{Code: "address-country", Type: "string"},
+			{Code: "address-postalcode", Type: "string"},
+			{Code: "address-state", Type: "string"},
+			{Code: "address-use", Type: "token"},
+			{Code: "birthdate", Type: "date"},
+			{Code: "death-date", Type: "date"},
+			{Code: "deceased", Type: "token"},
+			{Code: "email", Type: "token"},
+			{Code: "family", Type: "string"},
+			{Code: "gender", Type: "token"},
+			{Code: "general-practitioner", Type: "reference"},
+			{Code: "given", Type: "string"},
+			{Code: "identifier", Type: "token"},
+			{Code: "language", Type: "token"},
+			{Code: "link", Type: "reference"},
+			{Code: "name", Type: "string"},
+			{Code: "organization", Type: "reference"},
+			{Code: "phone", Type: "token"},
+			{Code: "phonetic", Type: "string"},
+			{Code: "telecom", Type: "token"},
+		},
+		"Resource": {
+			{Code: "_content", Type: "string"},
+			{Code: "_id", Type: "token"},
+			{Code: "_lastUpdated", Type: "date"},
+			{Code: "_profile", Type: "uri"},
+			{Code: "_query", Type: "token"},
+			{Code: "_security", Type: "token"},
+			{Code: "_source", Type: "uri"},
+			{Code: "_tag", Type: "token"},
+		},
+	},
+	Interactions: []string{
+		"read", "vread", "update", "patch", "delete", "history", "history-instance",
+		"history-type", "history-system", "create", "search", "search-type", "search-system",
+		"capabilities", "transaction", "batch", "operation",
+	},
+}
@@ -0,0 +1,99 @@
+// Code generated by cmd/generator. DO NOT EDIT.
+
+package version
+
+// r4bCatalog lists what FHIR 4.3.0 defines
+var r4bCatalog = &Catalog{
+	FHIRVersion: "4.3.0",
+	ResourceTypes: []string{
+		"Account", "ActivityDefinition", "AdministrableProductDefinition", "AdverseEvent",
+		"AllergyIntolerance", "Appointment", "AppointmentResponse", "AuditEvent", "Basic",
+		"Binary", "BiologicallyDerivedProduct", "BodyStructure", "Bundle", "CapabilityStatement",
+		"CarePlan", "CareTeam", "CatalogEntry", "ChargeItem", "ChargeItemDefinition", "Citation",
+		"Claim", "ClaimResponse", "ClinicalImpression", "ClinicalUseDefinition", "CodeSystem",
+		"Communication", "CommunicationRequest", "CompartmentDefinition", "Composition",
+		"ConceptMap", "Condition", "Consent", "Contract", "Coverage", "CoverageEligibilityRequest",
+		"CoverageEligibilityResponse", "DetectedIssue", "Device", "DeviceDefinition",
+		"DeviceMetric", "DeviceRequest", "DeviceUseStatement", "DiagnosticReport",
+		"DocumentManifest", "DocumentReference", "Encounter", "Endpoint", "EnrollmentRequest",
+		"EnrollmentResponse", "EpisodeOfCare", "EventDefinition", "Evidence", "EvidenceReport",
+		"EvidenceVariable", "ExampleScenario", "ExplanationOfBenefit", "FamilyMemberHistory",
+		"Flag", "Goal", "GraphDefinition", "Group", "GuidanceResponse", "HealthcareService",
+		"ImagingStudy", "Immunization", "ImmunizationEvaluation", "ImmunizationRecommendation",
+		"ImplementationGuide", "Ingredient", "InsurancePlan", "Invoice", "Library", "Linkage",
+		"List", "Location", "ManufacturedItemDefinition", "Measure", "MeasureReport", "Media",
+		"Medication", "MedicationAdministration", "MedicationDispense", "MedicationKnowledge",
+		"MedicationRequest", "MedicationStatement", "MedicinalProductDefinition",
+		"MessageDefinition", "MessageHeader", "MolecularSequence", "NamingSystem",
+		"NutritionOrder", "NutritionProduct", "Observation", "ObservationDefinition",
+		"OperationDefinition", "OperationOutcome", "Organization", "OrganizationAffiliation",
+		"PackagedProductDefinition", "Parameters", "Patient", "PaymentNotice",
+		"PaymentReconciliation", "Person", "PlanDefinition", "Practitioner", "PractitionerRole",
+		"Procedure", "Provenance", "Questionnaire", "QuestionnaireResponse",
+		"RegulatedAuthorization", "RelatedPerson", "RequestGroup", "ResearchDefinition",
+		"ResearchElementDefinition", "ResearchStudy", "ResearchSubject", "RiskAssessment",
+		"Schedule", "SearchParameter", "ServiceRequest", "Slot", "Specimen", "SpecimenDefinition",
+		"StructureDefinition", "StructureMap", "Subscription", "SubscriptionStatus",
+		"SubscriptionTopic", "Substance", "SubstanceDefinition", "SubstanceNucleicAcid",
+		"SubstancePolymer", "SubstanceProtein", "SubstanceReferenceInformation",
+		"SubstanceSourceMaterial", "SupplyDelivery", "SupplyRequest", "Task",
+		"TerminologyCapabilities", "TestReport", "TestScript", "ValueSet", "VerificationResult",
+		"VisionPrescription",
+	},
+	DataTypes: []string{
+		"Address", "Age", "Annotation", "Attachment", "BackboneElement", "CodeableConcept",
+		"CodeableReference", "Coding", "ContactDetail", "ContactPoint", "Contributor", "Count",
+		"DataRequirement", "Distance", "Dosage", "Duration", "Element", "ElementDefinition",
+		"Expression", "Extension", "HumanName", "Identifier", "MarketingStatus", "Meta", "Money",
+		"Narrative", "ParameterDefinition", "Period", "ProductShelfLife", "Quantity", "Range",
+		"Ratio", "RatioRange", "Reference", "RelatedArtifact", "SampledData", "Signature",
+		"Timing", "TriggerDefinition", "UsageContext", "base64Binary", "boolean", "canonical",
+		"code", "date", "dateTime", "decimal", "id", "instant", "integer", "markdown", "oid",
+		"positiveInt", "string", "time", "unsignedInt", "uri", "url", "uuid", "xhtml",
+	},
+	SearchParameters: map[string][]SearchParameter{
+		"DomainResource": {
+			{Code: "_text", Type: "string"},
+		},
+		"Patient": {
+			{Code: "active", Type: "token"},
+			{Code: "address", Type: "string"},
+			{Code: "address-city", Type: "string"},
+			{Code: "address-country", Type: "string"},
+			{Code: "address-postalcode", Type: "string"},
+			{Code: "address-state", Type: "string"},
+			{Code: "address-use", Type: "token"},
+			{Code: "birthdate", Type: "date"},
+			{Code: "death-date", Type: "date"},
+			{Code: "deceased", Type: "token"},
+			{Code: "email", Type: "token"},
+			{Code: "family", Type: "string"},
+			{Code: "gender", Type: "token"},
+			{Code: "general-practitioner", Type: "reference"},
+			{Code: "given", Type: "string"},
+			{Code: "identifier", Type: "token"},
+			{Code: "language", Type: "token"},
+			{Code: "link", Type: "reference"},
+			{Code: "name", Type: "string"},
+			{Code: "organization", Type: "reference"},
+			{Code: "phone", Type: "token"},
+			{Code: "phonetic", Type: "string"},
+			{Code: "telecom", Type: "token"},
+		},
+		"Resource": {
+			{Code: "_content", Type: "string"},
+			{Code: "_id", Type: "token"},
+			{Code: "_lastUpdated", Type: "date"},
+			{Code: "_profile", Type: "uri"},
+			{Code: "_query", Type: "token"},
+			{Code: "_security", Type: "token"},
+			{Code: "_source", Type: "uri"},
+			{Code: "_tag", Type: "token"},
+		},
+	},
+	Interactions: []string{
+		"read", "vread", "update", "patch", "delete", "history", "history-instance",
+		"history-type", "history-system", "create", "search", "search-type", "search-system",
+		"capabilities", "transaction", "batch", "operation",
+	},
+}
@@ -0,0 +1,108 @@
+// Code generated by cmd/generator. DO NOT EDIT.
+
+package version
+
+// r5Catalog lists what FHIR 5.0.0 defines
+var r5Catalog = &Catalog{
+	FHIRVersion: "5.0.0",
+	ResourceTypes: []string{
+		"Account", "ActivityDefinition", "ActorDefinition", "AdministrableProductDefinition",
+		"AdverseEvent", "AllergyIntolerance", "Appointment", "AppointmentResponse",
+		"ArtifactAssessment", "AuditEvent", "Basic", "Binary", "BiologicallyDerivedProduct",
+		"BiologicallyDerivedProductDispense", "BodyStructure", "Bundle", "CapabilityStatement",
+		"CarePlan", "CareTeam", "ChargeItem", "ChargeItemDefinition", "Citation", "Claim",
+		"ClaimResponse", "ClinicalImpression", "ClinicalUseDefinition", "CodeSystem",
+		"Communication", "CommunicationRequest", "CompartmentDefinition", "Composition",
+		"ConceptMap", "Condition", "ConditionDefinition", "Consent", "Contract", "Coverage",
+		"CoverageEligibilityRequest", "CoverageEligibilityResponse", "DetectedIssue", "Device",
+		"DeviceDefinition", "DeviceDispense", "DeviceMetric", "DeviceRequest", "DeviceUsage",
+		"DiagnosticReport", "DocumentReference", "Encounter", "EncounterHistory", "Endpoint",
+		"EnrollmentRequest", "EnrollmentResponse", "EpisodeOfCare", "EventDefinition", "Evidence",
+		"EvidenceReport", "EvidenceVariable", "ExampleScenario", "ExplanationOfBenefit",
+		"FamilyMemberHistory", "Flag", "FormularyItem", "GenomicStudy", "Goal", "GraphDefinition",
+		"Group", "GuidanceResponse", "HealthcareService", "ImagingSelection", "ImagingStudy",
+		"Immunization", "ImmunizationEvaluation", "ImmunizationRecommendation",
+		"ImplementationGuide", "Ingredient", "InsurancePlan", "InventoryItem", "InventoryReport",
+		"Invoice", "Library", "Linkage", "List", "Location", "ManufacturedItemDefinition",
+		"Measure", "MeasureReport", "Medication", "MedicationAdministration", "MedicationDispense",
+		"MedicationKnowledge", "MedicationRequest", "MedicationStatement",
+		"MedicinalProductDefinition", "MessageDefinition", "MessageHeader", "MolecularSequence",
+		"NamingSystem", "NutritionIntake", "NutritionOrder", "NutritionProduct", "Observation",
+		"ObservationDefinition", "OperationDefinition", "OperationOutcome", "Organization",
+		"OrganizationAffiliation", "PackagedProductDefinition", "Parameters", "Patient",
+		"PaymentNotice", "PaymentReconciliation", "Permission", "Person", "PlanDefinition",
+		"Practitioner", "PractitionerRole", "Procedure", "Provenance", "Questionnaire",
+		"QuestionnaireResponse", "RegulatedAuthorization", "RelatedPerson", "RequestOrchestration",
+		"Requirements", "ResearchStudy", "ResearchSubject", "RiskAssessment", "Schedule",
+		"SearchParameter", "ServiceRequest", "Slot", "Specimen", "SpecimenDefinition",
+		"StructureDefinition", "StructureMap", "Subscription", "SubscriptionStatus",
+		"SubscriptionTopic", "Substance", "SubstanceDefinition", "SubstanceNucleicAcid",
+		"SubstancePolymer", "SubstanceProtein", "SubstanceReferenceInformation",
+		"SubstanceSourceMaterial", "SupplyDelivery", "SupplyRequest", "Task",
+		"TerminologyCapabilities", "TestPlan", "TestReport", "TestScript", "Transport", "ValueSet",
+		"VerificationResult", "VisionPrescription",
+	},
+	DataTypes: []string{
+		"Address", "Age", "Annotation", "Attachment", "Availability", "CodeableConcept",
+		"CodeableReference", "Coding", "ContactDetail", "ContactPoint", "Contributor", "Count",
+		"DataRequirement", "Distance", "Dosage", "Duration", "ElementDefinition", "Expression",
+		"ExtendedContactDetail", "Extension", "HumanName", "Identifier", "MarketingStatus", "Meta",
+		"MonetaryComponent", "Money", "Narrative", "ParameterDefinition", "Period",
+		"ProductShelfLife", "Quantity", "Range", "Ratio", "RatioRange", "Reference",
+		"RelatedArtifact", "SampledData", "Signature", "Timing", "TriggerDefinition",
+		"UsageContext", "VirtualServiceDetail", "base64Binary", "boolean", "canonical", "code",
+		"date", "dateTime", "decimal", "id", "instant", "integer", "integer64", "markdown", "oid",
+		"positiveInt", "string", "time", "unsignedInt", "uri", "url", "uuid", "xhtml",
+	},
+	SearchParameters: map[string][]SearchParameter{
+		"DomainResource": {
+			{Code: "_text", Type: "string"},
+		},
+		"Patient": {
+			{Code: "active", Type: "token"},
+			{Code: "address", Type: "string"},
+			{Code: "address-city", Type: "string"},
+			{Code: "address-country", Type: "string"},
+			{Code: "address-postalcode", Type: "string"},
+			{Code: "address-state", Type: "string"},
+			{Code: "address-use", Type: "token"},
+			{Code: "birthdate", Type: "date"},
+			{Code: "death-date", Type: "date"},
+			{Code: "deceased", Type: "token"},
+			{Code: "email", Type: "token"},
+			{Code: "family", Type: "string"},
+			{Code: "gender", Type: "token"},
+			{Code: "general-practitioner", Type: "reference"},
+			{Code: "given", Type: "string"},
+			{Code: "identifier", Type: "token"},
+			{Code: "language", Type: "token"},
+			{Code: "link", Type: "reference"},
+			{Code: "name", Type: "string"},
+			{Code: "organization", Type: "reference"},
+			{Code: "phone", Type: "token"},
+			{Code: "phonetic", Type: "string"},
+			{Code: "telecom", Type: "token"},
+		},
+		"Resource": {
+			{Code: "_content", Type: "string"},
+			{Code: "_filter", Type: "special"},
+			{Code: "_has", Type: "special"},
+			{Code: "_id", Type: "token"},
+			{Code: "_in", Type: "reference"},
+			{Code: "_language", Type: "token"},
+			{Code: "_lastUpdated", Type: "date"},
+			{Code: "_list", Type: "special"},
+			{Code: "_profile", Type: "reference"},
+			{Code: "_query", Type: "token"},
+			{Code: "_security", Type: "token"},
+			{Code: "_source", Type: "uri"},
+			{Code: "_tag", Type: "token"},
+			{Code: "_type", Type: "special"},
+		},
+	},
+	Interactions: []string{
+		"read", "vread", "update", "patch", "delete", "history", "history-instance",
+		"history-type", "history-system", "create", "search", "search-type", "search-system",
+		"capabilities", "transaction", "batch", "operation",
+	},
+}
@@ -0,0 +1,89 @@
+// Code generated by cmd/generator. DO NOT EDIT.
+
+package version
+
+// stu3Catalog lists what FHIR 3.0.2 defines
+var stu3Catalog = &Catalog{
+	FHIRVersion: "3.0.2",
+	ResourceTypes: []string{
+		"Account", "ActivityDefinition", "AdverseEvent", "AllergyIntolerance", "Appointment",
+		"AppointmentResponse", "AuditEvent", "Basic", "Binary", "BodySite", "Bundle",
+		"CapabilityStatement", "CarePlan", "CareTeam", "ChargeItem", "Claim", "ClaimResponse",
+		"ClinicalImpression", "CodeSystem", "Communication", "CommunicationRequest",
+		"CompartmentDefinition", "Composition", "ConceptMap", "Condition", "Consent", "Contract",
+		"Coverage", "DataElement", "DetectedIssue", "Device", "DeviceComponent", "DeviceMetric",
+		"DeviceRequest", "DeviceUseStatement", "DiagnosticReport", "DocumentManifest",
+		"DocumentReference", "EligibilityRequest", "EligibilityResponse", "Encounter", "Endpoint",
+		"EnrollmentRequest", "EnrollmentResponse", "EpisodeOfCare", "ExpansionProfile",
+		"ExplanationOfBenefit", "FamilyMemberHistory", "Flag", "Goal", "GraphDefinition", "Group",
+		"GuidanceResponse", "HealthcareService", "ImagingManifest", "ImagingStudy", "Immunization",
+		"ImmunizationRecommendation", "ImplementationGuide", "Library", "Linkage", "List",
+		"Location", "Measure", "MeasureReport", "Media", "Medication", "MedicationAdministration",
+		"MedicationDispense", "MedicationRequest", "MedicationStatement", "MessageDefinition",
+		"MessageHeader", "NamingSystem", "NutritionOrder", "Observation", "OperationDefinition",
+		"OperationOutcome", "Organization", "Parameters", "Patient", "PaymentNotice",
+		"PaymentReconciliation", "Person", "PlanDefinition", "Practitioner", "PractitionerRole",
+		"Procedure", "ProcedureRequest", "ProcessRequest", "ProcessResponse", "Provenance",
+		"Questionnaire", "QuestionnaireResponse", "ReferralRequest", "RelatedPerson",
+		"RequestGroup", "ResearchStudy", "ResearchSubject", "RiskAssessment", "Schedule",
+		"SearchParameter", "Sequence", "ServiceDefinition", "Slot", "Specimen",
+		"StructureDefinition", "StructureMap", "Subscription", "Substance", "SupplyDelivery",
+		"SupplyRequest", "Task", "TestReport", "TestScript", "ValueSet", "VisionPrescription",
+	},
+	DataTypes: []string{
+		"Address", "Age", "Annotation", "Attachment", "BackboneElement", "CodeableConcept",
+		"Coding", "ContactDetail", "ContactPoint", "Contributor", "Count", "DataRequirement",
+		"Distance", "Dosage", "Duration", "Element", "ElementDefinition", "Extension", "HumanName",
+		"Identifier", "Meta", "Money", "Narrative", "ParameterDefinition", "Period", "Quantity",
+		"Range", "Ratio", "Reference", "RelatedArtifact", "SampledData", "Signature", "Timing",
+		"TriggerDefinition", "UsageContext", "base64Binary", "boolean", "code", "date", "dateTime",
+		"decimal", "id", "instant", "integer", "markdown", "oid", "positiveInt", "string", "time",
+		"unsignedInt", "uri", "uuid", "xhtml",
+	},
+	SearchParameters: map[string][]SearchParameter{
+		"DomainResource": {
+			{Code: "_text", Type: "string"},
+		},
+		"Patient": {
+			{Code: "active", Type: "token"},
+			{Code: "address", Type: "string"},
+			{Code: "address-city", Type: "string"},
+			{Code: "address-country", Type: "string"},
+			{Code: "address-postalcode", Type: "string"},
+			{Code: "address-state", Type: "string"},
+			{Code: "address-use", Type: "token"},
+			{Code: "animal-breed", Type: "token"},
+			{Code: "animal-species", Type: "token"},
+			{Code: "birthdate", Type: "date"},
+			{Code: "death-date", Type: "date"},
+			{Code: "deceased", Type: "token"},
+			{Code: "email", Type: "token"},
+			{Code: "family", Type: "string"},
+			{Code: "gender", Type: "token"},
+			{Code: "general-practitioner", Type: "reference"},
+			{Code: "given", Type: "string"},
+			{Code: "identifier", Type: "token"},
+			{Code: "language", Type: "token"},
+			{Code: "link", Type: "reference"},
+			{Code: "name", Type: "string"},
+			{Code: "organization", Type: "reference"},
+			{Code: "phone", Type: "token"},
+			{Code: "phonetic", Type: "string"},
+			{Code: "telecom", Type: "token"},
+		},
+		"Resource": {
+			{Code: "_content", Type: "string"},
+			{Code: "_id", Type: "token"},
+			{Code: "_lastUpdated", Type: "date"},
+			{Code: "_profile", Type: "uri"},
+			{Code: "_query", Type: "token"},
+			{Code: "_security", Type: "token"},
+			{Code: "_tag", Type: "token"},
+		},
+	},
+	Interactions: []string{
+		"read", "vread", "update", "patch", "delete", "history", "history-instance",
+		"history-type", "history-system", "create", "search", "search-type", "search-system",
+		"capabilities", "transaction", "batch", "operation",
+	},
+}
@@ -0,0 +1,6 @@
+package version
+
+//go:generate go run ../../cmd/generator -catalog stu3 -package hl7.fhir.r3.core#3.0.2 -output .
+//go:generate go run ../../cmd/generator -catalog r4 -package hl7.fhir.r4.core#4.0.1 -output .
+//go:generate go run ../../cmd/generator -catalog r4b -package hl7.fhir.r4b.core#4.3.0 -output .
+//go:generate go run ../../cmd/generator -catalog r5 -package hl7.fhir.r5.core#5.0.0 -output .
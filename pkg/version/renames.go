@@ -0,0 +1,57 @@
+package version
+
+// resourceRenames lists resource types that were replaced by another type
+// in a later version, with the version that replaced them. Where several
+// types were merged, the first listed is the one mapped back to.
+var resourceRenames = []struct {
+	from, to string
+	in       Version
+}{
+	{"BodySite", "BodyStructure", R4},
+	{"EligibilityRequest", "CoverageEligibilityRequest", R4},
+	{"EligibilityResponse", "CoverageEligibilityResponse", R4},
+	{"ProcedureRequest", "ServiceRequest", R4},
+	{"ReferralRequest", "ServiceRequest", R4},
+	{"Sequence", "MolecularSequence", R4},
+
+	{"MedicinalProduct", "MedicinalProductDefinition", R4B},
+	{"MedicinalProductAuthorization", "RegulatedAuthorization", R4B},
+	{"MedicinalProductIngredient", "Ingredient", R4B},
+	{"MedicinalProductManufactured", "ManufacturedItemDefinition", R4B},
+	{"MedicinalProductPackaged", "PackagedProductDefinition", R4B},
+	{"MedicinalProductPharmaceutical", "AdministrableProductDefinition", R4B},
+	{"MedicinalProductContraindication", "ClinicalUseDefinition", R4B},
+	{"MedicinalProductIndication", "ClinicalUseDefinition", R4B},
+	{"MedicinalProductInteraction", "ClinicalUseDefinition", R4B},
+	{"MedicinalProductUndesirableEffect", "ClinicalUseDefinition", R4B},
+	{"SubstanceSpecification", "SubstanceDefinition", R4B},
+
+	{"DeviceUseStatement", "DeviceUsage", R5},
+	{"Media", "DocumentReference", R5},
+	{"RequestGroup", "RequestOrchestration", R5},
+}
+
+// renamedIn returns the type a resource type was renamed to on the way to
+// version v, or renamed from if v is older than the rename. It returns ""
+// if the type was not renamed.
+func renamedIn(resourceType string, v Version) string {
+	for _, r := range resourceRenames {
+		if r.from == resourceType && index(r.in) <= index(v) {
+			return r.to
+		}
+		if r.to == resourceType && index(r.in) > index(v) {
+			return r.from
+		}
+	}
+	return ""
+}
+
+// index returns the position of a version in Versions, oldest first
+func index(v Version) int {
+	for i, known := range Versions {
+		if known == v {
+			return i
+		}
+	}
+	return -1
+}
@@ -6,10 +6,15 @@ import "fmt"
 type Version string
 
 const (
-	R4 Version = "R4"
-	R5 Version = "R5"
+	STU3 Version = "STU3"
+	R4   Version = "R4"
+	R4B  Version = "R4B"
+	R5   Version = "R5"
 )
 
+// Versions lists the supported FHIR versions, oldest first
+var Versions = []Version{STU3, R4, R4B, R5}
+
 // VersionInfo contains metadata about a FHIR version
 type VersionInfo struct {
 	Version     Version
@@ -31,12 +36,25 @@ type VersionManager interface {
 
 	// GetBaseResource returns the base resource type for version-specific resources
 	GetBaseResource(resourceType string) string
+
+	// ResourceTypes returns the resource types defined in this version
+	ResourceTypes() []string
+
+	// Catalog returns what this version defines: its resource types,
+	// datatypes, search parameters and interactions
+	Catalog() *Catalog
 }
 
 // baseVersionManager provides common functionality for version managers
 type baseVersionManager struct {
 	version Version
 	info    *VersionInfo
+	catalog *Catalog
+}
+
+// STU3Manager implements VersionManager for FHIR STU3
+type STU3Manager struct {
+	baseVersionManager
 }
 
 // R4Manager implements VersionManager for FHIR R4
@@ -44,11 +62,31 @@ type R4Manager struct {
 	baseVersionManager
 }
 
+// R4BManager implements VersionManager for FHIR R4B
+type R4BManager struct {
+	baseVersionManager
+}
+
 // R5Manager implements VersionManager for FHIR R5
 type R5Manager struct {
 	baseVersionManager
 }
 
+func newSTU3Manager() *STU3Manager {
+	return &STU3Manager{
+		baseVersionManager: baseVersionManager{
+			version: STU3,
+			info: &VersionInfo{
+				Version:     STU3,
+				APIVersion:  "3.0.2",
+				BaseURL:     "/baseDstu3",
+				Conformance: "metadata",
+			},
+			catalog: stu3Catalog,
+		},
+	}
+}
+
 func newR4Manager() *R4Manager {
 	return &R4Manager{
 		baseVersionManager: baseVersionManager{
@@ -59,6 +97,22 @@ func newR4Manager() *R4Manager {
 				BaseURL:     "/baseR4",
 				Conformance: "metadata",
 			},
+			catalog: r4Catalog,
+		},
+	}
+}
+
+func newR4BManager() *R4BManager {
+	return &R4BManager{
+		baseVersionManager: baseVersionManager{
+			version: R4B,
+			info: &VersionInfo{
+				Version:     R4B,
+				APIVersion:  "4.3.0",
+				BaseURL:     "/R4B",
+				Conformance: "metadata",
+			},
+			catalog: r4bCatalog,
 		},
 	}
 }
@@ -73,6 +127,7 @@ func newR5Manager() *R5Manager {
 				BaseURL:     "/R5",
 				Conformance: "metadata",
 			},
+			catalog: r5Catalog,
 		},
 	}
 }
@@ -87,35 +142,52 @@ func (b *baseVersionManager) GetVersionInfo() *VersionInfo {
 	return b.info
 }
 
-// IsSupported checks if a specific resource type is supported in this version
-func (r *R4Manager) IsSupported(resourceType string) bool {
-	// TODO: Implement R4-specific resource type validation
-	return true
+// ResourceTypes returns the resource types defined in this version
+func (b *baseVersionManager) ResourceTypes() []string {
+	return append([]string(nil), b.catalog.ResourceTypes...)
 }
 
-// GetBaseResource returns the base resource type for R4-specific resources
-func (r *R4Manager) GetBaseResource(resourceType string) string {
-	// TODO: Implement R4-specific resource type mapping
-	return resourceType
+// Catalog returns what this version defines
+func (b *baseVersionManager) Catalog() *Catalog {
+	return b.catalog
 }
 
 // IsSupported checks if a specific resource type is supported in this version
-func (r *R5Manager) IsSupported(resourceType string) bool {
-	// TODO: Implement R5-specific resource type validation
-	return true
+func (b *baseVersionManager) IsSupported(resourceType string) bool {
+	return b.catalog.HasResourceType(resourceType)
 }
 
-// GetBaseResource returns the base resource type for R5-specific resources
-func (r *R5Manager) GetBaseResource(resourceType string) string {
-	// TODO: Implement R5-specific resource type mapping
+// GetBaseResource returns the resource type of this version that replaced,
+// or was replaced by, a resource type of another version, e.g. DeviceUsage
+// for DeviceUseStatement in R5. Types this version defines, and those
+// without an equivalent, are returned unchanged.
+func (b *baseVersionManager) GetBaseResource(resourceType string) string {
+	if b.IsSupported(resourceType) {
+		return resourceType
+	}
+	name := resourceType
+	for range resourceRenames {
+		next := renamedIn(name, b.version)
+		if next == "" {
+			break
+		}
+		if b.IsSupported(next) {
+			return next
+		}
+		name = next
+	}
 	return resourceType
 }
 
 // NewVersionManager creates a new version manager for the specified FHIR version
 func NewVersionManager(version Version) (VersionManager, error) {
 	switch version {
+	case STU3:
+		return newSTU3Manager(), nil
 	case R4:
 		return newR4Manager(), nil
+	case R4B:
+		return newR4BManager(), nil
 	case R5:
 		return newR5Manager(), nil
 	default:
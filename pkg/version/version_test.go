@@ -0,0 +1,153 @@
+package version
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestNewVersionManager(t *testing.T) {
+	for _, tc := range []struct {
+		version    Version
+		apiVersion string
+		baseURL    string
+	}{
+		{STU3, "3.0.2", "/baseDstu3"},
+		{R4, "4.0.1", "/baseR4"},
+		{R4B, "4.3.0", "/R4B"},
+		{R5, "5.0.0", "/R5"},
+	} {
+		m, err := NewVersionManager(tc.version)
+		if err != nil {
+			t.Fatalf("NewVersionManager(%s) failed: %v", tc.version, err)
+		}
+		info := m.GetVersionInfo()
+		if m.GetVersion() != tc.version || info.APIVersion != tc.apiVersion || info.BaseURL != tc.baseURL {
+			t.Errorf("%s: unexpected version info %+v", tc.version, info)
+		}
+		if m.Catalog().FHIRVersion != tc.apiVersion {
+			t.Errorf("%s: catalog is of %s", tc.version, m.Catalog().FHIRVersion)
+		}
+		types := m.ResourceTypes()
+		if !sort.StringsAreSorted(types) || len(types) < 100 {
+			t.Errorf("%s: expected a sorted list of resource types, got %d", tc.version, len(types))
+		}
+	}
+
+	if _, err := NewVersionManager(Version("DSTU2")); err == nil {
+		t.Error("expected an error for an unsupported version")
+	}
+}
+
+func TestResourceTypes(t *testing.T) {
+	has := func(v Version, resourceType string) bool {
+		m, _ := NewVersionManager(v)
+		for _, t := range m.ResourceTypes() {
+			if t == resourceType {
+				return true
+			}
+		}
+		return false
+	}
+	for _, tc := range []struct {
+		resourceType string
+		in, notIn    []Version
+	}{
+		{"Patient", []Version{STU3, R4, R4B, R5}, nil},
+		{"ProcedureRequest", []Version{STU3}, []Version{R4, R4B, R5}},
+		{"ServiceRequest", []Version{R4, R4B, R5}, []Version{STU3}},
+		{"SubscriptionTopic", []Version{R4B, R5}, []Version{STU3, R4}},
+		{"MedicinalProduct", []Version{R4}, []Version{R4B, R5}},
+		{"DeviceUseStatement", []Version{STU3, R4, R4B}, []Version{R5}},
+		{"DeviceUsage", []Version{R5}, []Version{R4B}},
+	} {
+		for _, v := range tc.in {
+			if !has(v, tc.resourceType) {
+				t.Errorf("expected %s in %s", tc.resourceType, v)
+			}
+		}
+		for _, v := range tc.notIn {
+			if has(v, tc.resourceType) {
+				t.Errorf("expected no %s in %s", tc.resourceType, v)
+			}
+		}
+	}
+
+	// The list returned is a copy
+	m, _ := NewVersionManager(R4)
+	m.ResourceTypes()[0] = "Changed"
+	if m.ResourceTypes()[0] == "Changed" {
+		t.Error("ResourceTypes returned the manager's own list")
+	}
+}
+
+func TestIsSupported(t *testing.T) {
+	r4, _ := NewVersionManager(R4)
+	r4b, _ := NewVersionManager(R4B)
+	r5, _ := NewVersionManager(R5)
+	if !r4.IsSupported("Patient") || r4.IsSupported("SubscriptionTopic") || r4.IsSupported("NotAResource") {
+		t.Error("unexpected R4 resource types")
+	}
+	if !r4b.IsSupported("SubscriptionTopic") || r5.IsSupported("DeviceUseStatement") || !r5.IsSupported("DeviceUsage") {
+		t.Error("unexpected R4B or R5 resource types")
+	}
+}
+
+func TestGetBaseResource(t *testing.T) {
+	for _, tc := range []struct {
+		version            Version
+		resourceType, want string
+	}{
+		{R5, "Patient", "Patient"},
+		{R5, "DeviceUseStatement", "DeviceUsage"},
+		{R4, "DeviceUsage", "DeviceUseStatement"},
+		{R5, "ProcedureRequest", "ServiceRequest"},
+		{STU3, "ServiceRequest", "ProcedureRequest"},
+		{R4B, "MedicinalProductIndication", "ClinicalUseDefinition"},
+		{R4, "ClinicalUseDefinition", "MedicinalProductContraindication"},
+		{STU3, "RequestOrchestration", "RequestGroup"},
+		{R4, "SubscriptionTopic", "SubscriptionTopic"},
+	} {
+		m, _ := NewVersionManager(tc.version)
+		if got := m.GetBaseResource(tc.resourceType); got != tc.want {
+			t.Errorf("%s GetBaseResource(%s) = %s, want %s", tc.version, tc.resourceType, got, tc.want)
+		}
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	r4, _ := NewVersionManager(R4)
+	r5, _ := NewVersionManager(R5)
+	c4, c5 := r4.Catalog(), r5.Catalog()
+	if !c4.HasDataType("HumanName") || c4.HasDataType("CodeableReference") || !c5.HasDataType("CodeableReference") {
+		t.Error("unexpected datatypes")
+	}
+	if !c4.HasInteraction("search-type") || c4.HasInteraction("subscribe") {
+		t.Error("unexpected interactions")
+	}
+	if p, ok := c4.SearchParameter("Patient", "_lastUpdated"); !ok || p.Type != "date" {
+		t.Errorf("SearchParameter(Patient, _lastUpdated) = %+v, %v", p, ok)
+	}
+	if p, ok := c5.SearchParameter("Patient", "_profile"); !ok || p.Type != "reference" {
+		t.Errorf("SearchParameter(Patient, _profile) = %+v, %v", p, ok)
+	}
+	if _, ok := c4.SearchParameter("Patient", "_language"); ok {
+		t.Error("expected no _language search parameter in R4")
+	}
+
+	// Parameters of a single type
+	for _, c := range []*Catalog{c4, c5} {
+		if p, ok := c.SearchParameter("Patient", "birthdate"); !ok || p.Type != "date" {
+			t.Errorf("%s SearchParameter(Patient, birthdate) = %+v, %v", c.FHIRVersion, p, ok)
+		}
+		if _, ok := c.SearchParameter("Account", "birthdate"); ok {
+			t.Errorf("%s: expected no birthdate search parameter for Account", c.FHIRVersion)
+		}
+	}
+	stu3, _ := NewVersionManager(STU3)
+	if _, ok := stu3.Catalog().SearchParameter("Patient", "animal-species"); !ok {
+		t.Error("expected the animal-species search parameter in STU3")
+	}
+	if _, ok := c4.SearchParameter("Patient", "animal-species"); ok {
+		t.Error("expected no animal-species search parameter in R4")
+	}
+}
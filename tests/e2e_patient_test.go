@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/operations"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/search"
@@ -34,7 +35,7 @@ func TestE2E_Patient_CRUD(t *testing.T) {
 			Given:  []string{"Alice"},
 		}},
 		Gender:    "female",
-		BirthDate: ptrTime(time.Date(1990, 2, 3, 0, 0, 0, 0, time.UTC)),
+		BirthDate: ptrDate(fhir.NewDate(1990, time.February, 3)),
 	}
 	created, err := op.Create(ctx, string(models.ResourceTypePatient), patient)
 	if err != nil {
@@ -86,4 +87,4 @@ func TestE2E_Patient_CRUD(t *testing.T) {
 }
 
 func ptrBool(b bool) *bool           { return &b }
-func ptrTime(t time.Time) *time.Time { return &t }
+func ptrDate(d fhir.Date) *fhir.Date { return &d }
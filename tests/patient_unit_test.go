@@ -5,11 +5,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/eugeneosullivan/golang-fhir-client/pkg/fhir"
 	"github.com/eugeneosullivan/golang-fhir-client/pkg/models"
 )
 
 func TestPatient_JSONRoundTrip(t *testing.T) {
-	birth := time.Date(1980, 7, 15, 0, 0, 0, 0, time.UTC)
+	birth := fhir.NewDate(1980, time.July, 15)
 	patient := models.Patient{
 		Base: models.Base{
 			ResourceType: models.ResourceTypePatient,